@@ -0,0 +1,58 @@
+// Package axonet provides a package-level default client for small programs and examples that
+// do not need to pass a client.Client around. Larger programs should create their own clients
+// with client.NewClient.
+package axonet
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/jaxron/axonet/pkg/client"
+)
+
+// defaultClient is the client used by the package-level helpers, created on first use.
+var defaultClient atomic.Pointer[client.Client]
+
+// Default returns the default client, a client.NewClient with no options unless replaced with
+// SetDefault. It is safe for concurrent use.
+func Default() *client.Client {
+	if c := defaultClient.Load(); c != nil {
+		return c
+	}
+	defaultClient.CompareAndSwap(nil, client.NewClient())
+	return defaultClient.Load()
+}
+
+// SetDefault replaces the default client, typically once at startup before any request is made.
+// Requests already started keep the client they started with. A nil client restores a client
+// with no options.
+func SetDefault(c *client.Client) {
+	if c == nil {
+		c = client.NewClient()
+	}
+	defaultClient.Store(c)
+}
+
+// NewRequest creates a new request with the default client.
+func NewRequest() *client.Request {
+	return Default().NewRequest()
+}
+
+// Get sends a GET request to the URL with the default client.
+func Get(ctx context.Context, url string, opts ...client.RequestOption) (*http.Response, error) {
+	return NewRequest().
+		Method(http.MethodGet).
+		URL(url).
+		Do(ctx, opts...)
+}
+
+// Post sends a POST request to the URL with the default client, marshaling the body with the
+// codec registered for the content type, such as "application/json".
+func Post(ctx context.Context, url, contentType string, body interface{}, opts ...client.RequestOption) (*http.Response, error) {
+	return NewRequest().
+		Method(http.MethodPost).
+		URL(url).
+		BodyAs(contentType, body).
+		Do(ctx, opts...)
+}
@@ -0,0 +1,73 @@
+package axonet_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jaxron/axonet"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The default client is global, so the tests do not run in parallel.
+func TestDefault(t *testing.T) { //nolint:paralleltest
+	// The server echoes the method, content type and body of requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { axonet.SetDefault(nil) })
+
+	read := func(t *testing.T, resp *http.Response, err error) string {
+		t.Helper()
+
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("Send requests with the default client", func(t *testing.T) {
+		resp, err := axonet.Get(context.Background(), server.URL)
+		assert.Equal(t, "GET  ", read(t, resp, err))
+
+		resp, err = axonet.Post(context.Background(), server.URL, "application/json", map[string]string{"name": "Ada"})
+		assert.Equal(t, `POST application/json {"name":"Ada"}`, read(t, resp, err))
+	})
+
+	t.Run("Share the default client", func(t *testing.T) {
+		axonet.SetDefault(nil)
+
+		clients := make([]*client.Client, 8)
+		var wg sync.WaitGroup
+		for i := range clients {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				clients[i] = axonet.Default()
+			}()
+		}
+		wg.Wait()
+
+		for _, c := range clients {
+			assert.Same(t, clients[0], c)
+		}
+	})
+
+	t.Run("Replace the default client", func(t *testing.T) {
+		c := client.NewClient(client.WithBaseURL(server.URL))
+		axonet.SetDefault(c)
+		assert.Same(t, c, axonet.Default())
+
+		resp, err := axonet.Get(context.Background(), "/")
+		assert.Equal(t, "GET  ", read(t, resp, err))
+	})
+}
@@ -0,0 +1,108 @@
+// Package waitqueue provides the priority queue of the requests waiting for their turn in the
+// middleware limiting requests, such as the rate limit and concurrency middleware.
+package waitqueue
+
+import (
+	"container/heap"
+
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// Waiter is a request waiting in a Queue, carrying a value for whoever hands it its turn.
+type Waiter[T any] struct {
+	// Value is the value the waiter was pushed with.
+	Value T
+	// Ready is closed once the waiter is popped from the queue.
+	Ready    chan struct{}
+	priority middleware.Priority
+	seq      uint64
+	index    int
+}
+
+// Queue orders waiters by priority, then by arrival order. It is not safe for concurrent use.
+type Queue[T any] struct {
+	waiters waiters[T]
+	seq     uint64
+}
+
+// New creates a new empty Queue instance.
+func New[T any]() *Queue[T] {
+	return &Queue[T]{
+		waiters: nil,
+		seq:     0,
+	}
+}
+
+// Len returns the number of queued waiters.
+func (q *Queue[T]) Len() int {
+	return len(q.waiters)
+}
+
+// Push queues a waiter with the priority and value.
+func (q *Queue[T]) Push(priority middleware.Priority, value T) *Waiter[T] {
+	q.seq++
+	w := &Waiter[T]{
+		Value:    value,
+		Ready:    make(chan struct{}),
+		priority: priority,
+		seq:      q.seq,
+		index:    -1,
+	}
+	heap.Push(&q.waiters, w)
+	return w
+}
+
+// Pop removes the waiter with the highest priority and closes its Ready channel.
+// It returns nil if the queue is empty.
+func (q *Queue[T]) Pop() *Waiter[T] {
+	if len(q.waiters) == 0 {
+		return nil
+	}
+	w := heap.Pop(&q.waiters).(*Waiter[T])
+	close(w.Ready)
+	return w
+}
+
+// Remove removes the waiter from the queue if it is still queued, reporting whether it was.
+// A waiter that is no longer queued was popped and handed its turn.
+func (q *Queue[T]) Remove(w *Waiter[T]) bool {
+	if w.index < 0 {
+		return false
+	}
+	heap.Remove(&q.waiters, w.index)
+	return true
+}
+
+// waiters implements heap.Interface.
+type waiters[T any] []*Waiter[T]
+
+func (q waiters[T]) Len() int { return len(q) }
+
+func (q waiters[T]) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waiters[T]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiters[T]) Push(x interface{}) {
+	w := x.(*Waiter[T])
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiters[T]) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
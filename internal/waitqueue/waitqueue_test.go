@@ -0,0 +1,50 @@
+package waitqueue_test
+
+import (
+	"testing"
+
+	"github.com/jaxron/axonet/internal/waitqueue"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Pop by priority, then by arrival order", func(t *testing.T) {
+		t.Parallel()
+
+		queue := waitqueue.New[string]()
+		queue.Push(middleware.PriorityLow, "low")
+		queue.Push(middleware.PriorityNormal, "first")
+		queue.Push(middleware.PriorityHigh, "high")
+		queue.Push(middleware.PriorityNormal, "second")
+
+		var order []string
+		for queue.Len() > 0 {
+			w := queue.Pop()
+			select {
+			case <-w.Ready:
+			default:
+				t.Error("popped waiter is not ready")
+			}
+			order = append(order, w.Value)
+		}
+		assert.Equal(t, []string{"high", "first", "second", "low"}, order)
+		assert.Nil(t, queue.Pop())
+	})
+
+	t.Run("Remove only queued waiters", func(t *testing.T) {
+		t.Parallel()
+
+		queue := waitqueue.New[string]()
+		first := queue.Push(middleware.PriorityNormal, "first")
+		second := queue.Push(middleware.PriorityNormal, "second")
+
+		assert.True(t, queue.Remove(second))
+		assert.False(t, queue.Remove(second))
+		assert.Same(t, first, queue.Pop())
+		assert.False(t, queue.Remove(first))
+		assert.Equal(t, 0, queue.Len())
+	})
+}
@@ -0,0 +1,38 @@
+// Package all depends on every middleware module of axonet, for applications that want them all
+// with a single requirement. Each middleware is also its own module, so applications needing only
+// a few should require those instead, keeping the dependencies of the others, such as rueidis for
+// the Redis cache, out of their module graph:
+//
+//	import _ "github.com/jaxron/axonet/middleware/all"
+//
+// Importing the package registers the codecs of the compress middleware and the flags of the retry
+// and Redis middleware, as importing those packages does.
+package all
+
+import (
+	_ "github.com/jaxron/axonet/middleware/apikey"
+	_ "github.com/jaxron/axonet/middleware/challenge"
+	_ "github.com/jaxron/axonet/middleware/circuitbreaker"
+	_ "github.com/jaxron/axonet/middleware/compress"
+	_ "github.com/jaxron/axonet/middleware/concurrency"
+	_ "github.com/jaxron/axonet/middleware/config"
+	_ "github.com/jaxron/axonet/middleware/cookie"
+	_ "github.com/jaxron/axonet/middleware/drain"
+	_ "github.com/jaxron/axonet/middleware/dump"
+	_ "github.com/jaxron/axonet/middleware/errorbudget"
+	_ "github.com/jaxron/axonet/middleware/etag"
+	_ "github.com/jaxron/axonet/middleware/geo"
+	_ "github.com/jaxron/axonet/middleware/header"
+	_ "github.com/jaxron/axonet/middleware/maintenance"
+	_ "github.com/jaxron/axonet/middleware/metarefresh"
+	_ "github.com/jaxron/axonet/middleware/openapi"
+	_ "github.com/jaxron/axonet/middleware/otel"
+	_ "github.com/jaxron/axonet/middleware/preset"
+	_ "github.com/jaxron/axonet/middleware/proxy"
+	_ "github.com/jaxron/axonet/middleware/ratelimit"
+	_ "github.com/jaxron/axonet/middleware/redis"
+	_ "github.com/jaxron/axonet/middleware/retry"
+	_ "github.com/jaxron/axonet/middleware/routing"
+	_ "github.com/jaxron/axonet/middleware/singleflight"
+	_ "github.com/jaxron/axonet/middleware/timing"
+)
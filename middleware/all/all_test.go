@@ -0,0 +1,104 @@
+package all_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/challenge"
+	"github.com/jaxron/axonet/middleware/compress"
+	"github.com/jaxron/axonet/middleware/metarefresh"
+	"github.com/jaxron/axonet/middleware/retry"
+	"github.com/jaxron/axonet/middleware/singleflight"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposition(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Accept body readers running before decompression", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(
+			client.WithMiddleware(retry.New(3, 0, 0)),
+			client.WithMiddleware(challenge.New()),
+			client.WithMiddleware(metarefresh.New(3)),
+			client.WithMiddleware(compress.NewDecompress()),
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("Report body readers running after decompression", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(
+			client.WithMiddleware(compress.NewDecompress()),
+			client.WithMiddleware(challenge.New()),
+			client.WithMiddleware(metarefresh.New(3)),
+		)
+		require.ErrorIs(t, err, errors.ErrInvalidComposition)
+
+		var composition *clientMiddleware.CompositionError
+		require.ErrorAs(t, err, &composition)
+		assert.Equal(t, []clientMiddleware.CompositionIssue{
+			{
+				Index:      1,
+				Middleware: "*challenge.ChallengeMiddleware",
+				Reason:     "reads the response body, which *compress.DecompressMiddleware at index 0 only decodes afterwards",
+			},
+			{
+				Index:      2,
+				Middleware: "*metarefresh.MetaRefreshMiddleware",
+				Reason:     "reads the response body, which *compress.DecompressMiddleware at index 0 only decodes afterwards",
+			},
+		}, composition.Issues)
+	})
+
+	t.Run("Accept single flight running after retry", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(
+			client.WithMiddleware(retry.New(3, 0, 0)),
+			client.WithMiddleware(singleflight.New()),
+			client.WithMiddleware(challenge.New()),
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("Report single flight running after challenge replays", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(
+			client.WithMiddleware(challenge.New()),
+			client.WithMiddleware(singleflight.New()),
+		)
+
+		var composition *clientMiddleware.CompositionError
+		require.ErrorAs(t, err, &composition)
+		assert.Equal(t, []clientMiddleware.CompositionIssue{
+			{
+				Index:      1,
+				Middleware: "*singleflight.SingleFlightMiddleware",
+				Reason:     "shares responses between requests, which *challenge.ChallengeMiddleware at index 0 replays without numbering attempts",
+			},
+		}, composition.Issues)
+	})
+
+	t.Run("Validate the middleware added for a request", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := client.NewClientE(client.WithMiddleware(compress.NewDecompress()))
+		require.NoError(t, err)
+
+		_, err = c.NewRequest().
+			Method(http.MethodGet).
+			URL("http://localhost").
+			WithMiddleware(metarefresh.New(3)).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrInvalidComposition)
+	})
+}
@@ -0,0 +1,59 @@
+module github.com/jaxron/axonet/middleware/all
+
+go 1.24.0
+
+require (
+	github.com/jaxron/axonet v0.1.0
+	github.com/jaxron/axonet/middleware/apikey v0.1.0
+	github.com/jaxron/axonet/middleware/challenge v0.1.0
+	github.com/jaxron/axonet/middleware/circuitbreaker v0.1.0
+	github.com/jaxron/axonet/middleware/compress v0.1.0
+	github.com/jaxron/axonet/middleware/concurrency v0.1.0
+	github.com/jaxron/axonet/middleware/config v0.1.0
+	github.com/jaxron/axonet/middleware/cookie v0.1.0
+	github.com/jaxron/axonet/middleware/drain v0.1.0
+	github.com/jaxron/axonet/middleware/dump v0.1.0
+	github.com/jaxron/axonet/middleware/errorbudget v0.1.0
+	github.com/jaxron/axonet/middleware/etag v0.1.0
+	github.com/jaxron/axonet/middleware/geo v0.1.0
+	github.com/jaxron/axonet/middleware/header v0.1.0
+	github.com/jaxron/axonet/middleware/maintenance v0.1.0
+	github.com/jaxron/axonet/middleware/metarefresh v0.1.0
+	github.com/jaxron/axonet/middleware/openapi v0.1.0
+	github.com/jaxron/axonet/middleware/otel v0.1.0
+	github.com/jaxron/axonet/middleware/preset v0.1.0
+	github.com/jaxron/axonet/middleware/proxy v0.1.0
+	github.com/jaxron/axonet/middleware/ratelimit v0.1.0
+	github.com/jaxron/axonet/middleware/redis v0.1.0
+	github.com/jaxron/axonet/middleware/retry v0.1.0
+	github.com/jaxron/axonet/middleware/routing v0.1.0
+	github.com/jaxron/axonet/middleware/singleflight v0.1.0
+	github.com/jaxron/axonet/middleware/timing v0.1.0
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/bytedance/sonic v1.12.5 // indirect
+	github.com/bytedance/sonic/loader v0.2.1 // indirect
+	github.com/cespare/xxhash v1.1.0 // indirect
+	github.com/cloudwego/base64x v0.1.4 // indirect
+	github.com/cloudwego/iasm v0.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/klauspost/compress v1.17.11 // indirect
+	github.com/klauspost/cpuid/v2 v2.2.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/redis/rueidis v1.0.51 // indirect
+	github.com/sony/gobreaker v1.0.0 // indirect
+	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
+	go.opentelemetry.io/otel v1.28.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	golang.org/x/arch v0.12.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/time v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,336 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrKeysBenched = errors.New("all API keys are benched")
+
+type SkipAPIKeyKey struct{}
+
+// defaultBenchDuration is how long a key is benched when the API does not say when its quota resets.
+const defaultBenchDuration = time.Minute
+
+// unixThreshold is the smallest reset value read as a Unix timestamp rather than a number of seconds.
+const unixThreshold = 1_000_000_000
+
+// QuotaHeaders names the response headers reporting the quota of a key.
+type QuotaHeaders struct {
+	// Remaining reports the number of requests left in the quota of the key.
+	Remaining string
+	// Reset reports when the quota resets, as a number of seconds or a Unix timestamp.
+	Reset string
+}
+
+// DefaultQuotaHeaders are the conventional rate limit headers.
+var DefaultQuotaHeaders = QuotaHeaders{
+	Remaining: "X-RateLimit-Remaining",
+	Reset:     "X-RateLimit-Reset",
+}
+
+// Usage reports the quota consumption of a key.
+type Usage struct {
+	// Requests is the number of requests sent with the key.
+	Requests int
+	// Remaining is the number of requests left in the quota of the key, as last reported by the
+	// API, or -1 if unknown.
+	Remaining int
+	// BenchedUntil is when the key is used again, if it is benched.
+	BenchedUntil time.Time
+}
+
+// keyState tracks the quota consumption of a key.
+type keyState struct {
+	requests     int
+	remaining    int
+	benchedUntil time.Time
+}
+
+// APIKeyMiddleware rotates API keys from a pool across requests, benching keys that hit their
+// quota until it resets. It should be added after the retry middleware, so retries use another key.
+type APIKeyMiddleware struct {
+	keys          []string
+	states        map[string]*keyState
+	current       int
+	header        string
+	prefix        string
+	queryParam    string
+	quotaHeaders  QuotaHeaders
+	benchStatuses []int
+	benchDuration time.Duration
+	mu            sync.Mutex
+	logger        logger.Logger
+}
+
+// New creates a new APIKeyMiddleware instance setting the keys in the header, such as "X-API-Key".
+func New(keys []string, header string) *APIKeyMiddleware {
+	return &APIKeyMiddleware{
+		keys:          keys,
+		states:        newStates(keys, nil),
+		current:       0,
+		header:        header,
+		prefix:        "",
+		queryParam:    "",
+		quotaHeaders:  DefaultQuotaHeaders,
+		benchStatuses: []int{http.StatusTooManyRequests},
+		benchDuration: defaultBenchDuration,
+		mu:            sync.Mutex{},
+		logger:        &logger.NoOpLogger{},
+	}
+}
+
+// Process sets the next available key on the request before passing it to the next middleware,
+// then records the quota reported by the response.
+func (m *APIKeyMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if the API key middleware is disabled via context
+	if skip, ok := ctx.Value(SkipAPIKeyKey{}).(bool); ok && skip {
+		return next(ctx, httpClient, req)
+	}
+
+	key, index, err := m.selectKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return next(ctx, httpClient, req)
+	}
+
+	m.logger.WithFields(logger.Int("key", index)).Debug("Using API key")
+	m.apply(req, key)
+
+	resp, err := next(ctx, httpClient, req)
+	if resp != nil {
+		m.record(key, index, resp)
+	}
+	return resp, err
+}
+
+// selectKey chooses the next key that is not benched, returning its index in the pool.
+// It fails with ErrKeysBenched when every key is benched.
+func (m *APIKeyMiddleware) selectKey() (string, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.keys) == 0 {
+		return "", 0, nil
+	}
+
+	now := time.Now()
+	var wake time.Time
+	for range m.keys {
+		index := m.current % len(m.keys)
+		m.current = index + 1
+
+		key := m.keys[index]
+		state := m.states[key]
+		if now.Before(state.benchedUntil) {
+			if wake.IsZero() || state.benchedUntil.Before(wake) {
+				wake = state.benchedUntil
+			}
+			continue
+		}
+
+		state.requests++
+		return key, index, nil
+	}
+
+	m.logger.WithFields(logger.Duration("retry_after", wake.Sub(now))).Warn("All API keys are benched")
+	return "", 0, fmt.Errorf("%w: %w", ErrKeysBenched,
+		&clientErrors.OverloadedError{RetryAfter: wake.Sub(now), QueueDepth: 0})
+}
+
+// apply sets the key on the request, in the query parameter if one is set or in the header otherwise.
+func (m *APIKeyMiddleware) apply(req *http.Request, key string) {
+	m.mu.Lock()
+	header, prefix, queryParam := m.header, m.prefix, m.queryParam
+	m.mu.Unlock()
+
+	if queryParam != "" {
+		query := req.URL.Query()
+		query.Set(queryParam, key)
+		req.URL.RawQuery = query.Encode()
+		return
+	}
+	req.Header.Set(header, prefix+key)
+}
+
+// record updates the quota of the key from the response, benching the key if its quota is used
+// up or the response has a bench status.
+func (m *APIKeyMiddleware) record(key string, index int, resp *http.Response) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[key]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	reset, hasReset := parseReset(resp.Header.Get(m.quotaHeaders.Reset), now)
+	if remaining, err := strconv.Atoi(resp.Header.Get(m.quotaHeaders.Remaining)); err == nil {
+		state.remaining = remaining
+	}
+
+	exhausted := state.remaining == 0
+	if !exhausted && !slices.Contains(m.benchStatuses, resp.StatusCode) {
+		return
+	}
+
+	// Bench the key until its quota resets, as reported by the API if possible
+	until := now.Add(m.benchDuration)
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok && !exhausted {
+		until = retryAfter
+	} else if hasReset {
+		until = reset
+	}
+	state.benchedUntil = until
+	state.remaining = -1
+
+	m.logger.WithFields(
+		logger.Int("key", index),
+		logger.Int("status", resp.StatusCode),
+		logger.Duration("benched_for", until.Sub(now)),
+	).Warn("API key benched")
+}
+
+// parseReset parses a quota reset header holding a number of seconds or a Unix timestamp.
+func parseReset(value string, now time.Time) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, false
+	}
+	if seconds >= unixThreshold {
+		return time.Unix(seconds, 0), true
+	}
+	return now.Add(time.Duration(seconds) * time.Second), true
+}
+
+// parseRetryAfter parses a Retry-After header holding a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// newStates returns the states of the keys, keeping the existing state of keys already tracked.
+func newStates(keys []string, existing map[string]*keyState) map[string]*keyState {
+	states := make(map[string]*keyState, len(keys))
+	for _, key := range keys {
+		if state, ok := existing[key]; ok {
+			states[key] = state
+			continue
+		}
+		states[key] = &keyState{requests: 0, remaining: -1, benchedUntil: time.Time{}}
+	}
+	return states
+}
+
+// UpdateKeys updates the pool of keys at runtime. Keys already in the pool keep their quota state.
+func (m *APIKeyMiddleware) UpdateKeys(keys []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys = keys
+	m.states = newStates(keys, m.states)
+	m.current = 0
+
+	m.logger.WithFields(logger.Int("keys", len(keys))).Debug("API keys updated")
+}
+
+// SetPrefix sets the prefix of the header value, such as "Bearer " for the Authorization header.
+func (m *APIKeyMiddleware) SetPrefix(prefix string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prefix = prefix
+}
+
+// SetQueryParam sets the keys in the query parameter, such as "api_key", instead of the header.
+func (m *APIKeyMiddleware) SetQueryParam(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queryParam = name
+}
+
+// SetQuotaHeaders sets the response headers reporting the quota of a key.
+// Defaults to DefaultQuotaHeaders.
+func (m *APIKeyMiddleware) SetQuotaHeaders(headers QuotaHeaders) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.quotaHeaders = headers
+}
+
+// SetBenchStatuses sets the response statuses benching the key used, until the time given by the
+// Retry-After or quota reset header. Defaults to 429 Too Many Requests.
+func (m *APIKeyMiddleware) SetBenchStatuses(statuses ...int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.benchStatuses = statuses
+}
+
+// SetBenchDuration sets how long a key is benched when the response does not say when its quota
+// resets. Defaults to 1 minute.
+func (m *APIKeyMiddleware) SetBenchDuration(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.benchDuration = d
+}
+
+// Usage returns the quota consumption of the keys, in the order of the pool.
+func (m *APIKeyMiddleware) Usage() []Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := make([]Usage, len(m.keys))
+	for i, key := range m.keys {
+		state := m.states[key]
+		usage[i] = Usage{Requests: state.requests, Remaining: state.remaining, BenchedUntil: state.benchedUntil}
+	}
+	return usage
+}
+
+// Introspect returns the size of the key pool and the number of benched keys.
+func (m *APIKeyMiddleware) Introspect() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	benched := 0
+	for _, state := range m.states {
+		if now.Before(state.benchedUntil) {
+			benched++
+		}
+	}
+
+	return map[string]interface{}{
+		"keys":    len(m.keys),
+		"benched": benched,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *APIKeyMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
@@ -0,0 +1,147 @@
+package apikey_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/apikey"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	// send sends a request through the middleware, returning the key used
+	send := func(t *testing.T, middleware *apikey.APIKeyMiddleware, header http.Header, status int) (string, error) {
+		t.Helper()
+
+		var used string
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/items?page=2", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			used = req.Header.Get("Authorization") + req.URL.Query().Get("api_key")
+			return &http.Response{StatusCode: status, Header: header}, nil
+		})
+		return used, err
+	}
+
+	t.Run("Rotate keys in a header", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := apikey.New([]string{"key1", "key2"}, "Authorization")
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetPrefix("Bearer ")
+
+		var used []string
+		for range 4 {
+			key, err := send(t, middleware, http.Header{}, http.StatusOK)
+			require.NoError(t, err)
+			used = append(used, key)
+		}
+		assert.Equal(t, []string{"Bearer key1", "Bearer key2", "Bearer key1", "Bearer key2"}, used)
+		assert.Equal(t, 2, middleware.Usage()[0].Requests)
+	})
+
+	t.Run("Set keys in a query parameter", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := apikey.New([]string{"key1"}, "")
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetQueryParam("api_key")
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/items?page=2", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "api_key=key1&page=2", req.URL.RawQuery)
+	})
+
+	t.Run("Bench keys with an exhausted quota until reset", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := apikey.New([]string{"key1", "key2"}, "Authorization")
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		// The quota of key1 is used up and resets in an hour
+		reset := time.Now().Add(time.Hour).Unix()
+		_, err := send(t, middleware, http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {strconv.FormatInt(reset, 10)},
+		}, http.StatusOK)
+		require.NoError(t, err)
+
+		_, err = send(t, middleware, http.Header{"X-Ratelimit-Remaining": {"41"}}, http.StatusOK)
+		require.NoError(t, err)
+
+		key, err := send(t, middleware, http.Header{}, http.StatusOK)
+		require.NoError(t, err)
+		assert.Equal(t, "key2", key)
+
+		usage := middleware.Usage()
+		assert.Equal(t, time.Unix(reset, 0), usage[0].BenchedUntil)
+		assert.Equal(t, 41, usage[1].Remaining)
+		assert.Equal(t, 1, middleware.Introspect()["benched"])
+	})
+
+	t.Run("Bench keys on 429 until Retry-After", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := apikey.New([]string{"key1", "key2"}, "Authorization")
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		_, err := send(t, middleware, http.Header{"Retry-After": {"30"}}, http.StatusTooManyRequests)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(30*time.Second), middleware.Usage()[0].BenchedUntil, time.Second)
+
+		// Without a reset time, keys are benched for the default duration
+		_, err = send(t, middleware, http.Header{}, http.StatusTooManyRequests)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), middleware.Usage()[1].BenchedUntil, time.Second)
+	})
+
+	t.Run("Fail when every key is benched", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := apikey.New([]string{"key1"}, "Authorization")
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		_, err := send(t, middleware, http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"120"}}, http.StatusOK)
+		require.NoError(t, err)
+
+		_, err = send(t, middleware, http.Header{}, http.StatusOK)
+		require.ErrorIs(t, err, apikey.ErrKeysBenched)
+		require.ErrorIs(t, err, clientErrors.ErrOverloaded)
+
+		var overloaded *clientErrors.OverloadedError
+		require.ErrorAs(t, err, &overloaded)
+		assert.InDelta(t, 2*time.Minute, overloaded.RetryAfter, float64(time.Second))
+	})
+
+	t.Run("Keep the state of keys across updates", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := apikey.New([]string{"key1", "key2"}, "Authorization")
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		_, err := send(t, middleware, http.Header{}, http.StatusTooManyRequests)
+		require.NoError(t, err)
+
+		middleware.UpdateKeys([]string{"key3", "key1"})
+		usage := middleware.Usage()
+		assert.Equal(t, 0, usage[0].Requests)
+		assert.Equal(t, 1, usage[1].Requests)
+		assert.False(t, usage[1].BenchedUntil.IsZero())
+
+		key, err := send(t, middleware, http.Header{}, http.StatusOK)
+		require.NoError(t, err)
+		assert.Equal(t, "key3", key)
+		assert.Equal(t, map[string]interface{}{"keys": 2, "benched": 1}, middleware.Introspect())
+	})
+}
@@ -0,0 +1,314 @@
+package challenge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var (
+	ErrChallengeDetected = errors.New("anti-bot challenge detected")
+	ErrReadBody          = errors.New("failed to read request body")
+	ErrSolveFailed       = errors.New("failed to solve challenge")
+)
+
+// Challenge describes an anti-bot challenge returned instead of the expected response.
+type Challenge struct {
+	Provider   string
+	StatusCode int
+	Marker     string
+	URL        string
+	// Header and Body are those of the challenge response, for solvers that need them.
+	// Body is nil if the challenge was detected from headers only.
+	Header http.Header
+	Body   []byte
+}
+
+// ChallengeError is returned when a challenge is detected.
+// It matches ErrChallengeDetected with errors.Is.
+type ChallengeError struct {
+	Challenge Challenge
+}
+
+func (e *ChallengeError) Error() string {
+	return fmt.Sprintf("%s: %s challenge (status %d, marker %q) at %s",
+		ErrChallengeDetected, e.Challenge.Provider, e.Challenge.StatusCode, e.Challenge.Marker, e.Challenge.URL)
+}
+
+func (e *ChallengeError) Unwrap() error {
+	return ErrChallengeDetected
+}
+
+// Rule recognizes the challenge responses of a provider.
+// A response matches if its status code is listed (or no status codes are listed)
+// and any header or body marker is found.
+type Rule struct {
+	Provider    string
+	StatusCodes []int
+	// HeaderMarkers maps header names to substrings searched for in their values.
+	// An empty substring matches any value.
+	HeaderMarkers map[string]string
+	BodyMarkers   []string
+}
+
+// DefaultRules are the rules for common anti-bot providers.
+var DefaultRules = []Rule{
+	{
+		Provider:      "cloudflare",
+		StatusCodes:   []int{http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		HeaderMarkers: map[string]string{"Cf-Mitigated": "challenge"},
+		BodyMarkers:   []string{"cf-chl", "cf_chl_opt", "challenge-platform", "Just a moment..."},
+	},
+	{
+		Provider:      "akamai",
+		StatusCodes:   []int{http.StatusForbidden},
+		HeaderMarkers: nil,
+		BodyMarkers:   []string{"_abck", "ak_bmsc", "errors.edgesuite.net"},
+	},
+	{
+		Provider:      "perimeterx",
+		StatusCodes:   []int{http.StatusForbidden},
+		HeaderMarkers: nil,
+		BodyMarkers:   []string{"px-captcha", "_pxCaptcha", "_pxAppId"},
+	},
+	{
+		Provider:      "datadome",
+		StatusCodes:   []int{http.StatusForbidden},
+		HeaderMarkers: map[string]string{"X-Datadome": ""},
+		BodyMarkers:   []string{"captcha-delivery.com"},
+	},
+	{
+		Provider:      "captcha",
+		StatusCodes:   []int{http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		HeaderMarkers: nil,
+		BodyMarkers:   []string{"g-recaptcha", "h-captcha"},
+	},
+}
+
+// RotateFunc is called after a challenge is detected and before the request is retried,
+// such as to shuffle the proxies or cookies used for the next attempt.
+type RotateFunc func(ctx context.Context, challenge Challenge) error
+
+// Solution holds the headers and cookies that prove a challenge was solved.
+// They replace the headers and cookies of the same names on the request before it is replayed.
+type Solution struct {
+	Header  http.Header
+	Cookies []*http.Cookie
+}
+
+// SolverFunc solves a challenge, such as by calling an external solving service.
+type SolverFunc func(ctx context.Context, challenge Challenge) (*Solution, error)
+
+// ChallengeMiddleware detects anti-bot challenge responses and converts them into a ChallengeError.
+// It should be added before identity middleware such as proxy and cookie,
+// so that retried requests use a different identity.
+type ChallengeMiddleware struct {
+	rules      []Rule
+	maxRetries int
+	rotate     RotateFunc
+	solver     SolverFunc
+	mu         sync.RWMutex
+	logger     logger.Logger
+}
+
+// New creates a new ChallengeMiddleware instance using the default rules.
+func New() *ChallengeMiddleware {
+	return &ChallengeMiddleware{
+		rules:      slices.Clone(DefaultRules),
+		maxRetries: 0,
+		rotate:     nil,
+		solver:     nil,
+		mu:         sync.RWMutex{},
+		logger:     &logger.NoOpLogger{},
+	}
+}
+
+// Process detects challenges in the response of the next middleware, retrying the request if configured.
+func (m *ChallengeMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	m.mu.RLock()
+	rules := m.rules
+	maxRetries := m.maxRetries
+	rotate := m.rotate
+	solver := m.solver
+	m.mu.RUnlock()
+
+	// A solver is useless without replaying the request at least once
+	if solver != nil && maxRetries == 0 {
+		maxRetries = 1
+	}
+
+	// Buffer the body so the request can be replayed
+	var body []byte
+	if maxRetries > 0 && req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReadBody, err)
+		}
+		req.Body.Close()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := next(ctx, httpClient, req)
+		if err != nil {
+			return resp, err
+		}
+
+		challenge, err := detect(rules, req, resp)
+		if err != nil {
+			return resp, err
+		}
+		if challenge == nil {
+			return resp, nil
+		}
+
+		m.logger.WithFields(
+			logger.String("provider", challenge.Provider),
+			logger.Int("status", challenge.StatusCode),
+			logger.String("marker", challenge.Marker),
+			logger.Int("attempt", attempt+1),
+		).Warn("Challenge detected")
+
+		if attempt >= maxRetries {
+			return resp, &ChallengeError{Challenge: *challenge}
+		}
+
+		// Rotate the identity before retrying
+		if rotate != nil {
+			if err := rotate(ctx, *challenge); err != nil {
+				return resp, err
+			}
+		}
+
+		// Solve the challenge and apply the solution to the replayed request
+		if solver != nil {
+			solution, err := solver(ctx, *challenge)
+			if err != nil {
+				return resp, fmt.Errorf("%w: %w", ErrSolveFailed, err)
+			}
+			applySolution(req, solution)
+		}
+		resp.Body.Close()
+	}
+}
+
+// AddRule adds a rule used to detect challenges.
+func (m *ChallengeMiddleware) AddRule(rule Rule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = append(slices.Clone(m.rules), rule)
+}
+
+// SetRetry makes the middleware retry challenged requests up to maxRetries times,
+// calling rotate (if not nil) before each retry.
+func (m *ChallengeMiddleware) SetRetry(maxRetries int, rotate RotateFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxRetries = maxRetries
+	m.rotate = rotate
+}
+
+// SetSolver sets the function used to solve challenges before the request is replayed.
+// If no retries are configured, the request is replayed once after solving.
+func (m *ChallengeMiddleware) SetSolver(solver SolverFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.solver = solver
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *ChallengeMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware buffers the request body to send the request again once a challenge is solved,
+// and reads the response body to detect challenges.
+func (m *ChallengeMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody, middleware.ReplaysRequest, middleware.ReadsResponseBody}
+}
+
+// detect returns the challenge found in the response, or nil if there is none.
+func detect(rules []Rule, req *http.Request, resp *http.Response) (*Challenge, error) {
+	var body []byte
+	bodyRead := false
+
+	for _, rule := range rules {
+		if len(rule.StatusCodes) > 0 && !slices.Contains(rule.StatusCodes, resp.StatusCode) {
+			continue
+		}
+
+		for header, marker := range rule.HeaderMarkers {
+			if values := resp.Header.Values(header); len(values) > 0 && strings.Contains(strings.Join(values, ","), marker) {
+				return newChallenge(rule, req, resp, header, body), nil
+			}
+		}
+
+		if len(rule.BodyMarkers) == 0 || resp.Body == nil {
+			continue
+		}
+
+		// Read the body once and restore it for the caller
+		if !bodyRead {
+			var err error
+			body, err = io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			bodyRead = true
+		}
+
+		for _, marker := range rule.BodyMarkers {
+			if bytes.Contains(body, []byte(marker)) {
+				return newChallenge(rule, req, resp, marker, body), nil
+			}
+		}
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+// newChallenge creates the challenge matched by the rule.
+func newChallenge(rule Rule, req *http.Request, resp *http.Response, marker string, body []byte) *Challenge {
+	return &Challenge{
+		Provider:   rule.Provider,
+		StatusCode: resp.StatusCode,
+		Marker:     marker,
+		URL:        req.URL.String(),
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
+
+// applySolution adds the headers and cookies of the solution to the request, replacing the
+// cookies of the same names, such as an expired clearance.
+func applySolution(req *http.Request, solution *Solution) {
+	if solution == nil {
+		return
+	}
+
+	for key, values := range solution.Header {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	middleware.SetCookies(req, solution.Cookies...)
+}
@@ -0,0 +1,247 @@
+package challenge_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/challenge"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrSolver = errors.New("solver unavailable")
+
+func challengeResponse() *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     http.Header{"Server": []string{"cloudflare"}},
+		Body:       io.NopCloser(strings.NewReader(`<html><title>Just a moment...</title><script src="/cdn-cgi/challenge-platform/h/b"></script></html>`)),
+	}
+}
+
+func okResponse() *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("ok")),
+	}
+}
+
+func TestChallengeMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Detect challenge response", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/page", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return challengeResponse(), nil
+		})
+		require.ErrorIs(t, err, challenge.ErrChallengeDetected)
+		assert.NotNil(t, resp)
+
+		var challengeErr *challenge.ChallengeError
+		require.ErrorAs(t, err, &challengeErr)
+		assert.Equal(t, "cloudflare", challengeErr.Challenge.Provider)
+		assert.Equal(t, http.StatusForbidden, challengeErr.Challenge.StatusCode)
+		assert.Equal(t, "challenge-platform", challengeErr.Challenge.Marker)
+		assert.Equal(t, "http://example.com/page", challengeErr.Challenge.URL)
+
+		// The body is still readable
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "Just a moment...")
+	})
+
+	t.Run("Detect challenge header", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"Cf-Mitigated": []string{"challenge"}}}, nil
+		})
+
+		var challengeErr *challenge.ChallengeError
+		require.ErrorAs(t, err, &challengeErr)
+		assert.Equal(t, "Cf-Mitigated", challengeErr.Challenge.Marker)
+	})
+
+	t.Run("Regular responses pass", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusForbidden,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("forbidden")),
+			}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Custom rules", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.AddRule(challenge.Rule{
+			Provider:    "custom",
+			StatusCodes: []int{http.StatusOK},
+			BodyMarkers: []string{"verify you are human"},
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("please verify you are human"))}, nil
+		})
+
+		var challengeErr *challenge.ChallengeError
+		require.ErrorAs(t, err, &challengeErr)
+		assert.Equal(t, "custom", challengeErr.Challenge.Provider)
+	})
+
+	t.Run("Rotate and retry on challenge", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		rotations := 0
+		middleware.SetRetry(2, func(ctx context.Context, c challenge.Challenge) error {
+			rotations++
+			return nil
+		})
+
+		attempts := 0
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("payload"))
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			attempts++
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "payload", string(body))
+
+			if attempts < 2 {
+				return challengeResponse(), nil
+			}
+			return okResponse(), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 1, rotations)
+	})
+
+	t.Run("Give up after max retries", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetRetry(2, nil)
+
+		attempts := 0
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			attempts++
+			return challengeResponse(), nil
+		})
+		require.True(t, errors.Is(err, challenge.ErrChallengeDetected))
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Solve challenge before replaying", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetSolver(func(ctx context.Context, c challenge.Challenge) (*challenge.Solution, error) {
+			assert.Equal(t, "cloudflare", c.Provider)
+			assert.Contains(t, string(c.Body), "challenge-platform")
+			return &challenge.Solution{
+				Header:  http.Header{"X-Solution": []string{"token"}},
+				Cookies: []*http.Cookie{{Name: "cf_clearance", Value: "cleared"}},
+			}, nil
+		})
+
+		attempts := 0
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			attempts++
+			cookie, err := req.Cookie("cf_clearance")
+			if err != nil {
+				return challengeResponse(), nil
+			}
+			assert.Equal(t, "cleared", cookie.Value)
+			assert.Equal(t, "token", req.Header.Get("X-Solution"))
+			return okResponse(), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("Replace cookies with the solution", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetSolver(func(ctx context.Context, c challenge.Challenge) (*challenge.Solution, error) {
+			return &challenge.Solution{
+				Header:  nil,
+				Cookies: []*http.Cookie{{Name: "cf_clearance", Value: "cleared"}},
+			}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: "123"})
+		req.AddCookie(&http.Cookie{Name: "cf_clearance", Value: "expired"})
+
+		var cookies []*http.Cookie
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			cookies = req.Cookies()
+			if cookie, _ := req.Cookie("cf_clearance"); cookie.Value == "expired" {
+				return challengeResponse(), nil
+			}
+			return okResponse(), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, cookies, 2)
+		assert.Equal(t, "session", cookies[0].Name)
+		assert.Equal(t, "cleared", cookies[1].Value)
+	})
+
+	t.Run("Solver failure", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := challenge.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetSolver(func(ctx context.Context, c challenge.Challenge) (*challenge.Solution, error) {
+			return nil, ErrSolver
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return challengeResponse(), nil
+		})
+		require.ErrorIs(t, err, challenge.ErrSolveFailed)
+		require.ErrorIs(t, err, ErrSolver)
+	})
+}
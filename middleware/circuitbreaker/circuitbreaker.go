@@ -8,6 +8,7 @@ import (
 	"time"
 
 	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/sony/gobreaker"
@@ -47,7 +48,7 @@ func New(maxRequests uint32, interval, timeout time.Duration) *CircuitBreakerMid
 				logger.String("to", to.String()),
 			).Warn("Circuit breaker state changed")
 		},
-		IsSuccessful: nil,
+		IsSuccessful: isSuccessful,
 	})
 	middleware.breaker = breaker
 
@@ -56,9 +57,21 @@ func New(maxRequests uint32, interval, timeout time.Duration) *CircuitBreakerMid
 
 // Process applies the circuit breaker before passing the request to the next middleware.
 func (m *CircuitBreakerMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
-	// Execute the request with the circuit breaker
+	// Emit the state changes of the breaker caused by the request, such as opening it
+	from := m.breaker.State()
+	defer func() {
+		if to := m.breaker.State(); to != from {
+			events.Publish(ctx, events.BreakerStateChanged{From: from.String(), To: to.String()})
+		}
+	}()
+
+	// Execute the request with the circuit breaker, counting the outcomes classified as unhealthy as failures
 	result, err := m.breaker.Execute(func() (interface{}, error) {
-		return next(ctx, httpClient, req)
+		resp, err := next(ctx, httpClient, req)
+		if !middleware.Classify(ctx, resp, err).Healthy() {
+			return resp, &unhealthyError{err: err}
+		}
+		return resp, err
 	})
 	if err != nil {
 		switch err {
@@ -69,6 +82,12 @@ func (m *CircuitBreakerMiddleware) Process(ctx context.Context, httpClient *http
 		}
 	}
 
+	// Return the error of the request, if any, rather than the marker of its classification
+	var unhealthy *unhealthyError
+	if errors.As(err, &unhealthy) {
+		err = unhealthy.err
+	}
+
 	// Type assertion to get the response
 	resp, ok := result.(*http.Response)
 	if !ok {
@@ -79,6 +98,37 @@ func (m *CircuitBreakerMiddleware) Process(ctx context.Context, httpClient *http
 	return resp, err
 }
 
+// unhealthyError marks the outcome of a request classified as unhealthy, which the breaker counts as a failure.
+type unhealthyError struct {
+	err error
+}
+
+func (e *unhealthyError) Error() string {
+	if e.err == nil {
+		return "unhealthy response"
+	}
+	return e.err.Error()
+}
+
+// isSuccessful reports whether the request was classified as healthy.
+func isSuccessful(err error) bool {
+	var unhealthy *unhealthyError
+	return !errors.As(err, &unhealthy)
+}
+
+// Introspect returns the state and counts of the circuit breaker.
+func (m *CircuitBreakerMiddleware) Introspect() map[string]interface{} {
+	counts := m.breaker.Counts()
+	return map[string]interface{}{
+		"state":                m.breaker.State().String(),
+		"requests":             counts.Requests,
+		"totalSuccesses":       counts.TotalSuccesses,
+		"totalFailures":        counts.TotalFailures,
+		"consecutiveSuccesses": counts.ConsecutiveSuccesses,
+		"consecutiveFailures":  counts.ConsecutiveFailures,
+	}
+}
+
 // SetLogger sets the logger for the middleware.
 func (m *CircuitBreakerMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
@@ -9,7 +9,10 @@ import (
 	"time"
 
 	"github.com/jaxron/axonet/middleware/circuitbreaker"
+	"github.com/jaxron/axonet/pkg/client"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,6 +60,34 @@ func TestCircuitBreakerMiddleware(t *testing.T) {
 		assert.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
 	})
 
+	t.Run("Client errors do not open the circuit", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := circuitbreaker.New(3, 10*time.Second, 1*time.Second)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		handler := func(status int) func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody, Request: req}
+				return resp, clientErrors.NewHTTPError(resp)
+			}
+		}
+
+		for range 3 {
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler(http.StatusNotFound))
+			require.ErrorIs(t, err, clientErrors.ErrBadStatus)
+		}
+		assert.Equal(t, "closed", middleware.Introspect()["state"])
+
+		// Server errors open the circuit once they are 60% of the requests
+		for range 5 {
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler(http.StatusServiceUnavailable))
+			require.ErrorIs(t, err, clientErrors.ErrBadStatus)
+		}
+		assert.Equal(t, "open", middleware.Introspect()["state"])
+	})
+
 	t.Run("Circuit half-open state", func(t *testing.T) {
 		t.Parallel()
 
@@ -91,4 +122,54 @@ func TestCircuitBreakerMiddleware(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
+
+	t.Run("Server error responses open the circuit", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := circuitbreaker.New(1, 10*time.Second, 30*time.Second)
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+		}
+
+		// The responses are returned without error while the circuit is closed
+		for range 3 {
+			resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		}
+
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+	})
+
+	t.Run("Classify outcomes with the classifier of the client", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		t.Cleanup(server.Close)
+
+		// Missing resources mean the upstream lost its data
+		c := client.NewClient(
+			client.WithMiddleware(circuitbreaker.New(1, 10*time.Second, 30*time.Second)),
+			client.WithClassifier(func(resp *http.Response, err error) clientMiddleware.Class {
+				if resp != nil && resp.StatusCode == http.StatusNotFound {
+					return clientMiddleware.ClassFailure
+				}
+				return clientMiddleware.DefaultClassifier(resp, err)
+			}),
+		)
+
+		for range 3 {
+			resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		_, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+	})
 }
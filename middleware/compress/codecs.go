@@ -0,0 +1,61 @@
+package compress
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/jaxron/axonet/pkg/client/compression"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Importing this package registers the zstd, brotli and snappy codecs
+// in addition to the gzip and deflate codecs provided by the client.
+func init() {
+	compression.Register(ZstdCodec{})
+	compression.Register(BrotliCodec{})
+	compression.Register(SnappyCodec{})
+}
+
+// ZstdCodec implements the zstd content encoding.
+type ZstdCodec struct{}
+
+func (ZstdCodec) Encoding() string { return "zstd" }
+
+func (ZstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+func (ZstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+// BrotliCodec implements the br content encoding.
+type BrotliCodec struct{}
+
+func (BrotliCodec) Encoding() string { return "br" }
+
+func (BrotliCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+func (BrotliCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+// SnappyCodec implements the snappy content encoding using the framed format.
+type SnappyCodec struct{}
+
+func (SnappyCodec) Encoding() string { return "snappy" }
+
+func (SnappyCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(snappy.NewReader(r)), nil
+}
+
+func (SnappyCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
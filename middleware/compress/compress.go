@@ -0,0 +1,118 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/compression"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var (
+	ErrReadBody     = errors.New("failed to read request body")
+	ErrCompressBody = errors.New("failed to compress request body")
+)
+
+type SkipCompressKey struct{}
+
+// CompressMiddleware compresses request bodies using a registered compression codec.
+type CompressMiddleware struct {
+	encoding     string
+	minSize      int
+	negotiation  *Negotiation
+	capabilities map[string]hostCapability
+	mu           sync.Mutex
+	logger       logger.Logger
+}
+
+// New creates a new CompressMiddleware instance.
+// Request bodies smaller than minSize bytes are sent uncompressed.
+func New(encoding string, minSize int) *CompressMiddleware {
+	return &CompressMiddleware{
+		encoding:     encoding,
+		minSize:      minSize,
+		negotiation:  nil,
+		capabilities: make(map[string]hostCapability),
+		mu:           sync.Mutex{},
+		logger:       &logger.NoOpLogger{},
+	}
+}
+
+// Process compresses the request body before passing the request to the next middleware.
+func (m *CompressMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if compression should be skipped
+	if skipCompress, ok := ctx.Value(SkipCompressKey{}).(bool); ok && skipCompress {
+		return next(ctx, httpClient, req)
+	}
+
+	// Skip requests without a body or with an already encoded body
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return next(ctx, httpClient, req)
+	}
+
+	codec, ok := compression.Lookup(m.encoding)
+	if !ok {
+		return nil, fmt.Errorf("%w: %w: %s", ErrCompressBody, compression.ErrUnknownEncoding, m.encoding)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadBody, err)
+	}
+	req.Body.Close()
+
+	// Send small bodies as they are
+	if len(body) < m.minSize {
+		setBody(req, body)
+		return next(ctx, httpClient, req)
+	}
+
+	compressed, err := compression.Compress(codec, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCompressBody, err)
+	}
+
+	m.logger.WithFields(
+		logger.String("encoding", codec.Encoding()),
+		logger.Int("original_size", len(body)),
+		logger.Int("compressed_size", len(compressed)),
+	).Debug("Request body compressed")
+
+	if negotiation := m.negotiated(); negotiation != nil {
+		return m.sendNegotiated(ctx, httpClient, req, next, negotiation, codec.Encoding(), body, compressed)
+	}
+
+	setBody(req, compressed)
+	req.Header.Set("Content-Encoding", codec.Encoding())
+
+	return next(ctx, httpClient, req)
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *CompressMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware reads the request body to compress it, and restores it.
+// With negotiation, it also sends requests again uncompressed once rejected.
+func (m *CompressMiddleware) Capabilities() []middleware.Capability {
+	if m.negotiated() != nil {
+		return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody, middleware.ReplaysRequest}
+	}
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody}
+}
+
+// setBody replaces the request body, keeping ContentLength and GetBody consistent.
+func setBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
@@ -0,0 +1,221 @@
+package compress_test
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/compress"
+	"github.com/jaxron/axonet/pkg/client/compression"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressMiddleware(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("compressible body ", 100)
+
+	t.Run("Registered codecs round trip", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"br", "deflate", "gzip", "snappy", "zstd"}, compression.Encodings())
+
+		for _, encoding := range compression.Encodings() {
+			codec, ok := compression.Lookup(encoding)
+			require.True(t, ok)
+
+			compressed, err := compression.Compress(codec, []byte(body))
+			require.NoError(t, err, encoding)
+			assert.Less(t, len(compressed), len(body), encoding)
+
+			decompressed, err := compression.Decompress(codec, compressed)
+			require.NoError(t, err, encoding)
+			assert.Equal(t, body, string(decompressed), encoding)
+		}
+	})
+
+	t.Run("Compress request body", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("zstd", 64)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(body))
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "zstd", req.Header.Get("Content-Encoding"))
+
+			compressed, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(compressed)), req.ContentLength)
+
+			codec, _ := compression.Lookup("zstd")
+			decompressed, err := compression.Decompress(codec, compressed)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(decompressed))
+
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Small bodies are not compressed", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("gzip", 1024)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("small"))
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Empty(t, req.Header.Get("Content-Encoding"))
+
+			sent, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "small", string(sent))
+
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Unknown encoding", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("unknown", 0)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(body))
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		require.ErrorIs(t, err, compression.ErrUnknownEncoding)
+	})
+
+	t.Run("Skip compression via context", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("gzip", 0)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		ctx := context.WithValue(context.Background(), compress.SkipCompressKey{}, true)
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(body))
+		resp, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Empty(t, req.Header.Get("Content-Encoding"))
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Fall back to uncompressed uploads when rejected", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("gzip", 0)
+		middleware.SetNegotiation(compress.Negotiation{Probe: false, RejectStatuses: nil, TTL: 0})
+
+		// The server rejects compressed bodies
+		var encodings []string
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			encodings = append(encodings, req.Header.Get("Content-Encoding"))
+			sent, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			if req.Header.Get("Content-Encoding") != "" {
+				return &http.Response{StatusCode: http.StatusUnsupportedMediaType, Body: http.NoBody}, nil
+			}
+			assert.Equal(t, body, string(sent))
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}
+
+		for range 2 {
+			req := httptest.NewRequest(http.MethodPut, "http://example.com", strings.NewReader(body))
+			resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		// The second upload is sent uncompressed right away
+		assert.Equal(t, []string{"gzip", "", ""}, encodings)
+		accepted, known := middleware.Accepts("example.com")
+		assert.True(t, known)
+		assert.False(t, accepted)
+		assert.Equal(t, map[string]bool{"example.com": false}, middleware.Introspect()["hosts"])
+	})
+
+	t.Run("Only send replayable requests again", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("gzip", 0)
+		middleware.SetNegotiation(compress.Negotiation{Probe: false, RejectStatuses: nil, TTL: 0})
+
+		var encodings []string
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			encodings = append(encodings, req.Header.Get("Content-Encoding"))
+			switch {
+			case req.URL.Host == "strict.example.com":
+				return &http.Response{StatusCode: http.StatusBadRequest, Body: http.NoBody}, nil
+			case req.Header.Get("Content-Encoding") != "":
+				return &http.Response{StatusCode: http.StatusUnsupportedMediaType, Body: http.NoBody}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}
+		send := func(ctx context.Context, url string, header http.Header) int {
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+			maps.Copy(req.Header, header)
+			resp, err := middleware.Process(ctx, &http.Client{}, req, handler)
+			require.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		// POST requests are only sent again with an idempotency key
+		assert.Equal(t, http.StatusUnsupportedMediaType, send(context.Background(), "http://example.com", nil))
+		assert.Equal(t, http.StatusOK, send(context.Background(), "http://example.com", http.Header{"Idempotency-Key": {"abc"}}))
+		assert.Equal(t, []string{"gzip", "gzip", ""}, encodings)
+
+		// Replays can be forbidden, and only 415 rejects compressed uploads by default
+		encodings = nil
+		ctx := clientMiddleware.WithoutReplay(context.Background())
+		assert.Equal(t, http.StatusUnsupportedMediaType, send(ctx, "http://other.example.com", http.Header{"Idempotency-Key": {"abc"}}))
+		assert.Equal(t, http.StatusBadRequest, send(context.Background(), "http://strict.example.com", http.Header{"Idempotency-Key": {"abc"}}))
+		assert.Equal(t, []string{"gzip", "gzip"}, encodings)
+	})
+
+	t.Run("Probe the capability of hosts", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.New("zstd", 0)
+		middleware.SetNegotiation(compress.Negotiation{Probe: true, RejectStatuses: nil, TTL: 0})
+
+		var methods []string
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			methods = append(methods, req.Method+" "+req.Header.Get("Content-Encoding"))
+			header := http.Header{}
+			if req.URL.Host == "picky.example.com" {
+				header.Set("Accept-Encoding", "gzip, zstd;q=0")
+			} else {
+				header.Set("Accept-Encoding", "gzip, zstd")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}, nil
+		}
+
+		for _, url := range []string{"http://picky.example.com", "http://example.com", "http://example.com"} {
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, []string{"OPTIONS ", "POST ", "OPTIONS ", "POST zstd", "POST zstd"}, methods)
+	})
+}
@@ -0,0 +1,133 @@
+package compress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/compression"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrDecompressBody = errors.New("failed to decompress response body")
+
+// DefaultAcceptEncodings are the encodings accepted by default by the DecompressMiddleware.
+var DefaultAcceptEncodings = []string{"gzip", "br", "zstd"}
+
+type SkipDecompressKey struct{}
+
+// DecompressMiddleware advertises the encodings it accepts and transparently decompresses
+// response bodies, as the transport only does for gzip when it sets Accept-Encoding itself.
+// Requests with an Accept-Encoding header set by the caller are left as they are.
+type DecompressMiddleware struct {
+	encodings []string
+	logger    logger.Logger
+}
+
+// NewDecompress creates a new DecompressMiddleware instance accepting the registered encodings,
+// in order of preference. It accepts DefaultAcceptEncodings if none are given.
+func NewDecompress(encodings ...string) *DecompressMiddleware {
+	if len(encodings) == 0 {
+		encodings = DefaultAcceptEncodings
+	}
+
+	return &DecompressMiddleware{
+		encodings: slices.Clone(encodings),
+		logger:    &logger.NoOpLogger{},
+	}
+}
+
+// Process sets the Accept-Encoding header and decompresses the response of the next middleware.
+func (m *DecompressMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if decompression should be skipped
+	if skipDecompress, ok := ctx.Value(SkipDecompressKey{}).(bool); ok && skipDecompress {
+		return next(ctx, httpClient, req)
+	}
+
+	// Leave the response encoded if the caller asked for specific encodings
+	if req.Header.Get("Accept-Encoding") != "" {
+		return next(ctx, httpClient, req)
+	}
+
+	// Remove the header afterwards so a replayed request is handled the same way
+	req.Header.Set("Accept-Encoding", strings.Join(m.encodings, ", "))
+	defer req.Header.Del("Accept-Encoding")
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody || req.Method == http.MethodHead {
+		return resp, err
+	}
+
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || strings.EqualFold(encoding, "identity") {
+		return resp, nil
+	}
+
+	// Encodings are listed in the order they were applied, so they are removed in reverse
+	var codecs []compression.Codec
+	for _, token := range strings.Split(encoding, ",") {
+		codec, ok := compression.Lookup(token)
+		if !ok {
+			m.logger.WithFields(logger.String("encoding", encoding)).Warn("Unsupported response encoding")
+			return resp, nil
+		}
+		codecs = append(codecs, codec)
+	}
+
+	body := &decompressedBody{Reader: resp.Body, closers: []io.Closer{resp.Body}}
+	for _, codec := range slices.Backward(codecs) {
+		reader, err := codec.NewReader(body.Reader)
+		if err != nil {
+			body.Close()
+			return nil, fmt.Errorf("%w: %s: %w", ErrDecompressBody, codec.Encoding(), err)
+		}
+		body.Reader = reader
+		body.closers = append(body.closers, reader)
+	}
+
+	m.logger.WithFields(logger.String("encoding", encoding)).Debug("Response body decompressed")
+
+	// The body no longer matches the encoding and length the server sent
+	resp.Body = body
+	if limit, ok := middleware.MaxResponseBytesFromContext(ctx); ok && limit > 0 {
+		// Bound the decompressed size too, as a small body may inflate past the limit
+		resp.Body = middleware.LimitBody(body, limit, &clientErrors.ResponseTooLargeError{Limit: limit, URL: req.URL.String()})
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *DecompressMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware decodes the response body for the middleware before it.
+func (m *DecompressMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.DecodesResponseBody}
+}
+
+// decompressedBody reads a response body through its decompressing readers.
+type decompressedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the decompressing readers and the response body.
+func (b *decompressedBody) Close() error {
+	var errs []error
+	for _, closer := range slices.Backward(b.closers) {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package compress_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/compress"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/compression"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressMiddleware(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("compressible body ", 100)
+
+	// The server encodes the body with the encodings listed in the query, or the first accepted one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.URL.Query().Get("encoding")
+		if encoding == "" {
+			encoding, _, _ = strings.Cut(r.Header.Get("Accept-Encoding"), ",")
+		}
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+
+		data := []byte(body)
+		for _, token := range strings.Split(encoding, ",") {
+			codec, ok := compression.Lookup(token)
+			if !ok {
+				break
+			}
+			var err error
+			data, err = compression.Compress(codec, data)
+			require.NoError(t, err)
+		}
+
+		w.Header().Set("Content-Encoding", encoding)
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	get := func(t *testing.T, c *client.Client, url string, opts ...client.RequestOption) (*http.Response, string) {
+		t.Helper()
+
+		resp, err := c.NewRequest().URL(url).Do(context.Background(), opts...)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(b)
+	}
+
+	t.Run("Decompress the accepted encodings", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := compress.NewDecompress()
+		middleware.SetLogger(logger.NewBasicLogger())
+		c := client.NewClient(client.WithMiddleware(middleware))
+
+		for _, encoding := range []string{"gzip", "br", "zstd"} {
+			resp, got := get(t, c, server.URL+"?encoding="+encoding)
+			assert.Equal(t, body, got, encoding)
+			assert.Equal(t, "gzip, br, zstd", resp.Header.Get("X-Accept-Encoding"))
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.Equal(t, int64(-1), resp.ContentLength)
+			assert.True(t, resp.Uncompressed)
+		}
+	})
+
+	t.Run("Decompress stacked encodings in reverse", func(t *testing.T) {
+		t.Parallel()
+
+		c := client.NewClient(client.WithMiddleware(compress.NewDecompress()))
+
+		_, got := get(t, c, server.URL+"?encoding=gzip,br")
+		assert.Equal(t, body, got)
+	})
+
+	t.Run("Bound the decompressed size by the response limit", func(t *testing.T) {
+		t.Parallel()
+
+		// The compressed body is well below the limit, but inflates past it
+		c := client.NewClient(client.WithMiddleware(compress.NewDecompress()), client.WithMaxResponseBytes(500))
+
+		resp, err := c.NewRequest().URL(server.URL + "?encoding=gzip").Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+		assert.Len(t, b, 500)
+	})
+
+	t.Run("Advertise the configured encodings", func(t *testing.T) {
+		t.Parallel()
+
+		c := client.NewClient(client.WithMiddleware(compress.NewDecompress("zstd", "gzip")))
+
+		resp, got := get(t, c, server.URL)
+		assert.Equal(t, body, got)
+		assert.Equal(t, "zstd, gzip", resp.Header.Get("X-Accept-Encoding"))
+	})
+
+	t.Run("Leave responses to callers setting Accept-Encoding", func(t *testing.T) {
+		t.Parallel()
+
+		c := client.NewClient(client.WithMiddleware(compress.NewDecompress()))
+
+		resp, err := c.NewRequest().
+			URL(server.URL).
+			Header("Accept-Encoding", "br").
+			Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("Middleware disabled via context", func(t *testing.T) {
+		t.Parallel()
+
+		c := client.NewClient(client.WithMiddleware(compress.NewDecompress()))
+
+		// The transport falls back to requesting and decompressing gzip itself
+		resp, got := get(t, c, server.URL, client.WithContextValue(compress.SkipDecompressKey{}, true))
+		assert.Equal(t, "gzip", resp.Header.Get("X-Accept-Encoding"))
+		assert.Equal(t, body, got)
+	})
+}
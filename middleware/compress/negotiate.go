@@ -0,0 +1,229 @@
+package compress
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// DefaultCapabilityTTL is how long the compressed upload capability of a host is remembered by default.
+const DefaultCapabilityTTL = time.Hour
+
+// DefaultRejectStatuses are the statuses of responses rejecting compressed uploads by default.
+// Servers rejecting them with another status, such as 400 Bad Request, can be handled by adding it
+// to Negotiation.RejectStatuses.
+var DefaultRejectStatuses = []int{http.StatusUnsupportedMediaType}
+
+// Negotiation configures how the middleware learns whether hosts accept compressed uploads.
+type Negotiation struct {
+	// Probe sends an OPTIONS request to hosts of unknown capability before their first compressed
+	// upload, reading the encodings they accept from the Accept-Encoding header of the response,
+	// as advertised following RFC 7694.
+	Probe bool
+	// RejectStatuses are the statuses of responses rejecting a compressed upload, after which the
+	// request is sent again uncompressed if middleware.CanReplay allows it. Defaults to
+	// DefaultRejectStatuses when empty.
+	RejectStatuses []int
+	// TTL is how long the capability of a host is remembered. Defaults to DefaultCapabilityTTL
+	// when zero.
+	TTL time.Duration
+}
+
+// hostCapability is whether a host accepts compressed uploads.
+type hostCapability struct {
+	accepted bool
+	// known is false after a probe that did not tell, so the host is not probed again.
+	known   bool
+	expires time.Time
+}
+
+// SetNegotiation makes the middleware learn and remember for each host whether it accepts
+// compressed uploads, instead of always compressing. Uploads rejected with one of the reject
+// statuses are sent again uncompressed if the request can be replayed, and if that succeeds,
+// uploads to the host are no longer compressed until the capability expires. Rejected requests
+// that cannot be replayed, such as POST requests without an Idempotency-Key header, return the
+// rejection.
+func (m *CompressMiddleware) SetNegotiation(negotiation Negotiation) {
+	if len(negotiation.RejectStatuses) == 0 {
+		negotiation.RejectStatuses = DefaultRejectStatuses
+	}
+	if negotiation.TTL <= 0 {
+		negotiation.TTL = DefaultCapabilityTTL
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.negotiation = &negotiation
+	m.capabilities = make(map[string]hostCapability)
+}
+
+// Accepts reports whether the host accepts compressed uploads, and whether that is known.
+func (m *CompressMiddleware) Accepts(host string) (bool, bool) {
+	capability, ok := m.capability(host)
+	return capability.accepted, ok && capability.known
+}
+
+// Introspect returns the encoding, the minimum size of compressed bodies and, with negotiation,
+// whether the hosts of known capability accept compressed uploads.
+func (m *CompressMiddleware) Introspect() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hosts := make(map[string]bool)
+	now := time.Now()
+	for host, capability := range m.capabilities {
+		if capability.known && now.Before(capability.expires) {
+			hosts[host] = capability.accepted
+		}
+	}
+
+	return map[string]interface{}{
+		"encoding":    m.encoding,
+		"min_size":    m.minSize,
+		"negotiation": m.negotiation != nil,
+		"hosts":       hosts,
+	}
+}
+
+// negotiated returns the negotiation settings, or nil without negotiation.
+func (m *CompressMiddleware) negotiated() *Negotiation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.negotiation
+}
+
+// capability returns the remembered capability of the host, if it has not expired.
+func (m *CompressMiddleware) capability(host string) (hostCapability, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	capability, ok := m.capabilities[host]
+	if !ok || !time.Now().Before(capability.expires) {
+		return hostCapability{accepted: false, known: false, expires: time.Time{}}, false
+	}
+	return capability, true
+}
+
+// remember records the capability of the host.
+func (m *CompressMiddleware) remember(host string, accepted, known bool, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.capabilities[host] = hostCapability{accepted: accepted, known: known, expires: time.Now().Add(ttl)}
+}
+
+// sendNegotiated sends the compressed body to hosts accepting it or of unknown capability, and
+// the uncompressed body otherwise, falling back to the uncompressed body if the host rejects it.
+func (m *CompressMiddleware) sendNegotiated(
+	ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc,
+	negotiation *Negotiation, encoding string, body, compressed []byte,
+) (*http.Response, error) {
+	host := req.URL.Host
+	capability, ok := m.capability(host)
+	if !ok && negotiation.Probe {
+		capability = m.probe(ctx, httpClient, req, next, encoding)
+		m.remember(host, capability.accepted, capability.known, negotiation.TTL)
+	}
+
+	if capability.known && !capability.accepted {
+		setBody(req, body)
+		return next(ctx, httpClient, req)
+	}
+
+	setBody(req, compressed)
+	req.Header.Set("Content-Encoding", encoding)
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || !slices.Contains(negotiation.RejectStatuses, resp.StatusCode) {
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			m.remember(host, true, true, negotiation.TTL)
+		}
+		return resp, err
+	}
+
+	// Leave the rejection to the caller if the request must not be sent twice
+	if !middleware.CanReplay(ctx, req) {
+		m.logger.WithFields(
+			logger.String("host", host),
+			logger.Int("status", resp.StatusCode),
+		).Debug("Compressed upload rejected, not replaying the request")
+		return resp, nil
+	}
+
+	m.logger.WithFields(
+		logger.String("host", host),
+		logger.Int("status", resp.StatusCode),
+	).Debug("Compressed upload rejected, sending uncompressed")
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// Send the request again uncompressed, and only remember the host as not accepting compressed
+	// uploads if that is not rejected too, as the rejection may have had another cause
+	req.Header.Del("Content-Encoding")
+	setBody(req, body)
+	resp, err = next(ctx, httpClient, req)
+	if err == nil && !slices.Contains(negotiation.RejectStatuses, resp.StatusCode) {
+		m.logger.WithFields(logger.String("host", host)).Warn("Host does not accept compressed uploads")
+		m.remember(host, false, true, negotiation.TTL)
+	}
+	return resp, err
+}
+
+// probe asks the host which encodings it accepts with an OPTIONS request to the URL of the request.
+// The capability is unknown if the request fails or the response has no Accept-Encoding header.
+func (m *CompressMiddleware) probe(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc, encoding string) hostCapability {
+	unknown := hostCapability{accepted: false, known: false, expires: time.Time{}}
+
+	probe, err := http.NewRequestWithContext(ctx, http.MethodOptions, req.URL.String(), nil)
+	if err != nil {
+		return unknown
+	}
+	probe.Header = req.Header.Clone()
+	probe.Header.Del("Content-Encoding")
+	probe.Header.Del("Content-Type")
+
+	resp, err := next(ctx, httpClient, probe)
+	if err != nil {
+		m.logger.WithFields(logger.String("error", err.Error())).Debug("Compressed upload probe failed")
+		return unknown
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	values := resp.Header.Values("Accept-Encoding")
+	if len(values) == 0 {
+		return unknown
+	}
+	return hostCapability{accepted: acceptsEncoding(values, encoding), known: true, expires: time.Time{}}
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header values list the encoding, or any
+// encoding, with a non-zero quality.
+func acceptsEncoding(values []string, encoding string) bool {
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(entry, ";")
+			name = strings.TrimSpace(name)
+			if !strings.EqualFold(name, encoding) && name != "*" {
+				continue
+			}
+
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			quality, err := strconv.ParseFloat(q, 64)
+			return err != nil || quality > 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/internal/waitqueue"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// defaultRetryAfter is the retry hint given before any request has completed.
+const defaultRetryAfter = time.Second
+
+// durationWeight is the weight of the latest request in the average request duration.
+const durationWeight = 0.2
+
+// ConcurrencyMiddleware limits the number of requests processed concurrently.
+// Requests exceeding the limit are queued and served in order of their priority. Requests sharing
+// an order key set with WithOrderKey are processed one at a time, in the order they arrived.
+type ConcurrencyMiddleware struct {
+	maxConcurrent int
+	maxQueue      int
+	inflight      int
+	waiters       *waitqueue.Queue[string]
+	avgDuration   time.Duration
+	hosts         map[string]*hostStats
+	order         *keyOrder
+	mu            sync.Mutex
+	logger        logger.Logger
+}
+
+// New creates a new ConcurrencyMiddleware instance.
+func New(maxConcurrent int) *ConcurrencyMiddleware {
+	return &ConcurrencyMiddleware{
+		maxConcurrent: maxConcurrent,
+		maxQueue:      0,
+		inflight:      0,
+		waiters:       waitqueue.New[string](),
+		avgDuration:   0,
+		hosts:         make(map[string]*hostStats),
+		order:         newKeyOrder(),
+		mu:            sync.Mutex{},
+		logger:        &logger.NoOpLogger{},
+	}
+}
+
+// Process waits for a free slot before passing the request to the next middleware.
+func (m *ConcurrencyMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	host := req.URL.Host
+
+	// Wait for the requests with the same order key that arrived earlier
+	if key, ok := orderKeyFromContext(ctx); ok {
+		done, err := m.order.wait(ctx, key)
+		if err != nil {
+			return nil, waitError(err)
+		}
+		defer done()
+	}
+
+	// Wait for a free slot
+	wait, err := m.acquire(ctx, host)
+	if err != nil {
+		return nil, waitError(err)
+	}
+	defer m.release(host)
+
+	// Execute the next middleware in the chain, measuring the service rate for retry hints
+	start := time.Now()
+	resp, err := next(ctx, httpClient, req)
+	failed := middleware.Classify(ctx, resp, err) != middleware.ClassSuccess
+	m.observe(host, wait, time.Since(start), failed)
+
+	return resp, err
+}
+
+// waitError returns the error of a request that stopped waiting, reporting deadlines as timeouts.
+func waitError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return clientErrors.ErrTimeout
+	}
+	return err
+}
+
+// observe adds the duration of a completed request to the average request duration, and records
+// it in the statistics of its host.
+func (m *ConcurrencyMiddleware) observe(host string, wait, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hostStats(host).complete(wait, duration, failed, time.Now())
+	if m.avgDuration == 0 {
+		m.avgDuration = duration
+		return
+	}
+	m.avgDuration = movingAverage(m.avgDuration, duration)
+}
+
+// hostStats returns the statistics of the host, creating them if needed.
+// The caller must hold the lock.
+func (m *ConcurrencyMiddleware) hostStats(host string) *hostStats {
+	stats, ok := m.hosts[host]
+	if !ok {
+		stats = &hostStats{
+			inflight:     0,
+			queued:       0,
+			completed:    0,
+			failed:       0,
+			rejected:     0,
+			avgQueueWait: 0,
+			avgDuration:  0,
+			windowStart:  time.Time{},
+			current:      0,
+			previous:     0,
+		}
+		m.hosts[host] = stats
+	}
+	return stats
+}
+
+// retryAfter estimates when the queue will have room again, from the queue depth and the
+// rate slots free up at. It must be called with the lock held.
+func (m *ConcurrencyMiddleware) retryAfter(queued int) time.Duration {
+	if m.avgDuration == 0 || m.maxConcurrent <= 0 {
+		return defaultRetryAfter
+	}
+	return m.avgDuration * time.Duration(queued+1) / time.Duration(m.maxConcurrent)
+}
+
+// acquire blocks until a slot is available for the request to the host, returning how long the
+// request was queued.
+func (m *ConcurrencyMiddleware) acquire(ctx context.Context, host string) (time.Duration, error) {
+	m.mu.Lock()
+	stats := m.hostStats(host)
+	if m.inflight < m.maxConcurrent && m.waiters.Len() == 0 {
+		m.inflight++
+		stats.inflight++
+		m.mu.Unlock()
+		return 0, nil
+	}
+
+	// Reject the request rather than queueing it beyond the limit
+	if queued := m.waiters.Len(); m.maxQueue > 0 && queued >= m.maxQueue {
+		err := &clientErrors.OverloadedError{RetryAfter: m.retryAfter(queued), QueueDepth: queued}
+		stats.rejected++
+		m.mu.Unlock()
+
+		m.logger.WithFields(
+			logger.Int("queued", queued),
+			logger.Duration("retry_after", err.RetryAfter),
+		).Warn("Request rejected, concurrency queue is full")
+		return 0, err
+	}
+
+	priority := middleware.PriorityFromContext(ctx)
+	w := m.waiters.Push(priority, host)
+	stats.queued++
+	m.mu.Unlock()
+	queuedAt := time.Now()
+
+	m.logger.WithFields(logger.Int("priority", int(priority))).Debug("Request queued for concurrency slot")
+
+	select {
+	case <-w.Ready:
+		return time.Since(queuedAt), nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		removed := m.waiters.Remove(w)
+		if removed {
+			stats.queued--
+		}
+		m.mu.Unlock()
+
+		// The slot was handed over while the context was cancelled, pass it on
+		if !removed {
+			m.release(host)
+		}
+		return 0, ctx.Err()
+	}
+}
+
+// release frees the slot of a request to the host or hands it over to the queued request with
+// the highest priority.
+func (m *ConcurrencyMiddleware) release(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hostStats(host).inflight--
+	if m.waiters.Len() == 0 {
+		m.inflight--
+		return
+	}
+
+	w := m.waiters.Pop()
+	stats := m.hostStats(w.Value)
+	stats.queued--
+	stats.inflight++
+}
+
+// SetMaxQueue limits the number of queued requests. Requests arriving while the queue is full are
+// rejected with an *errors.OverloadedError suggesting when to retry. Zero, the default, queues
+// requests without limit.
+func (m *ConcurrencyMiddleware) SetMaxQueue(maxQueue int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxQueue = maxQueue
+}
+
+// Stats returns the statistics of every host the middleware processed requests to.
+func (m *ConcurrencyMiddleware) Stats() map[string]HostStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	stats := make(map[string]HostStats, len(m.hosts))
+	for host, s := range m.hosts {
+		stats[host] = s.snapshot(now)
+	}
+	return stats
+}
+
+// Introspect returns the configuration and live state of the concurrency limiter, along with the
+// statistics of every host.
+func (m *ConcurrencyMiddleware) Introspect() map[string]interface{} {
+	hosts := m.Stats()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return map[string]interface{}{
+		"maxConcurrent": m.maxConcurrent,
+		"maxQueue":      m.maxQueue,
+		"inflight":      m.inflight,
+		"queued":        m.waiters.Len(),
+		"orderWaiting":  m.order.waiting(),
+		"hosts":         hosts,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *ConcurrencyMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
@@ -0,0 +1,382 @@
+package concurrency_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/concurrency"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Limit concurrent requests", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(2)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var current, peak atomic.Int32
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			n := current.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			current.Add(-1)
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		var wg sync.WaitGroup
+		for range 6 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+				resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(2), peak.Load())
+	})
+
+	t.Run("Higher priority requests are served first", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var (
+			mu    sync.Mutex
+			order []clientMiddleware.Priority
+		)
+		release := make(chan struct{})
+		makeRequest := func(priority clientMiddleware.Priority, block bool) error {
+			ctx := clientMiddleware.WithPriority(context.Background(), priority)
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				if block {
+					<-release
+				}
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			return err
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, makeRequest(clientMiddleware.PriorityNormal, true))
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		for _, priority := range []clientMiddleware.Priority{clientMiddleware.PriorityLow, clientMiddleware.PriorityNormal, clientMiddleware.PriorityCritical} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, makeRequest(priority, false))
+			}()
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, []clientMiddleware.Priority{
+			clientMiddleware.PriorityNormal,
+			clientMiddleware.PriorityCritical,
+			clientMiddleware.PriorityNormal,
+			clientMiddleware.PriorityLow,
+		}, order)
+	})
+
+	t.Run("Queued request times out", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		release := make(chan struct{})
+		go func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				<-release
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		require.ErrorIs(t, err, clientErrors.ErrTimeout)
+		close(release)
+
+		// The slot is still usable after the timed out request left the queue
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Introspect reports live state", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(2)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				close(started)
+				<-release
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+		}()
+		<-started
+
+		assert.Equal(t, map[string]interface{}{
+			"maxConcurrent": 2,
+			"maxQueue":      0,
+			"inflight":      1,
+			"queued":        0,
+			"orderWaiting":  0,
+			"hosts": map[string]concurrency.HostStats{
+				"example.com": {InFlight: 1},
+			},
+		}, middleware.Introspect())
+		close(release)
+	})
+
+	t.Run("Track statistics per host", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "http://a.example.com", nil)
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				close(started)
+				<-release
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+		}()
+		<-started
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "http://b.example.com", nil)
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+			})
+		}()
+
+		require.Eventually(t, func() bool {
+			return middleware.Stats()["b.example.com"].Queued == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, 1, middleware.Stats()["a.example.com"].InFlight)
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		stats := middleware.Stats()
+		assert.Equal(t, uint64(1), stats["a.example.com"].Completed)
+		assert.Zero(t, stats["a.example.com"].Failed)
+		assert.Zero(t, stats["a.example.com"].AvgQueueWait)
+		assert.GreaterOrEqual(t, stats["a.example.com"].AvgDuration, 20*time.Millisecond)
+
+		// The queued request waited for the first one to complete
+		assert.Equal(t, 0, stats["b.example.com"].Queued)
+		assert.Equal(t, 0, stats["b.example.com"].InFlight)
+		assert.Equal(t, uint64(1), stats["b.example.com"].Failed)
+		assert.GreaterOrEqual(t, stats["b.example.com"].AvgQueueWait, 20*time.Millisecond)
+		assert.InDelta(t, 1.0/60, stats["b.example.com"].CompletionRate, 0.001)
+	})
+
+	t.Run("Reject requests when the queue is full", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(1)
+		middleware.SetMaxQueue(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 2)
+		blocking := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			started <- struct{}{}
+			<-release
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+				_, err := middleware.Process(context.Background(), &http.Client{}, req, blocking)
+				assert.NoError(t, err)
+			}()
+		}
+		<-started
+		require.Eventually(t, func() bool {
+			return middleware.Introspect()["queued"] == 1
+		}, time.Second, time.Millisecond)
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, blocking)
+		require.ErrorIs(t, err, clientErrors.ErrOverloaded)
+
+		var overloaded *clientErrors.OverloadedError
+		require.ErrorAs(t, err, &overloaded)
+		assert.Equal(t, 1, overloaded.QueueDepth)
+		assert.Equal(t, time.Second, overloaded.RetryAfter)
+
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("Preserve submission order per key", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(4)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var (
+			order []string
+			mu    sync.Mutex
+		)
+		releases := map[string]chan struct{}{"a1": make(chan struct{}), "a2": make(chan struct{}), "a3": make(chan struct{}), "b1": make(chan struct{})}
+		started := make(chan string, len(releases))
+
+		var wg sync.WaitGroup
+		send := func(name, key string) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx := concurrency.WithOrder(context.Background(), key)
+				req := httptest.NewRequest(http.MethodPut, "http://example.com/"+key, nil)
+				_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+					mu.Lock()
+					order = append(order, name)
+					mu.Unlock()
+					started <- name
+					<-releases[name]
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		waiting := func(n int) {
+			require.Eventually(t, func() bool {
+				return middleware.Introspect()["orderWaiting"] == n
+			}, time.Second, time.Millisecond)
+		}
+
+		send("a1", "user:1")
+		assert.Equal(t, "a1", <-started)
+		send("a2", "user:1")
+		waiting(1)
+		send("a3", "user:1")
+		waiting(2)
+
+		// Requests with other keys run in parallel
+		send("b1", "user:2")
+		assert.Equal(t, "b1", <-started)
+		close(releases["b1"])
+
+		close(releases["a1"])
+		assert.Equal(t, "a2", <-started)
+		close(releases["a2"])
+		assert.Equal(t, "a3", <-started)
+		close(releases["a3"])
+		wg.Wait()
+
+		assert.Equal(t, []string{"a1", "b1", "a2", "a3"}, order)
+		waiting(0)
+	})
+
+	t.Run("Retry hint follows the service rate", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := concurrency.New(1)
+		middleware.SetMaxQueue(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		// Serve a request to measure the duration of requests
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			time.Sleep(20 * time.Millisecond)
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		require.NoError(t, err)
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 2)
+		blocking := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			started <- struct{}{}
+			<-release
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+				_, _ = middleware.Process(context.Background(), &http.Client{}, req, blocking)
+			}()
+		}
+		<-started
+		require.Eventually(t, func() bool {
+			return middleware.Introspect()["queued"] == 1
+		}, time.Second, time.Millisecond)
+
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err = middleware.Process(context.Background(), &http.Client{}, req, blocking)
+
+		// Two requests served one at a time must complete before a slot frees up
+		var overloaded *clientErrors.OverloadedError
+		require.ErrorAs(t, err, &overloaded)
+		assert.GreaterOrEqual(t, overloaded.RetryAfter, 40*time.Millisecond)
+		assert.Less(t, overloaded.RetryAfter, time.Second)
+
+		close(release)
+		wg.Wait()
+	})
+}
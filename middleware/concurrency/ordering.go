@@ -0,0 +1,104 @@
+package concurrency
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client"
+)
+
+// orderKey is the context key of the order key of a request.
+type orderKey struct{}
+
+// WithOrderKey returns a request option ordering the request after the requests with the same key,
+// such as the ID of the resource it mutates. Requests sharing a key are processed one at a time in
+// the order they reached the middleware, while requests with different keys run in parallel.
+func WithOrderKey(key string) client.RequestOption {
+	return client.WithContextValue(orderKey{}, key)
+}
+
+// WithOrder returns a copy of ctx carrying the order key of the requests sent with it, like
+// WithOrderKey.
+func WithOrder(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, orderKey{}, key)
+}
+
+// orderKeyFromContext returns the order key of the request, if any.
+func orderKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(orderKey{}).(string)
+	return key, ok && key != ""
+}
+
+// keyOrder serializes the requests sharing a key in their order of arrival.
+type keyOrder struct {
+	// queues holds the turns of the requests of each key, starting with the request processed.
+	queues map[string][]chan struct{}
+	mu     sync.Mutex
+}
+
+// newKeyOrder creates a new keyOrder instance.
+func newKeyOrder() *keyOrder {
+	return &keyOrder{
+		queues: make(map[string][]chan struct{}),
+		mu:     sync.Mutex{},
+	}
+}
+
+// wait blocks until the requests with the key that arrived earlier are done. The returned function
+// must be called once the request is done, to let the next one proceed.
+func (o *keyOrder) wait(ctx context.Context, key string) (func(), error) {
+	turn := make(chan struct{})
+
+	o.mu.Lock()
+	o.queues[key] = append(o.queues[key], turn)
+	if len(o.queues[key]) == 1 {
+		close(turn)
+	}
+	o.mu.Unlock()
+
+	done := func() { o.done(key, turn) }
+	select {
+	case <-turn:
+		return done, nil
+	case <-ctx.Done():
+		// Give up the turn, passing it on if it was handed over meanwhile
+		done()
+		return nil, ctx.Err()
+	}
+}
+
+// done removes the turn from the queue of the key, handing the turn to the next request if the
+// removed one was processed.
+func (o *keyOrder) done(key string, turn chan struct{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	queue := o.queues[key]
+	i := slices.Index(queue, turn)
+	if i < 0 {
+		return
+	}
+
+	queue = slices.Delete(queue, i, i+1)
+	if len(queue) == 0 {
+		delete(o.queues, key)
+		return
+	}
+	if i == 0 {
+		close(queue[0])
+	}
+	o.queues[key] = queue
+}
+
+// waiting returns the number of requests waiting for the requests with their key to be done.
+func (o *keyOrder) waiting() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	waiting := 0
+	for _, queue := range o.queues {
+		waiting += len(queue) - 1
+	}
+	return waiting
+}
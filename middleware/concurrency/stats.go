@@ -0,0 +1,99 @@
+package concurrency
+
+import (
+	"time"
+)
+
+// rateWindow is the window over which the completion rate of a host is measured.
+const rateWindow = time.Minute
+
+// HostStats reports the requests to a host passing through the middleware, to tune the
+// concurrency and rate limits of the host from observed load.
+type HostStats struct {
+	// InFlight is the number of requests holding a slot.
+	InFlight int `json:"inFlight"`
+	// Queued is the number of requests waiting for a slot.
+	Queued int `json:"queued"`
+	// Completed is the number of requests that returned, successfully or not.
+	Completed uint64 `json:"completed"`
+	// Failed is the number of completed requests that did not succeed.
+	Failed uint64 `json:"failed"`
+	// Rejected is the number of requests rejected because the queue was full.
+	Rejected uint64 `json:"rejected"`
+	// AvgQueueWait is the moving average of the time requests waited for a slot, zero for
+	// requests that got one immediately.
+	AvgQueueWait time.Duration `json:"avgQueueWait"`
+	// AvgDuration is the moving average of the time requests held their slot.
+	AvgDuration time.Duration `json:"avgDuration"`
+	// CompletionRate is the number of requests completed per second over the last minute.
+	CompletionRate float64 `json:"completionRate"`
+}
+
+// hostStats tracks the requests to a host.
+type hostStats struct {
+	inflight     int
+	queued       int
+	completed    uint64
+	failed       uint64
+	rejected     uint64
+	avgQueueWait time.Duration
+	avgDuration  time.Duration
+	windowStart  time.Time
+	current      int
+	previous     int
+}
+
+// complete records a completed request.
+func (s *hostStats) complete(wait, duration time.Duration, failed bool, now time.Time) {
+	s.completed++
+	if failed {
+		s.failed++
+	}
+	if s.completed == 1 {
+		s.avgQueueWait = wait
+		s.avgDuration = duration
+	} else {
+		s.avgQueueWait = movingAverage(s.avgQueueWait, wait)
+		s.avgDuration = movingAverage(s.avgDuration, duration)
+	}
+
+	s.rotate(now)
+	s.current++
+}
+
+// rotate starts a new rate window once the current one is over.
+func (s *hostStats) rotate(now time.Time) {
+	elapsed := now.Sub(s.windowStart)
+	switch {
+	case elapsed >= 2*rateWindow:
+		s.previous, s.current = 0, 0
+		s.windowStart = now
+	case elapsed >= rateWindow:
+		s.previous, s.current = s.current, 0
+		s.windowStart = s.windowStart.Add(rateWindow)
+	}
+}
+
+// snapshot returns the statistics of the host, estimating the completion rate over a window
+// sliding across the current and previous ones.
+func (s *hostStats) snapshot(now time.Time) HostStats {
+	s.rotate(now)
+	weight := 1 - float64(now.Sub(s.windowStart))/float64(rateWindow)
+	completions := float64(s.current) + float64(s.previous)*weight
+
+	return HostStats{
+		InFlight:       s.inflight,
+		Queued:         s.queued,
+		Completed:      s.completed,
+		Failed:         s.failed,
+		Rejected:       s.rejected,
+		AvgQueueWait:   s.avgQueueWait,
+		AvgDuration:    s.avgDuration,
+		CompletionRate: completions / rateWindow.Seconds(),
+	}
+}
+
+// movingAverage adds the latest duration to the moving average.
+func movingAverage(avg, latest time.Duration) time.Duration {
+	return time.Duration(durationWeight*float64(latest) + (1-durationWeight)*float64(avg))
+}
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidConfig     = errors.New("invalid configuration")
+	ErrUnsupportedFormat = errors.New("unsupported configuration format")
+)
+
+// Format is the encoding of a configuration file.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Config describes the middleware stack of a client.
+// A nil section means the corresponding middleware is not part of the stack.
+type Config struct {
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	Retry     *RetryConfig     `json:"retry,omitempty"     yaml:"retry,omitempty"`
+	Proxy     *ProxyConfig     `json:"proxy,omitempty"     yaml:"proxy,omitempty"`
+	Cookie    *CookieConfig    `json:"cookie,omitempty"    yaml:"cookie,omitempty"`
+	Flags     map[string]bool  `json:"flags,omitempty"     yaml:"flags,omitempty"`
+}
+
+// RateLimitConfig configures the rate limit middleware.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond" yaml:"requestsPerSecond"`
+	Burst             int     `json:"burst"             yaml:"burst"`
+}
+
+// RetryConfig configures the retry middleware.
+type RetryConfig struct {
+	MaxAttempts     uint64   `json:"maxAttempts"     yaml:"maxAttempts"`
+	InitialInterval Duration `json:"initialInterval" yaml:"initialInterval"`
+	MaxInterval     Duration `json:"maxInterval"     yaml:"maxInterval"`
+}
+
+// ProxyConfig configures the proxy middleware.
+type ProxyConfig struct {
+	URLs []string `json:"urls" yaml:"urls"`
+}
+
+// CookieConfig configures the cookie middleware.
+type CookieConfig struct {
+	Sets [][]Cookie `json:"sets" yaml:"sets"`
+}
+
+// Cookie is a cookie of a cookie set.
+type Cookie struct {
+	Name  string `json:"name"  yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// Duration is a time.Duration encoded as a string such as "1.5s".
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// MarshalYAML encodes the duration as a string.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// UnmarshalYAML decodes a duration string.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.parse(value.Value)
+}
+
+// parse sets the duration from a string such as "1.5s".
+func (d *Duration) parse(s string) error {
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Parse decodes a configuration in the given format and validates it.
+func Parse(data []byte, format Format) (*Config, error) {
+	var cfg Config
+
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// LoadFile reads a configuration file, using its extension to choose between JSON and YAML.
+func LoadFile(path string) (*Config, error) {
+	var format Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, format)
+}
+
+// LoadEnv reads a configuration from environment variables with the given prefix:
+//
+//	<PREFIX>_RATELIMIT_RPS, <PREFIX>_RATELIMIT_BURST
+//	<PREFIX>_RETRY_MAX_ATTEMPTS, <PREFIX>_RETRY_INITIAL_INTERVAL, <PREFIX>_RETRY_MAX_INTERVAL
+//	<PREFIX>_PROXIES as comma-separated URLs
+//	<PREFIX>_COOKIES as cookie sets separated by "|", each in the "name=value; name=value" format
+//	<PREFIX>_FLAGS as comma-separated names of the enabled middleware flags
+//
+// A section is present if any of its variables is set.
+func LoadEnv(prefix string) (*Config, error) {
+	env := func(name string) (string, bool) {
+		return os.LookupEnv(prefix + "_" + name)
+	}
+
+	var (
+		cfg Config
+		err error
+	)
+
+	rps, hasRPS := env("RATELIMIT_RPS")
+	burst, hasBurst := env("RATELIMIT_BURST")
+	if hasRPS || hasBurst {
+		cfg.RateLimit = &RateLimitConfig{RequestsPerSecond: 0, Burst: 0}
+		if cfg.RateLimit.RequestsPerSecond, err = strconv.ParseFloat(rps, 64); hasRPS && err != nil {
+			return nil, fmt.Errorf("%w: %s_RATELIMIT_RPS: %w", ErrInvalidConfig, prefix, err)
+		}
+		if cfg.RateLimit.Burst, err = strconv.Atoi(burst); hasBurst && err != nil {
+			return nil, fmt.Errorf("%w: %s_RATELIMIT_BURST: %w", ErrInvalidConfig, prefix, err)
+		}
+	}
+
+	maxAttempts, hasMaxAttempts := env("RETRY_MAX_ATTEMPTS")
+	initialInterval, hasInitialInterval := env("RETRY_INITIAL_INTERVAL")
+	maxInterval, hasMaxInterval := env("RETRY_MAX_INTERVAL")
+	if hasMaxAttempts || hasInitialInterval || hasMaxInterval {
+		cfg.Retry = &RetryConfig{MaxAttempts: 0, InitialInterval: 0, MaxInterval: 0}
+		if cfg.Retry.MaxAttempts, err = strconv.ParseUint(maxAttempts, 10, 64); hasMaxAttempts && err != nil {
+			return nil, fmt.Errorf("%w: %s_RETRY_MAX_ATTEMPTS: %w", ErrInvalidConfig, prefix, err)
+		}
+		if hasInitialInterval {
+			if err := cfg.Retry.InitialInterval.parse(initialInterval); err != nil {
+				return nil, err
+			}
+		}
+		if hasMaxInterval {
+			if err := cfg.Retry.MaxInterval.parse(maxInterval); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if proxies, ok := env("PROXIES"); ok {
+		cfg.Proxy = &ProxyConfig{URLs: splitList(proxies, ",")}
+	}
+
+	if cookies, ok := env("COOKIES"); ok {
+		cfg.Cookie = &CookieConfig{Sets: nil}
+		for _, set := range splitList(cookies, "|") {
+			parsed, err := http.ParseCookie(set)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s_COOKIES: %w", ErrInvalidConfig, prefix, err)
+			}
+
+			cookieSet := make([]Cookie, 0, len(parsed))
+			for _, cookie := range parsed {
+				cookieSet = append(cookieSet, Cookie{Name: cookie.Name, Value: cookie.Value})
+			}
+			cfg.Cookie.Sets = append(cfg.Cookie.Sets, cookieSet)
+		}
+	}
+
+	if flags, ok := env("FLAGS"); ok {
+		cfg.Flags = make(map[string]bool)
+		for _, flag := range splitList(flags, ",") {
+			cfg.Flags[flag] = true
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks that the configuration can be applied.
+func (c *Config) Validate() error {
+	if c.RateLimit != nil {
+		if c.RateLimit.RequestsPerSecond <= 0 {
+			return fmt.Errorf("%w: rate limit must be positive", ErrInvalidConfig)
+		}
+		if c.RateLimit.Burst <= 0 {
+			return fmt.Errorf("%w: burst must be positive", ErrInvalidConfig)
+		}
+	}
+
+	if c.Retry != nil && c.Retry.MaxInterval < c.Retry.InitialInterval {
+		return fmt.Errorf("%w: max retry interval is shorter than the initial interval", ErrInvalidConfig)
+	}
+
+	if c.Proxy != nil {
+		if _, err := c.Proxy.parse(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parse returns the proxy URLs.
+func (c *ProxyConfig) parse() ([]*url.URL, error) {
+	proxies := make([]*url.URL, 0, len(c.URLs))
+	for _, rawURL := range c.URLs {
+		proxy, err := url.Parse(rawURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("%w: invalid proxy URL %q", ErrInvalidConfig, rawURL)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// cookies returns the cookie sets as HTTP cookies.
+func (c *CookieConfig) cookies() [][]*http.Cookie {
+	sets := make([][]*http.Cookie, 0, len(c.Sets))
+	for _, set := range c.Sets {
+		cookies := make([]*http.Cookie, 0, len(set))
+		for _, cookie := range set {
+			cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value}) //nolint:exhaustruct
+		}
+		sets = append(sets, cookies)
+	}
+	return sets
+}
+
+// flags returns the flag states as middleware flags.
+func (c *Config) flags() map[middleware.Flag]bool {
+	flags := make(map[middleware.Flag]bool, len(c.Flags))
+	for flag, enabled := range c.Flags {
+		flags[middleware.Flag(flag)] = enabled
+	}
+	return flags
+}
+
+// splitList splits s by sep, dropping empty elements.
+func splitList(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
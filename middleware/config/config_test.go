@@ -0,0 +1,235 @@
+package config_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/config"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yamlConfig = `
+rateLimit:
+  requestsPerSecond: 10
+  burst: 5
+retry:
+  maxAttempts: 3
+  initialInterval: 100ms
+  maxInterval: 1s
+proxy:
+  urls:
+    - http://proxy1.example.com:8080
+cookie:
+  sets:
+    - - name: session
+        value: abc
+`
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parse JSON and YAML", func(t *testing.T) {
+		t.Parallel()
+
+		fromYAML, err := config.Parse([]byte(yamlConfig), config.FormatYAML)
+		require.NoError(t, err)
+
+		fromJSON, err := config.Parse([]byte(`{
+			"rateLimit": {"requestsPerSecond": 10, "burst": 5},
+			"retry": {"maxAttempts": 3, "initialInterval": "100ms", "maxInterval": "1s"},
+			"proxy": {"urls": ["http://proxy1.example.com:8080"]},
+			"cookie": {"sets": [[{"name": "session", "value": "abc"}]]}
+		}`), config.FormatJSON)
+		require.NoError(t, err)
+
+		assert.Equal(t, fromYAML, fromJSON)
+		assert.Equal(t, config.Duration(100*time.Millisecond), fromJSON.Retry.InitialInterval)
+	})
+
+	t.Run("Reject invalid configuration", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := config.Parse([]byte(`{"rateLimit": {"requestsPerSecond": 10, "burst": 0}}`), config.FormatJSON)
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+
+		_, err = config.Parse([]byte(`{"proxy": {"urls": ["not a url"]}}`), config.FormatJSON)
+		require.ErrorIs(t, err, config.ErrInvalidConfig)
+
+		_, err = config.Parse([]byte(`{}`), config.Format("toml"))
+		require.ErrorIs(t, err, config.ErrUnsupportedFormat)
+	})
+}
+
+// TestLoadEnv is not parallel as it sets environment variables.
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("AXONET_TEST_RATELIMIT_RPS", "2.5")
+	t.Setenv("AXONET_TEST_RATELIMIT_BURST", "1")
+	t.Setenv("AXONET_TEST_PROXIES", "http://a.example.com, http://b.example.com")
+	t.Setenv("AXONET_TEST_COOKIES", "a=1; b=2|c=3")
+	t.Setenv("AXONET_TEST_FLAGS", "retry.aggressive, cache.stale-ok")
+
+	cfg, err := config.LoadEnv("AXONET_TEST")
+	require.NoError(t, err)
+
+	assert.Equal(t, &config.RateLimitConfig{RequestsPerSecond: 2.5, Burst: 1}, cfg.RateLimit)
+	assert.Nil(t, cfg.Retry)
+	assert.Equal(t, []string{"http://a.example.com", "http://b.example.com"}, cfg.Proxy.URLs)
+	assert.Equal(t, [][]config.Cookie{
+		{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}},
+		{{Name: "c", Value: "3"}},
+	}, cfg.Cookie.Sets)
+	assert.Equal(t, map[string]bool{"retry.aggressive": true, "cache.stale-ok": true}, cfg.Flags)
+}
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Hot reload without recreating the client", func(t *testing.T) {
+		t.Parallel()
+
+		var cookies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, _ := r.Cookie("session")
+			cookies = append(cookies, cookie.Value)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"cookie": {"sets": [[{"name": "session", "value": "old"}]]}}`), 0o600))
+
+		cfg, err := config.LoadFile(path)
+		require.NoError(t, err)
+
+		manager, err := config.New(cfg)
+		require.NoError(t, err)
+		manager.SetLogger(logger.NewBasicLogger())
+
+		c := client.NewClient(manager.Options()...)
+		do := func() {
+			resp, err := c.NewRequest().Method(http.MethodGet).URL(server.URL).Do(context.Background())
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		do()
+		require.NoError(t, os.WriteFile(path, []byte(`{"cookie": {"sets": [[{"name": "session", "value": "new"}]]}}`), 0o600))
+		require.NoError(t, manager.Reload(config.FileLoader(path)))
+		do()
+
+		assert.Equal(t, []string{"old", "new"}, cookies)
+		assert.Equal(t, "new", manager.Config().Cookie.Sets[0][0].Value)
+	})
+
+	t.Run("Keep the settings of requests in flight", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			cookies  []string
+			mu       sync.Mutex
+			started  = make(chan struct{})
+			released = make(chan struct{})
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, _ := r.Cookie("session")
+			mu.Lock()
+			cookies = append(cookies, cookie.Value)
+			first := len(cookies) == 1
+			mu.Unlock()
+
+			// Fail the first attempt once the configuration has changed
+			if first {
+				close(started)
+				<-released
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		newConfig := func(value string) *config.Config {
+			return &config.Config{
+				RateLimit: nil,
+				Retry:     &config.RetryConfig{MaxAttempts: 2, InitialInterval: config.Duration(time.Millisecond), MaxInterval: config.Duration(time.Millisecond)},
+				Proxy:     nil,
+				Cookie:    &config.CookieConfig{Sets: [][]config.Cookie{{{Name: "session", Value: value}}}},
+				Flags:     nil,
+			}
+		}
+
+		manager, err := config.New(newConfig("old"))
+		require.NoError(t, err)
+
+		c := client.NewClient(manager.Options()...)
+		do := func() error {
+			resp, err := c.NewRequest().Method(http.MethodGet).URL(server.URL).Do(context.Background())
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- do() }()
+
+		<-started
+		require.NoError(t, manager.Apply(newConfig("new")))
+		close(released)
+		require.NoError(t, <-done)
+		require.NoError(t, do())
+
+		// The retried attempt keeps the cookies of the configuration it started with
+		assert.Equal(t, []string{"old", "old", "new"}, cookies)
+	})
+
+	t.Run("Update flags", func(t *testing.T) {
+		t.Parallel()
+
+		manager, err := config.New(&config.Config{})
+		require.NoError(t, err)
+
+		c := client.NewClient(manager.Options()...)
+		require.NoError(t, manager.Apply(&config.Config{Flags: map[string]bool{"retry.aggressive": true}}))
+		assert.Equal(t, map[middleware.Flag]bool{"retry.aggressive": true}, c.Introspect().Flags)
+	})
+
+	t.Run("Reject layout changes", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := config.Parse([]byte(yamlConfig), config.FormatYAML)
+		require.NoError(t, err)
+
+		manager, err := config.New(cfg)
+		require.NoError(t, err)
+		assert.Len(t, manager.Options(), 5)
+
+		err = manager.Apply(&config.Config{RateLimit: nil, Retry: cfg.Retry, Proxy: cfg.Proxy, Cookie: cfg.Cookie})
+		require.ErrorIs(t, err, config.ErrLayoutChanged)
+		assert.Same(t, cfg, manager.Config())
+	})
+
+	t.Run("Keep the configuration when loading fails", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := config.Parse([]byte(yamlConfig), config.FormatYAML)
+		require.NoError(t, err)
+
+		manager, err := config.New(cfg)
+		require.NoError(t, err)
+
+		err = manager.Reload(config.FileLoader(filepath.Join(t.TempDir(), "missing.yaml")))
+		require.Error(t, err)
+		assert.Same(t, cfg, manager.Config())
+	})
+}
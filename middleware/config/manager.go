@@ -0,0 +1,254 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/cookie"
+	"github.com/jaxron/axonet/middleware/proxy"
+	"github.com/jaxron/axonet/middleware/ratelimit"
+	"github.com/jaxron/axonet/middleware/retry"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrLayoutChanged = errors.New("configuration adds or removes middleware")
+
+// LoaderFunc loads a configuration, such as from a file or a remote configuration service.
+type LoaderFunc func() (*Config, error)
+
+// FileLoader returns a LoaderFunc reading the configuration file at path.
+func FileLoader(path string) LoaderFunc {
+	return func() (*Config, error) {
+		return LoadFile(path)
+	}
+}
+
+// EnvLoader returns a LoaderFunc reading the configuration from environment variables with the given prefix.
+func EnvLoader(prefix string) LoaderFunc {
+	return func() (*Config, error) {
+		return LoadEnv(prefix)
+	}
+}
+
+// Manager constructs the middleware stack described by a configuration and
+// updates it when the configuration changes, without recreating the client.
+type Manager struct {
+	current   atomic.Pointer[generation]
+	retry     *slot[*retry.RetryMiddleware]
+	rateLimit *slot[*ratelimit.RateLimiterMiddleware]
+	proxy     *slot[*proxy.ProxyMiddleware]
+	cookie    *slot[*cookie.CookieMiddleware]
+	flags     *middleware.FlagSet
+	mu        sync.Mutex
+	logger    logger.Logger
+}
+
+// generation holds the middlewares built for a configuration. A request uses the generation
+// current when it reaches the first configured middleware for all of them, so it never sees a
+// mix of the settings of two configurations.
+type generation struct {
+	config    *Config
+	retry     *retry.RetryMiddleware
+	rateLimit *ratelimit.RateLimiterMiddleware
+	proxy     *proxy.ProxyMiddleware
+	cookie    *cookie.CookieMiddleware
+}
+
+// generationKey is the context key of the generation used by a request.
+type generationKey struct {
+	manager *Manager
+}
+
+// New creates a new Manager instance constructing the middlewares of the configuration.
+func New(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		current:   atomic.Pointer[generation]{},
+		retry:     nil,
+		rateLimit: nil,
+		proxy:     nil,
+		cookie:    nil,
+		flags:     middleware.NewFlagSet(),
+		mu:        sync.Mutex{},
+		logger:    &logger.NoOpLogger{},
+	}
+
+	gen := m.build(cfg, nil)
+	if gen.retry != nil {
+		m.retry = newSlot(m, func(g *generation) *retry.RetryMiddleware { return g.retry })
+	}
+	if gen.rateLimit != nil {
+		m.rateLimit = newSlot(m, func(g *generation) *ratelimit.RateLimiterMiddleware { return g.rateLimit })
+	}
+	if gen.proxy != nil {
+		m.proxy = newSlot(m, func(g *generation) *proxy.ProxyMiddleware { return g.proxy })
+	}
+	if gen.cookie != nil {
+		m.cookie = newSlot(m, func(g *generation) *cookie.CookieMiddleware { return g.cookie })
+	}
+	m.current.Store(gen)
+	m.flags.Replace(cfg.flags())
+
+	return m, nil
+}
+
+// Options returns the client options adding the configured middlewares, in the order
+// retry, rate limit, proxy and cookie, along with the configured flags.
+func (m *Manager) Options() []client.Option {
+	opts := []client.Option{client.WithFlags(m.flags)}
+	if m.retry != nil {
+		opts = append(opts, client.WithMiddleware(m.retry))
+	}
+	if m.rateLimit != nil {
+		opts = append(opts, client.WithMiddleware(m.rateLimit))
+	}
+	if m.proxy != nil {
+		opts = append(opts, client.WithMiddleware(m.proxy))
+	}
+	if m.cookie != nil {
+		opts = append(opts, client.WithMiddleware(m.cookie))
+	}
+	return opts
+}
+
+// Config returns the configuration currently applied.
+func (m *Manager) Config() *Config {
+	return m.current.Load().config
+}
+
+// Apply updates the middlewares and flags to match the configuration.
+// The configuration is validated as a whole, then the middlewares of the sections that changed
+// are rebuilt and swapped in at once: requests already in flight keep the settings they started
+// with, and the following ones use the new settings only. Rebuilt middlewares start over, such
+// as the tokens of the rate limiter or the rotation of the proxies. Sections can be changed but
+// not added or removed, as that would require rebuilding the middleware chain.
+// Flags can always be changed.
+func (m *Manager) Apply(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if (cfg.Retry == nil) != (m.retry == nil) ||
+		(cfg.RateLimit == nil) != (m.rateLimit == nil) ||
+		(cfg.Proxy == nil) != (m.proxy == nil) ||
+		(cfg.Cookie == nil) != (m.cookie == nil) {
+		return ErrLayoutChanged
+	}
+
+	gen := m.build(cfg, m.current.Load())
+	m.current.Store(gen)
+	m.flags.Replace(cfg.flags())
+
+	m.logger.Debug("Configuration applied")
+
+	return nil
+}
+
+// build returns the generation of the configuration, which must be valid, reusing the
+// middlewares of the sections unchanged since the previous generation.
+func (m *Manager) build(cfg *Config, prev *generation) *generation {
+	gen := &generation{config: cfg, retry: nil, rateLimit: nil, proxy: nil, cookie: nil}
+	if prev == nil {
+		prev = &generation{config: &Config{}, retry: nil, rateLimit: nil, proxy: nil, cookie: nil} //nolint:exhaustruct
+	}
+
+	switch {
+	case cfg.Retry == nil:
+	case reflect.DeepEqual(cfg.Retry, prev.config.Retry):
+		gen.retry = prev.retry
+	default:
+		gen.retry = retry.New(cfg.Retry.MaxAttempts, time.Duration(cfg.Retry.InitialInterval), time.Duration(cfg.Retry.MaxInterval))
+		m.retry.setLogger(gen.retry)
+	}
+
+	switch {
+	case cfg.RateLimit == nil:
+	case reflect.DeepEqual(cfg.RateLimit, prev.config.RateLimit):
+		gen.rateLimit = prev.rateLimit
+	default:
+		gen.rateLimit = ratelimit.New(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+		m.rateLimit.setLogger(gen.rateLimit)
+	}
+
+	switch {
+	case cfg.Proxy == nil:
+	case reflect.DeepEqual(cfg.Proxy, prev.config.Proxy):
+		gen.proxy = prev.proxy
+	default:
+		proxies, _ := cfg.Proxy.parse()
+		gen.proxy = proxy.New(proxies)
+		m.proxy.setLogger(gen.proxy)
+	}
+
+	switch {
+	case cfg.Cookie == nil:
+	case reflect.DeepEqual(cfg.Cookie, prev.config.Cookie):
+		gen.cookie = prev.cookie
+	default:
+		gen.cookie = cookie.New(cfg.Cookie.cookies())
+		m.cookie.setLogger(gen.cookie)
+	}
+
+	return gen
+}
+
+// generationOf returns the generation used by the request, and the context carrying it for the
+// middlewares after it.
+func (m *Manager) generationOf(ctx context.Context) (context.Context, *generation) {
+	key := generationKey{manager: m}
+	if gen, ok := ctx.Value(key).(*generation); ok {
+		return ctx, gen
+	}
+
+	gen := m.current.Load()
+	return context.WithValue(ctx, key, gen), gen
+}
+
+// Reload loads a configuration and applies it if it differs from the current one.
+func (m *Manager) Reload(load LoaderFunc) error {
+	cfg, err := load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if reflect.DeepEqual(cfg, m.Config()) {
+		return nil
+	}
+	return m.Apply(cfg)
+}
+
+// Watch reloads the configuration at every interval until the context is canceled.
+// Failed reloads are logged and leave the current configuration in place.
+func (m *Manager) Watch(ctx context.Context, load LoaderFunc, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(load); err != nil {
+				m.logger.WithFields(logger.String("error", err.Error())).Warn("Failed to reload configuration")
+			}
+		}
+	}
+}
+
+// SetLogger sets the logger for the manager.
+func (m *Manager) SetLogger(l logger.Logger) {
+	m.logger = l
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// slot is added to the chain in place of a configured middleware, and delegates to the
+// middleware of the generation used by the request.
+type slot[T middleware.Middleware] struct {
+	manager *Manager
+	get     func(*generation) T
+	logger  logger.Logger
+	mu      sync.Mutex
+}
+
+// newSlot creates a slot for the configured middleware returned by get.
+func newSlot[T middleware.Middleware](manager *Manager, get func(*generation) T) *slot[T] {
+	return &slot[T]{
+		manager: manager,
+		get:     get,
+		logger:  &logger.NoOpLogger{},
+		mu:      sync.Mutex{},
+	}
+}
+
+// Process delegates the request to the middleware of its generation.
+func (s *slot[T]) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	ctx, gen := s.manager.generationOf(ctx)
+	return s.get(gen).Process(ctx, httpClient, req, next)
+}
+
+// SetLogger sets the logger of the current middleware and of those built for later configurations.
+func (s *slot[T]) SetLogger(l logger.Logger) {
+	s.mu.Lock()
+	s.logger = l
+	s.mu.Unlock()
+
+	s.get(s.manager.current.Load()).SetLogger(l)
+}
+
+// setLogger sets the logger of the slot on a middleware built for a new configuration.
+func (s *slot[T]) setLogger(m T) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.SetLogger(s.logger)
+}
+
+// Capabilities returns the capabilities declared by the current middleware.
+func (s *slot[T]) Capabilities() []middleware.Capability {
+	if describer, ok := any(s.get(s.manager.current.Load())).(middleware.Describer); ok {
+		return describer.Capabilities()
+	}
+	return nil
+}
+
+// Introspect returns the state reported by the current middleware.
+func (s *slot[T]) Introspect() map[string]interface{} {
+	if introspector, ok := any(s.get(s.manager.current.Load())).(middleware.Introspector); ok {
+		return introspector.Introspect()
+	}
+	return nil
+}
@@ -1,36 +1,88 @@
 package cookie
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 )
 
+var (
+	ErrReadBody         = errors.New("failed to read request body")
+	ErrRefreshFailed    = errors.New("failed to refresh cookies")
+	ErrCookieSetsRested = errors.New("all cookie sets are rested")
+	ErrNoCookieSets     = errors.New("every cookie set is retired")
+)
+
 type SkipCookieKey struct{}
 
+// RefreshFunc obtains new cookies once the cookies of a set have expired, such as by logging in again.
+// It receives the expired cookie set and returns the set replacing it in the pool.
+type RefreshFunc func(ctx context.Context, httpClient *http.Client, expired []*http.Cookie) ([]*http.Cookie, error)
+
+// Quota limits the number of requests sent with each cookie set.
+type Quota struct {
+	// PerHour is the number of requests a set sends before it is rested until an hour has passed
+	// since its first request of the hour. Zero means no limit.
+	PerHour int
+	// Lifetime is the number of requests a set sends before it is retired from the pool.
+	// Zero means no limit.
+	Lifetime int
+}
+
+// usage counts the requests sent with a cookie set.
+type usage struct {
+	total       int
+	hourly      int
+	windowStart time.Time
+}
+
 // CookieMiddleware manages cookie rotation for HTTP requests.
 type CookieMiddleware struct {
-	cookies     [][]*http.Cookie
-	cookieCount int
-	current     atomic.Uint64
-	mu          sync.RWMutex
-	logger      logger.Logger
+	cookies         [][]*http.Cookie
+	usage           []*usage
+	cookieCount     int
+	current         atomic.Uint64
+	quota           Quota
+	onRest          func(cookies []*http.Cookie, until time.Time)
+	onRetire        func(cookies []*http.Cookie)
+	refreshers      map[string]RefreshFunc
+	refreshStatuses []int
+	headerFormat    HeaderFormat
+	retired         int
+	refreshMu       map[string]*sync.Mutex
+	mu              sync.RWMutex
+	logger          logger.Logger
 }
 
 // New creates a new CookieMiddleware instance.
 func New(cookies [][]*http.Cookie) *CookieMiddleware {
 	m := &CookieMiddleware{
-		cookies:     cookies,
-		cookieCount: len(cookies),
-		current:     atomic.Uint64{},
-		mu:          sync.RWMutex{},
-		logger:      &logger.NoOpLogger{},
+		cookies:         cookies,
+		usage:           newUsage(len(cookies)),
+		cookieCount:     len(cookies),
+		current:         atomic.Uint64{},
+		quota:           Quota{PerHour: 0, Lifetime: 0},
+		onRest:          nil,
+		onRetire:        nil,
+		refreshers:      make(map[string]RefreshFunc),
+		refreshStatuses: []int{http.StatusUnauthorized},
+		headerFormat:    HeaderFormat{Order: OrderSet, Duplicates: DuplicatesKeep, Separate: false},
+		retired:         0,
+		refreshMu:       make(map[string]*sync.Mutex),
+		mu:              sync.RWMutex{},
+		logger:          &logger.NoOpLogger{},
 	}
 	m.current.Store(0)
 	return m
@@ -45,34 +97,233 @@ func (m *CookieMiddleware) Process(ctx context.Context, httpClient *http.Client,
 
 	m.mu.RLock()
 	cookiesLen := len(m.cookies)
+	retired := m.retired
+	refresh := m.refreshers[req.URL.Hostname()]
 	m.mu.RUnlock()
 
-	if cookiesLen > 0 {
-		cookies := m.selectCookieSet()
+	// Requests are only sent without cookies if the pool never had any
+	if cookiesLen == 0 {
+		if retired > 0 {
+			return nil, ErrNoCookieSets
+		}
+		return next(ctx, httpClient, req)
+	}
+
+	index, cookies, err := m.selectCookieSet()
+	if err != nil {
+		return nil, err
+	}
 
-		m.logger.WithFields(logger.Int("cookies", len(cookies))).Debug("Using Cookie Set")
+	m.logger.WithFields(logger.Int("cookies", len(cookies))).Debug("Using Cookie Set")
 
-		// Apply the cookies to the request
-		for _, cookie := range cookies {
-			req.AddCookie(cookie)
+	// Buffer the body so the request can be replayed after a refresh
+	var body []byte
+	if refresh != nil && req.Body != nil && req.Body != http.NoBody {
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReadBody, err)
 		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	// Apply the cookies to the request
+	original := req.Header.Values("Cookie")
+	m.applyCookies(req, cookies)
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || refresh == nil || !m.isExpired(resp.StatusCode) {
+		return resp, err
+	}
+
+	m.logger.WithFields(
+		logger.String("host", req.URL.Hostname()),
+		logger.Int("status", resp.StatusCode),
+	).Warn("Cookies expired, refreshing")
+
+	resp.Body.Close()
+	cookies, err = m.refresh(ctx, httpClient, refresh, req.URL.Hostname(), index, cookies)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay the request once with the refreshed cookies
+	req.Header["Cookie"] = original
+	if len(original) == 0 {
+		req.Header.Del("Cookie")
+	}
+	m.applyCookies(req, cookies)
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
 	}
 
 	return next(ctx, httpClient, req)
 }
 
-// selectCookieSet chooses the next cookie set to use.
-func (m *CookieMiddleware) selectCookieSet() []*http.Cookie {
+// isExpired reports whether the status indicates that the cookies have expired.
+func (m *CookieMiddleware) isExpired(status int) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	return slices.Contains(m.refreshStatuses, status)
+}
+
+// refresh replaces the expired cookie set at index with the cookies returned by the refresher of
+// the host. Requests failing together with the same set share a single refresh, while the sets of
+// other hosts are refreshed concurrently.
+func (m *CookieMiddleware) refresh(ctx context.Context, httpClient *http.Client, refresh RefreshFunc, host string, index int, expired []*http.Cookie) ([]*http.Cookie, error) {
+	refreshMu := m.refreshLock(host)
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	// Use the set refreshed by another request, if any
+	m.mu.RLock()
+	if index < len(m.cookies) && !sameSet(m.cookies[index], expired) {
+		cookies := m.cookies[index]
+		m.mu.RUnlock()
+		return cookies, nil
+	}
+	m.mu.RUnlock()
+
+	cookies, err := refresh(ctx, httpClient, expired)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRefreshFailed, err)
+	}
+
+	m.mu.Lock()
+	if index < len(m.cookies) && sameSet(m.cookies[index], expired) {
+		m.cookies[index] = cookies
+	}
+	m.mu.Unlock()
+
+	m.logger.WithFields(logger.Int("cookies", len(cookies))).Debug("Cookies refreshed")
+	return cookies, nil
+}
+
+// refreshLock returns the lock serializing the refreshes of the host.
+func (m *CookieMiddleware) refreshLock(host string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refreshMu, ok := m.refreshMu[host]
+	if !ok {
+		refreshMu = &sync.Mutex{}
+		m.refreshMu[host] = refreshMu
+	}
+	return refreshMu
+}
+
+// sameSet reports whether both cookie sets are the same slice.
+func sameSet(a, b []*http.Cookie) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// selectCookieSet chooses the next cookie set to use, returning its index in the pool.
+// Rested sets are skipped, and the request is counted against the quota of the selected set.
+func (m *CookieMiddleware) selectCookieSet() (int, []*http.Cookie, error) {
+	m.mu.Lock()
+
 	if m.cookieCount == 0 {
-		return nil
+		m.mu.Unlock()
+		return 0, nil, ErrNoCookieSets
+	}
+
+	now := time.Now()
+	var wake time.Time
+	for range m.cookieCount {
+		current := m.current.Add(1) - 1
+		index := int(current % uint64(m.cookieCount)) // #nosec G115
+		if until, rested := m.restedUntil(index, now); rested {
+			if wake.IsZero() || until.Before(wake) {
+				wake = until
+			}
+			continue
+		}
+
+		cookies := m.cookies[index]
+		notify := m.use(index, now)
+		m.mu.Unlock()
+
+		// Run the callbacks outside of the lock, so they can update the pool
+		notify()
+		return index, cookies, nil
 	}
 
-	current := m.current.Add(1) - 1
-	index := current % uint64(m.cookieCount) // #nosec G115
-	return m.cookies[index]
+	m.mu.Unlock()
+
+	m.logger.WithFields(logger.Duration("retry_after", wake.Sub(now))).Warn("All cookie sets are rested")
+	return 0, nil, fmt.Errorf("%w: %w", ErrCookieSetsRested,
+		&clientErrors.OverloadedError{RetryAfter: wake.Sub(now), QueueDepth: 0})
+}
+
+// restedUntil returns when the set at index is no longer rested, if it is rested.
+// It must be called with the lock held.
+func (m *CookieMiddleware) restedUntil(index int, now time.Time) (time.Time, bool) {
+	u := m.usage[index]
+	until := u.windowStart.Add(time.Hour)
+	if m.quota.PerHour <= 0 || u.hourly < m.quota.PerHour || !now.Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// use counts a request against the quota of the set at index, resting or retiring the set once
+// it reaches its quota. It returns a function running the callbacks, which must be called once
+// the lock is released. It must be called with the lock held.
+func (m *CookieMiddleware) use(index int, now time.Time) func() {
+	u := m.usage[index]
+	if !now.Before(u.windowStart.Add(time.Hour)) {
+		u.windowStart = now
+		u.hourly = 0
+	}
+	u.total++
+	u.hourly++
+
+	cookies := m.cookies[index]
+	if m.quota.Lifetime > 0 && u.total >= m.quota.Lifetime {
+		m.cookies = slices.Delete(m.cookies, index, index+1)
+		m.usage = slices.Delete(m.usage, index, index+1)
+		m.cookieCount = len(m.cookies)
+		m.retired++
+
+		// Continue the rotation with the set taking the place of the retired one
+		m.current.Store(uint64(index)) // #nosec G115
+
+		m.logger.WithFields(
+			logger.Int("requests", u.total),
+			logger.Int("cookie_sets", m.cookieCount),
+		).Warn("Cookie set retired")
+
+		onRetire := m.onRetire
+		return func() {
+			if onRetire != nil {
+				onRetire(cookies)
+			}
+		}
+	}
+
+	if m.quota.PerHour > 0 && u.hourly >= m.quota.PerHour {
+		until := u.windowStart.Add(time.Hour)
+		m.logger.WithFields(logger.Duration("rested_for", until.Sub(now))).Debug("Cookie set rested")
+
+		onRest := m.onRest
+		return func() {
+			if onRest != nil {
+				onRest(cookies, until)
+			}
+		}
+	}
+
+	return func() {}
+}
+
+// newUsage returns the usage of count new cookie sets.
+func newUsage(count int) []*usage {
+	usages := make([]*usage, count)
+	for i := range usages {
+		usages[i] = &usage{total: 0, hourly: 0, windowStart: time.Time{}}
+	}
+	return usages
 }
 
 // UpdateCookies updates the list of cookies at runtime.
@@ -81,12 +332,57 @@ func (m *CookieMiddleware) UpdateCookies(newCookies [][]*http.Cookie) {
 	defer m.mu.Unlock()
 
 	m.cookies = newCookies
+	m.usage = newUsage(len(newCookies))
 	m.cookieCount = len(newCookies)
+	m.retired = 0
 	m.current.Store(0)
 
 	m.logger.WithFields(logger.Int("cookie_sets", len(newCookies))).Debug("Cookies updated")
 }
 
+// AddCookies adds cookie sets to the pool at runtime, such as to replace retired sets,
+// keeping the usage of the existing sets.
+func (m *CookieMiddleware) AddCookies(sets ...[]*http.Cookie) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cookies = append(m.cookies[:len(m.cookies):len(m.cookies)], sets...)
+	m.usage = append(m.usage[:len(m.usage):len(m.usage)], newUsage(len(sets))...)
+	m.cookieCount = len(m.cookies)
+
+	m.logger.WithFields(logger.Int("cookie_sets", m.cookieCount)).Debug("Cookies added")
+}
+
+// SetQuota sets the quota of every cookie set. A set reaching its hourly quota is rested and
+// skipped until the hour is over, and a set reaching its lifetime quota is retired from the pool.
+// When every set is rested, requests fail with ErrCookieSetsRested, which also matches
+// errors.ErrOverloaded and suggests when a set is available again. Once every set is retired,
+// requests fail with ErrNoCookieSets until sets are added.
+func (m *CookieMiddleware) SetQuota(quota Quota) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.quota = quota
+}
+
+// OnRest sets a function called with a cookie set once it is rested, along with the time
+// it is used again.
+func (m *CookieMiddleware) OnRest(fn func(cookies []*http.Cookie, until time.Time)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onRest = fn
+}
+
+// OnRetire sets a function called with a cookie set once it is retired, such as to provision a
+// new account and add its cookies with AddCookies.
+func (m *CookieMiddleware) OnRetire(fn func(cookies []*http.Cookie)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onRetire = fn
+}
+
 // Shuffle randomizes the order of the cookie sets.
 func (m *CookieMiddleware) Shuffle() {
 	m.mu.Lock()
@@ -94,11 +390,31 @@ func (m *CookieMiddleware) Shuffle() {
 
 	rand.New(rand.NewSource(time.Now().UnixNano())).Shuffle(len(m.cookies), func(i, j int) {
 		m.cookies[i], m.cookies[j] = m.cookies[j], m.cookies[i]
+		m.usage[i], m.usage[j] = m.usage[j], m.usage[i]
 	})
 
 	m.logger.Debug("Cookies shuffled")
 }
 
+// SetRefresher registers the function refreshing the cookies used with the host. When a response from
+// the host has a refresh status, the cookie set used is replaced in the pool with the refreshed cookies
+// and the request is replayed once with them.
+func (m *CookieMiddleware) SetRefresher(host string, refresh RefreshFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refreshers[host] = refresh
+}
+
+// SetRefreshStatuses sets the response statuses indicating that cookies have expired.
+// Defaults to 401 Unauthorized.
+func (m *CookieMiddleware) SetRefreshStatuses(statuses ...int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refreshStatuses = statuses
+}
+
 // GetCookieCount returns the current number of cookie sets in the list.
 func (m *CookieMiddleware) GetCookieCount() int {
 	m.mu.RLock()
@@ -107,6 +423,37 @@ func (m *CookieMiddleware) GetCookieCount() int {
 	return m.cookieCount
 }
 
+// Introspect returns the size of the cookie set pool and the number of rested sets.
+func (m *CookieMiddleware) Introspect() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	rested := 0
+	for index := range m.cookies {
+		if _, ok := m.restedUntil(index, now); ok {
+			rested++
+		}
+	}
+
+	return map[string]interface{}{
+		"cookieSets": m.cookieCount,
+		"rested":     rested,
+	}
+}
+
+// Capabilities declares that the middleware buffers the request body to replay the request once
+// cookies are refreshed, if refreshers are registered.
+func (m *CookieMiddleware) Capabilities() []middleware.Capability {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.refreshers) == 0 {
+		return nil
+	}
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody, middleware.ReplaysRequest}
+}
+
 // SetLogger sets the logger for the middleware.
 func (m *CookieMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
@@ -2,12 +2,17 @@ package cookie_test
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jaxron/axonet/middleware/cookie"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -289,4 +294,303 @@ func TestCookieMiddleware(t *testing.T) { //nolint:funlen,maintidx
 
 		assert.True(t, orderChanged, "Cookie order should have changed after multiple shuffle attempts")
 	})
+
+	t.Run("Refresh expired cookies and replay once", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "expired"}},
+		})
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		refreshes := 0
+		middleware.SetRefresher("example.com", func(ctx context.Context, httpClient *http.Client, expired []*http.Cookie) ([]*http.Cookie, error) {
+			refreshes++
+			assert.Equal(t, "expired", expired[0].Value)
+			return []*http.Cookie{{Name: "session", Value: "fresh"}}, nil
+		})
+
+		var bodies []string
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+
+			reqCookies := req.Cookies()
+			require.Len(t, reqCookies, 1)
+			if reqCookies[0].Value != "fresh" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("payload"))
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, refreshes)
+		assert.Equal(t, []string{"payload", "payload"}, bodies)
+
+		// The refreshed cookies replace the expired set in the pool
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err = middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, refreshes)
+	})
+
+	t.Run("Return the response if still expired after refresh", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "expired"}},
+		})
+		middleware.SetRefreshStatuses(http.StatusForbidden)
+		middleware.SetRefresher("example.com", func(ctx context.Context, httpClient *http.Client, expired []*http.Cookie) ([]*http.Cookie, error) {
+			return []*http.Cookie{{Name: "session", Value: "fresh"}}, nil
+		})
+
+		attempts := 0
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{StatusCode: http.StatusForbidden, Body: http.NoBody}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("Refresh failure", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "expired"}},
+		})
+		middleware.SetRefresher("example.com", func(ctx context.Context, httpClient *http.Client, expired []*http.Cookie) ([]*http.Cookie, error) {
+			return nil, errors.New("login failed")
+		})
+
+		body := &closeRecorder{Reader: strings.NewReader("expired")}
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: body}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, cookie.ErrRefreshFailed)
+		assert.Nil(t, resp)
+		assert.True(t, body.closed)
+	})
+
+	t.Run("No refresh for other hosts", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "expired"}},
+		})
+		middleware.SetRefresher("example.com", func(ctx context.Context, httpClient *http.Client, expired []*http.Cookie) ([]*http.Cookie, error) {
+			t.Error("unexpected refresh")
+			return nil, nil
+		})
+
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://other.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Rest cookie sets over their hourly quota", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "123"}},
+			{&http.Cookie{Name: "session", Value: "456"}},
+		})
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetQuota(cookie.Quota{PerHour: 2, Lifetime: 0})
+
+		var rested []string
+		middleware.OnRest(func(cookies []*http.Cookie, until time.Time) {
+			rested = append(rested, cookies[0].Value)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Minute)
+		})
+
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		var used []string
+		for range 4 {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+			used = append(used, req.Cookies()[0].Value)
+		}
+		assert.Equal(t, []string{"123", "456", "123", "456"}, used)
+		assert.Equal(t, []string{"123", "456"}, rested)
+		assert.Equal(t, 2, middleware.Introspect()["rested"])
+
+		// Every set is rested until the hour is over
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, cookie.ErrCookieSetsRested)
+		require.ErrorIs(t, err, clientErrors.ErrOverloaded)
+
+		var overloaded *clientErrors.OverloadedError
+		require.ErrorAs(t, err, &overloaded)
+		assert.InDelta(t, time.Hour, overloaded.RetryAfter, float64(time.Minute))
+	})
+
+	t.Run("Retire cookie sets over their lifetime quota", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "123"}},
+			{&http.Cookie{Name: "session", Value: "456"}},
+		})
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetQuota(cookie.Quota{PerHour: 0, Lifetime: 1})
+
+		// Provision a new set for every retired one
+		var retired []string
+		middleware.OnRetire(func(cookies []*http.Cookie) {
+			retired = append(retired, cookies[0].Value)
+			middleware.AddCookies([]*http.Cookie{{Name: "session", Value: "new-" + cookies[0].Value}})
+		})
+
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		var used []string
+		for range 3 {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+			used = append(used, req.Cookies()[0].Value)
+		}
+		assert.Equal(t, []string{"123", "456", "new-123"}, used)
+		assert.Equal(t, []string{"123", "456", "new-123"}, retired)
+		assert.Equal(t, 2, middleware.GetCookieCount())
+	})
+
+	t.Run("Fail once every cookie set is retired", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := cookie.New([][]*http.Cookie{
+			{&http.Cookie{Name: "session", Value: "123"}},
+		})
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetQuota(cookie.Quota{PerHour: 0, Lifetime: 1})
+
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, 0, middleware.GetCookieCount())
+
+		// Requests are not sent without cookies
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err = middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			t.Error("unexpected request")
+			return nil, nil
+		})
+		require.ErrorIs(t, err, cookie.ErrNoCookieSets)
+
+		// Adding a set resumes requests
+		middleware.AddCookies([]*http.Cookie{{Name: "session", Value: "456"}})
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err = middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, "456", req.Cookies()[0].Value)
+	})
+
+	t.Run("Assemble the Cookie header in the configured format", func(t *testing.T) {
+		t.Parallel()
+
+		set := []*http.Cookie{
+			{Name: "session", Value: "123"},
+			{Name: "theme", Value: "dark"},
+			{Name: "cart", Value: "7", Path: "/shop"},
+		}
+
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		for _, tc := range []struct {
+			format cookie.HeaderFormat
+			want   []string
+		}{
+			{
+				format: cookie.HeaderFormat{Order: cookie.OrderSet, Duplicates: cookie.DuplicatesKeep, Separate: false},
+				want:   []string{"session=123; theme=dark; cart=7"},
+			},
+			{
+				format: cookie.HeaderFormat{Order: cookie.OrderSet, Duplicates: cookie.DuplicatesKeep, Separate: true},
+				want:   []string{"theme=light", "session=123", "theme=dark", "cart=7"},
+			},
+			{
+				format: cookie.HeaderFormat{Order: cookie.OrderSorted, Duplicates: cookie.DuplicatesLast, Separate: false},
+				want:   []string{"cart=7; session=123; theme=dark"},
+			},
+			{
+				format: cookie.HeaderFormat{Order: cookie.OrderBrowser, Duplicates: cookie.DuplicatesFirst, Separate: true},
+				want:   []string{"cart=7", "theme=light", "session=123"},
+			},
+		} {
+			middleware := cookie.New([][]*http.Cookie{set})
+			middleware.SetHeaderFormat(tc.format)
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			req.Header.Set("Cookie", "theme=light")
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, req.Header.Values("Cookie"))
+		}
+	})
+}
+
+func TestCookieReplays(t *testing.T) {
+	t.Parallel()
+
+	middleware := cookie.New([][]*http.Cookie{
+		{{Name: "s", Value: "a"}},
+		{{Name: "s", Value: "b"}},
+	})
+
+	var sent []string
+	handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Header.Get("Cookie"))
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	// Replaying the same request, as the retry middleware does, sends a single identity each time
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	for range 3 {
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"s=a", "s=b", "s=a"}, sent)
+}
+
+// closeRecorder is a response body recording whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
 }
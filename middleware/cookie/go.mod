@@ -1,9 +1,9 @@
 module github.com/jaxron/axonet/middleware/cookie
 
-go 1.23.1
+go 1.24.0
 
 require (
-	github.com/jaxron/axonet v0.0.0-20241110114112-10fce0f238e1
+	github.com/jaxron/axonet v0.1.0
 	github.com/stretchr/testify v1.9.0
 )
 
@@ -0,0 +1,133 @@
+package cookie
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// Order is the order of the cookies in the Cookie header.
+type Order int
+
+const (
+	// OrderSet sends the cookies already on the request, then the cookies of the set in their order.
+	OrderSet Order = iota
+	// OrderSorted sends the cookies sorted by name.
+	OrderSorted
+	// OrderBrowser sends the cookies with longer paths first, keeping the order of the cookies
+	// with paths of the same length, as browsers do following RFC 6265. Cookies without a path
+	// count as having the root path.
+	OrderBrowser
+)
+
+// Duplicates is the policy for cookies of the same name.
+type Duplicates int
+
+const (
+	// DuplicatesKeep sends every cookie, including cookies of the same name. The zero HeaderFormat
+	// replaces the cookies of the same names instead, see SetHeaderFormat.
+	DuplicatesKeep Duplicates = iota
+	// DuplicatesFirst sends only the first cookie of each name, so the cookies already on the
+	// request win over those of the set.
+	DuplicatesFirst
+	// DuplicatesLast sends only the last cookie of each name, in its position, so the cookies of
+	// the set win over those already on the request.
+	DuplicatesLast
+)
+
+// HeaderFormat controls how the cookies of a set are assembled with the cookies already on the
+// request into Cookie headers, for servers that check it against browser behavior.
+type HeaderFormat struct {
+	// Order is the order of the cookies.
+	Order Order
+	// Duplicates is the policy for cookies of the same name.
+	Duplicates Duplicates
+	// Separate sends each cookie in its own Cookie header, as browsers may over HTTP/2, instead of
+	// joining them with "; " into a single header.
+	Separate bool
+}
+
+// SetHeaderFormat sets how cookies are assembled into Cookie headers. The zero HeaderFormat, the
+// default, appends the cookies of the set to the Cookie header of the request in their order,
+// replacing the cookies of the same names, so a replayed request never carries two identities.
+func (m *CookieMiddleware) SetHeaderFormat(format HeaderFormat) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.headerFormat = format
+}
+
+// applyCookies adds the cookies to the request in the header format of the middleware.
+func (m *CookieMiddleware) applyCookies(req *http.Request, cookies []*http.Cookie) {
+	m.mu.RLock()
+	format := m.headerFormat
+	m.mu.RUnlock()
+
+	if format == (HeaderFormat{}) { //nolint:exhaustruct
+		middleware.SetCookies(req, cookies...)
+		return
+	}
+
+	all := append(req.Cookies(), cookies...)
+	switch format.Order {
+	case OrderSorted:
+		slices.SortStableFunc(all, func(a, b *http.Cookie) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+	case OrderBrowser:
+		slices.SortStableFunc(all, func(a, b *http.Cookie) int {
+			return cmp.Compare(pathLength(b), pathLength(a))
+		})
+	case OrderSet:
+	}
+	all = dedupe(all, format.Duplicates)
+
+	values := make([]string, len(all))
+	for i, cookie := range all {
+		values[i] = (&http.Cookie{Name: cookie.Name, Value: cookie.Value, Quoted: cookie.Quoted}).String()
+	}
+
+	req.Header.Del("Cookie")
+	switch {
+	case len(values) == 0:
+	case format.Separate:
+		req.Header["Cookie"] = values
+	default:
+		req.Header.Set("Cookie", strings.Join(values, "; "))
+	}
+}
+
+// pathLength returns the length of the path of the cookie, which is the root path if unset.
+func pathLength(cookie *http.Cookie) int {
+	return max(len(cookie.Path), 1)
+}
+
+// dedupe removes the cookies of the same name following the policy.
+func dedupe(cookies []*http.Cookie, policy Duplicates) []*http.Cookie {
+	switch policy {
+	case DuplicatesFirst:
+		seen := make(map[string]bool, len(cookies))
+		return slices.DeleteFunc(cookies, func(cookie *http.Cookie) bool {
+			duplicate := seen[cookie.Name]
+			seen[cookie.Name] = true
+			return duplicate
+		})
+	case DuplicatesLast:
+		last := make(map[string]int, len(cookies))
+		for i, cookie := range cookies {
+			last[cookie.Name] = i
+		}
+		kept := cookies[:0]
+		for i, cookie := range cookies {
+			if last[cookie.Name] == i {
+				kept = append(kept, cookie)
+			}
+		}
+		return kept
+	default:
+		return cookies
+	}
+}
@@ -0,0 +1,174 @@
+package drain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// DefaultMaxDrainBytes is the number of bytes read from a body by default to return its connection
+// to the pool. Bodies with more left are closed without reading the rest, discarding the connection.
+const DefaultMaxDrainBytes = 256 << 10
+
+// Stats reports the response bodies handled by the middleware.
+type Stats struct {
+	// Responses is the number of responses with a body.
+	Responses uint64 `json:"responses"`
+	// Leaked is the number of bodies collected without being closed.
+	Leaked uint64 `json:"leaked"`
+	// DrainedBytes is the number of bytes read from leaked bodies to reuse their connection.
+	DrainedBytes uint64 `json:"drainedBytes"`
+	// Discarded is the number of leaked bodies too large to drain, whose connection was closed.
+	Discarded uint64 `json:"discarded"`
+}
+
+// DrainMiddleware guarantees that response bodies are drained and closed when callers forget to,
+// so their connections return to the pool instead of exhausting it. Bodies collected by the garbage
+// collector without being closed are logged, counted as leaked, drained and closed.
+type DrainMiddleware struct {
+	maxDrain     atomic.Int64
+	responses    atomic.Uint64
+	leaked       atomic.Uint64
+	drainedBytes atomic.Uint64
+	discarded    atomic.Uint64
+	logger       logger.Logger
+}
+
+// New creates a new DrainMiddleware instance draining up to DefaultMaxDrainBytes from leaked bodies.
+func New() *DrainMiddleware {
+	m := &DrainMiddleware{
+		maxDrain:     atomic.Int64{},
+		responses:    atomic.Uint64{},
+		leaked:       atomic.Uint64{},
+		drainedBytes: atomic.Uint64{},
+		discarded:    atomic.Uint64{},
+		logger:       &logger.NoOpLogger{},
+	}
+	m.maxDrain.Store(DefaultMaxDrainBytes)
+	return m
+}
+
+// SetMaxDrain sets the number of bytes read from a leaked body before closing it. Bodies with more
+// left are closed without reading the rest, discarding their connection.
+func (m *DrainMiddleware) SetMaxDrain(n int64) {
+	m.maxDrain.Store(n)
+}
+
+// Process watches the body of the response for being collected without being closed.
+func (m *DrainMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+
+	m.responses.Add(1)
+
+	body := &guardedBody{
+		ReadCloser: resp.Body,
+		method:     req.Method,
+		url:        req.URL.Redacted(),
+		closed:     atomic.Bool{},
+	}
+	runtime.SetFinalizer(body, m.reclaim)
+
+	// Return a copy of the response, as the transport holds the original until its body is closed
+	guarded := *resp
+	guarded.Body = body
+	return &guarded, nil
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *DrainMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Stats returns the response bodies handled by the middleware.
+func (m *DrainMiddleware) Stats() Stats {
+	return Stats{
+		Responses:    m.responses.Load(),
+		Leaked:       m.leaked.Load(),
+		DrainedBytes: m.drainedBytes.Load(),
+		Discarded:    m.discarded.Load(),
+	}
+}
+
+// Introspect returns the response bodies handled by the middleware.
+func (m *DrainMiddleware) Introspect() map[string]interface{} {
+	stats := m.Stats()
+	return map[string]interface{}{
+		"responses":    stats.Responses,
+		"leaked":       stats.Leaked,
+		"drainedBytes": stats.DrainedBytes,
+		"discarded":    stats.Discarded,
+	}
+}
+
+// reclaim drains and closes a body collected without being closed. It runs from the finalizer
+// goroutine, so the body is read in another goroutine to not hold up other finalizers.
+func (m *DrainMiddleware) reclaim(body *guardedBody) {
+	if body.closed.Load() {
+		return
+	}
+
+	m.logger.WithFields(
+		logger.String("method", body.method),
+		logger.String("url", body.url),
+	).Warn("Response body was not closed, draining it")
+
+	rc := body.ReadCloser
+	go func() {
+		drained, complete := drain(rc, m.maxDrain.Load())
+		_ = rc.Close()
+
+		m.drainedBytes.Add(uint64(drained)) //nolint:gosec // drained is never negative
+		if !complete {
+			m.discarded.Add(1)
+		}
+		m.leaked.Add(1)
+	}()
+}
+
+// guardedBody is a response body drained and closed by the middleware if it is collected
+// without being closed.
+type guardedBody struct {
+	io.ReadCloser
+	method string
+	url    string
+	closed atomic.Bool
+}
+
+// Close closes the body and stops watching it.
+func (b *guardedBody) Close() error {
+	if !b.closed.Swap(true) {
+		runtime.SetFinalizer(b, nil)
+	}
+	return b.ReadCloser.Close()
+}
+
+// DrainAndClose reads up to DefaultMaxDrainBytes left in the body of the response and closes it,
+// so its connection returns to the pool, for callers discarding a response without reading it.
+// The error of closing the body is returned, if any.
+func DrainAndClose(resp *http.Response) error {
+	if resp == nil || resp.Body == nil {
+		return nil
+	}
+
+	drain(resp.Body, DefaultMaxDrainBytes)
+	return resp.Body.Close()
+}
+
+// drain reads up to maxBytes from the body, returning the bytes read and whether the body was
+// read to its end.
+func drain(body io.Reader, maxBytes int64) (int64, bool) {
+	n, err := io.CopyN(io.Discard, body, maxBytes+1)
+	if n > maxBytes {
+		return n, false
+	}
+	return n, errors.Is(err, io.EOF)
+}
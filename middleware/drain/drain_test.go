@@ -0,0 +1,117 @@
+package drain_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/drain"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// newServer returns a server replying with a body of the size in the query, counting its connections
+	newServer := func(t *testing.T) (*httptest.Server, *atomic.Int32) {
+		t.Helper()
+
+		var conns atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			size := 1024
+			if r.URL.Query().Get("size") == "large" {
+				size = 64 << 10
+			}
+			_, _ = w.Write([]byte(strings.Repeat("x", size)))
+		}))
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		server.Start()
+		t.Cleanup(server.Close)
+		return server, &conns
+	}
+
+	// leak sends a request without closing the response body
+	leak := func(t *testing.T, c *client.Client, url string) {
+		t.Helper()
+
+		resp, err := c.NewRequest().URL(url).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	t.Run("Drain and close leaked bodies", func(t *testing.T) {
+		t.Parallel()
+
+		server, conns := newServer(t)
+		middleware := drain.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		c := client.NewClient(client.WithMiddleware(middleware))
+
+		leak(t, c, server.URL)
+		assert.Eventually(t, func() bool {
+			runtime.GC()
+			return middleware.Stats().Leaked == 1
+		}, time.Second, 10*time.Millisecond)
+
+		// The connection of the leaked body is reused
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, drain.DrainAndClose(resp))
+		assert.Equal(t, int32(1), conns.Load())
+
+		assert.Equal(t, drain.Stats{Responses: 2, Leaked: 1, DrainedBytes: 1024, Discarded: 0}, middleware.Stats())
+	})
+
+	t.Run("Discard the connection of large leaked bodies", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newServer(t)
+		middleware := drain.New()
+		middleware.SetMaxDrain(4 << 10)
+		c := client.NewClient(client.WithMiddleware(middleware))
+
+		leak(t, c, server.URL+"?size=large")
+		assert.Eventually(t, func() bool {
+			runtime.GC()
+			return middleware.Stats().Leaked == 1
+		}, time.Second, 10*time.Millisecond)
+
+		stats := middleware.Stats()
+		assert.Equal(t, uint64(1), stats.Discarded)
+		assert.Equal(t, uint64(4<<10+1), stats.DrainedBytes)
+	})
+
+	t.Run("Ignore closed bodies", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newServer(t)
+		middleware := drain.New()
+		c := client.NewClient(client.WithMiddleware(middleware))
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		runtime.GC()
+		runtime.GC()
+		assert.Equal(t, map[string]interface{}{
+			"responses":    uint64(1),
+			"leaked":       uint64(0),
+			"drainedBytes": uint64(0),
+			"discarded":    uint64(0),
+		}, middleware.Introspect())
+	})
+}
@@ -0,0 +1,324 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// DefaultMaxBodyBytes is the number of body bytes dumped before truncating.
+const DefaultMaxBodyBytes = 4096
+
+// redacted replaces the values of redacted headers and fields.
+const redacted = "[REDACTED]"
+
+type SkipDumpKey struct{}
+
+// DumpMiddleware logs requests as curl commands and responses with their status, headers and
+// truncated body at debug level, for troubleshooting. Credentials are redacted from the headers,
+// and from the query parameters and body fields configured with SetRedactedFields.
+type DumpMiddleware struct {
+	headers      map[string]struct{}
+	fields       *regexp.Regexp
+	params       map[string]struct{}
+	maxBodyBytes int
+	mu           sync.RWMutex
+	logger       logger.Logger
+}
+
+// New creates a new DumpMiddleware instance redacting the logger.DefaultRedactedHeaders.
+func New() *DumpMiddleware {
+	m := &DumpMiddleware{
+		headers:      nil,
+		fields:       nil,
+		params:       nil,
+		maxBodyBytes: DefaultMaxBodyBytes,
+		mu:           sync.RWMutex{},
+		logger:       &logger.NoOpLogger{},
+	}
+	m.SetRedactedHeaders(logger.DefaultRedactedHeaders...)
+	return m
+}
+
+// SetRedactedHeaders sets the headers whose values are redacted, replacing the defaults.
+func (m *DumpMiddleware) SetRedactedHeaders(headers ...string) {
+	set := make(map[string]struct{}, len(headers))
+	for _, header := range headers {
+		set[textproto.CanonicalMIMEHeaderKey(header)] = struct{}{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.headers = set
+}
+
+// SetRedactedFields sets the names of the query parameters, form fields and JSON fields whose
+// values are redacted. JSON fields are redacted at any depth when their value is a string, number,
+// boolean or null, even in truncated bodies.
+func (m *DumpMiddleware) SetRedactedFields(fields ...string) {
+	params := make(map[string]struct{}, len(fields))
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		params[field] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+
+	var pattern *regexp.Regexp
+	if len(fields) > 0 {
+		pattern = regexp.MustCompile(`("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[^,{}\[\]\s]+)`)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fields = pattern
+	m.params = params
+}
+
+// SetMaxBodyBytes sets the number of body bytes dumped before truncating. Zero omits bodies.
+func (m *DumpMiddleware) SetMaxBodyBytes(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxBodyBytes = n
+}
+
+// Process dumps the request before passing it to the next middleware, then dumps the response.
+// Bodies are restored, so they remain readable.
+func (m *DumpMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if dumping is disabled via context
+	if skip, ok := ctx.Value(SkipDumpKey{}).(bool); ok && skip {
+		return next(ctx, httpClient, req)
+	}
+
+	body, err := m.peekRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.WithFields(append(middleware.LogFields(ctx),
+		logger.String("curl", m.curl(ctx, req, body)),
+	)...).Debug("Request dump")
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil {
+		m.logger.WithFields(append(middleware.LogFields(ctx),
+			logger.String("url", m.redactURL(req)),
+			logger.String("error", err.Error()),
+		)...).Debug("Response dump")
+		return resp, err
+	}
+	if resp == nil {
+		return resp, nil
+	}
+
+	body, err = m.peekResponseBody(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	m.logger.WithFields(append(middleware.LogFields(ctx),
+		logger.String("url", m.redactURL(req)),
+		logger.String("status", resp.Status),
+		logger.String("headers", m.formatHeaders(resp.Header)),
+		logger.String("body", m.formatBody(body, resp.ContentLength, resp.Header.Get("Content-Type"))),
+	)...).Debug("Response dump")
+	return resp, nil
+}
+
+// curl returns a curl command sending the request, through the proxy selected for it, if any.
+func (m *DumpMiddleware) curl(ctx context.Context, req *http.Request, body []byte) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != http.MethodGet {
+		b.WriteString(" -X " + quote(req.Method))
+	}
+	b.WriteString(" " + quote(m.redactURL(req)))
+
+	if proxy, ok := middleware.ProxyFromContext(ctx); ok {
+		b.WriteString(" --proxy " + quote(proxy.Redacted()))
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		b.WriteString(" -H " + quote("Host: "+req.Host))
+	}
+
+	for _, key := range sortedKeys(req.Header) {
+		for _, value := range req.Header[key] {
+			b.WriteString(" -H " + quote(key+": "+m.redactHeader(key, value)))
+		}
+	}
+
+	if len(body) > 0 {
+		b.WriteString(" --data-raw " + quote(m.formatBody(body, req.ContentLength, req.Header.Get("Content-Type"))))
+	}
+	return b.String()
+}
+
+// formatHeaders returns the headers as lines of the form "Key: value", redacting their values.
+func (m *DumpMiddleware) formatHeaders(header http.Header) string {
+	lines := make([]string, 0, len(header))
+	for _, key := range sortedKeys(header) {
+		for _, value := range header[key] {
+			lines = append(lines, key+": "+m.redactHeader(key, value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatBody returns the peeked body with its redacted fields, noting how much was truncated.
+// The length is the full length of the body, or -1 if unknown.
+func (m *DumpMiddleware) formatBody(body []byte, length int64, contentType string) string {
+	m.mu.RLock()
+	limit, fields, params := m.maxBodyBytes, m.fields, m.params
+	m.mu.RUnlock()
+	if limit <= 0 {
+		return ""
+	}
+
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+
+	text := string(body)
+	switch {
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		text = redactQuery(text, params)
+	case fields != nil:
+		text = fields.ReplaceAllString(text, `${1}"`+redacted+`"`)
+	}
+
+	if truncated {
+		if length > 0 {
+			return fmt.Sprintf("%s... (%d more bytes)", text, length-int64(limit))
+		}
+		return text + "... (truncated)"
+	}
+	return text
+}
+
+// redactHeader returns the value of the header, redacted if needed.
+func (m *DumpMiddleware) redactHeader(key, value string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.headers[textproto.CanonicalMIMEHeaderKey(key)]; ok {
+		return redacted
+	}
+	return value
+}
+
+// redactURL returns the URL of the request with its password and redacted query parameters
+// replaced.
+func (m *DumpMiddleware) redactURL(req *http.Request) string {
+	m.mu.RLock()
+	params := m.params
+	m.mu.RUnlock()
+
+	u := *req.URL
+	u.RawQuery = redactQuery(u.RawQuery, params)
+	return u.Redacted()
+}
+
+// peekRequestBody returns the beginning of the request body, restoring the body for the next
+// middleware.
+func (m *DumpMiddleware) peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	peeked, body, err := m.peek(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = body
+	return peeked, nil
+}
+
+// peekResponseBody returns the beginning of the response body, restoring the body for the caller.
+func (m *DumpMiddleware) peekResponseBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil, nil
+	}
+
+	peeked, body, err := m.peek(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = body
+	return peeked, nil
+}
+
+// peek reads one byte past the dumped bytes of the body, to detect truncation, and returns a body
+// replaying them before the rest.
+func (m *DumpMiddleware) peek(body io.ReadCloser) ([]byte, io.ReadCloser, error) {
+	m.mu.RLock()
+	limit := m.maxBodyBytes
+	m.mu.RUnlock()
+	if limit <= 0 {
+		return nil, body, nil
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	return peeked, &replayBody{Reader: io.MultiReader(bytes.NewReader(peeked), body), Closer: body}, nil
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *DumpMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware reads the request body and restores it.
+func (m *DumpMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody}
+}
+
+// replayBody is a body replaying the bytes read ahead of the rest.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// redactQuery replaces the values of the redacted parameters of a URL-encoded query.
+func redactQuery(query string, params map[string]struct{}) string {
+	if query == "" || len(params) == 0 {
+		return query
+	}
+
+	pairs := strings.Split(query, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if _, ok := params[key]; ok {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sortedKeys returns the keys of the header in order, so dumps are stable.
+func sortedKeys(header http.Header) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// quote quotes the value for a POSIX shell.
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
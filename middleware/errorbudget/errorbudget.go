@@ -0,0 +1,286 @@
+package errorbudget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrBudgetExhausted = errors.New("error budget exhausted")
+
+const (
+	// bucketCount is the number of buckets the rolling window is divided into.
+	bucketCount = 10
+	// minRequests is the number of requests a window must hold before shedding can start.
+	minRequests = 10
+)
+
+// ErrorBudgetMiddleware tracks the success rate of each host over a rolling window and sheds
+// low priority requests when the error budget of the host is close to being exhausted.
+type ErrorBudgetMiddleware struct {
+	objective float64
+	threshold float64
+	window    time.Duration
+	hosts     map[string]*hostBudget
+	mu        sync.Mutex
+	logger    logger.Logger
+}
+
+// bucket holds the outcomes recorded during one slice of the rolling window.
+type bucket struct {
+	slot      int64
+	successes int
+	failures  int
+}
+
+// inflightRequest is a sheddable request that is currently being processed.
+type inflightRequest struct {
+	priority middleware.Priority
+	cancel   context.CancelCauseFunc
+}
+
+// hostBudget holds the rolling window and in-flight requests of a single host.
+type hostBudget struct {
+	buckets  [bucketCount]bucket
+	inflight map[uint64]inflightRequest
+	nextID   uint64
+}
+
+// New creates a new ErrorBudgetMiddleware instance.
+// The objective is the target success rate (e.g. 0.99) measured over the rolling window.
+// Once the consumed fraction of the error budget reaches the threshold (e.g. 0.8), low priority
+// requests are shed. Once the budget is fully consumed, normal priority requests are shed as well.
+// High and critical priority requests are never shed.
+func New(objective float64, window time.Duration, threshold float64) *ErrorBudgetMiddleware {
+	return &ErrorBudgetMiddleware{
+		objective: objective,
+		threshold: threshold,
+		window:    window,
+		hosts:     make(map[string]*hostBudget),
+		mu:        sync.Mutex{},
+		logger:    &logger.NoOpLogger{},
+	}
+}
+
+// Process sheds the request if the error budget of the host is exhausted, otherwise it passes
+// the request to the next middleware and records the outcome.
+func (m *ErrorBudgetMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	host := req.URL.Host
+	priority := middleware.PriorityFromContext(ctx)
+
+	m.mu.Lock()
+	budget := m.getHostBudget(host)
+	slot := m.currentSlot()
+	if budget.shouldShed(priority, m.consumed(budget, slot), m.threshold) {
+		m.mu.Unlock()
+		m.logger.WithFields(
+			logger.String("host", host),
+			logger.Int("priority", int(priority)),
+		).Debug("Request shed by error budget")
+		return nil, ErrBudgetExhausted
+	}
+
+	// Track sheddable requests so they can be cancelled if the budget runs out while in flight.
+	// Their context is only released once the response body is closed, as it is still being read.
+	var id uint64
+	release := func() {}
+	if priority < middleware.PriorityHigh {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		id = budget.track(priority, cancel)
+		release = middleware.OnCleanup(ctx, func() { cancel(nil) })
+	}
+	m.mu.Unlock()
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || resp == nil || resp.Body == nil {
+		defer release()
+	} else {
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release, once: sync.Once{}}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if priority < middleware.PriorityHigh {
+		delete(budget.inflight, id)
+	}
+
+	// Requests cancelled by this middleware don't count towards the budget
+	if err != nil && errors.Is(context.Cause(ctx), ErrBudgetExhausted) {
+		return nil, fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+	}
+
+	slot = m.currentSlot()
+	budget.record(slot, middleware.Classify(ctx, resp, err).Healthy())
+	m.cancelShedRequests(host, budget, m.consumed(budget, slot))
+
+	return resp, err
+}
+
+// Degraded reports whether the error budget of any host is nearly exhausted.
+// It implements middleware.DegradationSource, so the client can enter degraded mode automatically.
+func (m *ErrorBudgetMiddleware) Degraded() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slot := m.currentSlot()
+	for _, budget := range m.hosts {
+		if m.consumed(budget, slot) >= m.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Introspect returns the configuration and the consumed error budget of every tracked host.
+func (m *ErrorBudgetMiddleware) Introspect() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slot := m.currentSlot()
+	consumed := make(map[string]float64, len(m.hosts))
+	for host, budget := range m.hosts {
+		consumed[host] = m.consumed(budget, slot)
+	}
+
+	return map[string]interface{}{
+		"objective": m.objective,
+		"threshold": m.threshold,
+		"window":    m.window.String(),
+		"consumed":  consumed,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *ErrorBudgetMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// getHostBudget returns the budget for the host, creating it if needed.
+// The caller must hold the lock.
+func (m *ErrorBudgetMiddleware) getHostBudget(host string) *hostBudget {
+	budget, ok := m.hosts[host]
+	if !ok {
+		budget = &hostBudget{
+			buckets:  [bucketCount]bucket{},
+			inflight: make(map[uint64]inflightRequest),
+			nextID:   0,
+		}
+		m.hosts[host] = budget
+	}
+	return budget
+}
+
+// currentSlot returns the index of the window slice the current time falls into.
+func (m *ErrorBudgetMiddleware) currentSlot() int64 {
+	width := m.window.Nanoseconds() / bucketCount
+	if width <= 0 {
+		width = 1
+	}
+	return time.Now().UnixNano() / width
+}
+
+// consumed returns the fraction of the error budget consumed within the rolling window.
+// The caller must hold the lock.
+func (m *ErrorBudgetMiddleware) consumed(budget *hostBudget, slot int64) float64 {
+	successes, failures := budget.counts(slot)
+	total := successes + failures
+	if total < minRequests || failures == 0 {
+		return 0
+	}
+
+	allowed := 1 - m.objective
+	if allowed <= 0 {
+		return 1
+	}
+
+	return (float64(failures) / float64(total)) / allowed
+}
+
+// cancelShedRequests cancels in-flight requests that would be shed at the current consumption.
+// The caller must hold the lock.
+func (m *ErrorBudgetMiddleware) cancelShedRequests(host string, budget *hostBudget, consumed float64) {
+	for id, inflight := range budget.inflight {
+		if budget.shouldShed(inflight.priority, consumed, m.threshold) {
+			inflight.cancel(ErrBudgetExhausted)
+			delete(budget.inflight, id)
+
+			m.logger.WithFields(
+				logger.String("host", host),
+				logger.Int("priority", int(inflight.priority)),
+			).Debug("In-flight request cancelled by error budget")
+		}
+	}
+}
+
+// shouldShed reports whether a request of the given priority should be shed.
+func (b *hostBudget) shouldShed(priority middleware.Priority, consumed, threshold float64) bool {
+	switch {
+	case priority >= middleware.PriorityHigh:
+		return false
+	case consumed >= 1:
+		return true
+	case consumed >= threshold:
+		return priority < middleware.PriorityNormal
+	default:
+		return false
+	}
+}
+
+// track registers a sheddable in-flight request and returns its identifier.
+func (b *hostBudget) track(priority middleware.Priority, cancel context.CancelCauseFunc) uint64 {
+	b.nextID++
+	b.inflight[b.nextID] = inflightRequest{
+		priority: priority,
+		cancel:   cancel,
+	}
+	return b.nextID
+}
+
+// record adds the outcome of a request to the bucket of the given slot.
+func (b *hostBudget) record(slot int64, success bool) {
+	bkt := &b.buckets[slot%bucketCount]
+	if bkt.slot != slot {
+		*bkt = bucket{slot: slot, successes: 0, failures: 0}
+	}
+
+	if success {
+		bkt.successes++
+	} else {
+		bkt.failures++
+	}
+}
+
+// counts returns the number of successes and failures within the rolling window ending at slot.
+func (b *hostBudget) counts(slot int64) (int, int) {
+	var successes, failures int
+	for _, bkt := range b.buckets {
+		if bkt.slot > slot-bucketCount && bkt.slot <= slot {
+			successes += bkt.successes
+			failures += bkt.failures
+		}
+	}
+	return successes, failures
+}
+
+// releaseBody is a response body releasing the context of its request when closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Close closes the body and releases the context.
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,158 @@
+package errorbudget_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/errorbudget"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrFailed = errors.New("simulated failure")
+
+func TestErrorBudgetMiddleware(t *testing.T) {
+	t.Parallel()
+
+	successHandler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	failingHandler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+		return nil, ErrFailed
+	}
+
+	t.Run("Requests pass while budget is healthy", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := errorbudget.New(0.9, time.Minute, 0.8)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		ctx := clientMiddleware.WithPriority(context.Background(), clientMiddleware.PriorityLow)
+		for range 20 {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			resp, err := middleware.Process(ctx, &http.Client{}, req, successHandler)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+		assert.False(t, middleware.Degraded())
+	})
+
+	t.Run("Release the context of requests once their body is closed", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := errorbudget.New(0.9, time.Minute, 0.8)
+
+		var sent context.Context
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			sent = ctx
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		require.NoError(t, sent.Err())
+
+		require.NoError(t, resp.Body.Close())
+		require.ErrorIs(t, sent.Err(), context.Canceled)
+	})
+
+	t.Run("Low priority requests are shed when budget is nearly exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := errorbudget.New(0.5, time.Minute, 0.5)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		// 3 failures out of 10 requests consumes 60% of a 50% error budget
+		for i := range 10 {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			handler := successHandler
+			if i < 3 {
+				handler = failingHandler
+			}
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, handler)
+		}
+
+		lowCtx := clientMiddleware.WithPriority(context.Background(), clientMiddleware.PriorityLow)
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(lowCtx, &http.Client{}, req, successHandler)
+		require.ErrorIs(t, err, errorbudget.ErrBudgetExhausted)
+
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, successHandler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Only high priority requests pass when budget is exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := errorbudget.New(0.9, time.Minute, 0.8)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		for range 10 {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, failingHandler)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, successHandler)
+		require.ErrorIs(t, err, errorbudget.ErrBudgetExhausted)
+
+		highCtx := clientMiddleware.WithPriority(context.Background(), clientMiddleware.PriorityHigh)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(highCtx, &http.Client{}, req, successHandler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, middleware.Degraded())
+
+		// Other hosts are not affected
+		req = httptest.NewRequest(http.MethodGet, "http://other.example.com", nil)
+		resp, err = middleware.Process(context.Background(), &http.Client{}, req, successHandler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("In-flight low priority requests are cancelled when budget runs out", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := errorbudget.New(0.9, time.Minute, 0.8)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		started := make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			lowCtx := clientMiddleware.WithPriority(context.Background(), clientMiddleware.PriorityLow)
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, err := middleware.Process(lowCtx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			done <- err
+		}()
+		<-started
+
+		for range 10 {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, _ = middleware.Process(context.Background(), &http.Client{}, req, failingHandler)
+		}
+
+		select {
+		case err := <-done:
+			require.ErrorIs(t, err, errorbudget.ErrBudgetExhausted)
+		case <-time.After(time.Second):
+			t.Fatal("In-flight request was not cancelled")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package etag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// resourceKey is the context key used to store the tracked resource.
+type resourceKey struct{}
+
+// Resource tracks the ETag of a single logical resource across requests.
+type Resource struct {
+	etag string
+	mu   sync.RWMutex
+}
+
+// NewResource creates a new Resource without a known ETag.
+func NewResource() *Resource {
+	return &Resource{
+		etag: "",
+		mu:   sync.RWMutex{},
+	}
+}
+
+// ETag returns the last known ETag of the resource.
+func (r *Resource) ETag() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.etag
+}
+
+// SetETag sets the ETag of the resource.
+func (r *Resource) SetETag(etag string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.etag = etag
+}
+
+// WithResource returns a copy of ctx tracking the given resource.
+func WithResource(ctx context.Context, resource *Resource) context.Context {
+	return context.WithValue(ctx, resourceKey{}, resource)
+}
+
+// ResourceFromContext returns the resource tracked by ctx, if any.
+func ResourceFromContext(ctx context.Context) (*Resource, bool) {
+	resource, ok := ctx.Value(resourceKey{}).(*Resource)
+	return resource, ok
+}
+
+// ETagMiddleware carries ETags from reads into subsequent writes of the same resource.
+// Requests are only affected if a Resource is tracked via WithResource.
+type ETagMiddleware struct {
+	logger logger.Logger
+}
+
+// New creates a new ETagMiddleware instance.
+func New() *ETagMiddleware {
+	return &ETagMiddleware{
+		logger: &logger.NoOpLogger{},
+	}
+}
+
+// Process sets If-Match on writes and records the ETag returned by the server.
+func (m *ETagMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	resource, ok := ResourceFromContext(ctx)
+	if !ok {
+		return next(ctx, httpClient, req)
+	}
+
+	// Make writes conditional on the resource not having changed since it was read
+	if isWrite(req.Method) && req.Header.Get("If-Match") == "" {
+		if etag := resource.ETag(); etag != "" {
+			req.Header.Set("If-Match", etag)
+			m.logger.WithFields(logger.String("etag", etag)).Debug("Using ETag for conditional request")
+		}
+	}
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return resp, ErrPreconditionFailed
+	}
+
+	// Record the latest version of the resource
+	if etag := resp.Header.Get("ETag"); etag != "" && resp.StatusCode < http.StatusMultipleChoices {
+		resource.SetETag(etag)
+	}
+
+	return resp, nil
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *ETagMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// isWrite reports whether the method modifies the resource.
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package etag_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/etag"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Carry ETag from read into write", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := etag.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		resource := etag.NewResource()
+		ctx := etag.WithResource(context.Background(), resource)
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/item", nil)
+		_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Empty(t, req.Header.Get("If-Match"))
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": []string{`"v1"`}}}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `"v1"`, resource.ETag())
+
+		req = httptest.NewRequest(http.MethodPut, "http://example.com/item", nil)
+		_, err = middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Equal(t, `"v1"`, req.Header.Get("If-Match"))
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": []string{`"v2"`}}}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `"v2"`, resource.ETag())
+	})
+
+	t.Run("Return ErrPreconditionFailed on 412", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := etag.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		resource := etag.NewResource()
+		resource.SetETag(`"stale"`)
+		ctx := etag.WithResource(context.Background(), resource)
+
+		req := httptest.NewRequest(http.MethodPatch, "http://example.com/item", nil)
+		resp, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusPreconditionFailed, Header: http.Header{}}, nil
+		})
+		require.ErrorIs(t, err, etag.ErrPreconditionFailed)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		assert.Equal(t, `"stale"`, resource.ETag())
+	})
+
+	t.Run("Requests without a resource are untouched", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := etag.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodPut, "http://example.com/item", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Empty(t, req.Header.Get("If-Match"))
+			return &http.Response{StatusCode: http.StatusPreconditionFailed, Header: http.Header{}}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	t.Run("Explicit If-Match is preserved", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := etag.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		resource := etag.NewResource()
+		resource.SetETag(`"v1"`)
+		ctx := etag.WithResource(context.Background(), resource)
+
+		req := httptest.NewRequest(http.MethodDelete, "http://example.com/item", nil)
+		req.Header.Set("If-Match", "*")
+		resp, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "*", req.Header.Get("If-Match"))
+			return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+}
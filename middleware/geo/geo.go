@@ -0,0 +1,249 @@
+package geo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrGeoMismatch = errors.New("response does not match proxy geo")
+
+// countryHeaders are headers set by CDNs and edge proxies that carry the country of the client.
+var countryHeaders = []string{
+	"CF-IPCountry",
+	"CloudFront-Viewer-Country",
+	"X-Country-Code",
+	"X-Geo-Country",
+}
+
+// currencyMarkers are the markers that indicate a currency in a response body.
+var currencyMarkers = map[string][]string{
+	"USD": {"USD", "US$"},
+	"EUR": {"EUR", "€"},
+	"GBP": {"GBP", "£"},
+	"JPY": {"JPY", "¥"},
+	"CNY": {"CNY", "RMB"},
+	"INR": {"INR", "₹"},
+	"KRW": {"KRW", "₩"},
+	"BRL": {"BRL", "R$"},
+	"RUB": {"RUB", "₽"},
+	"CAD": {"CAD", "CA$"},
+	"AUD": {"AUD", "A$"},
+}
+
+// Expectation describes the geo a proxy is expected to appear from.
+// Empty fields are not checked.
+type Expectation struct {
+	// Country is the ISO 3166-1 alpha-2 country code reported by country headers.
+	Country string
+	// Languages are the primary language tags accepted in the Content-Language header.
+	Languages []string
+	// Currencies are the ISO 4217 currency codes accepted in the response body.
+	Currencies []string
+}
+
+// Mismatch describes a response indicator that does not match the expected geo of a proxy.
+type Mismatch struct {
+	Proxy     string
+	Indicator string
+	Expected  string
+	Actual    string
+}
+
+// GeoMiddleware compares responses against the expected geo of the proxy selected by the
+// proxy middleware, flagging proxies that appear to be transparent or mislabeled.
+// It must be added after the proxy middleware.
+type GeoMiddleware struct {
+	expectations map[string]Expectation
+	onMismatch   func(Mismatch)
+	strict       bool
+	mu           sync.RWMutex
+	logger       logger.Logger
+}
+
+// New creates a new GeoMiddleware instance.
+// The expectations are keyed by proxy host.
+func New(expectations map[string]Expectation) *GeoMiddleware {
+	return &GeoMiddleware{
+		expectations: expectations,
+		onMismatch:   nil,
+		strict:       false,
+		mu:           sync.RWMutex{},
+		logger:       &logger.NoOpLogger{},
+	}
+}
+
+// Process checks the response of the next middleware against the expected geo of the proxy.
+func (m *GeoMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	resp, err := next(ctx, httpClient, req)
+	if err != nil {
+		return resp, err
+	}
+
+	proxy, ok := middleware.ProxyFromContext(ctx)
+	if !ok {
+		return resp, nil
+	}
+
+	m.mu.RLock()
+	expectation, ok := m.expectations[proxy.Host]
+	onMismatch := m.onMismatch
+	strict := m.strict
+	m.mu.RUnlock()
+
+	if !ok {
+		return resp, nil
+	}
+
+	mismatches, err := check(proxy.Host, expectation, resp)
+	if err != nil {
+		return resp, err
+	}
+
+	for _, mismatch := range mismatches {
+		m.logger.WithFields(
+			logger.String("proxy", mismatch.Proxy),
+			logger.String("indicator", mismatch.Indicator),
+			logger.String("expected", mismatch.Expected),
+			logger.String("actual", mismatch.Actual),
+		).Warn("Response does not match proxy geo")
+
+		if onMismatch != nil {
+			onMismatch(mismatch)
+		}
+	}
+
+	if strict && len(mismatches) > 0 {
+		return resp, fmt.Errorf("%w: %s %s is %q, expected %q",
+			ErrGeoMismatch, mismatches[0].Proxy, mismatches[0].Indicator, mismatches[0].Actual, mismatches[0].Expected)
+	}
+
+	return resp, nil
+}
+
+// UpdateExpectations updates the expectations at runtime.
+func (m *GeoMiddleware) UpdateExpectations(expectations map[string]Expectation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expectations = expectations
+}
+
+// OnMismatch sets a function called for every mismatch found, such as to mark the proxy as unhealthy.
+func (m *GeoMiddleware) OnMismatch(fn func(Mismatch)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onMismatch = fn
+}
+
+// SetStrict makes the middleware return ErrGeoMismatch along with the response on mismatches.
+func (m *GeoMiddleware) SetStrict(strict bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.strict = strict
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *GeoMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware reads the response body to find the currencies shown.
+func (m *GeoMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsResponseBody}
+}
+
+// check returns the indicators of the response that don't match the expectation.
+func check(proxy string, expectation Expectation, resp *http.Response) ([]Mismatch, error) {
+	var mismatches []Mismatch
+
+	// Compare the country reported by edge headers
+	if expectation.Country != "" {
+		for _, header := range countryHeaders {
+			country := resp.Header.Get(header)
+			if country != "" && !strings.EqualFold(country, expectation.Country) {
+				mismatches = append(mismatches, Mismatch{
+					Proxy:     proxy,
+					Indicator: header,
+					Expected:  expectation.Country,
+					Actual:    country,
+				})
+			}
+		}
+	}
+
+	// Compare the primary language of the content
+	if len(expectation.Languages) > 0 {
+		if language := resp.Header.Get("Content-Language"); language != "" && !matchesLanguage(language, expectation.Languages) {
+			mismatches = append(mismatches, Mismatch{
+				Proxy:     proxy,
+				Indicator: "Content-Language",
+				Expected:  strings.Join(expectation.Languages, ","),
+				Actual:    language,
+			})
+		}
+	}
+
+	// Compare the currencies found in the body
+	if len(expectation.Currencies) > 0 && resp.Body != nil {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		found := detectCurrencies(body)
+		if len(found) > 0 && !slices.ContainsFunc(found, func(currency string) bool {
+			return slices.Contains(expectation.Currencies, currency)
+		}) {
+			mismatches = append(mismatches, Mismatch{
+				Proxy:     proxy,
+				Indicator: "currency",
+				Expected:  strings.Join(expectation.Currencies, ","),
+				Actual:    strings.Join(found, ","),
+			})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// matchesLanguage reports whether any language of the Content-Language header has one of the expected primary tags.
+func matchesLanguage(header string, languages []string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		for _, language := range languages {
+			if strings.EqualFold(primary, language) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectCurrencies returns the sorted codes of the currencies found in the body.
+func detectCurrencies(body []byte) []string {
+	var found []string
+	for currency, markers := range currencyMarkers {
+		for _, marker := range markers {
+			if bytes.Contains(body, []byte(marker)) {
+				found = append(found, currency)
+				break
+			}
+		}
+	}
+	slices.Sort(found)
+	return found
+}
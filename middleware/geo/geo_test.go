@@ -0,0 +1,107 @@
+package geo_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/geo"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoMiddleware(t *testing.T) {
+	t.Parallel()
+
+	proxyURL, _ := url.Parse("http://de.proxy.example.com")
+	expectations := map[string]geo.Expectation{
+		"de.proxy.example.com": {
+			Country:    "DE",
+			Languages:  []string{"de"},
+			Currencies: []string{"EUR"},
+		},
+	}
+
+	respond := func(header http.Header, body string) clientMiddleware.NextFunc {
+		return func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		}
+	}
+
+	t.Run("Matching response passes", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := geo.New(expectations)
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetStrict(true)
+
+		ctx := clientMiddleware.WithProxy(context.Background(), proxyURL)
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		header := http.Header{"Cf-Ipcountry": []string{"DE"}, "Content-Language": []string{"de-DE"}}
+		resp, err := middleware.Process(ctx, &http.Client{}, req, respond(header, "Preis: 10 €"))
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "Preis: 10 €", string(body))
+	})
+
+	t.Run("Mismatches are reported", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := geo.New(expectations)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var mismatches []geo.Mismatch
+		middleware.OnMismatch(func(mismatch geo.Mismatch) {
+			mismatches = append(mismatches, mismatch)
+		})
+
+		ctx := clientMiddleware.WithProxy(context.Background(), proxyURL)
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		header := http.Header{"Cf-Ipcountry": []string{"US"}, "Content-Language": []string{"en-US"}}
+		_, err := middleware.Process(ctx, &http.Client{}, req, respond(header, "Price: US$10"))
+		require.NoError(t, err)
+
+		require.Len(t, mismatches, 3)
+		assert.Equal(t, geo.Mismatch{Proxy: "de.proxy.example.com", Indicator: "CF-IPCountry", Expected: "DE", Actual: "US"}, mismatches[0])
+		assert.Equal(t, "Content-Language", mismatches[1].Indicator)
+		assert.Equal(t, "USD", mismatches[2].Actual)
+	})
+
+	t.Run("Strict mode returns an error", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := geo.New(expectations)
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetStrict(true)
+
+		ctx := clientMiddleware.WithProxy(context.Background(), proxyURL)
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		resp, err := middleware.Process(ctx, &http.Client{}, req, respond(http.Header{"Cf-Ipcountry": []string{"US"}}, ""))
+		require.ErrorIs(t, err, geo.ErrGeoMismatch)
+		assert.NotNil(t, resp)
+	})
+
+	t.Run("Requests without a proxy are not checked", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := geo.New(expectations)
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetStrict(true)
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, respond(http.Header{"Cf-Ipcountry": []string{"US"}}, ""))
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,310 @@
+package maintenance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/backoff"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+const (
+	// DefaultDuration is how long a host is considered under maintenance when its signal does not
+	// say, before any learning.
+	DefaultDuration = time.Minute
+	// DefaultMaxDuration bounds the learned duration of a maintenance window.
+	DefaultMaxDuration = time.Hour
+)
+
+// maxMarkerBytes is the number of bytes of a response body searched for a body marker.
+const maxMarkerBytes = 64 << 10
+
+// Signal describes a response announcing planned maintenance. A response matches when it has one
+// of the statuses, if any, and the header, if set, and contains the body marker, if set. At least
+// one condition must be set.
+type Signal struct {
+	// Statuses are the status codes of the response, such as 503 Service Unavailable.
+	Statuses []int
+	// Header is the name of a header the response must have, such as "X-Maintenance".
+	Header string
+	// HeaderValue is a value the header must contain, if set.
+	HeaderValue string
+	// BodyMarker is a string the first 64KiB of the response body must contain, such as
+	// "scheduled maintenance".
+	BodyMarker string
+}
+
+// matches reports whether the response announces maintenance, reading the start of the body only
+// when the other conditions match.
+func (s Signal) matches(resp *http.Response) bool {
+	if len(s.Statuses) == 0 && s.Header == "" && s.BodyMarker == "" {
+		return false
+	}
+	if len(s.Statuses) > 0 && !slices.Contains(s.Statuses, resp.StatusCode) {
+		return false
+	}
+	if s.Header != "" {
+		values := resp.Header.Values(s.Header)
+		if len(values) == 0 {
+			return false
+		}
+		if s.HeaderValue != "" && !slices.ContainsFunc(values, func(v string) bool {
+			return strings.Contains(v, s.HeaderValue)
+		}) {
+			return false
+		}
+	}
+	if s.BodyMarker != "" && !bytes.Contains(peekBody(resp), []byte(s.BodyMarker)) {
+		return false
+	}
+	return true
+}
+
+// String describes the signal.
+func (s Signal) String() string {
+	var parts []string
+	if len(s.Statuses) > 0 {
+		statuses := make([]string, len(s.Statuses))
+		for i, status := range s.Statuses {
+			statuses[i] = strconv.Itoa(status)
+		}
+		parts = append(parts, "status "+strings.Join(statuses, "|"))
+	}
+	if s.Header != "" {
+		parts = append(parts, "header "+s.Header)
+	}
+	if s.BodyMarker != "" {
+		parts = append(parts, fmt.Sprintf("body %q", s.BodyMarker))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hostState tracks the maintenance window of a host.
+type hostState struct {
+	until  time.Time
+	reason string
+	// windows is the number of consecutive maintenance windows without a successful response,
+	// which doubles the learned duration of the next one.
+	windows uint64
+}
+
+// MaintenanceMiddleware recognizes responses announcing planned maintenance and fails the
+// requests to the host fast with an *errors.MaintenanceError until the window is over, instead of
+// burning retries and circuit breaker budget during planned downtime.
+type MaintenanceMiddleware struct {
+	signals         []Signal
+	defaultDuration time.Duration
+	maxDuration     time.Duration
+	hosts           map[string]*hostState
+	mu              sync.Mutex
+	logger          logger.Logger
+}
+
+// New creates a new MaintenanceMiddleware instance recognizing the signals.
+func New(signals ...Signal) *MaintenanceMiddleware {
+	return &MaintenanceMiddleware{
+		signals:         signals,
+		defaultDuration: DefaultDuration,
+		maxDuration:     DefaultMaxDuration,
+		hosts:           make(map[string]*hostState),
+		mu:              sync.Mutex{},
+		logger:          &logger.NoOpLogger{},
+	}
+}
+
+// SetDurations sets how long a host is considered under maintenance when its signal has no
+// Retry-After header, and the maximum duration of a window. The duration doubles for each
+// consecutive window without a successful response, up to the maximum.
+func (m *MaintenanceMiddleware) SetDurations(duration, maxDuration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.defaultDuration = duration
+	m.maxDuration = maxDuration
+}
+
+// Process rejects the request if its host is under maintenance, otherwise it passes the request
+// to the next middleware and starts a maintenance window if the response announces one.
+func (m *MaintenanceMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	host := req.URL.Host
+	if err := m.check(host, time.Now()); err != nil {
+		m.logger.WithFields(logger.String("host", host)).Debug("Request rejected during maintenance")
+		return nil, err
+	}
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	for _, signal := range m.signals {
+		if signal.matches(resp) {
+			_ = resp.Body.Close()
+			return nil, m.start(host, signal.String(), resp.Header.Get("Retry-After"), time.Now())
+		}
+	}
+
+	m.succeed(host)
+	return resp, nil
+}
+
+// Until returns when the maintenance window of the host ends, if it is under maintenance.
+func (m *MaintenanceMiddleware) Until(host string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.hosts[host]
+	if !ok || !time.Now().Before(state.until) {
+		return time.Time{}, false
+	}
+	return state.until, true
+}
+
+// Introspect returns the configuration and the hosts under maintenance with the end of their window.
+func (m *MaintenanceMiddleware) Introspect() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	hosts := make(map[string]string)
+	for host, state := range m.hosts {
+		if now.Before(state.until) {
+			hosts[host] = state.until.Format(time.RFC3339)
+		}
+	}
+
+	signals := make([]string, len(m.signals))
+	for i, signal := range m.signals {
+		signals[i] = signal.String()
+	}
+
+	return map[string]interface{}{
+		"signals":          signals,
+		"default_duration": m.defaultDuration.String(),
+		"max_duration":     m.maxDuration.String(),
+		"hosts":            hosts,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *MaintenanceMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware reads the start of the response body to find maintenance markers.
+func (m *MaintenanceMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsResponseBody}
+}
+
+// check returns an *errors.MaintenanceError if the host is under maintenance.
+func (m *MaintenanceMiddleware) check(host string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.hosts[host]
+	if !ok || !now.Before(state.until) {
+		return nil
+	}
+	return &clientErrors.MaintenanceError{
+		Host:       host,
+		Until:      state.until,
+		RetryAfter: state.until.Sub(now),
+		Reason:     state.reason,
+	}
+}
+
+// start starts a maintenance window for the host, lasting until the Retry-After header of the
+// signal or for the learned duration, and returns the error for the request that announced it.
+func (m *MaintenanceMiddleware) start(host, reason, retryAfter string, now time.Time) error {
+	m.mu.Lock()
+	state, ok := m.hosts[host]
+	if !ok {
+		state = &hostState{until: time.Time{}, reason: "", windows: 0}
+		m.hosts[host] = state
+	}
+
+	// Requests in flight when the window started announce the same window
+	if now.Before(state.until) {
+		until := state.until
+		m.mu.Unlock()
+		return &clientErrors.MaintenanceError{
+			Host:       host,
+			Until:      until,
+			RetryAfter: until.Sub(now),
+			Reason:     reason,
+		}
+	}
+
+	duration, ok := parseRetryAfter(retryAfter, now)
+	if !ok {
+		duration = backoff.Doubling(m.defaultDuration, m.maxDuration).Delay(state.windows + 1)
+	}
+	duration = min(max(duration, 0), m.maxDuration)
+
+	until := now.Add(duration)
+	state.until = until
+	state.reason = reason
+	state.windows++
+	m.mu.Unlock()
+
+	m.logger.WithFields(
+		logger.String("host", host),
+		logger.String("reason", reason),
+		logger.Duration("duration", duration),
+	).Warn("Upstream under maintenance")
+
+	return &clientErrors.MaintenanceError{
+		Host:       host,
+		Until:      until,
+		RetryAfter: duration,
+		Reason:     reason,
+	}
+}
+
+// succeed forgets the maintenance windows of the host after a response without a signal.
+func (m *MaintenanceMiddleware) succeed(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.hosts, host)
+}
+
+// parseRetryAfter parses a Retry-After header holding a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now), true
+	}
+	return 0, false
+}
+
+// peekBody returns the start of the response body, leaving the body readable from the start.
+func peekBody(resp *http.Response) []byte {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	prefix, _ := io.ReadAll(io.LimitReader(resp.Body, maxMarkerBytes))
+	resp.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(prefix), resp.Body), Closer: resp.Body}
+	return prefix
+}
+
+// peekedBody is a response body whose start was read and is replayed before the rest.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
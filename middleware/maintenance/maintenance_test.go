@@ -0,0 +1,104 @@
+package maintenance_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/maintenance"
+	"github.com/jaxron/axonet/pkg/axonettest"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	t.Parallel()
+
+	signals := []maintenance.Signal{
+		{Statuses: []int{http.StatusServiceUnavailable}, Header: "X-Maintenance"},
+		{Statuses: []int{http.StatusOK, http.StatusServiceUnavailable}, BodyMarker: "scheduled maintenance"},
+	}
+
+	newClient := func(m *maintenance.MaintenanceMiddleware, mock *axonettest.Mock) *client.Client {
+		return client.NewClient(
+			client.WithBaseURL("https://api.example.com"),
+			client.WithMiddleware(m),
+			mock.Option(),
+		)
+	}
+
+	t.Run("Fail fast for the duration announced by the signal", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.OnGET("/*").Once().Reply(http.StatusServiceUnavailable, "").
+			ReplyHeader("X-Maintenance", "true").ReplyHeader("Retry-After", "120")
+		mock.OnGET("/*").Reply(http.StatusOK, "ok")
+
+		m := maintenance.New(signals...)
+		c := newClient(m, mock)
+
+		_, err := c.NewRequest().URL("/users").Do(context.Background())
+		var maintenanceErr *errors.MaintenanceError
+		require.ErrorAs(t, err, &maintenanceErr)
+		assert.Equal(t, "api.example.com", maintenanceErr.Host)
+		assert.Equal(t, 2*time.Minute, maintenanceErr.RetryAfter)
+
+		// Later requests are rejected without reaching the upstream
+		_, err = c.NewRequest().URL("/teams").Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrMaintenance)
+		mock.AssertNumberOfCalls(t, http.MethodGet, "/*", 1)
+
+		until, ok := m.Until("api.example.com")
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(2*time.Minute), until, time.Second)
+		assert.Contains(t, m.Introspect()["hosts"], "api.example.com")
+
+		// Maintenance says nothing about the health of the upstream
+		assert.Equal(t, clientMiddleware.ClassClientError, clientMiddleware.DefaultClassifier(nil, err))
+	})
+
+	t.Run("Learn longer windows while maintenance goes on", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.OnGET("/*").Reply(http.StatusOK, "<html>Down for scheduled maintenance</html>")
+
+		m := maintenance.New(signals...)
+		m.SetDurations(20*time.Millisecond, 50*time.Millisecond)
+		c := newClient(m, mock)
+
+		var retryAfters []time.Duration
+		for range 3 {
+			_, err := c.NewRequest().URL("/").Do(context.Background())
+			var maintenanceErr *errors.MaintenanceError
+			require.ErrorAs(t, err, &maintenanceErr)
+			retryAfters = append(retryAfters, maintenanceErr.RetryAfter)
+
+			time.Sleep(maintenanceErr.RetryAfter + 5*time.Millisecond)
+		}
+		assert.Equal(t, []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}, retryAfters)
+	})
+
+	t.Run("Pass responses without a signal", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.OnGET("/*").Reply(http.StatusServiceUnavailable, "overloaded")
+
+		c := newClient(maintenance.New(signals...), mock)
+		resp, err := c.NewRequest().URL("/").Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		// Bodies searched for a marker are left readable from the start
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "overloaded", string(body))
+	})
+}
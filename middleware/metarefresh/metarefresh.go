@@ -0,0 +1,204 @@
+package metarefresh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var (
+	ErrTooManyRedirects = errors.New("too many HTML redirects")
+	ErrRedirectLoop     = errors.New("HTML redirect loop")
+)
+
+var (
+	// metaRefreshPattern matches <meta http-equiv="refresh" content="0; url=...">.
+	metaRefreshPattern = regexp.MustCompile(`(?i)<meta[^>]+http-equiv\s*=\s*["']?refresh["']?[^>]*>`)
+	// metaContentPattern extracts the URL from the content attribute of a meta refresh tag.
+	metaContentPattern = regexp.MustCompile(`(?i)content\s*=\s*["']?\s*\d*\s*;?\s*url\s*=\s*['"]?([^"'>\s]+)`)
+	// scriptRedirectPattern matches a script element holding nothing but a location.href or location
+	// assignment, or a location.replace or assign call.
+	scriptRedirectPattern = regexp.MustCompile(`(?i)<script[^>]*>\s*(?:` +
+		`(?:window\.|document\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']|` +
+		`(?:window\.|document\.)?location\.(?:replace|assign)\(\s*["']([^"']+)["']\s*\)` +
+		`)\s*;?\s*</script>`)
+	// tagPattern matches HTML tags, to tell pages with content besides a script redirect.
+	tagPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// credentialHeaders are the headers removed from redirects leaving the origin of the request.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// MetaRefreshMiddleware follows meta refresh and, if enabled, simple JavaScript redirects found in
+// HTML responses. It should be added first so that followed requests pass through the rest of the chain.
+type MetaRefreshMiddleware struct {
+	maxHops int
+	scripts atomic.Bool
+	logger  logger.Logger
+}
+
+// New creates a new MetaRefreshMiddleware instance following at most maxHops redirects per request.
+func New(maxHops int) *MetaRefreshMiddleware {
+	return &MetaRefreshMiddleware{
+		maxHops: maxHops,
+		scripts: atomic.Bool{},
+		logger:  &logger.NoOpLogger{},
+	}
+}
+
+// SetScriptRedirects sets whether JavaScript redirects are followed. Only pages holding nothing but
+// a script assigning location or calling location.replace or location.assign are followed, as other
+// pages may only redirect under conditions the middleware cannot evaluate. Disabled by default.
+func (m *MetaRefreshMiddleware) SetScriptRedirects(enabled bool) {
+	m.scripts.Store(enabled)
+}
+
+// Process follows HTML redirects in the responses of the next middleware.
+func (m *MetaRefreshMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	origin := req.URL
+	visited := map[string]bool{req.URL.String(): true}
+
+	for hop := 0; ; hop++ {
+		resp, err := next(ctx, httpClient, req)
+		if err != nil {
+			return resp, err
+		}
+
+		target, err := findRedirect(req, resp, m.scripts.Load())
+		if err != nil || target == nil {
+			return resp, err
+		}
+
+		if hop >= m.maxHops {
+			return resp, fmt.Errorf("%w: %d hops", ErrTooManyRedirects, hop)
+		}
+		if visited[target.String()] {
+			return resp, fmt.Errorf("%w: %s", ErrRedirectLoop, target)
+		}
+		visited[target.String()] = true
+
+		m.logger.WithFields(
+			logger.String("from", req.URL.String()),
+			logger.String("to", target.String()),
+			logger.Int("hop", hop+1),
+		).Debug("Following HTML redirect")
+
+		resp.Body.Close()
+		req = redirectRequest(ctx, req, target, !sameOrigin(origin, target))
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *MetaRefreshMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware reads the response body to find redirects.
+func (m *MetaRefreshMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsResponseBody}
+}
+
+// findRedirect returns the redirect target found in an HTML response, or nil if there is none.
+func findRedirect(req *http.Request, resp *http.Response, scripts bool) (*url.URL, error) {
+	if resp.StatusCode != http.StatusOK || resp.Body == nil ||
+		!strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		return nil, nil //nolint:nilnil
+	}
+
+	// Read the body and restore it for the caller
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	location := ""
+	if tag := metaRefreshPattern.Find(body); tag != nil {
+		if match := metaContentPattern.FindSubmatch(tag); match != nil {
+			location = string(match[1])
+		}
+	}
+	if location == "" && scripts {
+		location = scriptRedirect(body)
+	}
+	if location == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	target, err := req.URL.Parse(html.UnescapeString(location))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, nil //nolint:nilnil,nilerr // Ignore redirects we can't follow
+	}
+	return target, nil
+}
+
+// scriptRedirect returns the location of the script redirect of a page with no other content, or
+// an empty string if there is none.
+func scriptRedirect(body []byte) string {
+	loc := scriptRedirectPattern.FindSubmatchIndex(body)
+	if loc == nil {
+		return ""
+	}
+
+	// The page must hold nothing but markup besides the script
+	rest := append(bytes.Clone(body[:loc[0]]), body[loc[1]:]...)
+	if len(bytes.TrimSpace(tagPattern.ReplaceAll(rest, nil))) > 0 {
+		return ""
+	}
+
+	if loc[2] >= 0 {
+		return string(body[loc[2]:loc[3]])
+	}
+	return string(body[loc[4]:loc[5]])
+}
+
+// redirectRequest creates the GET request following a redirect to target. Credentials are removed
+// from redirects leaving the origin of the original request, as the client does for HTTP redirects.
+func redirectRequest(ctx context.Context, req *http.Request, target *url.URL, crossOrigin bool) *http.Request {
+	redirect := req.Clone(ctx)
+	redirect.Method = http.MethodGet
+	redirect.URL = target
+	redirect.Host = target.Host
+	redirect.Body = nil
+	redirect.GetBody = nil
+	redirect.ContentLength = 0
+	redirect.Header.Del("Content-Type")
+	redirect.Header.Del("Content-Length")
+	redirect.Header.Set("Referer", req.URL.String())
+	if crossOrigin {
+		for _, key := range credentialHeaders {
+			redirect.Header.Del(key)
+		}
+	}
+	return redirect
+}
+
+// sameOrigin reports whether both URLs have the same scheme, host and port.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		port(a) == port(b)
+}
+
+// port returns the port of the URL, defaulting to the port of its scheme.
+func port(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
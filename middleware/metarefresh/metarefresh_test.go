@@ -0,0 +1,169 @@
+package metarefresh_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/metarefresh"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pages returns a handler serving the HTML pages keyed by URL path.
+func pages(pages map[string]string, visited *[]string) func(context.Context, *http.Client, *http.Request) (*http.Response, error) {
+	return func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+		*visited = append(*visited, req.URL.Path)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader(pages[req.URL.Path])),
+		}, nil
+	}
+}
+
+func TestMetaRefreshMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Follow meta refresh and script redirects", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := metarefresh.New(5)
+		middleware.SetScriptRedirects(true)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var visited []string
+		handler := pages(map[string]string{
+			"/start":  `<html><head><meta http-equiv="refresh" content="0; url=/script"></head></html>`,
+			"/script": `<script>window.location.href = "https://example.com/final";</script>`,
+			"/final":  `<html>done</html>`,
+		}, &visited)
+
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/start", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "<html>done</html>", string(body))
+		assert.Equal(t, []string{"/start", "/script", "/final"}, visited)
+	})
+
+	t.Run("Detect redirect loops", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := metarefresh.New(5)
+		middleware.SetScriptRedirects(true)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var visited []string
+		handler := pages(map[string]string{
+			"/a": `<script>location.replace('/b')</script>`,
+			"/b": `<script>location.replace('/a')</script>`,
+		}, &visited)
+
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/a", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, metarefresh.ErrRedirectLoop)
+	})
+
+	t.Run("Limit the number of hops", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := metarefresh.New(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var visited []string
+		handler := pages(map[string]string{
+			"/1": `<meta http-equiv='refresh' content='0;URL=/2'>`,
+			"/2": `<meta http-equiv='refresh' content='0;URL=/3'>`,
+		}, &visited)
+
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/1", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, metarefresh.ErrTooManyRedirects)
+		assert.Equal(t, []string{"/1", "/2"}, visited)
+	})
+
+	t.Run("Only follow scripts of otherwise empty pages when enabled", func(t *testing.T) {
+		t.Parallel()
+
+		var visited []string
+		handler := pages(map[string]string{
+			"/empty":   `<html><body><script>location.assign("/final")</script></body></html>`,
+			"/content": `<p>Welcome</p><script>location.href = "/final";</script>`,
+			"/code":    `<script>if (mobile) { location.href = "/final"; }</script>`,
+		}, &visited)
+
+		middleware := metarefresh.New(5)
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/empty", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+
+		middleware.SetScriptRedirects(true)
+		for _, path := range []string{"/empty", "/content", "/code"} {
+			req := httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil)
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"/empty", "/empty", "/final", "/content", "/code"}, visited)
+	})
+
+	t.Run("Strip credentials from cross-origin redirects", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := metarefresh.New(5)
+
+		credentials := make(map[string]string)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			credentials[req.URL.Host+req.URL.Path] = req.Header.Get("Authorization") + "|" + req.Header.Get("Cookie")
+			page := ""
+			switch req.URL.Path {
+			case "/start":
+				page = `<meta http-equiv="refresh" content="0; url=/same">`
+			case "/same":
+				page = `<meta http-equiv="refresh" content="0; url=https://other.example.com/away">`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/html"}},
+				Body:       io.NopCloser(strings.NewReader(page)),
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/start", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("Proxy-Authorization", "Basic proxy")
+		req.Header.Set("Cookie", "session=abc")
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{
+			"example.com/start":      "Bearer token|session=abc",
+			"example.com/same":       "Bearer token|session=abc",
+			"other.example.com/away": "|",
+		}, credentials)
+	})
+
+	t.Run("Non-HTML responses are untouched", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := metarefresh.New(5)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"location.href": "x"}`)),
+			}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
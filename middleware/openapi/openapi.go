@@ -0,0 +1,340 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var ErrContractViolation = errors.New("OpenAPI contract violation")
+
+type SkipValidationKey struct{}
+
+// ViolationError lists the mismatches between a request or response and the spec.
+// It matches ErrContractViolation and ErrPermanent with errors.Is, so the request is not retried.
+type ViolationError struct {
+	// Direction is "request" or "response".
+	Direction  string
+	Method     string
+	URL        string
+	Violations []string
+}
+
+// Error implements the error interface.
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("%s: %s %s %s: %s", ErrContractViolation, e.Direction, e.Method, e.URL, strings.Join(e.Violations, "; "))
+}
+
+// Unwrap returns ErrContractViolation and ErrPermanent.
+func (e *ViolationError) Unwrap() []error {
+	return []error{ErrContractViolation, clientErrors.ErrPermanent}
+}
+
+// OpenAPIMiddleware validates requests and responses against an OpenAPI spec, so client code
+// catches contract drift early. It is meant for development and tests: mismatches are logged as
+// warnings, or fail the request in strict mode.
+type OpenAPIMiddleware struct {
+	spec       *Spec
+	strict     bool
+	violations atomic.Int64
+	mu         sync.RWMutex
+	logger     logger.Logger
+}
+
+// New creates a new OpenAPIMiddleware instance validating against the spec.
+func New(spec *Spec) *OpenAPIMiddleware {
+	return &OpenAPIMiddleware{
+		spec:       spec,
+		strict:     false,
+		violations: atomic.Int64{},
+		mu:         sync.RWMutex{},
+		logger:     &logger.NoOpLogger{},
+	}
+}
+
+// Process validates the request before passing it to the next middleware, then validates the response.
+func (m *OpenAPIMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if validation is disabled via context
+	if skip, ok := ctx.Value(SkipValidationKey{}).(bool); ok && skip {
+		return next(ctx, httpClient, req)
+	}
+
+	operation, violations, err := m.validateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.report("request", req, violations); err != nil {
+		return nil, err
+	}
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || resp == nil || operation == nil {
+		return resp, err
+	}
+
+	violations, err = m.validateResponse(operation, resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if err := m.report("response", req, violations); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// validateRequest checks the path, parameters and body of the request, returning the operation
+// it matches, if any.
+func (m *OpenAPIMiddleware) validateRequest(req *http.Request) (map[string]interface{}, []string, error) {
+	spec := m.spec
+
+	route, pathParams, ok := spec.match(req.URL.Path)
+	if !ok {
+		return nil, []string{fmt.Sprintf("no path of the spec matches %s", req.URL.Path)}, nil
+	}
+	operation, ok := spec.resolve(route.item[strings.ToLower(req.Method)]).(map[string]interface{})
+	if !ok {
+		return nil, []string{fmt.Sprintf("%s is not an operation of %s", req.Method, route.template)}, nil
+	}
+
+	var violations []string
+	query := req.URL.Query()
+	for _, param := range spec.parameters(route.item, operation) {
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+
+		var values []string
+		switch in {
+		case "path":
+			if value, ok := pathParams[name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[name]
+		case "header":
+			values = req.Header.Values(name)
+		case "cookie":
+			if cookie, err := req.Cookie(name); err == nil {
+				values = []string{cookie.Value}
+			}
+		}
+
+		if len(values) == 0 {
+			if required {
+				violations = append(violations, fmt.Sprintf("missing required %s parameter %q", in, name))
+			}
+			continue
+		}
+		value := spec.coerce(param["schema"], strings.Join(values, ","))
+		spec.validateSchema(param["schema"], value, fmt.Sprintf("%s parameter %q", in, name), &violations)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	requestBody, _ := spec.resolve(operation["requestBody"]).(map[string]interface{})
+	switch {
+	case requestBody == nil:
+	case len(body) == 0:
+		if required, _ := requestBody["required"].(bool); required {
+			violations = append(violations, "missing required request body")
+		}
+	default:
+		violations = append(violations, m.validateBody(requestBody, req.Header.Get("Content-Type"), body, "request body")...)
+	}
+
+	return operation, violations, nil
+}
+
+// validateResponse checks the status and body of the response against the operation.
+func (m *OpenAPIMiddleware) validateResponse(operation map[string]interface{}, resp *http.Response) ([]string, error) {
+	spec := m.spec
+	responses, _ := spec.resolve(operation["responses"]).(map[string]interface{})
+
+	status := strconv.Itoa(resp.StatusCode)
+	documented, ok := responses[status]
+	if !ok {
+		documented, ok = responses[status[:1]+"XX"]
+	}
+	if !ok {
+		documented, ok = responses["default"]
+	}
+	if !ok {
+		return []string{fmt.Sprintf("status %d is not documented", resp.StatusCode)}, nil
+	}
+
+	response, _ := spec.resolve(documented).(map[string]interface{})
+	if _, hasContent := response["content"]; !hasContent || resp.Body == nil || resp.Body == http.NoBody {
+		return nil, nil
+	}
+
+	// Read the body and restore it for the caller
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(body) == 0 {
+		return nil, nil
+	}
+	return m.validateBody(response, resp.Header.Get("Content-Type"), body, "response body"), nil
+}
+
+// validateBody checks that the content type of a body is documented and that JSON bodies match
+// their schema.
+func (m *OpenAPIMiddleware) validateBody(node map[string]interface{}, contentType string, body []byte, at string) []string {
+	content, _ := node["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	media, ok := lookupMediaType(content, mediaType)
+	if !ok {
+		return []string{fmt.Sprintf("%s: content type %q is not documented", at, mediaType)}
+	}
+
+	mediaObject, _ := m.spec.resolve(media).(map[string]interface{})
+	schema, ok := mediaObject["schema"]
+	if !ok || !strings.Contains(mediaType, "json") {
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: invalid JSON: %v", at, err)}
+	}
+
+	var violations []string
+	m.spec.validateSchema(schema, value, at, &violations)
+	return violations
+}
+
+// report logs the violations, returning a *ViolationError in strict mode.
+func (m *OpenAPIMiddleware) report(direction string, req *http.Request, violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	m.violations.Add(int64(len(violations)))
+
+	m.mu.RLock()
+	strict := m.strict
+	m.mu.RUnlock()
+
+	m.logger.WithFields(
+		logger.String("direction", direction),
+		logger.String("method", req.Method),
+		logger.String("url", req.URL.String()),
+		logger.String("violations", strings.Join(violations, "; ")),
+	).Warn("OpenAPI contract violation")
+
+	if !strict {
+		return nil
+	}
+	return &ViolationError{Direction: direction, Method: req.Method, URL: req.URL.String(), Violations: violations}
+}
+
+// parameters returns the parameters of the operation, including those of its path item that the
+// operation does not override.
+func (s *Spec) parameters(item, operation map[string]interface{}) []map[string]interface{} {
+	var params []map[string]interface{}
+	index := make(map[string]int)
+	for _, list := range []interface{}{item["parameters"], operation["parameters"]} {
+		for _, node := range asList(list) {
+			param, ok := s.resolve(node).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := fmt.Sprint(param["in"], ":", param["name"])
+			if i, ok := index[key]; ok {
+				params[i] = param
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// lookupMediaType returns the documented media type matching the content type, trying ranges
+// such as application/* and */* after the exact type.
+func lookupMediaType(content map[string]interface{}, mediaType string) (interface{}, bool) {
+	if media, ok := content[mediaType]; ok {
+		return media, true
+	}
+	if main, _, ok := strings.Cut(mediaType, "/"); ok {
+		if media, ok := content[main+"/*"]; ok {
+			return media, true
+		}
+	}
+	media, ok := content["*/*"]
+	return media, ok
+}
+
+// readRequestBody returns the body of the request, restoring it for the next middleware.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// SetStrict makes requests fail with a *ViolationError on mismatches instead of only logging them.
+// Requests are not sent when they mismatch, and responses are closed.
+func (m *OpenAPIMiddleware) SetStrict(strict bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.strict = strict
+}
+
+// Introspect returns the number of operations of the spec and the number of violations found.
+func (m *OpenAPIMiddleware) Introspect() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return map[string]interface{}{
+		"operations": m.spec.operations(),
+		"violations": m.violations.Load(),
+		"strict":     m.strict,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *OpenAPIMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Capabilities declares that the middleware reads the request body and restores it, and reads the
+// response body to validate both against the spec.
+func (m *OpenAPIMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody, middleware.ReadsResponseBody}
+}
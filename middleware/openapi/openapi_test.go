@@ -0,0 +1,207 @@
+package openapi_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/openapi"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const specYAML = `
+openapi: 3.0.3
+info: {title: Users, version: "1"}
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema: {type: integer, minimum: 1}
+    get:
+      parameters:
+        - name: fields
+          in: query
+          schema: {type: array, items: {type: string, enum: [name, email]}}
+      responses:
+        200:
+          description: A user
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+        4XX:
+          description: An error
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [message]
+                properties: {message: {type: string}}
+  /users/me:
+    get:
+      responses:
+        200: {description: The current user}
+  /users:
+    post:
+      parameters:
+        - name: X-Request-ID
+          in: header
+          required: true
+          schema: {type: string, pattern: '^[a-f0-9-]+$'}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        201: {description: Created}
+components:
+  schemas:
+    User:
+      type: object
+      required: [id, name]
+      additionalProperties: false
+      properties:
+        id: {type: integer}
+        name: {type: string, minLength: 1}
+        email: {type: string, nullable: true}
+        roles:
+          type: array
+          items: {type: string, enum: [admin, member]}
+`
+
+func TestOpenAPIMiddleware(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	spec, err := openapi.Load([]byte(specYAML))
+	require.NoError(t, err)
+
+	// send sends a request through the middleware, answering with the status and JSON body
+	send := func(t *testing.T, middleware *openapi.OpenAPIMiddleware, req *http.Request, status int, body string) (*http.Response, error) {
+		t.Helper()
+
+		return middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		})
+	}
+
+	newStrict := func() *openapi.OpenAPIMiddleware {
+		middleware := openapi.New(spec)
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetStrict(true)
+		return middleware
+	}
+
+	violations := func(t *testing.T, err error) []string {
+		t.Helper()
+
+		var violationErr *openapi.ViolationError
+		require.ErrorAs(t, err, &violationErr)
+		return violationErr.Violations
+	}
+
+	t.Run("Accept exchanges matching the spec", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := newStrict()
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/v1/users/42?fields=name,email", nil)
+		resp, err := send(t, middleware, req, http.StatusOK, `{"id": 42, "name": "Ada", "email": null, "roles": ["admin"]}`)
+		require.NoError(t, err)
+
+		// The body is left readable
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "Ada")
+
+		req = httptest.NewRequest(http.MethodGet, "https://api.example.com/v1/users/7", nil)
+		_, err = send(t, middleware, req, http.StatusNotFound, `{"message": "not found"}`)
+		require.NoError(t, err)
+
+		// Literal paths take precedence over templated ones
+		req = httptest.NewRequest(http.MethodGet, "https://api.example.com/v1/users/me", nil)
+		_, err = send(t, middleware, req, http.StatusOK, `{}`)
+		require.NoError(t, err)
+	})
+
+	t.Run("Reject requests mismatching the spec", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := newStrict()
+
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/v1/users/0?fields=phone", nil)
+		_, err := send(t, middleware, req, http.StatusOK, `{}`)
+		require.ErrorIs(t, err, openapi.ErrContractViolation)
+		assert.False(t, clientErrors.IsTemporary(err))
+		assert.Len(t, violations(t, err), 2)
+
+		req = httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/users", strings.NewReader(`{"id": "1", "extra": true}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err = send(t, middleware, req, http.StatusCreated, ``)
+		assert.ElementsMatch(t, []string{
+			`missing required header parameter "X-Request-ID"`,
+			`request body: missing required property "name"`,
+			`request body.id: expected integer, got string`,
+			`request body: unexpected property "extra"`,
+		}, violations(t, err))
+
+		req = httptest.NewRequest(http.MethodDelete, "https://api.example.com/v1/users/1", nil)
+		_, err = send(t, middleware, req, http.StatusOK, ``)
+		assert.Equal(t, []string{"DELETE is not an operation of /users/{id}"}, violations(t, err))
+
+		req = httptest.NewRequest(http.MethodGet, "https://api.example.com/v2/users/1", nil)
+		_, err = send(t, middleware, req, http.StatusOK, ``)
+		require.ErrorIs(t, err, openapi.ErrContractViolation)
+	})
+
+	t.Run("Reject responses mismatching the spec", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := newStrict()
+
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/v1/users/1", nil)
+		_, err := send(t, middleware, req, http.StatusOK, `{"id": 1, "name": "", "roles": ["owner"]}`)
+		assert.ElementsMatch(t, []string{
+			`response body.name: has 0 characters, minLength is 1`,
+			`response body.roles[0]: owner is not one of [admin member]`,
+		}, violations(t, err))
+
+		_, err = send(t, middleware, req, http.StatusInternalServerError, `{}`)
+		assert.Equal(t, []string{"status 500 is not documented"}, violations(t, err))
+	})
+
+	t.Run("Only log violations outside strict mode", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := openapi.New(spec)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/v1/users/1", nil)
+		resp, err := send(t, middleware, req, http.StatusOK, `{"id": 1}`)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"operations": 3, "violations": int64(1), "strict": false}, middleware.Introspect())
+	})
+
+	t.Run("Reject invalid specs", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := openapi.Load([]byte(`swagger: "2.0"`))
+		require.ErrorIs(t, err, openapi.ErrInvalidSpec)
+
+		_, err = openapi.Load([]byte(`{"openapi": "3.1.0", "paths": {"/a": "b"}}`))
+		require.ErrorIs(t, err, openapi.ErrInvalidSpec)
+	})
+}
@@ -0,0 +1,244 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// patterns caches the compiled pattern keywords of schemas.
+var patterns sync.Map
+
+// validateSchema checks value against the schema, appending a message for each mismatch.
+// It supports the keywords used to describe API payloads: type, nullable, enum, properties,
+// required, additionalProperties, items, allOf, anyOf, oneOf and the numeric, length and
+// pattern bounds.
+func (s *Spec) validateSchema(node interface{}, value interface{}, at string, errs *[]string) {
+	schema, ok := s.resolve(node).(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for _, sub := range asList(schema["allOf"]) {
+		s.validateSchema(sub, value, at, errs)
+	}
+	if anyOf := asList(schema["anyOf"]); len(anyOf) > 0 && s.countMatches(anyOf, value, at) == 0 {
+		*errs = append(*errs, fmt.Sprintf("%s: matches none of anyOf", at))
+	}
+	if oneOf := asList(schema["oneOf"]); len(oneOf) > 0 {
+		if n := s.countMatches(oneOf, value, at); n != 1 {
+			*errs = append(*errs, fmt.Sprintf("%s: matches %d of oneOf instead of exactly one", at, n))
+		}
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable && !allowsType(schema, "null") && hasType(schema) {
+			*errs = append(*errs, fmt.Sprintf("%s: is null", at))
+		}
+		return
+	}
+
+	if !checkType(schema, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %v, got %s", at, schema["type"], typeOf(value)))
+		return
+	}
+
+	if enum := asList(schema["enum"]); len(enum) > 0 && !containsValue(enum, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.validateObject(schema, v, at, errs)
+	case []interface{}:
+		checkBound(schema, "minItems", float64(len(v)), false, at, "items", errs)
+		checkBound(schema, "maxItems", float64(len(v)), true, at, "items", errs)
+		if items, ok := schema["items"]; ok {
+			for i, item := range v {
+				s.validateSchema(items, item, fmt.Sprintf("%s[%d]", at, i), errs)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		checkBound(schema, "minLength", length, false, at, "characters", errs)
+		checkBound(schema, "maxLength", length, true, at, "characters", errs)
+		if pattern, ok := schema["pattern"].(string); ok && !matchPattern(pattern, v) {
+			*errs = append(*errs, fmt.Sprintf("%s: %q does not match %q", at, v, pattern))
+		}
+	case float64:
+		checkBound(schema, "minimum", v, false, at, "", errs)
+		checkBound(schema, "maximum", v, true, at, "", errs)
+	}
+}
+
+// validateObject checks the properties of an object.
+func (s *Spec) validateObject(schema, object map[string]interface{}, at string, errs *[]string) {
+	for _, name := range asList(schema["required"]) {
+		if key, ok := name.(string); ok {
+			if _, present := object[key]; !present {
+				*errs = append(*errs, fmt.Sprintf("%s: missing required property %q", at, key))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for key, value := range object {
+		if property, ok := properties[key]; ok {
+			s.validateSchema(property, value, at+"."+key, errs)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*errs = append(*errs, fmt.Sprintf("%s: unexpected property %q", at, key))
+			}
+		case map[string]interface{}:
+			s.validateSchema(additional, value, at+"."+key, errs)
+		}
+	}
+}
+
+// countMatches returns the number of schemas the value matches.
+func (s *Spec) countMatches(schemas []interface{}, value interface{}, at string) int {
+	matches := 0
+	for _, sub := range schemas {
+		var subErrs []string
+		s.validateSchema(sub, value, at, &subErrs)
+		if len(subErrs) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// checkType reports whether the value has one of the types of the schema, if it has any.
+func checkType(schema map[string]interface{}, value interface{}) bool {
+	if !hasType(schema) {
+		return true
+	}
+	actual := typeOf(value)
+	if allowsType(schema, actual) {
+		return true
+	}
+	// Integers are numbers too
+	return actual == "integer" && allowsType(schema, "number")
+}
+
+// hasType reports whether the schema restricts the type of values.
+func hasType(schema map[string]interface{}) bool {
+	_, ok := schema["type"]
+	return ok
+}
+
+// allowsType reports whether the type keyword of the schema, a string or a list in OpenAPI 3.1,
+// includes the type.
+func allowsType(schema map[string]interface{}, name string) bool {
+	switch t := schema["type"].(type) {
+	case string:
+		return t == name
+	case []interface{}:
+		for _, v := range t {
+			if v == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of a decoded JSON value.
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// checkBound checks a numeric keyword bounding the value from below, or from above if upper is set.
+func checkBound(schema map[string]interface{}, keyword string, value float64, upper bool, at, unit string, errs *[]string) {
+	bound, ok := schema[keyword].(float64)
+	if !ok || (upper && value <= bound) || (!upper && value >= bound) {
+		return
+	}
+	if unit != "" {
+		*errs = append(*errs, fmt.Sprintf("%s: has %v %s, %s is %v", at, value, unit, keyword, bound))
+		return
+	}
+	*errs = append(*errs, fmt.Sprintf("%s: %v is beyond the %s of %v", at, value, keyword, bound))
+}
+
+// matchPattern reports whether the value matches the pattern. Invalid patterns match anything.
+func matchPattern(pattern, value string) bool {
+	compiled, ok := patterns.Load(pattern)
+	if !ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return true
+		}
+		compiled, _ = patterns.LoadOrStore(pattern, re)
+	}
+	return compiled.(*regexp.Regexp).MatchString(value) //nolint:forcetypeassert
+}
+
+// containsValue reports whether the list contains the value.
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, v := range list {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// asList returns the node as a list, or nil if it is not one.
+func asList(node interface{}) []interface{} {
+	list, _ := node.([]interface{})
+	return list
+}
+
+// coerce converts the raw value of a parameter to the type of its schema, so it is checked like
+// a JSON value. Values that do not convert are kept as strings and fail the type check.
+func (s *Spec) coerce(node interface{}, raw string) interface{} {
+	schema, ok := s.resolve(node).(map[string]interface{})
+	if !ok {
+		return raw
+	}
+
+	switch {
+	case allowsType(schema, "integer"), allowsType(schema, "number"):
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	case allowsType(schema, "boolean"):
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	case allowsType(schema, "array"):
+		// Arrays of the default form style are comma separated
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(raw, ",") {
+			items = append(items, s.coerce(schema["items"], item))
+		}
+		return items
+	}
+	return raw
+}
@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidSpec = errors.New("invalid OpenAPI spec")
+
+// methods are the operations of a path item.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is a loaded OpenAPI 3 document.
+type Spec struct {
+	root     map[string]interface{}
+	basePath string
+	routes   []route
+}
+
+// route is a path template of the spec.
+type route struct {
+	template string
+	segments []string
+	literals int
+	item     map[string]interface{}
+}
+
+// Load parses an OpenAPI 3 document in JSON or YAML.
+func Load(data []byte) (*Spec, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+	root, ok := normalize(doc).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: document is not an object", ErrInvalidSpec)
+	}
+	if version, _ := root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidSpec, version)
+	}
+
+	spec := &Spec{root: root, basePath: "", routes: nil}
+
+	// Paths are relative to the path of the first server
+	if servers, ok := root["servers"].([]interface{}); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			if serverURL, ok := server["url"].(string); ok {
+				if parsed, err := url.Parse(serverURL); err == nil {
+					spec.basePath = strings.TrimSuffix(parsed.Path, "/")
+				}
+			}
+		}
+	}
+
+	paths, _ := root["paths"].(map[string]interface{})
+	for template, item := range paths {
+		itemMap, ok := spec.resolve(item).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: path %q is not an object", ErrInvalidSpec, template)
+		}
+		segments := splitPath(template)
+		literals := 0
+		for _, segment := range segments {
+			if !isParam(segment) {
+				literals++
+			}
+		}
+		spec.routes = append(spec.routes, route{template: template, segments: segments, literals: literals, item: itemMap})
+	}
+
+	// Literal segments take precedence over templated ones, such as /users/me over /users/{id}
+	sort.Slice(spec.routes, func(i, j int) bool {
+		if spec.routes[i].literals != spec.routes[j].literals {
+			return spec.routes[i].literals > spec.routes[j].literals
+		}
+		return spec.routes[i].template < spec.routes[j].template
+	})
+
+	return spec, nil
+}
+
+// LoadFile reads and parses an OpenAPI 3 document in JSON or YAML.
+func LoadFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// match returns the route matching the request path and the values of its path parameters.
+func (s *Spec) match(path string) (*route, map[string]string, bool) {
+	if s.basePath != "" {
+		trimmed := strings.TrimPrefix(path, s.basePath)
+		if len(trimmed) == len(path) {
+			return nil, nil, false
+		}
+		path = trimmed
+	}
+	segments := splitPath(path)
+
+	for i := range s.routes {
+		r := &s.routes[i]
+		if len(r.segments) != len(segments) {
+			continue
+		}
+
+		params := make(map[string]string)
+		matched := true
+		for j, segment := range r.segments {
+			if isParam(segment) {
+				value, err := url.PathUnescape(segments[j])
+				if err != nil {
+					value = segments[j]
+				}
+				params[strings.Trim(segment, "{}")] = value
+				continue
+			}
+			if segment != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+// operations returns the number of operations of the spec.
+func (s *Spec) operations() int {
+	count := 0
+	for _, r := range s.routes {
+		for _, method := range methods {
+			if _, ok := r.item[method]; ok {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// resolve follows the $ref of a node to the node it points to within the document.
+func (s *Spec) resolve(node interface{}) interface{} {
+	for range 32 {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+
+		var target interface{} = s.root
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			parent, ok := target.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			target = parent[token]
+		}
+		node = target
+	}
+	return nil
+}
+
+// normalize converts a decoded YAML document to the types of a decoded JSON document, with
+// string keys and float64 numbers.
+func normalize(node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalize(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// decodeJSON decodes a JSON body into the types the schemas are checked against.
+func decodeJSON(data []byte) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// splitPath splits a path into its segments.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// isParam reports whether a segment of a path template is a parameter, such as {id}.
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
@@ -0,0 +1,211 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans of the middleware.
+const instrumentationName = "github.com/jaxron/axonet/middleware/otel"
+
+type SkipTracingKey struct{}
+
+// OTelMiddleware starts an OpenTelemetry client span for each request and propagates the trace
+// context and baggage to the server in the W3C traceparent, tracestate and baggage headers.
+// Spans record the status of the response or the error of the request, and the events reported
+// by inner middleware, such as retries and cache hits and misses.
+type OTelMiddleware struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	spans      atomic.Int64
+	errors     atomic.Int64
+	mu         sync.RWMutex
+	logger     logger.Logger
+}
+
+// New creates a new OTelMiddleware instance using the global tracer provider.
+func New() *OTelMiddleware {
+	return &OTelMiddleware{
+		provider:   nil,
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		spans:      atomic.Int64{},
+		errors:     atomic.Int64{},
+		mu:         sync.RWMutex{},
+		logger:     &logger.NoOpLogger{},
+	}
+}
+
+// SetTracerProvider sets the provider of the tracer starting the spans.
+// A nil provider uses the global tracer provider.
+func (m *OTelMiddleware) SetTracerProvider(provider trace.TracerProvider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.provider = provider
+}
+
+// SetPropagator sets the propagator injecting the trace context into the request headers.
+// A nil propagator disables propagation.
+func (m *OTelMiddleware) SetPropagator(propagator propagation.TextMapPropagator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.propagator = propagator
+}
+
+// Process starts a span for the request, which ends once the response body was read to the end or
+// closed.
+func (m *OTelMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if tracing is disabled via context
+	if skip, ok := ctx.Value(SkipTracingKey{}).(bool); ok && skip {
+		return next(ctx, httpClient, req)
+	}
+
+	m.mu.RLock()
+	provider, propagator := m.provider, m.propagator
+	m.mu.RUnlock()
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	ctx, span := provider.Tracer(instrumentationName).Start(ctx, req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(ctx, req)...),
+	)
+	m.spans.Add(1)
+
+	// Annotate the span with the events of inner middleware
+	ctx = middleware.WithEventFunc(ctx, func(name string, fields ...logger.Field) {
+		span.AddEvent(name, trace.WithAttributes(fieldAttributes(fields)...))
+	})
+
+	if propagator != nil {
+		propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil {
+		m.errors.Add(1)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+	if resp == nil {
+		span.End()
+		return resp, nil
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		m.errors.Add(1)
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		span.End()
+		return resp, nil
+	}
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span, once: sync.Once{}}
+	return resp, nil
+}
+
+// requestAttributes returns the attributes describing the request, following the HTTP semantic
+// conventions.
+func requestAttributes(ctx context.Context, req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", req.URL.Redacted()),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, attribute.Int("server.port", port))
+	}
+	if attempt := middleware.AttemptFromContext(ctx); attempt > 1 {
+		attrs = append(attrs, attribute.Int("http.request.resend_count", int(attempt-1)))
+	}
+	return attrs
+}
+
+// fieldAttributes converts the fields of an event to span attributes.
+func fieldAttributes(fields []logger.Field) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		switch v := field.Value.(type) {
+		case string:
+			attrs = append(attrs, attribute.String(field.Key, v))
+		case int:
+			attrs = append(attrs, attribute.Int(field.Key, v))
+		case int64:
+			attrs = append(attrs, attribute.Int64(field.Key, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(field.Key, v))
+		case float64:
+			attrs = append(attrs, attribute.Float64(field.Key, v))
+		case time.Duration:
+			attrs = append(attrs, attribute.String(field.Key, v.String()))
+		default:
+			attrs = append(attrs, attribute.String(field.Key, fmt.Sprint(v)))
+		}
+	}
+	return attrs
+}
+
+// Introspect returns the number of spans started and of those ending with an error.
+func (m *OTelMiddleware) Introspect() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return map[string]interface{}{
+		"spans":       m.spans.Load(),
+		"errors":      m.errors.Load(),
+		"propagating": m.propagator != nil,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *OTelMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// spanBody is a response body ending the span of its request once read to the end or closed.
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+}
+
+// Read reads the body, ending the span at the end.
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.end()
+	}
+	return n, err
+}
+
+// Close closes the body, ending the span if the body was not read to the end.
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.end()
+	return err
+}
+
+// end ends the span once.
+func (b *spanBody) end() {
+	b.once.Do(func() { b.span.End() })
+}
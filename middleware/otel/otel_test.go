@@ -0,0 +1,170 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/otel"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTelMiddleware(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	newTraced := func() (*otel.OTelMiddleware, *tracetest.SpanRecorder) {
+		recorder := tracetest.NewSpanRecorder()
+		middleware := otel.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		return middleware, recorder
+	}
+
+	respond := func(status int, body string) middleware.NextFunc {
+		return func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}
+	}
+
+	attributes := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, attr := range span.Attributes() {
+			attrs[attr.Key] = attr.Value
+		}
+		return attrs
+	}
+
+	t.Run("Record a client span ending with the body", func(t *testing.T) {
+		t.Parallel()
+
+		m, recorder := newTraced()
+		req := httptest.NewRequest(http.MethodGet, "https://example.com:8443/users?id=1", nil)
+		resp, err := m.Process(context.Background(), &http.Client{}, req, respond(http.StatusOK, "ok"))
+		require.NoError(t, err)
+		assert.Empty(t, recorder.Ended())
+
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET", spans[0].Name())
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+		attrs := attributes(spans[0])
+		assert.Equal(t, "GET", attrs["http.request.method"].AsString())
+		assert.Equal(t, "example.com", attrs["server.address"].AsString())
+		assert.Equal(t, int64(8443), attrs["server.port"].AsInt64())
+		assert.Equal(t, int64(http.StatusOK), attrs["http.response.status_code"].AsInt64())
+	})
+
+	t.Run("Record errors and error statuses", func(t *testing.T) {
+		t.Parallel()
+
+		m, recorder := newTraced()
+		req := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+		resp, err := m.Process(context.Background(), &http.Client{}, req, respond(http.StatusServiceUnavailable, ""))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		errFailed := errors.New("connection reset")
+		_, err = m.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return nil, errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, codes.Error, spans[1].Status().Code)
+		assert.Equal(t, "connection reset", spans[1].Status().Description)
+		require.Len(t, spans[1].Events(), 1)
+		assert.Equal(t, "exception", spans[1].Events()[0].Name)
+		assert.Equal(t, map[string]interface{}{"spans": int64(2), "errors": int64(2), "propagating": true}, m.Introspect())
+	})
+
+	t.Run("Propagate the trace context and baggage", func(t *testing.T) {
+		t.Parallel()
+
+		m, recorder := newTraced()
+		member, err := baggage.NewMember("tenant", "acme")
+		require.NoError(t, err)
+		bag, err := baggage.New(member)
+		require.NoError(t, err)
+		ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+		var header http.Header
+		req := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+		resp, err := m.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			header = req.Header.Clone()
+			return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+		})
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		spanContext := spans[0].SpanContext()
+		assert.Equal(t, "00-"+spanContext.TraceID().String()+"-"+spanContext.SpanID().String()+"-01", header.Get("traceparent"))
+		assert.Equal(t, "tenant=acme", header.Get("baggage"))
+	})
+
+	t.Run("Annotate the span with middleware events", func(t *testing.T) {
+		t.Parallel()
+
+		m, recorder := newTraced()
+		req := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+		resp, err := m.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			middleware.RecordEvent(ctx, middleware.EventCacheMiss, logger.String("key", "users"))
+			middleware.RecordEvent(ctx, middleware.EventRetry, logger.Int("attempt", 1))
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		// Spans of retried attempts count the previous attempts
+		ctx := middleware.WithAttempt(context.Background(), 3)
+		resp, err = m.Process(ctx, &http.Client{}, req, respond(http.StatusOK, ""))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		events := spans[0].Events()
+		require.Len(t, events, 2)
+		assert.Equal(t, middleware.EventCacheMiss, events[0].Name)
+		assert.Equal(t, []attribute.KeyValue{attribute.String("key", "users")}, events[0].Attributes)
+		assert.Equal(t, middleware.EventRetry, events[1].Name)
+		assert.Equal(t, []attribute.KeyValue{attribute.Int("attempt", 1)}, events[1].Attributes)
+		assert.Equal(t, int64(2), attributes(spans[1])["http.request.resend_count"].AsInt64())
+	})
+
+	t.Run("Skip tracing via context", func(t *testing.T) {
+		t.Parallel()
+
+		m, recorder := newTraced()
+		ctx := context.WithValue(context.Background(), otel.SkipTracingKey{}, true)
+		req := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+		resp, err := m.Process(ctx, &http.Client{}, req, respond(http.StatusOK, ""))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Empty(t, recorder.Ended())
+		assert.Empty(t, req.Header.Get("traceparent"))
+	})
+}
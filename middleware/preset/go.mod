@@ -0,0 +1,23 @@
+module github.com/jaxron/axonet/middleware/preset
+
+go 1.24.0
+
+require (
+	github.com/jaxron/axonet v0.1.0
+	github.com/jaxron/axonet/middleware/challenge v0.1.0
+	github.com/jaxron/axonet/middleware/circuitbreaker v0.1.0
+	github.com/jaxron/axonet/middleware/cookie v0.1.0
+	github.com/jaxron/axonet/middleware/header v0.1.0
+	github.com/jaxron/axonet/middleware/proxy v0.1.0
+	github.com/jaxron/axonet/middleware/ratelimit v0.1.0
+	github.com/jaxron/axonet/middleware/retry v0.1.0
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sony/gobreaker v1.0.0 // indirect
+	golang.org/x/time v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
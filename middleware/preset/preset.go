@@ -0,0 +1,65 @@
+// Package preset provides clients with a correctly ordered middleware stack and sane defaults,
+// for users who do not want to compose the middleware themselves.
+package preset
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/challenge"
+	"github.com/jaxron/axonet/middleware/circuitbreaker"
+	"github.com/jaxron/axonet/middleware/cookie"
+	"github.com/jaxron/axonet/middleware/header"
+	"github.com/jaxron/axonet/middleware/proxy"
+	"github.com/jaxron/axonet/middleware/ratelimit"
+	"github.com/jaxron/axonet/middleware/retry"
+	"github.com/jaxron/axonet/pkg/client"
+)
+
+// IdentityPool holds the identities a scraping client rotates through. Each request uses the
+// next proxy and the next set of cookies, independently of each other.
+type IdentityPool struct {
+	Proxies []*url.URL
+	Cookies [][]*http.Cookie
+	Header  http.Header
+}
+
+// Resilient returns the options of a client that retries failed requests, stops sending requests
+// to a failing upstream and limits its request rate. The circuit breaker runs before the retry,
+// so a request counts once however often it is retried, and every attempt is rate limited.
+func Resilient() []client.Option {
+	return []client.Option{
+		client.WithMiddleware(circuitbreaker.New(5, 10*time.Second, 30*time.Second)),
+		client.WithMiddleware(retry.New(3, 1*time.Second, 5*time.Second)),
+		client.WithMiddleware(ratelimit.New(10, 5)),
+		client.WithRequiredOrder((*retry.RetryMiddleware)(nil), (*ratelimit.RateLimiterMiddleware)(nil)),
+	}
+}
+
+// Scraping returns the options of a resilient client that rotates through the identities of the
+// pool. Challenge responses are retried once with the next identity, so the challenge middleware
+// runs before the rate limiter, which limits the retries, and the identity middleware.
+func Scraping(pool IdentityPool) []client.Option {
+	challengeMiddleware := challenge.New()
+	challengeMiddleware.SetRetry(1, nil)
+
+	return append(Resilient(),
+		client.WithMiddlewareBefore((*ratelimit.RateLimiterMiddleware)(nil), challengeMiddleware),
+		client.WithMiddleware(header.New(pool.Header)),
+		client.WithMiddleware(proxy.New(pool.Proxies)),
+		client.WithMiddleware(cookie.New(pool.Cookies)),
+		client.WithRequiredOrder((*challenge.ChallengeMiddleware)(nil), (*proxy.ProxyMiddleware)(nil)),
+		client.WithRequiredOrder((*challenge.ChallengeMiddleware)(nil), (*cookie.CookieMiddleware)(nil)),
+	)
+}
+
+// NewResilientClient creates a new client with the Resilient options, followed by the given options.
+func NewResilientClient(opts ...client.Option) *client.Client {
+	return client.NewClient(append(Resilient(), opts...)...)
+}
+
+// NewScrapingClient creates a new client with the Scraping options for the pool, followed by the given options.
+func NewScrapingClient(pool IdentityPool, opts ...client.Option) *client.Client {
+	return client.NewClient(append(Scraping(pool), opts...)...)
+}
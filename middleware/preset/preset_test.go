@@ -0,0 +1,74 @@
+package preset_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/preset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresets(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Agent", r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Resilient client", func(t *testing.T) {
+		t.Parallel()
+
+		c := preset.NewResilientClient()
+		require.NoError(t, c.Err())
+
+		var order []string
+		for _, m := range c.Introspect().Middlewares {
+			order = append(order, m.Type)
+		}
+		assert.Equal(t, []string{
+			"*circuitbreaker.CircuitBreakerMiddleware",
+			"*retry.RetryMiddleware",
+			"*ratelimit.RateLimiterMiddleware",
+		}, order)
+
+		resp, err := c.NewRequest().Method(http.MethodGet).URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Scraping client", func(t *testing.T) {
+		t.Parallel()
+
+		c := preset.NewScrapingClient(preset.IdentityPool{
+			Proxies: nil,
+			Cookies: nil,
+			Header:  http.Header{"User-Agent": {"axonet"}},
+		})
+		require.NoError(t, c.Err())
+
+		var order []string
+		for _, m := range c.Introspect().Middlewares {
+			order = append(order, m.Type)
+		}
+		assert.Equal(t, []string{
+			"*circuitbreaker.CircuitBreakerMiddleware",
+			"*retry.RetryMiddleware",
+			"*challenge.ChallengeMiddleware",
+			"*ratelimit.RateLimiterMiddleware",
+			"*header.HeaderMiddleware",
+			"*proxy.ProxyMiddleware",
+			"*cookie.CookieMiddleware",
+		}, order)
+
+		resp, err := c.NewRequest().Method(http.MethodGet).URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "axonet", resp.Header.Get("X-Agent"))
+	})
+}
@@ -3,38 +3,68 @@ package proxy
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 )
 
-var ErrInvalidTransport = errors.New("invalid transport")
+var (
+	ErrInvalidTransport = errors.New("invalid transport")
+	ErrProxiesSaturated = errors.New("all proxies are saturated")
+)
 
 type SkipProxyKey struct{}
 
+// Limits configures how a proxy is selected.
+type Limits struct {
+	// Weight is the relative chance of the proxy being selected. Zero defaults to 1.
+	Weight int
+	// MaxInFlight caps the number of requests sent through the proxy at once, counting each
+	// request until its response body is closed. Zero means no cap. Requests are released by the
+	// cleanups of the chain, so caps only apply to requests sent through a client or chain.
+	MaxInFlight int
+}
+
 // ProxyMiddleware manages proxy rotation for HTTP requests.
 type ProxyMiddleware struct {
-	proxies    []*url.URL
-	proxyCount int
-	current    atomic.Uint64
-	mu         sync.RWMutex
-	logger     logger.Logger
+	proxies       []*url.URL
+	proxyCount    int
+	current       atomic.Uint64
+	transports    *transportPool
+	limits        map[string]Limits
+	inflight      map[string]int
+	weighted      bool
+	blockSaturate bool
+	released      chan struct{}
+	mu            sync.RWMutex
+	logger        logger.Logger
 }
 
 // New creates a new ProxyMiddleware instance.
 func New(proxies []*url.URL) *ProxyMiddleware {
 	m := &ProxyMiddleware{
-		proxies:    proxies,
-		proxyCount: len(proxies),
-		current:    atomic.Uint64{},
-		mu:         sync.RWMutex{},
-		logger:     &logger.NoOpLogger{},
+		proxies:       proxies,
+		proxyCount:    len(proxies),
+		current:       atomic.Uint64{},
+		transports:    newTransportPool(),
+		limits:        make(map[string]Limits),
+		inflight:      make(map[string]int),
+		weighted:      false,
+		blockSaturate: false,
+		released:      make(chan struct{}),
+		mu:            sync.RWMutex{},
+		logger:        &logger.NoOpLogger{},
 	}
 	m.current.Store(0)
 	return m
@@ -51,54 +81,198 @@ func (m *ProxyMiddleware) Process(ctx context.Context, httpClient *http.Client,
 	m.mu.RUnlock()
 
 	if proxyLen > 0 {
-		proxy := m.selectProxy()
+		proxy, release, err := m.acquireProxy(ctx)
+		if err != nil {
+			return nil, err
+		}
 		m.logger.WithFields(logger.String("proxy", proxy.Host)).Debug("Using Proxy")
+		events.Publish(ctx, events.ProxyRotated{Proxy: proxy})
 
-		var err error
-		httpClient, err = m.applyProxyToClient(httpClient, proxy)
+		httpClient, err = m.applyProxyToClient(ctx, httpClient, proxy)
 		if err != nil {
+			release()
 			return nil, err
 		}
+
+		// Expose the selected proxy to the following middleware
+		ctx = middleware.WithProxy(ctx, proxy)
+
+		// Release the proxy once this attempt is over, as retries acquire a proxy for each attempt
+		resp, err := next(ctx, httpClient, req)
+		if err != nil || resp == nil {
+			release()
+			return resp, err
+		}
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release, once: sync.Once{}}
+		return resp, nil
 	}
 
 	return next(ctx, httpClient, req)
 }
 
-// selectProxy chooses the next proxy to use.
-func (m *ProxyMiddleware) selectProxy() *url.URL {
+// Preconnect warms the connections of every proxy with warm concurrently, or only of the proxy
+// stored in ctx with middleware.WithProxy. Without proxies, the connections of httpClient are
+// warmed. It implements the middleware.Preconnector interface.
+func (m *ProxyMiddleware) Preconnect(ctx context.Context, httpClient *http.Client, warm middleware.WarmFunc) error {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
+	proxies := slices.Clone(m.proxies)
+	m.mu.RUnlock()
+
+	if proxy, ok := middleware.ProxyFromContext(ctx); ok {
+		proxies = []*url.URL{proxy}
+	}
+	if len(proxies) == 0 {
+		return warm(ctx, httpClient)
+	}
+
+	errs := make([]error, len(proxies))
+	var wg sync.WaitGroup
+	for i, proxy := range proxies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			proxyClient, err := m.applyProxyToClient(ctx, httpClient, proxy)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = warm(middleware.WithProxy(ctx, proxy), proxyClient)
+		}()
+	}
+	wg.Wait()
+
+	m.logger.WithFields(logger.Int("proxies", len(proxies))).Debug("Preconnected proxies")
+	return errors.Join(errs...)
+}
+
+// acquireProxy selects a proxy below its in-flight cap, counting the request against the cap until
+// the returned function is called. The chain also releases the proxy when it runs its cleanups,
+// should the attempt be abandoned. When every proxy is at its cap, it waits for a proxy to be
+// released if blocking is enabled, and fails with ErrProxiesSaturated otherwise.
+func (m *ProxyMiddleware) acquireProxy(ctx context.Context) (*url.URL, func(), error) {
+	for {
+		m.mu.Lock()
+		proxy := m.selectProxy()
+		if proxy != nil {
+			release := func() {}
+			key := proxy.String()
+			if m.limits[key].MaxInFlight > 0 {
+				m.inflight[key]++
+				release = middleware.OnCleanup(ctx, func() { m.releaseProxy(key) })
+			}
+			m.mu.Unlock()
+			return proxy, release, nil
+		}
 
+		block := m.blockSaturate
+		released := m.released
+		m.mu.Unlock()
+
+		if !block {
+			m.logger.Warn("All proxies are saturated")
+			return nil, nil, fmt.Errorf("%w: %w", ErrProxiesSaturated, clientErrors.ErrOverloaded)
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// selectProxy chooses the next proxy to use among the proxies below their in-flight cap,
+// or returns nil if there is none. It must be called with the lock held.
+func (m *ProxyMiddleware) selectProxy() *url.URL {
 	if m.proxyCount == 0 {
 		return nil
 	}
 
-	current := m.current.Add(1) - 1
-	index := current % uint64(m.proxyCount) // #nosec G115
-	return m.proxies[index]
+	// Select proxies in turn unless they are weighted
+	if !m.weighted {
+		for range m.proxyCount {
+			current := m.current.Add(1) - 1
+			proxy := m.proxies[current%uint64(m.proxyCount)] // #nosec G115
+			if m.available(proxy) {
+				return proxy
+			}
+		}
+		return nil
+	}
+
+	total := 0
+	for _, proxy := range m.proxies {
+		if m.available(proxy) {
+			total += m.weight(proxy)
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total) // #nosec G404
+	for _, proxy := range m.proxies {
+		if !m.available(proxy) {
+			continue
+		}
+		if n -= m.weight(proxy); n < 0 {
+			return proxy
+		}
+	}
+	return nil
+}
+
+// available reports whether the proxy is below its in-flight cap.
+func (m *ProxyMiddleware) available(proxy *url.URL) bool {
+	key := proxy.String()
+	limit := m.limits[key].MaxInFlight
+	return limit <= 0 || m.inflight[key] < limit
+}
+
+// weight returns the selection weight of the proxy.
+func (m *ProxyMiddleware) weight(proxy *url.URL) int {
+	if weight := m.limits[proxy.String()].Weight; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// releaseProxy releases an in-flight request of the proxy and wakes up the requests waiting for one.
+func (m *ProxyMiddleware) releaseProxy(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.inflight[key]--; m.inflight[key] <= 0 {
+		delete(m.inflight, key)
+	}
+	m.notifyReleased()
+}
+
+// notifyReleased wakes up the requests waiting for a proxy. It must be called with the lock held.
+func (m *ProxyMiddleware) notifyReleased() {
+	close(m.released)
+	m.released = make(chan struct{})
 }
 
 // applyProxyToClient applies the proxy to the given http.Client.
-func (m *ProxyMiddleware) applyProxyToClient(httpClient *http.Client, proxy *url.URL) (*http.Client, error) {
+// The transport of each proxy is pooled, so connections, resolved addresses and TLS sessions are reused.
+func (m *ProxyMiddleware) applyProxyToClient(ctx context.Context, httpClient *http.Client, proxy *url.URL) (*http.Client, error) {
 	// Get the transport from the client
 	transport, err := m.getTransport(httpClient)
 	if err != nil {
 		return nil, err
 	}
 
-	// Clone the transport
-	newTransport := transport.Clone()
-
-	// Modify only the necessary fields
-	newTransport.Proxy = http.ProxyURL(proxy)
-	newTransport.OnProxyConnectResponse = func(ctx context.Context, proxyURL *url.URL, connectReq *http.Request, connectRes *http.Response) error {
+	// Get the transport of the proxy
+	_, resolved := middleware.ResolverFromContext(ctx)
+	proxyTransport := m.transports.get(transport, proxy, resolved, func(proxyURL *url.URL) {
 		m.logger.WithFields(logger.String("proxy", proxyURL.Host)).Debug("Proxy connection established")
-		return nil
-	}
+	})
 
-	// Create a new client with the modified transport
+	// Create a new client with the proxy transport
 	return &http.Client{
-		Transport:     newTransport,
+		Transport:     proxyTransport,
 		CheckRedirect: httpClient.CheckRedirect,
 		Jar:           httpClient.Jar,
 		Timeout:       httpClient.Timeout,
@@ -123,10 +297,46 @@ func (m *ProxyMiddleware) UpdateProxies(newProxies []*url.URL) {
 	m.proxies = newProxies
 	m.proxyCount = len(newProxies)
 	m.current.Store(0)
+	m.transports.retain(newProxies)
+	m.notifyReleased()
 
 	m.logger.WithFields(logger.Int("proxy_count", len(newProxies))).Debug("Proxies updated")
 }
 
+// SetLimits sets the selection weight and in-flight cap of the proxy, which are kept when the
+// proxies are updated. Once a proxy has a weight other than 1, proxies are selected at random in
+// proportion to their weight instead of in turn.
+func (m *ProxyMiddleware) SetLimits(proxy *url.URL, limits Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if limits == (Limits{}) {
+		delete(m.limits, proxy.String())
+	} else {
+		m.limits[proxy.String()] = limits
+	}
+
+	m.weighted = false
+	for _, l := range m.limits {
+		if l.Weight > 1 {
+			m.weighted = true
+			break
+		}
+	}
+
+	// A raised cap may let waiting requests through
+	m.notifyReleased()
+}
+
+// SetBlockWhenSaturated sets whether requests wait for a proxy to be released when every proxy is
+// at its in-flight cap, rather than failing with ErrProxiesSaturated.
+func (m *ProxyMiddleware) SetBlockWhenSaturated(block bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blockSaturate = block
+}
+
 // Shuffle randomizes the order of the proxies.
 func (m *ProxyMiddleware) Shuffle() {
 	m.mu.Lock()
@@ -147,7 +357,52 @@ func (m *ProxyMiddleware) GetProxyCount() int {
 	return m.proxyCount
 }
 
+// Stats returns how often connections through the proxies reused cached DNS and TLS session state.
+func (m *ProxyMiddleware) Stats() Stats {
+	return m.transports.snapshot()
+}
+
+// Introspect returns the size of the proxy pool, the in-flight requests of capped proxies and
+// its cache statistics. Proxies are reported with their password redacted.
+func (m *ProxyMiddleware) Introspect() map[string]interface{} {
+	m.mu.RLock()
+	inflight := make(map[string]int, len(m.inflight))
+	for key, count := range m.inflight {
+		inflight[redact(key)] += count
+	}
+	m.mu.RUnlock()
+
+	return map[string]interface{}{
+		"proxies":  m.GetProxyCount(),
+		"inflight": inflight,
+		"stats":    m.Stats(),
+	}
+}
+
+// redact returns the proxy URL with its password redacted.
+func redact(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return "invalid proxy URL"
+	}
+	return u.Redacted()
+}
+
 // SetLogger sets the logger for the middleware.
 func (m *ProxyMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
 }
+
+// releaseBody is a response body releasing the proxy of its request when closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Close closes the body and releases the proxy.
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/dns"
+)
+
+// dnsCacheTTL is how long resolved addresses are reused before being resolved again.
+const dnsCacheTTL = 5 * time.Minute
+
+// Stats reports how often connections through the proxies reused cached state.
+type Stats struct {
+	DNSHits          uint64 `json:"dnsHits"`
+	DNSMisses        uint64 `json:"dnsMisses"`
+	TLSSessionHits   uint64 `json:"tlsSessionHits"`
+	TLSSessionMisses uint64 `json:"tlsSessionMisses"`
+}
+
+// cacheStats counts cache hits and misses.
+type cacheStats struct {
+	tlsSessionHits   atomic.Uint64
+	tlsSessionMisses atomic.Uint64
+}
+
+// transportKey identifies a pooled transport by the transport it was cloned from and its proxy.
+type transportKey struct {
+	base  *http.Transport
+	proxy string
+}
+
+// transportPool keeps one transport per proxy, so connections, resolved addresses and
+// TLS session tickets are reused when a proxy is selected again.
+type transportPool struct {
+	transports map[transportKey]*http.Transport
+	dns        *dns.Cache
+	stats      *cacheStats
+	mu         sync.Mutex
+}
+
+// newTransportPool creates a new transportPool instance.
+func newTransportPool() *transportPool {
+	cache := dns.NewCache(net.DefaultResolver)
+	cache.SetTTL(dnsCacheTTL)
+
+	return &transportPool{
+		transports: make(map[transportKey]*http.Transport),
+		dns:        cache,
+		stats:      &cacheStats{},
+		mu:         sync.Mutex{},
+	}
+}
+
+// get returns the transport for the proxy, cloning the base transport on first use. Unless the
+// base transport already resolves hosts with the resolver of the client, the hosts of the proxies
+// are resolved through the DNS cache of the pool.
+func (p *transportPool) get(base *http.Transport, proxy *url.URL, resolved bool, onConnect func(*url.URL)) *http.Transport {
+	key := transportKey{base: base, proxy: proxy.String()}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if transport, ok := p.transports[key]; ok {
+		return transport
+	}
+
+	transport := base.Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	transport.OnProxyConnectResponse = func(ctx context.Context, proxyURL *url.URL, connectReq *http.Request, connectRes *http.Response) error {
+		onConnect(proxyURL)
+		return nil
+	}
+	if !resolved {
+		transport.DialContext = dns.Dial(p.dns, base.DialContext)
+	}
+
+	// Keep TLS session tickets per proxy, so resumed sessions match the identity they were issued to
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{} //nolint:exhaustruct,gosec
+	}
+	transport.TLSClientConfig.ClientSessionCache = &sessionCache{
+		cache: tls.NewLRUClientSessionCache(0),
+		stats: p.stats,
+	}
+
+	p.transports[key] = transport
+	return transport
+}
+
+// retain drops the transports of proxies that are no longer in use and closes their idle connections.
+func (p *transportPool) retain(proxies []*url.URL) {
+	keep := make(map[string]struct{}, len(proxies))
+	for _, proxy := range proxies {
+		keep[proxy.String()] = struct{}{}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, transport := range p.transports {
+		if _, ok := keep[key.proxy]; !ok {
+			transport.CloseIdleConnections()
+			delete(p.transports, key)
+		}
+	}
+}
+
+// snapshot returns the current cache statistics.
+func (p *transportPool) snapshot() Stats {
+	dnsStats := p.dns.Stats()
+	return Stats{
+		DNSHits:          dnsStats.Hits,
+		DNSMisses:        dnsStats.Misses,
+		TLSSessionHits:   p.stats.tlsSessionHits.Load(),
+		TLSSessionMisses: p.stats.tlsSessionMisses.Load(),
+	}
+}
+
+// sessionCache is a tls.ClientSessionCache counting resumed sessions.
+type sessionCache struct {
+	cache tls.ClientSessionCache
+	stats *cacheStats
+}
+
+// Get returns the session for the key, counting it as a hit or miss.
+func (c *sessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	session, ok := c.cache.Get(sessionKey)
+	if ok {
+		c.stats.tlsSessionHits.Add(1)
+	} else {
+		c.stats.tlsSessionMisses.Add(1)
+	}
+	return session, ok
+}
+
+// Put stores the session for the key.
+func (c *sessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	c.cache.Put(sessionKey, cs)
+}
@@ -2,9 +2,13 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/jaxron/axonet/internal/waitqueue"
 	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
@@ -12,24 +16,56 @@ import (
 )
 
 // RateLimiterMiddleware implements a rate limiting middleware for HTTP requests.
+// Requests waiting for a token are served in order of their priority.
 type RateLimiterMiddleware struct {
-	limiter *rate.Limiter
-	logger  logger.Logger
+	limiter        *rate.Limiter
+	degraded       *rate.Limiter
+	degradedFactor float64
+	waiters        *waitqueue.Queue[struct{}]
+	maxQueue       int
+	waiting        bool
+	schedule       *schedule
+	profile        string
+	override       string
+	checked        time.Time
+	mu             sync.Mutex
+	logger         logger.Logger
 }
 
+// defaultDegradedFactor is the fraction of the rate limit allowed while the client is degraded.
+const defaultDegradedFactor = 0.5
+
 // New creates a new RateLimiterMiddleware instance.
 func New(requestsPerSecond float64, burst int) *RateLimiterMiddleware {
 	return &RateLimiterMiddleware{
-		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
-		logger:  &logger.NoOpLogger{},
+		limiter:        rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		degraded:       newDegradedLimiter(requestsPerSecond, burst, defaultDegradedFactor),
+		degradedFactor: defaultDegradedFactor,
+		waiters:        waitqueue.New[struct{}](),
+		maxQueue:       0,
+		waiting:        false,
+		schedule:       nil,
+		profile:        "",
+		override:       "",
+		checked:        time.Time{},
+		mu:             sync.Mutex{},
+		logger:         &logger.NoOpLogger{},
 	}
 }
 
+// newDegradedLimiter creates the limiter applied on top of the regular one while the client is degraded.
+func newDegradedLimiter(requestsPerSecond float64, burst int, factor float64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(requestsPerSecond*factor), max(1, int(float64(burst)*factor)))
+}
+
 // Process applies rate limiting before passing the request to the next middleware.
 func (m *RateLimiterMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Switch to the profile of the schedule, if any
+	m.applySchedule(time.Now())
+
 	// Wait for rate limiter permission
-	if err := m.limiter.Wait(ctx); err != nil {
-		if strings.Contains(err.Error(), "would exceed context deadline") {
+	if err := m.wait(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "would exceed context deadline") {
 			return nil, clientErrors.ErrTimeout
 		}
 		return nil, err
@@ -39,6 +75,160 @@ func (m *RateLimiterMiddleware) Process(ctx context.Context, httpClient *http.Cl
 	return next(ctx, httpClient, req)
 }
 
+// EstimateCost estimates how long the request would wait for a token, including the tokens
+// needed by requests already queued. It does not consume any tokens.
+func (m *RateLimiterMiddleware) EstimateCost(_ *http.Request) time.Duration {
+	m.mu.Lock()
+	queued := m.waiters.Len()
+	m.mu.Unlock()
+
+	return m.retryAfter(queued)
+}
+
+// retryAfter estimates how long a request arriving behind the queued requests would wait for a
+// token, from the rate tokens are added at.
+func (m *RateLimiterMiddleware) retryAfter(queued int) time.Duration {
+	limit := m.limiter.Limit()
+	missing := float64(queued+1) - m.limiter.Tokens()
+	if limit == rate.Inf || missing <= 0 || limit <= 0 {
+		return 0
+	}
+	return time.Duration(missing / float64(limit) * float64(time.Second))
+}
+
+// wait blocks until the request is allowed by the rate limiter.
+// Only one request waits on the limiter at a time, the others are queued by priority.
+func (m *RateLimiterMiddleware) wait(ctx context.Context) error {
+	if err := m.acquire(ctx); err != nil {
+		return err
+	}
+	defer m.release()
+
+	if err := m.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	// Reduce the rate further while the client is degraded
+	if middleware.DegradedFromContext(ctx) {
+		m.mu.Lock()
+		degraded := m.degraded
+		m.mu.Unlock()
+
+		return degraded.Wait(ctx)
+	}
+	return nil
+}
+
+// acquire blocks until it is the request's turn to wait on the limiter.
+func (m *RateLimiterMiddleware) acquire(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.waiting && m.waiters.Len() == 0 {
+		m.waiting = true
+		m.mu.Unlock()
+		return nil
+	}
+
+	// Reject the request rather than queueing it beyond the limit
+	if queued := m.waiters.Len(); m.maxQueue > 0 && queued >= m.maxQueue {
+		err := &clientErrors.OverloadedError{RetryAfter: m.retryAfter(queued), QueueDepth: queued}
+		m.mu.Unlock()
+
+		m.logger.WithFields(
+			logger.Int("queued", queued),
+			logger.Duration("retry_after", err.RetryAfter),
+		).Warn("Request rejected, rate limiter queue is full")
+		return err
+	}
+
+	priority := middleware.PriorityFromContext(ctx)
+	w := m.waiters.Push(priority, struct{}{})
+	m.mu.Unlock()
+
+	m.logger.WithFields(logger.Int("priority", int(priority))).Debug("Request queued for rate limiter")
+
+	select {
+	case <-w.Ready:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		removed := m.waiters.Remove(w)
+		m.mu.Unlock()
+
+		// The turn was handed over while the context was cancelled, pass it on
+		if !removed {
+			m.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// release hands the turn over to the next queued request with the highest priority.
+func (m *RateLimiterMiddleware) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.waiters.Len() == 0 {
+		m.waiting = false
+		return
+	}
+
+	m.waiters.Pop()
+}
+
+// UpdateLimit updates the rate and burst of the limiter at runtime.
+// With a schedule, the limit is replaced on the next profile switch.
+func (m *RateLimiterMiddleware) UpdateLimit(requestsPerSecond float64, burst int) {
+	m.limiter.SetLimit(rate.Limit(requestsPerSecond))
+	m.limiter.SetBurst(burst)
+
+	m.mu.Lock()
+	m.degraded = newDegradedLimiter(requestsPerSecond, burst, m.degradedFactor)
+	m.mu.Unlock()
+
+	m.logger.WithFields(
+		logger.Float64("requests_per_second", requestsPerSecond),
+		logger.Int("burst", burst),
+	).Debug("Rate limit updated")
+}
+
+// SetMaxQueue limits the number of requests queued for the limiter. Requests arriving while the
+// queue is full are rejected with an *errors.OverloadedError suggesting when to retry. Zero, the
+// default, queues requests without limit.
+func (m *RateLimiterMiddleware) SetMaxQueue(maxQueue int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxQueue = maxQueue
+}
+
+// SetDegradedFactor sets the fraction of the rate limit allowed while the client is degraded.
+// A factor of 1 keeps the rate limit unchanged.
+func (m *RateLimiterMiddleware) SetDegradedFactor(factor float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.degradedFactor = factor
+	m.degraded = newDegradedLimiter(float64(m.limiter.Limit()), m.limiter.Burst(), factor)
+}
+
+// Introspect returns the configuration and live state of the rate limiter.
+func (m *RateLimiterMiddleware) Introspect() map[string]interface{} {
+	m.mu.Lock()
+	queued := m.waiters.Len()
+	maxQueue := m.maxQueue
+	profile := m.profile
+	m.mu.Unlock()
+
+	return map[string]interface{}{
+		"limit":    float64(m.limiter.Limit()),
+		"burst":    m.limiter.Burst(),
+		"tokens":   m.limiter.Tokens(),
+		"queued":   queued,
+		"maxQueue": maxQueue,
+		"profile":  profile,
+	}
+}
+
 // SetLogger sets the logger for the middleware.
 func (m *RateLimiterMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
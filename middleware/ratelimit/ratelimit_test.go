@@ -4,12 +4,14 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jaxron/axonet/middleware/ratelimit"
 	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,4 +85,144 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		require.Error(t, err)
 		assert.ErrorIs(t, err, clientErrors.ErrTimeout)
 	})
+
+	t.Run("Higher priority requests are served first", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(20, 1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var (
+			mu    sync.Mutex
+			order []clientMiddleware.Priority
+		)
+		makeRequest := func(priority clientMiddleware.Priority) error {
+			ctx := clientMiddleware.WithPriority(context.Background(), priority)
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(ctx)
+			_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			return err
+		}
+
+		// Consume the burst so the following requests have to wait
+		require.NoError(t, makeRequest(clientMiddleware.PriorityNormal))
+
+		var wg sync.WaitGroup
+		for _, priority := range []clientMiddleware.Priority{clientMiddleware.PriorityNormal, clientMiddleware.PriorityLow, clientMiddleware.PriorityLow, clientMiddleware.PriorityHigh} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, makeRequest(priority))
+			}()
+			time.Sleep(5 * time.Millisecond)
+		}
+		wg.Wait()
+
+		// The first waiter was already waiting on the limiter, the rest are ordered by priority
+		assert.Equal(t, []clientMiddleware.Priority{
+			clientMiddleware.PriorityNormal,
+			clientMiddleware.PriorityNormal,
+			clientMiddleware.PriorityHigh,
+			clientMiddleware.PriorityLow,
+			clientMiddleware.PriorityLow,
+		}, order)
+	})
+
+	t.Run("Estimate cost without consuming tokens", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(10, 1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		assert.Equal(t, time.Duration(0), middleware.EstimateCost(req))
+		assert.Equal(t, time.Duration(0), middleware.EstimateCost(req))
+
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		require.NoError(t, err)
+
+		cost := middleware.EstimateCost(req)
+		assert.Greater(t, cost, 50*time.Millisecond)
+		assert.LessOrEqual(t, cost, 100*time.Millisecond)
+	})
+
+	t.Run("Reduce rate while degraded", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(100, 4)
+		middleware.SetLogger(logger.NewBasicLogger())
+		middleware.SetDegradedFactor(0.25)
+
+		makeRequest := func(ctx context.Context) error {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(ctx)
+			_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			return err
+		}
+
+		// The degraded limit allows a single request at 25 requests per second
+		degradedCtx := clientMiddleware.WithDegraded(context.Background())
+		require.NoError(t, makeRequest(degradedCtx))
+
+		ctx, cancel := context.WithTimeout(degradedCtx, 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, makeRequest(ctx), clientErrors.ErrTimeout)
+
+		// The regular limit still allows the remaining burst
+		require.NoError(t, makeRequest(context.Background()))
+		require.NoError(t, makeRequest(context.Background()))
+	})
+
+	t.Run("Reject requests when the queue is full", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(1, 1)
+		middleware.SetMaxQueue(1)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		makeRequest := func(ctx context.Context) error {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(ctx)
+			_, err := middleware.Process(ctx, &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			return err
+		}
+
+		// The first request uses the burst, the second waits on the limiter and the third is queued
+		require.NoError(t, makeRequest(ctx))
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = makeRequest(ctx)
+			}()
+		}
+		require.Eventually(t, func() bool {
+			return middleware.Introspect()["queued"] == 1
+		}, time.Second, time.Millisecond)
+
+		err := makeRequest(ctx)
+		require.ErrorIs(t, err, clientErrors.ErrOverloaded)
+
+		// The waiting and queued requests take the next two tokens, added at one token per second
+		var overloaded *clientErrors.OverloadedError
+		require.ErrorAs(t, err, &overloaded)
+		assert.Equal(t, 1, overloaded.QueueDepth)
+		assert.Greater(t, overloaded.RetryAfter, 2500*time.Millisecond)
+		assert.LessOrEqual(t, overloaded.RetryAfter, 3*time.Second)
+
+		cancel()
+		wg.Wait()
+	})
 }
@@ -0,0 +1,309 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid rate limit schedule")
+	ErrUnknownProfile  = errors.New("unknown rate limit profile")
+)
+
+// Profile is a named rate limit.
+type Profile struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// ScheduleEntry applies a profile during the minutes matched by a cron expression.
+type ScheduleEntry struct {
+	// Cron is a cron expression with minute, hour, day of month, month and day of week fields,
+	// such as "* 9-17 * * 1-5" for every minute of working hours. Fields accept *, values,
+	// ranges, lists and steps.
+	Cron string
+	// Profile is the name of the profile applied while the expression matches.
+	Profile string
+}
+
+// Schedule switches the rate limiter between named profiles depending on the time of day.
+type Schedule struct {
+	// Profiles are the profiles selected by the entries, by name.
+	Profiles map[string]Profile
+	// Entries select the profile applied at a given time. The first matching entry applies.
+	Entries []ScheduleEntry
+	// Default is the name of the profile applied when no entry matches.
+	Default string
+	// Location is the time zone the expressions are evaluated in. Defaults to the local time zone.
+	Location *time.Location
+}
+
+// schedule is a validated Schedule with parsed cron expressions.
+type schedule struct {
+	profiles map[string]Profile
+	entries  []scheduleEntry
+	fallback string
+	location *time.Location
+}
+
+// scheduleEntry is a ScheduleEntry with a parsed cron expression.
+type scheduleEntry struct {
+	cron    *cronExpr
+	profile string
+}
+
+// newSchedule validates the schedule and parses its cron expressions.
+func newSchedule(s Schedule) (*schedule, error) {
+	if _, ok := s.Profiles[s.Default]; !ok {
+		return nil, fmt.Errorf("%w: default profile %q is not defined", ErrInvalidSchedule, s.Default)
+	}
+
+	entries := make([]scheduleEntry, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		if _, ok := s.Profiles[entry.Profile]; !ok {
+			return nil, fmt.Errorf("%w: profile %q is not defined", ErrInvalidSchedule, entry.Profile)
+		}
+		cron, err := parseCron(entry.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSchedule, entry.Cron, err)
+		}
+		entries = append(entries, scheduleEntry{cron: cron, profile: entry.Profile})
+	}
+
+	location := s.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	return &schedule{
+		profiles: s.Profiles,
+		entries:  entries,
+		fallback: s.Default,
+		location: location,
+	}, nil
+}
+
+// profileAt returns the name of the profile applied at t.
+func (s *schedule) profileAt(t time.Time) string {
+	t = t.In(s.location)
+	for _, entry := range s.entries {
+		if entry.cron.matches(t) {
+			return entry.profile
+		}
+	}
+	return s.fallback
+}
+
+// SetSchedule switches the rate limiter between the profiles of the schedule from now on, checking
+// the schedule once a minute as requests arrive. It returns ErrInvalidSchedule if an expression
+// cannot be parsed or a profile is not defined. A nil Profiles map removes the schedule, keeping
+// the current limit.
+func (m *RateLimiterMiddleware) SetSchedule(s Schedule) error {
+	if s.Profiles == nil {
+		m.mu.Lock()
+		m.schedule = nil
+		m.profile = ""
+		m.mu.Unlock()
+		return nil
+	}
+
+	parsed, err := newSchedule(s)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.schedule = parsed
+	m.profile = ""
+	m.checked = time.Time{}
+	m.mu.Unlock()
+
+	m.applySchedule(time.Now())
+	return nil
+}
+
+// SetProfile applies the profile of the schedule, overriding the schedule until ResumeSchedule is
+// called. It returns ErrUnknownProfile if the schedule has no such profile.
+func (m *RateLimiterMiddleware) SetProfile(name string) error {
+	m.mu.Lock()
+	if m.schedule == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %q, no schedule is set", ErrUnknownProfile, name)
+	}
+	if _, ok := m.schedule.profiles[name]; !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+	m.override = name
+	m.mu.Unlock()
+
+	m.switchProfile(name)
+	return nil
+}
+
+// ResumeSchedule ends the override set by SetProfile, applying the profile of the schedule again.
+func (m *RateLimiterMiddleware) ResumeSchedule() {
+	m.mu.Lock()
+	m.override = ""
+	m.checked = time.Time{}
+	m.mu.Unlock()
+
+	m.applySchedule(time.Now())
+}
+
+// Profile returns the name of the profile currently applied, or an empty string without a schedule.
+func (m *RateLimiterMiddleware) Profile() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.profile
+}
+
+// ProfileAt returns the name of the profile the schedule applies at t, ignoring any override,
+// or an empty string without a schedule.
+func (m *RateLimiterMiddleware) ProfileAt(t time.Time) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.schedule == nil {
+		return ""
+	}
+	return m.schedule.profileAt(t)
+}
+
+// applySchedule switches to the profile of the schedule at now, checking at most once a minute.
+func (m *RateLimiterMiddleware) applySchedule(now time.Time) {
+	minute := now.Truncate(time.Minute)
+
+	m.mu.Lock()
+	if m.schedule == nil || m.override != "" || minute.Equal(m.checked) {
+		m.mu.Unlock()
+		return
+	}
+	m.checked = minute
+	name := m.schedule.profileAt(now)
+	m.mu.Unlock()
+
+	m.switchProfile(name)
+}
+
+// switchProfile applies the profile unless it is already applied.
+func (m *RateLimiterMiddleware) switchProfile(name string) {
+	m.mu.Lock()
+	if m.schedule == nil || m.profile == name {
+		m.mu.Unlock()
+		return
+	}
+	profile := m.schedule.profiles[name]
+	m.profile = name
+	m.mu.Unlock()
+
+	m.UpdateLimit(profile.RequestsPerSecond, profile.Burst)
+	m.logger.WithFields(logger.String("profile", name)).Info("Rate limit profile applied")
+}
+
+// cronField is the set of values matched by a field of a cron expression, as a bit mask.
+type cronField uint64
+
+// cronExpr is a parsed cron expression.
+type cronExpr struct {
+	minute, hour, dom, month, dow cronField
+	anyDom, anyDow                bool
+}
+
+// cronBounds are the minimum and maximum values of the fields of a cron expression.
+// Day of week accepts 7 as Sunday.
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// parseCron parses a cron expression with minute, hour, day of month, month and day of week fields.
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronBounds) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(cronBounds), len(fields))
+	}
+
+	var parsed [5]cronField
+	for i, field := range fields {
+		f, err := parseCronField(field, cronBounds[i][0], cronBounds[i][1])
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = f
+	}
+
+	// Sunday is both 0 and 7
+	if parsed[4]&(1<<7) != 0 {
+		parsed[4] |= 1
+	}
+
+	return &cronExpr{
+		minute: parsed[0],
+		hour:   parsed[1],
+		dom:    parsed[2],
+		month:  parsed[3],
+		dow:    parsed[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps within bounds.
+func parseCronField(field string, lo, hi int) (cronField, error) {
+	var result cronField
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		start, end := lo, hi
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if hasStep {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, lo, hi)
+		}
+
+		for v := start; v <= end; v += step {
+			result |= 1 << v
+		}
+	}
+	return result, nil
+}
+
+// matches reports whether the expression matches the minute of t. As in cron, when both the day of
+// month and day of week are restricted, either one matching is enough.
+func (c *cronExpr) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if !c.anyDom && !c.anyDow {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,120 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/ratelimit"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	profiles := map[string]ratelimit.Profile{
+		"normal": {RequestsPerSecond: 10, Burst: 5},
+		"peak":   {RequestsPerSecond: 2, Burst: 1},
+		"night":  {RequestsPerSecond: 50, Burst: 20},
+	}
+
+	t.Run("Select profiles by time of day", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(10, 5)
+		middleware.SetLogger(logger.NewBasicLogger())
+		require.NoError(t, middleware.SetSchedule(ratelimit.Schedule{
+			Profiles: profiles,
+			Entries: []ratelimit.ScheduleEntry{
+				{Cron: "* 9-17 * * 1-5", Profile: "peak"},
+				{Cron: "*/15 0-5 * * *", Profile: "night"},
+			},
+			Default:  "normal",
+			Location: time.UTC,
+		}))
+
+		at := func(value string) string {
+			tm, err := time.Parse(time.RFC3339, value)
+			require.NoError(t, err)
+			return middleware.ProfileAt(tm)
+		}
+
+		assert.Equal(t, "peak", at("2024-11-11T09:00:00Z"))   // Monday
+		assert.Equal(t, "peak", at("2024-11-15T17:59:00Z"))   // Friday
+		assert.Equal(t, "normal", at("2024-11-16T12:00:00Z")) // Saturday
+		assert.Equal(t, "normal", at("2024-11-11T18:00:00Z"))
+		assert.Equal(t, "night", at("2024-11-16T03:30:00Z"))
+		assert.Equal(t, "normal", at("2024-11-16T03:31:00Z"))
+
+		// Time zones are converted to the location of the schedule
+		assert.Equal(t, "peak", at("2024-11-11T10:00:00+01:00"))
+	})
+
+	t.Run("Match either the day of month or the day of week", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(10, 5)
+		require.NoError(t, middleware.SetSchedule(ratelimit.Schedule{
+			Profiles: profiles,
+			Entries:  []ratelimit.ScheduleEntry{{Cron: "* * 1 * 0", Profile: "night"}},
+			Default:  "normal",
+			Location: time.UTC,
+		}))
+
+		assert.Equal(t, "night", middleware.ProfileAt(time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)))  // Friday 1st
+		assert.Equal(t, "night", middleware.ProfileAt(time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC)))  // Sunday
+		assert.Equal(t, "normal", middleware.ProfileAt(time.Date(2024, 11, 4, 12, 0, 0, 0, time.UTC))) // Monday
+	})
+
+	t.Run("Reject invalid schedules", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(10, 5)
+		for _, s := range []ratelimit.Schedule{
+			{Profiles: profiles, Entries: nil, Default: "missing", Location: nil},
+			{Profiles: profiles, Entries: []ratelimit.ScheduleEntry{{Cron: "* * * * *", Profile: "missing"}}, Default: "normal", Location: nil},
+			{Profiles: profiles, Entries: []ratelimit.ScheduleEntry{{Cron: "* * * *", Profile: "peak"}}, Default: "normal", Location: nil},
+			{Profiles: profiles, Entries: []ratelimit.ScheduleEntry{{Cron: "60 * * * *", Profile: "peak"}}, Default: "normal", Location: nil},
+			{Profiles: profiles, Entries: []ratelimit.ScheduleEntry{{Cron: "*/0 * * * *", Profile: "peak"}}, Default: "normal", Location: nil},
+			{Profiles: profiles, Entries: []ratelimit.ScheduleEntry{{Cron: "* 5-2 * * *", Profile: "peak"}}, Default: "normal", Location: nil},
+		} {
+			require.ErrorIs(t, middleware.SetSchedule(s), ratelimit.ErrInvalidSchedule)
+		}
+		assert.Empty(t, middleware.Profile())
+	})
+
+	t.Run("Override the schedule until resumed", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := ratelimit.New(1, 1)
+		middleware.SetLogger(logger.NewBasicLogger())
+		require.NoError(t, middleware.SetSchedule(ratelimit.Schedule{
+			Profiles: profiles,
+			Entries:  []ratelimit.ScheduleEntry{{Cron: "* * * * *", Profile: "night"}},
+			Default:  "normal",
+			Location: nil,
+		}))
+		assert.Equal(t, "night", middleware.Profile())
+		assert.InDelta(t, 50.0, middleware.Introspect()["limit"], 0)
+
+		require.NoError(t, middleware.SetProfile("peak"))
+		require.ErrorIs(t, middleware.SetProfile("missing"), ratelimit.ErrUnknownProfile)
+
+		// Requests do not switch back to the scheduled profile
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "peak", middleware.Introspect()["profile"])
+		assert.InDelta(t, 2.0, middleware.Introspect()["limit"], 0)
+
+		middleware.ResumeSchedule()
+		assert.Equal(t, "night", middleware.Profile())
+		assert.InDelta(t, 50.0, middleware.Introspect()["limit"], 0)
+	})
+}
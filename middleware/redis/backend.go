@@ -0,0 +1,195 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/backoff"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/redis/rueidis"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive backend errors after which the cache is bypassed.
+	DefaultFailureThreshold = 3
+	// DefaultMinBypass is how long the cache is first bypassed once the backend is unavailable.
+	DefaultMinBypass = time.Second
+	// DefaultMaxBypass is the longest the cache is bypassed between two probes of the backend.
+	DefaultMaxBypass = time.Minute
+)
+
+// BackendStatus describes the health of the Redis backend as observed by the middleware.
+type BackendStatus struct {
+	// Degraded reports whether the cache is bypassed because the backend is unavailable.
+	Degraded bool
+	// ConsecutiveFailures is the number of backend errors since the last successful command.
+	ConsecutiveFailures int
+	// BypassUntil is when the backend is probed again, while degraded.
+	BypassUntil time.Time
+	// Bypassed is the number of requests sent straight to the origin while degraded.
+	Bypassed int64
+	// LastError is the last backend error, if any.
+	LastError error
+}
+
+// backendHealth tracks the errors of the Redis backend. Once failures reach the threshold, the
+// cache is bypassed for a period doubling after each failed probe, from minBypass to maxBypass.
+// A single request probes the backend at the end of each period, and a success restores the cache.
+type backendHealth struct {
+	threshold int
+	policy    backoff.Policy
+	failures  int
+	probes    uint64
+	bypass    time.Duration
+	until     time.Time
+	probing   bool
+	bypassed  int64
+	lastErr   error
+	mu        sync.Mutex
+}
+
+// newBackendHealth creates a new backendHealth instance.
+func newBackendHealth(threshold int, minBypass, maxBypass time.Duration) *backendHealth {
+	return &backendHealth{
+		threshold: threshold,
+		policy:    backoff.Doubling(minBypass, maxBypass),
+		failures:  0,
+		probes:    0,
+		bypass:    0,
+		until:     time.Time{},
+		probing:   false,
+		bypassed:  0,
+		lastErr:   nil,
+		mu:        sync.Mutex{},
+	}
+}
+
+// allow reports whether the request may use the cache at the time. While degraded, only the
+// request probing the backend once the bypass period ends is allowed.
+func (h *backendHealth) allow(now time.Time) bool {
+	if h == nil {
+		return true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.degraded() {
+		return true
+	}
+	if now.Before(h.until) || h.probing {
+		h.bypassed++
+		return false
+	}
+	h.probing = true
+	return true
+}
+
+// success records a successful command, reporting whether the backend recovered.
+func (h *backendHealth) success() bool {
+	if h == nil {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recovered := h.degraded()
+	h.failures = 0
+	h.probes = 0
+	h.bypass = 0
+	h.until = time.Time{}
+	h.probing = false
+	return recovered
+}
+
+// failure records a failed command at the time, returning the bypass period if the backend
+// became unavailable or a probe failed, and zero otherwise.
+func (h *backendHealth) failure(err error, now time.Time) time.Duration {
+	if h == nil {
+		return 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failures++
+	h.lastErr = err
+
+	switch {
+	case h.threshold <= 0 || h.failures < h.threshold:
+		return 0
+	case h.failures == h.threshold:
+		h.probes = 0
+	case h.probing:
+		h.probes++
+	default:
+		// Commands sent before the backend became unavailable fail without extending the bypass
+		return 0
+	}
+
+	h.bypass = h.policy.Delay(h.probes + 1)
+	h.until = now.Add(h.bypass)
+	h.probing = false
+	return h.bypass
+}
+
+// abort releases the probe of a command abandoned by its caller, so another request probes.
+func (h *backendHealth) abort() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.probing = false
+}
+
+// status returns the health of the backend.
+func (h *backendHealth) status() BackendStatus {
+	if h == nil {
+		return BackendStatus{} //exhaustruct:ignore
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := BackendStatus{
+		Degraded:            h.degraded(),
+		ConsecutiveFailures: h.failures,
+		BypassUntil:         time.Time{},
+		Bypassed:            h.bypassed,
+		LastError:           h.lastErr,
+	}
+	if status.Degraded {
+		status.BypassUntil = h.until
+	}
+	return status
+}
+
+// degraded reports whether the cache is bypassed. The mutex must be held.
+func (h *backendHealth) degraded() bool {
+	return h.threshold > 0 && h.failures >= h.threshold
+}
+
+// observeBackend records the outcome of a Redis command. Missing keys are successes, and commands
+// abandoned by their caller are not counted.
+func (m *RedisMiddleware) observeBackend(ctx context.Context, err error) {
+	switch {
+	case err == nil || rueidis.IsRedisNil(err):
+		if m.health.success() {
+			m.logger.Info("Cache backend recovered")
+		}
+	case ctx.Err() != nil:
+		m.health.abort()
+	default:
+		if bypass := m.health.failure(err, time.Now()); bypass > 0 {
+			m.logger.WithFields(
+				logger.String("error", err.Error()),
+				logger.Duration("bypass", bypass),
+			).Warn("Cache backend unavailable, bypassing cache")
+		}
+	}
+}
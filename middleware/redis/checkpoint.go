@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/pagination"
+	"github.com/redis/rueidis"
+)
+
+var _ pagination.CheckpointStore = (*CheckpointStore)(nil)
+
+// CheckpointStore is a pagination.CheckpointStore that keeps cursors in Redis.
+type CheckpointStore struct {
+	client     rueidis.Client
+	expiration time.Duration
+}
+
+// NewCheckpointStore creates a new CheckpointStore instance.
+// Saved cursors expire after the given duration, or never if it is zero.
+func NewCheckpointStore(redisClient rueidis.Client, expiration time.Duration) *CheckpointStore {
+	return &CheckpointStore{
+		client:     redisClient,
+		expiration: expiration,
+	}
+}
+
+// Load returns the saved cursor for key, or an empty string if there is none.
+func (s *CheckpointStore) Load(ctx context.Context, key string) (string, error) {
+	cmd := s.client.B().Get().Key(checkpointKey(key)).Build()
+	cursor, err := s.client.Do(ctx, cmd).ToString()
+	if rueidis.IsRedisNil(err) {
+		return "", nil
+	}
+	return cursor, err
+}
+
+// Save stores the cursor for key.
+func (s *CheckpointStore) Save(ctx context.Context, key, cursor string) error {
+	if s.expiration > 0 {
+		cmd := s.client.B().Set().Key(checkpointKey(key)).Value(cursor).Ex(s.expiration).Build()
+		return s.client.Do(ctx, cmd).Error()
+	}
+
+	cmd := s.client.B().Set().Key(checkpointKey(key)).Value(cursor).Build()
+	return s.client.Do(ctx, cmd).Error()
+}
+
+// Delete removes the cursor for key.
+func (s *CheckpointStore) Delete(ctx context.Context, key string) error {
+	cmd := s.client.B().Del().Key(checkpointKey(key)).Build()
+	return s.client.Do(ctx, cmd).Error()
+}
+
+// checkpointKey returns the Redis key used to store the cursor for key.
+func checkpointKey(key string) string {
+	return "checkpoint:" + key
+}
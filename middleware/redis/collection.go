@@ -0,0 +1,246 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/pagination"
+	"github.com/redis/rueidis"
+)
+
+var _ pagination.CollectionStore = (*CollectionStore)(nil)
+
+// beginScript starts a generation in the current epoch of the collection.
+// KEYS: pending generations, epoch. ARGV: generation, expiration in milliseconds (0 for none).
+var beginScript = rueidis.NewLuaScript(`
+local epoch = redis.call('GET', KEYS[2]) or '0'
+redis.call('HSET', KEYS[1], ARGV[1], epoch)
+if ARGV[2] ~= '0' then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// savePageScript stores a page of a generation begun in the current epoch, returning 0 otherwise.
+// KEYS: pending generations, epoch, page. ARGV: generation, page, expiration in milliseconds (0 for none).
+var savePageScript = rueidis.NewLuaScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= (redis.call('GET', KEYS[2]) or '0') then
+	return 0
+end
+if ARGV[3] ~= '0' then
+	redis.call('SET', KEYS[3], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[3], ARGV[2])
+end
+return 1
+`)
+
+// commitScript makes a pending generation begun in the current epoch current and deletes the
+// pages of the previous one, returning 0 if the generation is not pending in the current epoch.
+// KEYS: current generation, pending generations, epoch. ARGV: generation, page count, expiration
+// in milliseconds (0 for none), prefix of the page keys.
+var commitScript = rueidis.NewLuaScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= (redis.call('GET', KEYS[3]) or '0') then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+local previous = redis.call('GET', KEYS[1])
+if ARGV[3] ~= '0' then
+	redis.call('SET', KEYS[1], ARGV[1] .. ':' .. ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[1] .. ':' .. ARGV[2])
+end
+if previous then
+	local generation, count = string.match(previous, '^(.*):(%d+)$')
+	for i = 0, tonumber(count) - 1 do
+		redis.call('DEL', ARGV[4] .. generation .. ':' .. i)
+	end
+end
+return 1
+`)
+
+// discardScript deletes a pending generation and its pages, which are stored from the first.
+// KEYS: pending generations. ARGV: generation, prefix of the page keys.
+var discardScript = rueidis.NewLuaScript(`
+redis.call('HDEL', KEYS[1], ARGV[1])
+local i = 0
+while redis.call('DEL', ARGV[2] .. ARGV[1] .. ':' .. i) == 1 do
+	i = i + 1
+end
+return 1
+`)
+
+// invalidateScript deletes the current generation, the pending generations and their pages, and
+// starts a new epoch so the generations begun before can no longer be stored.
+// KEYS: current generation, pending generations, epoch. ARGV: prefix of the page keys.
+var invalidateScript = rueidis.NewLuaScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local generation, count = string.match(current, '^(.*):(%d+)$')
+	for i = 0, tonumber(count) - 1 do
+		redis.call('DEL', ARGV[1] .. generation .. ':' .. i)
+	end
+end
+for _, generation in ipairs(redis.call('HKEYS', KEYS[2])) do
+	local i = 0
+	while redis.call('DEL', ARGV[1] .. generation .. ':' .. i) == 1 do
+		i = i + 1
+	end
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+redis.call('INCR', KEYS[3])
+return 1
+`)
+
+// CollectionStore is a pagination.CollectionStore that keeps collections in Redis.
+// The keys of a collection share a hash tag, so they live on the same node of a cluster.
+type CollectionStore struct {
+	client     rueidis.Client
+	expiration time.Duration
+}
+
+// NewCollectionStore creates a new CollectionStore instance.
+// Cached collections expire after the given duration, or never if it is zero. The pages of failed
+// refreshes are discarded, while those of refreshes abandoned midway are only removed by the
+// expiration or the next invalidation.
+func NewCollectionStore(redisClient rueidis.Client, expiration time.Duration) *CollectionStore {
+	return &CollectionStore{
+		client:     redisClient,
+		expiration: expiration,
+	}
+}
+
+// LoadPages returns the pages of the current generation of key, or false if there is none.
+func (s *CollectionStore) LoadPages(ctx context.Context, key string) ([][]byte, bool, error) {
+	cmd := s.client.B().Get().Key(collectionKey(key)).Build()
+	current, err := s.client.Do(ctx, cmd).ToString()
+	if rueidis.IsRedisNil(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	generation, count, err := parseGeneration(current)
+	if err != nil {
+		return nil, false, err
+	}
+
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = pageKey(key, generation, i)
+	}
+
+	values, err := s.client.Do(ctx, s.client.B().Mget().Key(keys...).Build()).ToArray()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A page removed by a concurrent commit or expiration means the generation is gone
+	pages := make([][]byte, len(values))
+	for i, value := range values {
+		page, err := value.AsBytes()
+		if rueidis.IsRedisNil(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		pages[i] = page
+	}
+	return pages, true, nil
+}
+
+// Begin starts the generation of key in the current epoch of the collection.
+func (s *CollectionStore) Begin(ctx context.Context, key, generation string) error {
+	keys := []string{pendingKey(key), epochKey(key)}
+	args := []string{generation, strconv.FormatInt(s.expiration.Milliseconds(), 10)}
+	return beginScript.Exec(ctx, s.client, keys, args).Error()
+}
+
+// SavePage stores the page at index for the generation of key, failing with
+// pagination.ErrGenerationInvalidated if the collection was invalidated since the generation began.
+func (s *CollectionStore) SavePage(ctx context.Context, key, generation string, index int, page []byte) error {
+	keys := []string{pendingKey(key), epochKey(key), pageKey(key, generation, index)}
+	args := []string{generation, rueidis.BinaryString(page), strconv.FormatInt(s.expiration.Milliseconds(), 10)}
+	return stored(savePageScript.Exec(ctx, s.client, keys, args), generation)
+}
+
+// Commit makes the generation of key current, replacing the previous one, failing with
+// pagination.ErrGenerationInvalidated if the collection was invalidated since the generation began.
+func (s *CollectionStore) Commit(ctx context.Context, key, generation string, count int) error {
+	keys := []string{collectionKey(key), pendingKey(key), epochKey(key)}
+	args := []string{
+		generation,
+		strconv.Itoa(count),
+		strconv.FormatInt(s.expiration.Milliseconds(), 10),
+		pagePrefix(key),
+	}
+	return stored(commitScript.Exec(ctx, s.client, keys, args), generation)
+}
+
+// Discard removes the pending generation of key and its pages.
+func (s *CollectionStore) Discard(ctx context.Context, key, generation string) error {
+	keys := []string{pendingKey(key)}
+	return discardScript.Exec(ctx, s.client, keys, []string{generation, pagePrefix(key)}).Error()
+}
+
+// Invalidate removes the current and pending generations of key and starts a new epoch.
+func (s *CollectionStore) Invalidate(ctx context.Context, key string) error {
+	keys := []string{collectionKey(key), pendingKey(key), epochKey(key)}
+	return invalidateScript.Exec(ctx, s.client, keys, []string{pagePrefix(key)}).Error()
+}
+
+// stored returns the error of a script storing a generation, which returns 0 if the generation
+// was not begun in the current epoch.
+func stored(resp rueidis.RedisResult, generation string) error {
+	ok, err := resp.AsInt64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return fmt.Errorf("%w: generation %s", pagination.ErrGenerationInvalidated, generation)
+	}
+	return nil
+}
+
+// parseGeneration splits the value of the current generation into its identifier and page count.
+func parseGeneration(value string) (string, int, error) {
+	generation, count, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("%w: invalid generation %q", pagination.ErrCollectionCache, value)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: invalid generation %q", pagination.ErrCollectionCache, value)
+	}
+	return generation, n, nil
+}
+
+// collectionKey returns the Redis key storing the current generation of the collection.
+func collectionKey(key string) string {
+	return "collection:{" + key + "}"
+}
+
+// pendingKey returns the Redis key storing the pending generations of the collection.
+func pendingKey(key string) string {
+	return "collection:{" + key + "}:pending"
+}
+
+// epochKey returns the Redis key counting the invalidations of the collection.
+func epochKey(key string) string {
+	return "collection:{" + key + "}:epoch"
+}
+
+// pagePrefix returns the prefix of the Redis keys storing the pages of the collection.
+func pagePrefix(key string) string {
+	return "collection:{" + key + "}:page:"
+}
+
+// pageKey returns the Redis key storing a page of a generation of the collection.
+func pageKey(key, generation string, index int) string {
+	return pagePrefix(key) + generation + ":" + strconv.Itoa(index)
+}
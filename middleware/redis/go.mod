@@ -1,11 +1,11 @@
 module github.com/jaxron/axonet/middleware/redis
 
-go 1.23.1
+go 1.24.0
 
 require (
 	github.com/bytedance/sonic v1.12.5
 	github.com/cespare/xxhash v1.1.0
-	github.com/jaxron/axonet v0.0.0-20241110114112-10fce0f238e1
+	github.com/jaxron/axonet v0.1.0
 	github.com/redis/rueidis v1.0.51
 	github.com/stretchr/testify v1.9.0
 )
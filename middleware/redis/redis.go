@@ -7,10 +7,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/cespare/xxhash"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/compression"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/redis/rueidis"
@@ -18,11 +23,39 @@ import (
 
 type SkipCacheKey struct{}
 
+// FlagStaleOK serves stale cached responses even while the client is not degraded.
+const FlagStaleOK middleware.Flag = "cache.stale-ok"
+
+func init() {
+	middleware.RegisterFlag(FlagStaleOK, "Serves stale cached responses kept with SetStaleTTL")
+}
+
+// WithSkipCache returns a request option that bypasses the cache for the request.
+func WithSkipCache() client.RequestOption {
+	return client.WithContextValue(SkipCacheKey{}, true)
+}
+
 // RedisMiddleware implements a caching middleware using Redis.
 type RedisMiddleware struct {
-	client     rueidis.Client
-	logger     logger.Logger
-	expiration time.Duration
+	client      rueidis.Client
+	logger      logger.Logger
+	expiration  time.Duration
+	staleTTL    time.Duration
+	compression compression.Codec
+	writes      *writeLog
+	writeWindow time.Duration
+	normalizer  *middleware.URLNormalizer
+	headers     *middleware.HeaderFilter
+	health      *backendHealth
+	lifecycle   lifecycle
+}
+
+// lifecycle counts the cache writes running in the background, so Stop can wait for them.
+type lifecycle struct {
+	mu      sync.Mutex
+	stopped bool
+	pending int
+	idle    chan struct{}
 }
 
 // CachedResponse represents the structure of a cached HTTP response.
@@ -35,19 +68,85 @@ type CachedResponse struct {
 	TransferEncoding []string    `json:"transferEncoding"`
 	Uncompressed     bool        `json:"uncompressed"`
 	Trailer          http.Header `json:"trailer"`
+	Encoding         string      `json:"encoding,omitempty"`
+	StoredAt         time.Time   `json:"storedAt"`
 }
 
 // New creates a new RedisMiddleware instance.
 func New(redisClient rueidis.Client, expiration time.Duration) *RedisMiddleware {
 	return &RedisMiddleware{
-		client:     redisClient,
-		logger:     &logger.NoOpLogger{},
-		expiration: expiration,
+		client:      redisClient,
+		logger:      &logger.NoOpLogger{},
+		expiration:  expiration,
+		staleTTL:    0,
+		compression: nil,
+		writes:      newWriteLog(),
+		writeWindow: DefaultWriteWindow,
+		normalizer:  middleware.NewURLNormalizer(),
+		headers:     middleware.NewHeaderFilter(),
+		health:      newBackendHealth(DefaultFailureThreshold, DefaultMinBypass, DefaultMaxBypass),
+		lifecycle:   lifecycle{mu: sync.Mutex{}, stopped: false, pending: 0, idle: nil},
 	}
 }
 
+// SetNormalizer sets the normalizer applied to request URLs before deriving their cache key, so
+// semantically identical URLs share entries. Passing nil uses URLs as they are.
+func (m *RedisMiddleware) SetNormalizer(normalizer *middleware.URLNormalizer) {
+	m.normalizer = normalizer
+}
+
+// SetHeaderFilter sets the filter selecting the response headers stored in the cache. By default,
+// the middleware.SensitiveHeaders such as Set-Cookie are not stored. Passing nil stores every header.
+func (m *RedisMiddleware) SetHeaderFilter(filter *middleware.HeaderFilter) {
+	m.headers = filter
+}
+
+// SetCompression sets the codec used to compress cached bodies.
+// Passing nil stores bodies uncompressed.
+func (m *RedisMiddleware) SetCompression(codec compression.Codec) {
+	m.compression = codec
+}
+
+// SetStaleTTL keeps cached responses for staleTTL past their expiration.
+// Stale responses are only served while the client is degraded or to requests with the FlagStaleOK
+// flag, other requests refresh them.
+func (m *RedisMiddleware) SetStaleTTL(staleTTL time.Duration) {
+	m.staleTTL = staleTTL
+}
+
+// SetWriteWindow sets how long reads bypass the cache after a POST, PUT, PATCH or DELETE request,
+// so callers observe their own writes. Affected reads are those of the written resource and its
+// sub-resources, of its parent collection, and those sharing a tag set with WithTags. Afterwards,
+// responses cached before the write are refreshed instead of served, for as long as they may stay
+// cached. Writes are only known to the middleware that sent them: other processes sharing the
+// cache, or this one once restarted, may still serve responses cached before them until they
+// expire. Zero disables tracking writes.
+func (m *RedisMiddleware) SetWriteWindow(window time.Duration) {
+	m.writeWindow = window
+}
+
+// SetBackendBackoff sets how the middleware reacts to an unavailable Redis backend. After
+// threshold consecutive backend errors, requests bypass the cache and go straight to the origin
+// for minBypass, then a single request probes the backend. Each failed probe doubles the bypass
+// period up to maxBypass, and a successful one restores the cache. Zero never bypasses the cache.
+func (m *RedisMiddleware) SetBackendBackoff(threshold int, minBypass, maxBypass time.Duration) {
+	m.health = newBackendHealth(threshold, minBypass, maxBypass)
+}
+
+// Backend returns the health of the Redis backend, reporting whether the cache is bypassed.
+func (m *RedisMiddleware) Backend() BackendStatus {
+	return m.health.status()
+}
+
 // Process implements the middleware.Middleware interface.
 func (m *RedisMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Record writes once they complete, whether they succeeded or not
+	if m.writeWindow > 0 && m.writes != nil && isWrite(req.Method) {
+		defer func() {
+			m.writes.record(req, tagsFromContext(ctx), time.Now(), m.writeWindow+m.expiration+m.staleTTL)
+		}()
+	}
+
 	// Check if caching should be skipped
 	if skipCache, ok := ctx.Value(SkipCacheKey{}).(bool); ok && skipCache {
 		return next(ctx, httpClient, req)
@@ -55,11 +154,32 @@ func (m *RedisMiddleware) Process(ctx context.Context, httpClient *http.Client,
 
 	key := m.GenerateKey(req)
 
+	// Bypass the cache while the backend is unavailable
+	if !m.health.allow(time.Now()) {
+		events.Publish(ctx, events.CacheMiss{Key: key, Reason: "bypass"})
+		return next(ctx, httpClient, req)
+	}
+
 	// Try to get the cached response
 	cachedResp, err := m.getFromCache(ctx, key)
 	if err == nil {
-		m.logger.Debug("Cache hit")
-		return m.ReconstructResponse(cachedResp), nil
+		switch {
+		case m.writtenSince(ctx, req, cachedResp):
+			m.logger.Debug("Bypassing cache after a write")
+			events.Publish(ctx, events.CacheMiss{Key: key, Reason: "write"})
+		case !m.isStale(cachedResp):
+			m.logger.Debug("Cache hit")
+			events.Publish(ctx, events.CacheHit{Key: key, Stale: false})
+			return m.ReconstructResponse(cachedResp), nil
+		case middleware.DegradedFromContext(ctx) || middleware.FlagEnabled(ctx, FlagStaleOK):
+			m.logger.Debug("Serving stale cached response")
+			events.Publish(ctx, events.CacheHit{Key: key, Stale: true})
+			return m.ReconstructResponse(cachedResp), nil
+		default:
+			events.Publish(ctx, events.CacheMiss{Key: key, Reason: "stale"})
+		}
+	} else {
+		events.Publish(ctx, events.CacheMiss{Key: key, Reason: ""})
 	}
 
 	// Cache miss, proceed with the request
@@ -79,22 +199,116 @@ func (m *RedisMiddleware) Process(ctx context.Context, httpClient *http.Client,
 		resp.Body.Close()
 		resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 
-		// Cache the response
-		go m.cacheResponse(ctx, key, resp, bodyBytes)
+		// Cache the response in the background, unless the middleware is stopped
+		if m.lifecycle.begin() {
+			go func() {
+				defer m.lifecycle.end()
+				m.cacheResponse(context.WithoutCancel(ctx), key, resp, bodyBytes)
+			}()
+		}
 	}
 
 	return resp, nil
 }
 
+// writtenSince reports whether a write affecting the request was made since the response was
+// cached, or recently enough for the cache to be bypassed.
+func (m *RedisMiddleware) writtenSince(ctx context.Context, req *http.Request, cachedResp *CachedResponse) bool {
+	if m.writeWindow <= 0 || m.writes == nil || !isRead(req.Method) {
+		return false
+	}
+	written, ok := m.writes.lastWrite(req, tagsFromContext(ctx))
+	return ok && (cachedResp.StoredAt.Before(written) || time.Since(written) < m.writeWindow)
+}
+
+// Start resumes caching responses after Stop. It implements the middleware.LifecycleMiddleware
+// interface.
+func (m *RedisMiddleware) Start(_ context.Context) error {
+	m.lifecycle.mu.Lock()
+	defer m.lifecycle.mu.Unlock()
+
+	m.lifecycle.stopped = false
+	return nil
+}
+
+// Stop stops caching responses and waits for the pending cache writes until ctx is done. Cached
+// responses are still served. It implements the middleware.LifecycleMiddleware interface.
+func (m *RedisMiddleware) Stop(ctx context.Context) error {
+	m.lifecycle.mu.Lock()
+	m.lifecycle.stopped = true
+	if m.lifecycle.pending == 0 {
+		m.lifecycle.mu.Unlock()
+		return nil
+	}
+	if m.lifecycle.idle == nil {
+		m.lifecycle.idle = make(chan struct{})
+	}
+	idle := m.lifecycle.idle
+	m.lifecycle.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin counts a cache write, reporting false if the middleware is stopped.
+func (l *lifecycle) begin() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		return false
+	}
+	l.pending++
+	return true
+}
+
+// end ends a cache write, waking up Stop once none is pending.
+func (l *lifecycle) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.pending--; l.pending == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
 // SetLogger sets the logger for the middleware.
 func (m *RedisMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
 }
 
+// Capabilities declares that the middleware reads the request body to derive its key, and restores it.
+func (m *RedisMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody}
+}
+
+// Introspect returns the health of the Redis backend.
+func (m *RedisMiddleware) Introspect() map[string]interface{} {
+	status := m.Backend()
+	return map[string]interface{}{
+		"degraded":            status.Degraded,
+		"consecutiveFailures": status.ConsecutiveFailures,
+		"bypassUntil":         status.BypassUntil,
+		"bypassed":            status.Bypassed,
+	}
+}
+
+// isStale reports whether the cached response is past its expiration.
+// Responses cached without a storage time are never stale.
+func (m *RedisMiddleware) isStale(cachedResp *CachedResponse) bool {
+	return !cachedResp.StoredAt.IsZero() && time.Since(cachedResp.StoredAt) > m.expiration
+}
+
 // getFromCache retrieves a cached response from Redis.
 func (m *RedisMiddleware) getFromCache(ctx context.Context, key string) (*CachedResponse, error) {
 	cmd := m.client.B().Get().Key(key).Build()
 	result, err := m.client.Do(ctx, cmd).AsBytes()
+	m.observeBackend(ctx, err)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +319,20 @@ func (m *RedisMiddleware) getFromCache(ctx context.Context, key string) (*Cached
 		return nil, err
 	}
 
+	// Decompress the body with the codec it was stored with
+	if cachedResp.Encoding != "" {
+		codec, ok := compression.Lookup(cachedResp.Encoding)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", compression.ErrUnknownEncoding, cachedResp.Encoding)
+		}
+
+		cachedResp.Body, err = compression.Decompress(codec, cachedResp.Body)
+		if err != nil {
+			return nil, err
+		}
+		cachedResp.Encoding = ""
+	}
+
 	return &cachedResp, nil
 }
 
@@ -114,12 +342,25 @@ func (m *RedisMiddleware) cacheResponse(ctx context.Context, key string, resp *h
 	cachedResp := CachedResponse{
 		Status:           resp.Status,
 		StatusCode:       resp.StatusCode,
-		Header:           resp.Header,
+		Header:           m.filterHeaders(resp.Header),
 		Body:             bodyBytes,
 		ContentLength:    resp.ContentLength,
 		TransferEncoding: resp.TransferEncoding,
 		Uncompressed:     resp.Uncompressed,
-		Trailer:          resp.Trailer,
+		Trailer:          m.filterHeaders(resp.Trailer),
+		Encoding:         "",
+		StoredAt:         time.Now(),
+	}
+
+	// Compress the body if a codec is set
+	if m.compression != nil {
+		compressed, err := compression.Compress(m.compression, bodyBytes)
+		if err != nil {
+			m.logger.WithFields(logger.String("error", err.Error())).Error("Failed to compress cached response")
+			return
+		}
+		cachedResp.Body = compressed
+		cachedResp.Encoding = m.compression.Encoding()
 	}
 
 	jsonData, err := sonic.Marshal(cachedResp)
@@ -128,18 +369,31 @@ func (m *RedisMiddleware) cacheResponse(ctx context.Context, key string, resp *h
 		return
 	}
 
-	cmd := m.client.B().Set().Key(key).Value(string(jsonData)).Ex(m.expiration).Build()
+	cmd := m.client.B().Set().Key(key).Value(string(jsonData)).Ex(m.expiration + m.staleTTL).Build()
 	err = m.client.Do(ctx, cmd).Error()
+	m.observeBackend(ctx, err)
 	if err != nil && !errors.Is(err, context.Canceled) {
 		m.logger.WithFields(logger.String("error", err.Error())).Error("Failed to cache response")
 	}
 }
 
-// GenerateKey creates a unique cache key based on the request method, URL, headers, and body.
+// filterHeaders returns the headers of the response to store.
+func (m *RedisMiddleware) filterHeaders(header http.Header) http.Header {
+	if m.headers == nil {
+		return header
+	}
+	return m.headers.Filter(header)
+}
+
+// GenerateKey creates a unique cache key based on the request method, normalized URL, headers, and body.
 func (m *RedisMiddleware) GenerateKey(req *http.Request) string {
 	h := xxhash.New()
 	h.Write([]byte(req.Method))
-	h.Write([]byte(req.URL.String()))
+	if m.normalizer != nil {
+		h.Write([]byte(m.normalizer.Normalize(req.URL)))
+	} else {
+		h.Write([]byte(req.URL.String()))
+	}
 	for key, values := range req.Header {
 		h.Write([]byte(key))
 		for _, value := range values {
@@ -161,14 +415,29 @@ func (m *RedisMiddleware) GenerateKey(req *http.Request) string {
 }
 
 // ReconstructResponse creates an http.Response from a cached response.
+// The stored body is complete and already decoded, so the length fields are recomputed from it
+// rather than replayed: the response has no transfer encoding, and its ContentLength and
+// Content-Length header match the body even if the original was chunked, compressed or truncated.
 func (m *RedisMiddleware) ReconstructResponse(cachedResp *CachedResponse) *http.Response {
+	header := cachedResp.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(cachedResp.Body)))
+
+	// The body was decoded when the response was received
+	if cachedResp.Uncompressed {
+		header.Del("Content-Encoding")
+	}
+
 	return &http.Response{
 		Status:           cachedResp.Status,
 		StatusCode:       cachedResp.StatusCode,
-		Header:           cachedResp.Header,
+		Header:           header,
 		Body:             io.NopCloser(bytes.NewReader(cachedResp.Body)),
-		ContentLength:    cachedResp.ContentLength,
-		TransferEncoding: cachedResp.TransferEncoding,
+		ContentLength:    int64(len(cachedResp.Body)),
+		TransferEncoding: nil,
 		Uncompressed:     cachedResp.Uncompressed,
 		Trailer:          cachedResp.Trailer,
 	} //exhaustruct:ignore
@@ -75,16 +75,54 @@ func TestRedisMiddleware(t *testing.T) {
 
 		assert.Equal(t, originalResp.Status, reconstructedResp.Status)
 		assert.Equal(t, originalResp.StatusCode, reconstructedResp.StatusCode)
-		assert.Equal(t, originalResp.Header, reconstructedResp.Header)
-		assert.Equal(t, originalResp.ContentLength, reconstructedResp.ContentLength)
-		assert.Equal(t, originalResp.TransferEncoding, reconstructedResp.TransferEncoding)
+		assert.Equal(t, "application/json", reconstructedResp.Header.Get("Content-Type"))
 		assert.Equal(t, originalResp.Uncompressed, reconstructedResp.Uncompressed)
 		assert.Equal(t, originalResp.Trailer, reconstructedResp.Trailer)
 
+		// The length fields describe the stored body rather than the original transfer
+		assert.Equal(t, int64(len(body)), reconstructedResp.ContentLength)
+		assert.Equal(t, "27", reconstructedResp.Header.Get("Content-Length"))
+		assert.Nil(t, reconstructedResp.TransferEncoding)
+
 		reconstructedBody, err := io.ReadAll(reconstructedResp.Body)
 		require.NoError(t, err)
 		assert.Equal(t, body, reconstructedBody)
 	})
+
+	t.Run("Normalize length fields of decoded bodies", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := redis.RedisMiddleware{}
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		body := []byte("decompressed body")
+		cachedResp := &redis.CachedResponse{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Encoding":  []string{"gzip"},
+				"Content-Length":    []string{"5"},
+				"Transfer-Encoding": []string{"chunked"},
+			},
+			Body:             body,
+			ContentLength:    -1,
+			TransferEncoding: []string{"chunked"},
+			Uncompressed:     true,
+		}
+
+		reconstructedResp := middleware.ReconstructResponse(cachedResp)
+		assert.Equal(t, int64(len(body)), reconstructedResp.ContentLength)
+		assert.Nil(t, reconstructedResp.TransferEncoding)
+		assert.Equal(t, http.Header{"Content-Length": []string{"17"}}, reconstructedResp.Header)
+
+		// The cached response is left untouched
+		assert.Equal(t, "5", cachedResp.Header.Get("Content-Length"))
+
+		// Responses without headers get a Content-Length too
+		reconstructedResp = middleware.ReconstructResponse(&redis.CachedResponse{StatusCode: http.StatusNoContent})
+		assert.Equal(t, int64(0), reconstructedResp.ContentLength)
+		assert.Equal(t, "0", reconstructedResp.Header.Get("Content-Length"))
+	})
 }
 
 func TestCachedResponseSerialization(t *testing.T) {
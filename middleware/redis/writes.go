@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+)
+
+// DefaultWriteWindow is the time after a write during which reads of the written resources
+// bypass the cache.
+const DefaultWriteWindow = 5 * time.Second
+
+type TagsKey struct{}
+
+// WithTags returns a request option tagging the request with the resources it reads or writes,
+// such as "user:42". A write invalidates the cached reads sharing one of its tags.
+func WithTags(tags ...string) client.RequestOption {
+	return client.WithContextValue(TagsKey{}, tags)
+}
+
+// writeLog records the resources written through the middleware, so later reads observe the
+// writes instead of responses cached before them. The log is kept in memory, so it only covers
+// the writes sent by this process.
+type writeLog struct {
+	// resources holds the last write to resources, which also affects their sub-resources.
+	resources map[string]time.Time
+	// related holds the last write affecting collections and tags, without their sub-resources.
+	related map[string]time.Time
+	mu      sync.Mutex
+}
+
+// newWriteLog creates a new writeLog instance.
+func newWriteLog() *writeLog {
+	return &writeLog{
+		resources: make(map[string]time.Time),
+		related:   make(map[string]time.Time),
+		mu:        sync.Mutex{},
+	}
+}
+
+// record marks the resource of the request, its parent collection and its tags as written at
+// the time, forgetting writes older than retention.
+func (l *writeLog) record(req *http.Request, tags []string, at time.Time, retention time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, marks := range []map[string]time.Time{l.resources, l.related} {
+		for key, written := range marks {
+			if at.Sub(written) > retention {
+				delete(marks, key)
+			}
+		}
+	}
+
+	resource := resourceKey(req)
+	l.resources[resource] = at
+	if parent := parentKey(resource); parent != "" {
+		l.related[parent] = at
+	}
+	for _, tag := range tags {
+		l.related["tag:"+tag] = at
+	}
+}
+
+// lastWrite returns the time of the last write affecting a read of the request: a write to its
+// resource or a resource containing it, to a resource of its collection or to one of its tags.
+func (l *writeLog) lastWrite(req *http.Request, tags []string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var last time.Time
+	observe := func(marks map[string]time.Time, key string) {
+		if written, ok := marks[key]; ok && written.After(last) {
+			last = written
+		}
+	}
+
+	resource := resourceKey(req)
+	observe(l.related, resource)
+	for key := resource; key != ""; key = parentKey(key) {
+		observe(l.resources, key)
+	}
+	for _, tag := range tags {
+		observe(l.related, "tag:"+tag)
+	}
+	return last, !last.IsZero()
+}
+
+// isWrite reports whether the method changes the resource it is sent to.
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRead reports whether the method reads the resource it is sent to.
+func isRead(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// resourceKey identifies the resource of the request by its host and path, ignoring the query.
+func resourceKey(req *http.Request) string {
+	return strings.ToLower(req.URL.Host) + path.Clean("/"+req.URL.Path)
+}
+
+// parentKey returns the key of the collection containing the resource, or an empty string for
+// the root of a host.
+func parentKey(key string) string {
+	if strings.HasSuffix(key, "/") {
+		return ""
+	}
+	parent := key[:strings.LastIndex(key, "/")]
+	if !strings.Contains(parent, "/") {
+		return parent + "/"
+	}
+	return parent
+}
+
+// tagsFromContext returns the tags of the request.
+func tagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(TagsKey{}).([]string)
+	return tags
+}
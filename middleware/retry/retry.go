@@ -2,20 +2,32 @@ package retry
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
+	"github.com/jaxron/axonet/pkg/backoff"
 	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 )
 
+// FlagAggressive doubles the number of retry attempts.
+const FlagAggressive middleware.Flag = "retry.aggressive"
+
+func init() {
+	middleware.RegisterFlag(FlagAggressive, "Doubles the number of retry attempts")
+}
+
 // RetryMiddleware implements retry logic for HTTP requests with exponential backoff.
 type RetryMiddleware struct {
 	maxAttempts     uint64
 	initialInterval time.Duration
 	maxInterval     time.Duration
+	jitter          backoff.Jitter
+	mu              sync.RWMutex
 	logger          logger.Logger
 }
 
@@ -25,33 +37,66 @@ func New(maxAttempts uint64, initialInterval, maxInterval time.Duration) *RetryM
 		maxAttempts:     maxAttempts,
 		initialInterval: initialInterval,
 		maxInterval:     maxInterval,
+		jitter:          backoff.JitterProportional,
+		mu:              sync.RWMutex{},
 		logger:          &logger.NoOpLogger{},
 	}
 }
 
 // Process applies retry logic before passing the request to the next middleware.
 func (m *RetryMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
-	// Create an exponential backoff strategy with a maximum number of retries
-	expBackoff := backoff.WithMaxRetries(backoff.NewExponentialBackOff(
-		backoff.WithInitialInterval(m.initialInterval),
-		backoff.WithMaxInterval(m.maxInterval),
-	), m.maxAttempts)
-	backoffStrategy := backoff.WithContext(expBackoff, ctx)
+	m.mu.RLock()
+	maxAttempts := m.maxAttempts
+	initialInterval := m.initialInterval
+	maxInterval := m.maxInterval
+	jitter := m.jitter
+	m.mu.RUnlock()
+
+	if middleware.FlagEnabled(ctx, FlagAggressive) {
+		maxAttempts *= 2
+	}
+
+	// Create an exponential backoff policy with a maximum number of retries
+	policy := backoff.Exponential(initialInterval, maxInterval).
+		WithMaxRetries(maxAttempts).
+		WithJitter(jitter)
 
-	var resp *http.Response
+	var (
+		resp    *http.Response
+		attempt uint64
+		start   = time.Now()
+	)
 
-	// Retry the request using the backoff strategy
-	err := backoff.RetryNotify(
+	// Retry the request using the backoff policy
+	err := backoff.Retry(ctx, policy,
 		func() error {
+			// Expose the attempt number so inner middleware can scope their state per attempt
+			attempt++
+			attemptCtx := middleware.WithAttempt(ctx, attempt)
+
+			// Read the body again for retried attempts, as the previous attempt consumed it
+			if attempt > 1 && req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return backoff.Permanent(err)
+				}
+				req.Body = body
+			}
+
 			var err error
-			resp, err = next(ctx, httpClient, req)
-			return m.handleRetryError(resp, err)
+			resp, err = next(attemptCtx, httpClient, req)
+			return m.handleRetryError(attemptCtx, resp, err)
 		},
-		backoffStrategy,
 		func(err error, duration time.Duration) {
+			events.Publish(ctx, events.RetryScheduled{Attempt: int(attempt), Err: err, Delay: duration})
+
+			// The budget is the first attempt plus the retries
 			m.logger.WithFields(
 				logger.String("error", err.Error()),
 				logger.String("url", req.URL.String()),
+				logger.Int("attempt", int(attempt)),
+				logger.Int("max_attempts", int(maxAttempts)+1),
+				logger.Duration("elapsed", time.Since(start)),
 				logger.Duration("retry_in", duration),
 			).Warn("Retrying request")
 		},
@@ -61,33 +106,64 @@ func (m *RetryMiddleware) Process(ctx context.Context, httpClient *http.Client,
 	return resp, err
 }
 
-// handleRetryError determines whether to retry the request based on the status code and error type.
-func (m *RetryMiddleware) handleRetryError(resp *http.Response, err error) error {
-	if resp != nil {
-		switch {
-		case resp.StatusCode >= 500:
-			// Server errors are typically temporary
-			return clientErrors.ErrBadStatus
-		case resp.StatusCode == http.StatusTooManyRequests:
-			// Too Many Requests - should be retried
-			return clientErrors.ErrBadStatus
-		case resp.StatusCode >= 400:
-			// Client errors are typically permanent
-			return backoff.Permanent(clientErrors.ErrBadStatus)
-		}
+// handleRetryError determines whether to retry the request based on the classification of its outcome.
+func (m *RetryMiddleware) handleRetryError(ctx context.Context, resp *http.Response, err error) error {
+	class := middleware.Classify(ctx, resp, err)
+	if class == middleware.ClassSuccess {
+		return nil // Success, stop retrying
+	}
+
+	// Describe bad statuses with the response
+	if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+		err = clientErrors.NewHTTPError(resp)
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: request classified as %s", clientErrors.ErrBadStatus, class)
 	}
 
-	if err != nil {
-		if clientErrors.IsTemporary(err) {
-			return err // This will trigger a retry for temporary errors
-		}
-		return backoff.Permanent(err) // This will stop retries for permanent errors
+	if class.Retryable() {
+		return err // This will trigger a retry
 	}
+	return backoff.Permanent(err) // This will stop retries
+}
+
+// UpdateSettings updates the retry settings at runtime.
+// Requests already being retried keep their previous settings.
+func (m *RetryMiddleware) UpdateSettings(maxAttempts uint64, initialInterval, maxInterval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxAttempts = maxAttempts
+	m.initialInterval = initialInterval
+	m.maxInterval = maxInterval
+}
+
+// SetJitter sets how the delays between retries are randomized.
+func (m *RetryMiddleware) SetJitter(jitter backoff.Jitter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return nil // Success, stop retrying
+	m.jitter = jitter
+}
+
+// Introspect returns the configuration of the retry middleware.
+func (m *RetryMiddleware) Introspect() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return map[string]interface{}{
+		"maxAttempts":     m.maxAttempts,
+		"initialInterval": m.initialInterval.String(),
+		"maxInterval":     m.maxInterval.String(),
+	}
 }
 
 // SetLogger sets the logger for the middleware.
 func (m *RetryMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
 }
+
+// Capabilities declares that the middleware sends the request again on failure, numbering the attempts.
+func (m *RetryMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReplaysRequest, middleware.NumbersAttempts}
+}
@@ -2,14 +2,17 @@ package retry_test
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jaxron/axonet/middleware/retry"
 	"github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -77,6 +80,52 @@ func TestRetryMiddleware(t *testing.T) {
 		assert.Equal(t, int(maxAttempts)+1, attempts) // The middleware makes one more attempt than maxAttempts
 	})
 
+	t.Run("Log attempt fields", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := logger.NewRecordingLogger()
+		middleware := retry.New(2, 10*time.Millisecond, 100*time.Millisecond)
+		middleware.SetLogger(recorder)
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return nil, errors.ErrTemporary
+		}
+
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, errors.ErrTemporary)
+
+		entries := recorder.Find("Retrying request")
+		require.Len(t, entries, 2)
+		for i, entry := range entries {
+			assert.Equal(t, "WARN", entry.Level)
+			assert.Equal(t, i+1, entry.Fields["attempt"])
+			assert.Equal(t, 3, entry.Fields["max_attempts"])
+			assert.Equal(t, "http://example.com", entry.Fields["url"])
+			assert.IsType(t, time.Duration(0), entry.Fields["elapsed"])
+			assert.IsType(t, time.Duration(0), entry.Fields["retry_in"])
+		}
+	})
+
+	t.Run("Double retries with the aggressive flag", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		middleware := retry.New(2, time.Millisecond, 10*time.Millisecond)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			attempts++
+			return nil, errors.ErrTemporary
+		}
+
+		ctx := clientMiddleware.WithFlag(context.Background(), retry.FlagAggressive, true)
+		_, err := middleware.Process(ctx, &http.Client{}, req, handler)
+		require.ErrorIs(t, err, errors.ErrTemporary)
+		assert.Equal(t, 5, attempts)
+	})
+
 	t.Run("No retry on permanent error", func(t *testing.T) {
 		t.Parallel()
 
@@ -97,6 +146,41 @@ func TestRetryMiddleware(t *testing.T) {
 		assert.Equal(t, 1, attempts)
 	})
 
+	t.Run("Describe bad statuses with an HTTPError", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := retry.New(3, 10*time.Millisecond, 100*time.Millisecond)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/items", nil)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Status:     "400 Bad Request",
+				Header:     http.Header{"X-Request-Id": {"abc"}},
+				Body:       io.NopCloser(strings.NewReader("invalid item")),
+				Request:    req,
+			}, nil
+		}
+
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, errors.ErrBadStatus)
+
+		var httpErr *errors.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+		assert.Equal(t, "400 Bad Request", httpErr.Status)
+		assert.Equal(t, "abc", httpErr.Header.Get("X-Request-Id"))
+		assert.Equal(t, "invalid item", httpErr.BodySnippet)
+		assert.Equal(t, "http://example.com/items", httpErr.URL)
+		assert.Equal(t, http.MethodPost, httpErr.Method)
+
+		// The body remains readable
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid item", string(body))
+	})
+
 	t.Run("Respect context cancellation", func(t *testing.T) {
 		t.Parallel()
 
@@ -120,4 +204,80 @@ func TestRetryMiddleware(t *testing.T) {
 		require.ErrorIs(t, err, context.Canceled)
 		assert.Equal(t, 2, attempts)
 	})
+
+	t.Run("Attempt number is exposed via context", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := retry.New(3, 10*time.Millisecond, 100*time.Millisecond)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var seen []uint64
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			seen = append(seen, clientMiddleware.AttemptFromContext(ctx))
+			if len(seen) < 3 {
+				return nil, errors.ErrTemporary
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []uint64{1, 2, 3}, seen)
+	})
+
+	t.Run("Report retries as events", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := retry.New(3, 10*time.Millisecond, 100*time.Millisecond)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		var retried []interface{}
+		ctx := clientMiddleware.WithEventFunc(context.Background(), func(name string, fields ...logger.Field) {
+			assert.Equal(t, clientMiddleware.EventRetry, name)
+			retried = append(retried, fields[0].Value)
+		})
+
+		attempts := 0
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts < 3 {
+				return nil, errors.ErrTemporary
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		_, err := middleware.Process(ctx, &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{1, 2}, retried)
+	})
+
+	t.Run("Read the body again for retried attempts", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := retry.New(3, 10*time.Millisecond, 100*time.Millisecond)
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("payload"))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("payload")), nil
+		}
+
+		var bodies []string
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+			if len(bodies) < 3 {
+				return nil, errors.ErrTemporary
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		resp, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+	})
 }
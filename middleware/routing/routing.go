@@ -0,0 +1,225 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+var (
+	ErrReadBody   = errors.New("failed to read request body")
+	ErrDecodeBody = errors.New("failed to decode request body")
+)
+
+type SkipRoutingKey struct{}
+
+// Route describes where and as whom a matching request is sent.
+type Route struct {
+	// BaseURL replaces the scheme and host of the request, and prefixes its path with the path of the base URL.
+	BaseURL *url.URL
+	// Header holds headers replacing those of the request, such as the credentials of a regional account.
+	Header http.Header
+	// Cookies are added to the request.
+	Cookies []*http.Cookie
+}
+
+// Rule selects a route for requests whose payload matches.
+type Rule struct {
+	// Field is the dot-separated path of a field in the payload, such as "user.region".
+	Field string
+	// Values are the values of the field matching the rule, compared as strings.
+	// If empty, the rule matches any payload holding the field.
+	Values []string
+	// Match, if set, replaces Field and Values to match the decoded payload.
+	Match func(payload interface{}) bool
+	// Route is applied to the matching requests.
+	Route Route
+}
+
+// DecodeFunc decodes a request body with the given content type into a payload for the rules.
+type DecodeFunc func(body []byte, contentType string) (interface{}, error)
+
+// RoutingMiddleware routes requests to a base URL or identity selected from their payload,
+// for APIs sharded by data attributes such as a region. It should be added before middleware
+// depending on the host of the request, such as the circuit breaker.
+type RoutingMiddleware struct {
+	rules  []Rule
+	decode DecodeFunc
+	mu     sync.RWMutex
+	logger logger.Logger
+}
+
+// New creates a new RoutingMiddleware instance. The first matching rule applies to a request,
+// and payloads are decoded from JSON and form bodies.
+func New(rules []Rule) *RoutingMiddleware {
+	return &RoutingMiddleware{
+		rules:  rules,
+		decode: Decode,
+		mu:     sync.RWMutex{},
+		logger: &logger.NoOpLogger{},
+	}
+}
+
+// Process applies the route of the first rule matching the payload before passing the request to the next middleware.
+func (m *RoutingMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if routing is disabled via context
+	if skipRouting, ok := ctx.Value(SkipRoutingKey{}).(bool); ok && skipRouting {
+		return next(ctx, httpClient, req)
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return next(ctx, httpClient, req)
+	}
+
+	m.mu.RLock()
+	rules := m.rules
+	decode := m.decode
+	m.mu.RUnlock()
+
+	// Read the body and restore it for the next middleware
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadBody, err)
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	payload, err := decode(body, req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeBody, err)
+	}
+
+	for _, rule := range rules {
+		if !matches(rule, payload) {
+			continue
+		}
+
+		// Route a copy, so the request is routed the same way again if it is replayed
+		req = req.Clone(ctx)
+		apply(req, rule.Route)
+
+		m.logger.WithFields(
+			logger.String("field", rule.Field),
+			logger.String("url", req.URL.String()),
+		).Debug("Request routed")
+		break
+	}
+
+	return next(ctx, httpClient, req)
+}
+
+// UpdateRules updates the rules at runtime.
+func (m *RoutingMiddleware) UpdateRules(rules []Rule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = rules
+}
+
+// SetDecoder sets the function decoding request bodies into payloads, such as for protobuf bodies.
+func (m *RoutingMiddleware) SetDecoder(decode DecodeFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.decode = decode
+}
+
+// Capabilities declares that the middleware reads the request body to route it, and restores it.
+func (m *RoutingMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *RoutingMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Decode is the default DecodeFunc. It decodes form bodies into a map of their first values,
+// and other bodies as JSON, returning a nil payload for bodies that are not JSON.
+func Decode(body []byte, contentType string) (interface{}, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+
+		payload := make(map[string]interface{}, len(values))
+		for key := range values {
+			payload[key] = values.Get(key)
+		}
+		return payload, nil
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// matches reports whether the payload matches the rule.
+func matches(rule Rule, payload interface{}) bool {
+	if rule.Match != nil {
+		return rule.Match(payload)
+	}
+
+	value, ok := lookup(payload, rule.Field)
+	if !ok {
+		return false
+	}
+	return len(rule.Values) == 0 || slices.Contains(rule.Values, fmt.Sprint(value))
+}
+
+// lookup returns the value at the dot-separated path in the payload.
+func lookup(payload interface{}, path string) (interface{}, bool) {
+	value := payload
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// apply sends the request to the base URL and with the identity of the route, replacing the
+// headers and cookies of the same names.
+func apply(req *http.Request, route Route) {
+	if route.BaseURL != nil {
+		req.URL.Scheme = route.BaseURL.Scheme
+		req.URL.Host = route.BaseURL.Host
+		req.Host = ""
+		if path := strings.TrimSuffix(route.BaseURL.Path, "/"); path != "" {
+			req.URL.Path = path + "/" + strings.TrimPrefix(req.URL.Path, "/")
+			req.URL.RawPath = ""
+		}
+	}
+
+	for key, values := range route.Header {
+		req.Header[http.CanonicalHeaderKey(key)] = slices.Clone(values)
+	}
+	middleware.SetCookies(req, route.Cookies...)
+}
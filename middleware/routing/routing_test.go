@@ -0,0 +1,204 @@
+package routing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/middleware/routing"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	euURL, _ := url.Parse("https://eu.api.example.com/v2")
+	usURL, _ := url.Parse("https://us.api.example.com")
+	rules := []routing.Rule{
+		{
+			Field:  "user.region",
+			Values: []string{"eu", "uk"},
+			Route: routing.Route{
+				BaseURL: euURL,
+				Header:  http.Header{"Authorization": {"Bearer eu-token"}},
+				Cookies: nil,
+			},
+		},
+		{
+			Field:  "user.region",
+			Values: nil,
+			Route:  routing.Route{BaseURL: usURL, Header: nil, Cookies: nil},
+		},
+	}
+
+	// seen returns a handler recording the request it receives, along with its body
+	seen := func(got **http.Request, body *string) func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+		return func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			b, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			*got, *body = req, string(b)
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+	}
+
+	t.Run("Route by a field of the JSON payload", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New(rules)
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		payload := `{"user":{"region":"eu"}}`
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/orders?id=1", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer default")
+
+		var (
+			got  *http.Request
+			body string
+		)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, seen(&got, &body))
+		require.NoError(t, err)
+
+		assert.Equal(t, "https://eu.api.example.com/v2/orders?id=1", got.URL.String())
+		assert.Equal(t, "Bearer eu-token", got.Header.Get("Authorization"))
+		assert.Equal(t, payload, body)
+	})
+
+	t.Run("Fall through to the next rule", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New(rules)
+
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/orders", strings.NewReader(`{"user":{"region":"us"}}`))
+
+		var (
+			got  *http.Request
+			body string
+		)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, seen(&got, &body))
+		require.NoError(t, err)
+		assert.Equal(t, "https://us.api.example.com/orders", got.URL.String())
+	})
+
+	t.Run("Route by a form field", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New(rules[:1])
+		middleware.UpdateRules([]routing.Rule{{
+			Field:  "region",
+			Values: []string{"eu"},
+			Match:  nil,
+			Route:  rules[0].Route,
+		}})
+
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/orders", strings.NewReader("region=eu&id=1"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var (
+			got  *http.Request
+			body string
+		)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, seen(&got, &body))
+		require.NoError(t, err)
+		assert.Equal(t, "eu.api.example.com", got.URL.Host)
+		assert.Equal(t, "region=eu&id=1", body)
+	})
+
+	t.Run("Leave unmatched requests unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New(rules)
+
+		for _, payload := range []string{`{"id":1}`, "not json"} {
+			req := httptest.NewRequest(http.MethodPost, "https://api.example.com/orders", strings.NewReader(payload))
+
+			var (
+				got  *http.Request
+				body string
+			)
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, seen(&got, &body))
+			require.NoError(t, err)
+			assert.Equal(t, "https://api.example.com/orders", got.URL.String())
+			assert.Equal(t, payload, body)
+		}
+	})
+
+	t.Run("Match with a custom function", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New([]routing.Rule{{
+			Field:  "",
+			Values: nil,
+			Match: func(payload interface{}) bool {
+				items, ok := payload.([]interface{})
+				return ok && len(items) > 2
+			},
+			Route: routing.Route{BaseURL: usURL, Header: nil, Cookies: nil},
+		}})
+
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/batch", strings.NewReader(`[1,2,3]`))
+
+		var (
+			got  *http.Request
+			body string
+		)
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, seen(&got, &body))
+		require.NoError(t, err)
+		assert.Equal(t, "us.api.example.com", got.URL.Host)
+	})
+
+	t.Run("Middleware disabled via context", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New(rules)
+
+		ctx := context.WithValue(context.Background(), routing.SkipRoutingKey{}, true)
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/orders", strings.NewReader(`{"user":{"region":"eu"}}`))
+
+		var (
+			got  *http.Request
+			body string
+		)
+		_, err := middleware.Process(ctx, &http.Client{}, req, seen(&got, &body))
+		require.NoError(t, err)
+		assert.Equal(t, "api.example.com", got.URL.Host)
+	})
+	t.Run("Route replayed requests the same way", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := routing.New([]routing.Rule{{
+			Field:  "user.region",
+			Values: nil,
+			Route: routing.Route{
+				BaseURL: euURL,
+				Header:  nil,
+				Cookies: []*http.Cookie{{Name: "region", Value: "eu"}},
+			},
+		}})
+
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/orders", strings.NewReader(`{"user":{"region":"eu"}}`))
+		req.AddCookie(&http.Cookie{Name: "region", Value: "default"})
+		req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+		var (
+			got  *http.Request
+			body string
+		)
+		for range 2 {
+			_, err := middleware.Process(context.Background(), &http.Client{}, req, seen(&got, &body))
+			require.NoError(t, err)
+			req.Body, err = req.GetBody()
+			require.NoError(t, err)
+
+			assert.Equal(t, "https://eu.api.example.com/v2/orders", got.URL.String())
+			assert.Equal(t, "session=abc; region=eu", got.Header.Get("Cookie"))
+		}
+		assert.Equal(t, "https://api.example.com/orders", req.URL.String())
+	})
+}
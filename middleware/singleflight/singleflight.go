@@ -1,3 +1,11 @@
+// Package singleflight provides a middleware that deduplicates concurrent identical requests.
+//
+// When composed with the retry middleware, the order matters. Placing singleflight before
+// retry shares the whole retry loop between callers. Placing it after retry deduplicates
+// each attempt individually; in that case the request key is salted with the attempt number,
+// so a caller retrying after a failure never joins a flight started for an earlier attempt.
+// The chain reports singleflight placed after a middleware replaying requests without
+// numbering its attempts, such as the challenge middleware, since the salting relies on them.
 package singleflight
 
 import (
@@ -22,26 +30,35 @@ var (
 	ErrHashHeader    = errors.New("failed to hash header")
 	ErrReadBody      = errors.New("failed to read request body")
 	ErrHashBody      = errors.New("failed to hash body")
+	ErrHashAttempt   = errors.New("failed to hash attempt")
 )
 
 // SingleFlightMiddleware implements the singleflight pattern to deduplicate concurrent identical requests.
 type SingleFlightMiddleware struct {
-	sfGroup *singleflight.Group
-	logger  logger.Logger
+	sfGroup    *singleflight.Group
+	normalizer *middleware.URLNormalizer
+	logger     logger.Logger
 }
 
 // New creates a new SingleFlightMiddleware instance.
 func New() *SingleFlightMiddleware {
 	return &SingleFlightMiddleware{
-		sfGroup: &singleflight.Group{},
-		logger:  &logger.NoOpLogger{},
+		sfGroup:    &singleflight.Group{},
+		normalizer: middleware.NewURLNormalizer(),
+		logger:     &logger.NoOpLogger{},
 	}
 }
 
+// SetNormalizer sets the normalizer applied to request URLs before deriving their key, so
+// requests to semantically identical URLs share a flight. Passing nil uses URLs as they are.
+func (m *SingleFlightMiddleware) SetNormalizer(normalizer *middleware.URLNormalizer) {
+	m.normalizer = normalizer
+}
+
 // Process applies the singleflight pattern before passing the request to the next middleware.
 func (m *SingleFlightMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
 	// Generate a unique key for the request
-	key, err := m.generateRequestKey(req)
+	key, err := m.generateRequestKey(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrKeyGeneration, err)
 	}
@@ -61,8 +78,9 @@ func (m *SingleFlightMiddleware) Process(ctx context.Context, httpClient *http.C
 	return resp, err
 }
 
-// generateRequestKey generates a unique key for the request based on the method, URL, headers, and body.
-func (m *SingleFlightMiddleware) generateRequestKey(req *http.Request) (string, error) {
+// generateRequestKey generates a unique key for the request based on the method, URL, headers, body,
+// and retry attempt if the request is being retried.
+func (m *SingleFlightMiddleware) generateRequestKey(ctx context.Context, req *http.Request) (string, error) {
 	h := xxhash.New()
 
 	// Helper function to write to hash and handle errors
@@ -74,31 +92,53 @@ func (m *SingleFlightMiddleware) generateRequestKey(req *http.Request) (string,
 	}
 
 	// Hash method and URL
-	if err := writeToHash([]byte(req.Method+req.URL.String()), ErrHashMethod); err != nil {
-		return "", fmt.Errorf("%w: %w", ErrKeyGeneration, err)
+	requestURL := req.URL.String()
+	if m.normalizer != nil {
+		requestURL = m.normalizer.Normalize(req.URL)
+	}
+	if err := writeToHash([]byte(req.Method+requestURL), ErrHashMethod); err != nil {
+		return "", err
 	}
 
 	// Hash headers (excluding Authorization)
 	for key, values := range req.Header {
 		if key != "Authorization" {
 			if err := writeToHash([]byte(key+fmt.Sprint(values)), ErrHashHeader); err != nil {
-				return "", fmt.Errorf("%w: %w", ErrKeyGeneration, err)
+				return "", err
 			}
 		}
 	}
 
-	// Hash body if it exists
-	if req.Body != nil {
+	// Hash body if it exists, streaming a copy of it if the body can be read again
+	switch {
+	case req.GetBody != nil:
+		body, err := req.GetBody()
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrReadBody, err)
+		}
+		_, err = io.Copy(h, body)
+		body.Close()
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrHashBody, err)
+		}
+	case req.Body != nil:
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
-			return "", fmt.Errorf("%w: %w", ErrKeyGeneration, err)
+			return "", fmt.Errorf("%w: %w", ErrReadBody, err)
 		}
 		if err := writeToHash(body, ErrHashBody); err != nil {
-			return "", fmt.Errorf("%w: %w", ErrKeyGeneration, err)
+			return "", err
 		}
 		req.Body = io.NopCloser(bytes.NewReader(body))
 	}
 
+	// Salt with the attempt number so retried attempts don't join flights of earlier attempts
+	if attempt := middleware.AttemptFromContext(ctx); attempt > 0 {
+		if err := writeToHash([]byte(strconv.FormatUint(attempt, 10)), ErrHashAttempt); err != nil {
+			return "", err
+		}
+	}
+
 	return strconv.FormatUint(h.Sum64(), 16), nil
 }
 
@@ -106,3 +146,9 @@ func (m *SingleFlightMiddleware) generateRequestKey(req *http.Request) (string,
 func (m *SingleFlightMiddleware) SetLogger(l logger.Logger) {
 	m.logger = l
 }
+
+// Capabilities declares that the middleware reads the request body to derive its key, restores it,
+// and shares responses between concurrent identical requests.
+func (m *SingleFlightMiddleware) Capabilities() []middleware.Capability {
+	return []middleware.Capability{middleware.ReadsBody, middleware.RewindsBody, middleware.SharesResponses}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/jaxron/axonet/middleware/singleflight"
 	"github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -95,6 +96,44 @@ func TestSingleFlightMiddleware(t *testing.T) {
 		assert.Equal(t, len(urls), requestCount, "Expected each different request to be processed")
 	})
 
+	t.Run("Deduplicate requests to equivalent URLs", func(t *testing.T) {
+		t.Parallel()
+
+		run := func(normalizer *clientMiddleware.URLNormalizer) int {
+			middleware := singleflight.New()
+			middleware.SetLogger(logger.NewBasicLogger())
+			middleware.SetNormalizer(normalizer)
+
+			requestCount := 0
+			var mu sync.Mutex
+
+			handler := func(_ context.Context, _ *http.Client, _ *http.Request) (*http.Response, error) { //nolint:unparam
+				mu.Lock()
+				requestCount++
+				mu.Unlock()
+				time.Sleep(100 * time.Millisecond) // Simulate work
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			}
+
+			var wg sync.WaitGroup
+			urls := []string{"http://example.com/?a=1&b=2", "http://EXAMPLE.com:80/?b=2&a=1", "http://example.com/?a=1&b=2&utm_source=mail"}
+			for _, url := range urls {
+				wg.Add(1)
+				go func(url string) {
+					defer wg.Done()
+					req := httptest.NewRequest(http.MethodGet, url, nil)
+					_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+					assert.NoError(t, err)
+				}(url)
+			}
+			wg.Wait()
+			return requestCount
+		}
+
+		assert.Equal(t, 1, run(clientMiddleware.NewURLNormalizer()), "Expected equivalent URLs to share a flight")
+		assert.Equal(t, 3, run(nil), "Expected URLs to be used as they are without a normalizer")
+	})
+
 	t.Run("Requests with different bodies are not deduplicated", func(t *testing.T) {
 		t.Parallel()
 
@@ -150,6 +189,26 @@ func TestSingleFlightMiddleware(t *testing.T) {
 		assert.ErrorIs(t, err, errors.ErrNetwork)
 	})
 
+	t.Run("Key generation errors are wrapped once", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := singleflight.New()
+
+		handler := func(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
+			t.Fatal("Request should not be sent")
+			return nil, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
+		req.GetBody = func() (io.ReadCloser, error) { return nil, io.ErrUnexpectedEOF }
+
+		_, err := middleware.Process(context.Background(), &http.Client{}, req, handler)
+		require.ErrorIs(t, err, singleflight.ErrKeyGeneration)
+		require.ErrorIs(t, err, singleflight.ErrReadBody)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, 1, strings.Count(err.Error(), singleflight.ErrKeyGeneration.Error()))
+	})
+
 	t.Run("Request body can be read after key generation", func(t *testing.T) {
 		t.Parallel()
 
@@ -171,4 +230,43 @@ func TestSingleFlightMiddleware(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
+
+	t.Run("Requests from different retry attempts are not deduplicated", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := singleflight.New()
+		middleware.SetLogger(logger.NewBasicLogger())
+
+		requestCount := 0
+		var mu sync.Mutex
+
+		handler := func(_ context.Context, _ *http.Client, _ *http.Request) (*http.Response, error) { //nolint:unparam
+			mu.Lock()
+			requestCount++
+			mu.Unlock()
+			time.Sleep(100 * time.Millisecond) // Simulate work
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		makeRequest := func(attempt uint64) (*http.Response, error) {
+			ctx := clientMiddleware.WithAttempt(context.Background(), attempt)
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			return middleware.Process(ctx, &http.Client{}, req, handler)
+		}
+
+		var wg sync.WaitGroup
+		attempts := []uint64{1, 2, 2}
+		for _, attempt := range attempts {
+			wg.Add(1)
+			go func(attempt uint64) {
+				defer wg.Done()
+				resp, err := makeRequest(attempt)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}(attempt)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 2, requestCount, "Expected one flight per attempt")
+	})
 }
@@ -0,0 +1,188 @@
+package timing
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+)
+
+const (
+	// DefaultHistogramWindow is the window over which the latencies of an endpoint are kept by default.
+	DefaultHistogramWindow = time.Minute
+	// DefaultMaxEndpoints is the number of endpoints with their own histogram by default.
+	DefaultMaxEndpoints = 1000
+)
+
+// OverflowRoute is the route the latencies of new endpoints are recorded under once the
+// middleware tracks the maximum number of endpoints.
+const OverflowRoute = "*"
+
+type RouteKey struct{}
+
+// WithRoute returns a request option recording the latency of the request under the route
+// template, such as "/users/{id}", instead of the template derived from its path.
+func WithRoute(route string) client.RequestOption {
+	return client.WithContextValue(RouteKey{}, route)
+}
+
+// Endpoint identifies the requests sharing a latency histogram.
+type Endpoint struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Route  string `json:"route"`
+}
+
+// EndpointStats reports the latencies of the requests to an endpoint over the last one to two
+// histogram windows, from the start of the request until its body was read or closed.
+type EndpointStats struct {
+	Endpoint
+
+	// Count is the number of requests.
+	Count uint64 `json:"count"`
+	// Mean is the average latency.
+	Mean time.Duration `json:"mean"`
+	// P50, P95 and P99 are the latencies below which 50, 95 and 99 percent of the requests fall,
+	// within 3% of the exact latency.
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	// Max is the highest latency.
+	Max time.Duration `json:"max"`
+}
+
+// endpointStats holds the latency histograms of an endpoint for the current and previous windows.
+type endpointStats struct {
+	current     *histogram
+	previous    *histogram
+	windowStart time.Time
+}
+
+// newEndpointStats creates a new endpointStats instance with a window starting now.
+func newEndpointStats(now time.Time) *endpointStats {
+	return &endpointStats{
+		current:     newHistogram(),
+		previous:    newHistogram(),
+		windowStart: now,
+	}
+}
+
+// record adds a latency to the histogram of the current window.
+func (s *endpointStats) record(d time.Duration, window time.Duration, now time.Time) {
+	s.rotate(window, now)
+	s.current.record(d)
+}
+
+// rotate starts a new window once the current one is over.
+func (s *endpointStats) rotate(window time.Duration, now time.Time) {
+	elapsed := now.Sub(s.windowStart)
+	switch {
+	case elapsed >= 2*window:
+		s.previous, s.current = newHistogram(), newHistogram()
+		s.windowStart = now
+	case elapsed >= window:
+		s.previous, s.current = s.current, newHistogram()
+		s.windowStart = s.windowStart.Add(window)
+	}
+}
+
+// merged returns the latencies of the current and previous windows.
+func (s *endpointStats) merged(window time.Duration, now time.Time) *histogram {
+	s.rotate(window, now)
+
+	h := newHistogram()
+	h.merge(s.previous)
+	h.merge(s.current)
+	return h
+}
+
+// snapshot returns the statistics of the endpoint.
+func (s *endpointStats) snapshot(endpoint Endpoint, window time.Duration, now time.Time) EndpointStats {
+	h := s.merged(window, now)
+
+	var mean time.Duration
+	if h.count > 0 {
+		mean = h.sum / time.Duration(h.count) //nolint:gosec // count fits
+	}
+	return EndpointStats{
+		Endpoint: endpoint,
+		Count:    h.count,
+		Mean:     mean,
+		P50:      h.quantile(0.50),
+		P95:      h.quantile(0.95),
+		P99:      h.quantile(0.99),
+		Max:      h.max,
+	}
+}
+
+// endpointOf returns the endpoint of the request, with the route set by WithRoute or derived
+// from its path.
+func endpointOf(ctx context.Context, req *http.Request) Endpoint {
+	route, ok := ctx.Value(RouteKey{}).(string)
+	if !ok || route == "" {
+		route = RouteTemplate(req.URL.Path)
+	}
+	return Endpoint{Method: req.Method, Host: req.URL.Host, Route: route}
+}
+
+// RouteTemplate returns the route template of a path, with the segments that look like
+// identifiers, such as numbers, UUIDs and long hexadecimal or mixed alphanumeric tokens, replaced
+// by "{id}", so requests to different resources share the histogram of their endpoint.
+func RouteTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if isIdentifier(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	route := strings.Join(segments, "/")
+	if route == "" {
+		return "/"
+	}
+	return route
+}
+
+// isIdentifier reports whether a path segment looks like an identifier rather than a fixed name.
+func isIdentifier(segment string) bool {
+	if segment == "" {
+		return false
+	}
+
+	var digits, letters, others int
+	for _, r := range segment {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			letters++
+		default:
+			others++
+		}
+	}
+
+	switch {
+	case digits == len(segment):
+		return true // numeric ID
+	case len(segment) == 36 && strings.Count(segment, "-") == 4 && others == 4:
+		return true // UUID
+	case len(segment) >= 16 && digits > 0 && others == 0:
+		return true // hash or opaque token
+	}
+	return false
+}
+
+// sortStats sorts the statistics by host, route and method.
+func sortStats(stats []EndpointStats) {
+	slices.SortFunc(stats, func(a, b EndpointStats) int {
+		return cmp.Or(
+			cmp.Compare(a.Host, b.Host),
+			cmp.Compare(a.Route, b.Route),
+			cmp.Compare(a.Method, b.Method),
+		)
+	})
+}
@@ -0,0 +1,119 @@
+package timing_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/timing"
+	"github.com/jaxron/axonet/pkg/axonettest"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointStats(t *testing.T) {
+	t.Parallel()
+
+	// newClient returns a client timing requests answered by a mock, slowly for /slow
+	newClient := func(middleware *timing.TimingMiddleware) *client.Client {
+		mock := axonettest.NewMock()
+		mock.OnGET("/slow").Delay(20 * time.Millisecond)
+		mock.On("", "/*")
+		return client.NewClient(
+			client.WithBaseURL("https://api.example.com"),
+			client.WithMiddleware(middleware),
+			mock.Option(),
+		)
+	}
+
+	do := func(t *testing.T, c *client.Client, method, url string, opts ...client.RequestOption) {
+		t.Helper()
+
+		resp, err := c.NewRequest().Method(method).URL(url).Do(context.Background(), opts...)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	t.Run("Track latency percentiles per endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		m := timing.New()
+		c := newClient(m)
+		for i := range 20 {
+			do(t, c, http.MethodGet, "/users/"+strconv.Itoa(i))
+		}
+		for range 3 {
+			do(t, c, http.MethodGet, "/slow")
+		}
+		do(t, c, http.MethodPost, "/search", timing.WithRoute("/search/{query}"))
+
+		stats := m.Stats()
+		require.Len(t, stats, 3)
+		assert.Equal(t, timing.Endpoint{Method: http.MethodPost, Host: "api.example.com", Route: "/search/{query}"}, stats[0].Endpoint)
+		assert.Equal(t, timing.Endpoint{Method: http.MethodGet, Host: "api.example.com", Route: "/slow"}, stats[1].Endpoint)
+		assert.Equal(t, timing.Endpoint{Method: http.MethodGet, Host: "api.example.com", Route: "/users/{id}"}, stats[2].Endpoint)
+
+		slow := stats[1]
+		assert.Equal(t, uint64(3), slow.Count)
+		assert.GreaterOrEqual(t, slow.P50, 20*time.Millisecond)
+		assert.LessOrEqual(t, slow.P50, slow.P99)
+		assert.LessOrEqual(t, slow.P99, slow.Max)
+		assert.Equal(t, uint64(20), stats[2].Count)
+		assert.Less(t, stats[2].P99, 20*time.Millisecond)
+
+		p50, ok := m.Quantile(slow.Endpoint, 0.5)
+		require.True(t, ok)
+		assert.Equal(t, slow.P50, p50)
+		_, ok = m.Quantile(timing.Endpoint{Method: http.MethodGet, Host: "api.example.com", Route: "/missing"}, 0.5)
+		assert.False(t, ok)
+
+		assert.Equal(t, stats, m.Introspect()["endpoints"])
+	})
+
+	t.Run("Limit the tracked endpoints", func(t *testing.T) {
+		t.Parallel()
+
+		m := timing.New()
+		m.SetMaxEndpoints(1)
+		c := newClient(m)
+		do(t, c, http.MethodGet, "/users")
+		do(t, c, http.MethodGet, "/teams")
+		do(t, c, http.MethodGet, "/projects")
+
+		stats := m.Stats()
+		require.Len(t, stats, 2)
+		assert.Equal(t, timing.OverflowRoute, stats[0].Route)
+		assert.Equal(t, uint64(2), stats[0].Count)
+		assert.Equal(t, "/users", stats[1].Route)
+	})
+
+	t.Run("Forget latencies after two windows", func(t *testing.T) {
+		t.Parallel()
+
+		m := timing.New()
+		m.SetHistogramWindow(20 * time.Millisecond)
+		c := newClient(m)
+		do(t, c, http.MethodGet, "/users")
+		require.Len(t, m.Stats(), 1)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, m.Stats())
+	})
+
+	t.Run("Derive route templates from paths", func(t *testing.T) {
+		t.Parallel()
+
+		for path, route := range map[string]string{
+			"":                "/",
+			"/users/42/posts": "/users/{id}/posts",
+			"/v2/orders/3f2b1c4e-8a9d-4e6f-b1c2-0d9e8f7a6b5c":   "/v2/orders/{id}",
+			"/commits/9fceb02d0ae598e95dc970b74767f19372d61af8": "/commits/{id}",
+			"/files/report-final.pdf":                           "/files/report-final.pdf",
+		} {
+			assert.Equal(t, route, timing.RouteTemplate(path), path)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package timing
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// subBucketBits sets the precision of histograms: each power of two range of microseconds is
+// split into 2^(subBucketBits-1) linear buckets, bounding the relative error of quantiles to 1/32.
+const subBucketBits = 6
+
+// subBuckets is the number of buckets recording exact values, before the log-linear ones.
+const subBuckets = 1 << subBucketBits
+
+// histogram is an HDR-style histogram of latencies with log-linear buckets, so it keeps a fixed
+// relative precision over any range with a few kilobytes of counts.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// newHistogram creates a new empty histogram.
+func newHistogram() *histogram {
+	return &histogram{
+		counts: nil,
+		count:  0,
+		sum:    0,
+		max:    0,
+	}
+}
+
+// record adds a latency to the histogram.
+func (h *histogram) record(d time.Duration) {
+	d = max(d, 0)
+	i := bucketIndex(uint64(d.Microseconds()))
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i+1-len(h.counts))...)
+	}
+
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+// merge adds the latencies of another histogram to the histogram.
+func (h *histogram) merge(other *histogram) {
+	if len(other.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, len(other.counts)-len(h.counts))...)
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	h.count += other.count
+	h.sum += other.sum
+	h.max = max(h.max, other.max)
+}
+
+// quantile returns the latency below which the fraction q of the latencies fall, as the upper
+// bound of its bucket, or zero if the histogram is empty.
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	rank = min(max(rank, 1), h.count)
+
+	var seen uint64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			return min(time.Duration(bucketUpperBound(i))*time.Microsecond, h.max) //nolint:gosec // bounds fit
+		}
+	}
+	return h.max
+}
+
+// bucketIndex returns the index of the bucket of a value. Values below subBuckets have their own
+// bucket, and each following power of two range is split into subBuckets/2 buckets.
+func bucketIndex(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits
+	// top is in [subBuckets/2, subBuckets)
+	top := v >> shift
+	return subBuckets + (shift-1)*(subBuckets/2) + int(top-subBuckets/2) //nolint:gosec // top < subBuckets
+}
+
+// bucketUpperBound returns the highest value of the bucket at the index.
+func bucketUpperBound(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i) //nolint:gosec // i is never negative
+	}
+	shift := (i-subBuckets)/(subBuckets/2) + 1
+	top := uint64((i-subBuckets)%(subBuckets/2) + subBuckets/2) //nolint:gosec // i is never negative
+	return (top+1)<<shift - 1
+}
@@ -0,0 +1,339 @@
+package timing
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+type SkipTimingKey struct{}
+
+// timingsKey is the context key used to store the timings of a request.
+type timingsKey struct{}
+
+// Timings is the latency breakdown of a request. Phases that did not happen, such as DNS and
+// connecting on a reused connection, are zero.
+type Timings struct {
+	// DNS is the time spent resolving the host.
+	DNS time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLS is the time spent in the TLS handshake.
+	TLS time.Duration
+	// TTFB is the time from the start of the request to the first byte of the response.
+	TTFB time.Duration
+	// Total is the time from the start of the request until its body was read to the end or
+	// closed. It is zero until then.
+	Total time.Duration
+	// Reused reports whether the request was sent on a reused connection.
+	Reused bool
+}
+
+// tracker records the timings of a request from the trace callbacks.
+type tracker struct {
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      Timings
+	done         bool
+	mu           sync.Mutex
+}
+
+// FromResponse returns the timings of the request of the response, if it went through the
+// middleware. Total is only set once the body was read to the end or closed.
+func FromResponse(resp *http.Response) (Timings, bool) {
+	if resp == nil || resp.Request == nil {
+		return Timings{}, false
+	}
+	t, ok := resp.Request.Context().Value(timingsKey{}).(*tracker)
+	if !ok {
+		return Timings{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timings, true
+}
+
+// TimingMiddleware traces requests with net/http/httptrace, attaching a breakdown of their latency
+// to the response and logging it, for latency debugging. It keeps a latency histogram per endpoint,
+// queryable for percentiles with Stats and Quantile.
+type TimingMiddleware struct {
+	observer     func(req *http.Request, timings Timings)
+	requests     int64
+	total        time.Duration
+	ttfb         time.Duration
+	endpoints    map[Endpoint]*endpointStats
+	window       time.Duration
+	maxEndpoints int
+	mu           sync.Mutex
+	logger       logger.Logger
+}
+
+// New creates a new TimingMiddleware instance.
+func New() *TimingMiddleware {
+	return &TimingMiddleware{
+		observer:     nil,
+		requests:     0,
+		total:        0,
+		ttfb:         0,
+		endpoints:    make(map[Endpoint]*endpointStats),
+		window:       DefaultHistogramWindow,
+		maxEndpoints: DefaultMaxEndpoints,
+		mu:           sync.Mutex{},
+		logger:       &logger.NoOpLogger{},
+	}
+}
+
+// SetHistogramWindow sets the window over which the latencies of an endpoint are kept. Percentiles
+// cover the requests of the current and previous windows, so they follow changes in latency.
+func (m *TimingMiddleware) SetHistogramWindow(window time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.window = window
+}
+
+// SetMaxEndpoints sets the number of endpoints with their own histogram. The latencies of further
+// endpoints are recorded under OverflowRoute for their method and host.
+func (m *TimingMiddleware) SetMaxEndpoints(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxEndpoints = n
+}
+
+// OnTiming sets a function called with the timings of each request once its body was read to the
+// end or closed, such as to export them as metrics.
+func (m *TimingMiddleware) OnTiming(fn func(req *http.Request, timings Timings)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.observer = fn
+}
+
+// Process traces the request and records its timings once the response body is done.
+func (m *TimingMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	// Check if timing is disabled via context
+	if skip, ok := ctx.Value(SkipTimingKey{}).(bool); ok && skip {
+		return next(ctx, httpClient, req)
+	}
+
+	t := &tracker{
+		start:        time.Now(),
+		dnsStart:     time.Time{},
+		connectStart: time.Time{},
+		tlsStart:     time.Time{},
+		timings:      Timings{},
+		done:         false,
+		mu:           sync.Mutex{},
+	}
+	endpoint := endpointOf(ctx, req)
+	ctx = context.WithValue(ctx, timingsKey{}, t)
+	ctx = httptrace.WithClientTrace(ctx, t.trace())
+
+	resp, err := next(ctx, httpClient, req)
+	if err != nil || resp == nil {
+		m.finish(req, endpoint, t)
+		return resp, err
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		m.finish(req, endpoint, t)
+		return resp, nil
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, finish: func() { m.finish(req, endpoint, t) }}
+	return resp, nil
+}
+
+// trace returns the trace callbacks recording the phases of the request.
+func (t *tracker) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{ //nolint:exhaustruct
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.DNS = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.Connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TLS = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.Reused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TTFB = time.Since(t.start)
+		},
+	}
+}
+
+// finish records the total time of the request in the histogram of its endpoint, logs its timings
+// and reports them to the observer.
+func (m *TimingMiddleware) finish(req *http.Request, endpoint Endpoint, t *tracker) {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return
+	}
+	t.done = true
+	t.timings.Total = time.Since(t.start)
+	timings := t.timings
+	t.mu.Unlock()
+
+	m.mu.Lock()
+	m.requests++
+	m.total += timings.Total
+	m.ttfb += timings.TTFB
+	now := time.Now()
+	m.endpointStats(endpoint, now).record(timings.Total, m.window, now)
+	observer := m.observer
+	m.mu.Unlock()
+
+	m.logger.WithFields(
+		logger.String("method", req.Method),
+		logger.String("url", req.URL.String()),
+		logger.Duration("dns", timings.DNS),
+		logger.Duration("connect", timings.Connect),
+		logger.Duration("tls", timings.TLS),
+		logger.Duration("ttfb", timings.TTFB),
+		logger.Duration("total", timings.Total),
+		logger.Bool("reused", timings.Reused),
+	).Debug("Request timings")
+
+	if observer != nil {
+		observer(req, timings)
+	}
+}
+
+// endpointStats returns the statistics of the endpoint, creating them on first use or returning
+// those of its overflow endpoint once the maximum number of endpoints is tracked.
+// It must be called with the lock held.
+func (m *TimingMiddleware) endpointStats(endpoint Endpoint, now time.Time) *endpointStats {
+	if s, ok := m.endpoints[endpoint]; ok {
+		return s
+	}
+	if len(m.endpoints) >= m.maxEndpoints {
+		endpoint.Route = OverflowRoute
+		if s, ok := m.endpoints[endpoint]; ok {
+			return s
+		}
+	}
+
+	s := newEndpointStats(now)
+	m.endpoints[endpoint] = s
+	return s
+}
+
+// Stats returns the latency statistics of every endpoint with requests in the current or previous
+// histogram window, sorted by host, route and method.
+func (m *TimingMiddleware) Stats() []EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]EndpointStats, 0, len(m.endpoints))
+	for endpoint, s := range m.endpoints {
+		if snapshot := s.snapshot(endpoint, m.window, now); snapshot.Count > 0 {
+			stats = append(stats, snapshot)
+		}
+	}
+	sortStats(stats)
+	return stats
+}
+
+// Quantile returns the latency below which the fraction q, between 0 and 1, of the requests to the
+// endpoint fall over the current and previous histogram windows, such as 0.99 for the p99 latency.
+// It reports false if the endpoint had no requests in these windows.
+func (m *TimingMiddleware) Quantile(endpoint Endpoint, q float64) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.endpoints[endpoint]
+	if !ok {
+		return 0, false
+	}
+	h := s.merged(m.window, time.Now())
+	return h.quantile(q), h.count > 0
+}
+
+// Introspect returns the number of timed requests, their average TTFB and total time, and the
+// latency statistics of every endpoint.
+func (m *TimingMiddleware) Introspect() map[string]interface{} {
+	stats := m.Stats()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var avgTTFB, avgTotal time.Duration
+	if m.requests > 0 {
+		avgTTFB = m.ttfb / time.Duration(m.requests)
+		avgTotal = m.total / time.Duration(m.requests)
+	}
+	return map[string]interface{}{
+		"requests":  m.requests,
+		"avgTTFB":   avgTTFB.String(),
+		"avgTotal":  avgTotal.String(),
+		"endpoints": stats,
+	}
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *TimingMiddleware) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// timedBody is a response body finishing the timings of its request once read to the end or closed.
+// Finishing the timings more than once has no effect.
+type timedBody struct {
+	io.ReadCloser
+	finish func()
+}
+
+// Read reads the body, finishing the timings at the end.
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+// Close closes the body, finishing the timings if the body was not read to the end.
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
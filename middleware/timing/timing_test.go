@@ -0,0 +1,111 @@
+package timing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/middleware/timing"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("body"))
+	}))
+	t.Cleanup(server.Close)
+
+	// newClient returns a client trusting the server and timing its requests
+	newClient := func(middleware *timing.TimingMiddleware) *client.Client {
+		middleware.SetLogger(logger.NewBasicLogger())
+		return client.NewClient(
+			client.WithMiddleware(middleware),
+			client.WithTLSConfig(server.Client().Transport.(*http.Transport).TLSClientConfig), //nolint:forcetypeassert
+		)
+	}
+
+	t.Run("Attach the timings to the response", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(timing.New())
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+
+		timings, ok := timing.FromResponse(resp)
+		require.True(t, ok)
+		assert.Positive(t, timings.Connect)
+		assert.Positive(t, timings.TLS)
+		assert.GreaterOrEqual(t, timings.TTFB, 20*time.Millisecond)
+		assert.Zero(t, timings.Total)
+		assert.False(t, timings.Reused)
+
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		timings, _ = timing.FromResponse(resp)
+		assert.GreaterOrEqual(t, timings.Total, timings.TTFB)
+
+		// The second request reuses the connection
+		resp, err = c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		timings, _ = timing.FromResponse(resp)
+		assert.True(t, timings.Reused)
+		assert.Zero(t, timings.TLS)
+	})
+
+	t.Run("Report the timings once the body is done", func(t *testing.T) {
+		t.Parallel()
+
+		middleware := timing.New()
+		var (
+			mu       sync.Mutex
+			reported []timing.Timings
+		)
+		middleware.OnTiming(func(req *http.Request, timings timing.Timings) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, timings)
+		})
+		c := newClient(middleware)
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, reported, 1)
+		assert.Positive(t, reported[0].Total)
+		assert.Equal(t, int64(1), middleware.Introspect()["requests"])
+	})
+
+	t.Run("Skip timing via context", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(timing.New())
+
+		ctx := context.WithValue(context.Background(), timing.SkipTimingKey{}, true)
+		resp, err := c.NewRequest().URL(server.URL).Do(ctx)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		_, ok := timing.FromResponse(resp)
+		assert.False(t, ok)
+	})
+}
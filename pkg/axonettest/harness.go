@@ -0,0 +1,291 @@
+package axonettest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// ErrScripted is the error of the failing calls to next scripted by CheckContract.
+var ErrScripted = errors.New("axonettest: scripted failure")
+
+// Harness runs a middleware against a scripted next handler, for testing middleware without a
+// client or a server. Each call to next replies with the next step of the script, and the last
+// step is repeated once the script is exhausted. Without steps, next replies 200 with an empty body.
+//
+// Like a transport, next reads and closes the body of the requests it receives, so a middleware
+// calling next again with the same request must restore the body, such as with Request.GetBody.
+type Harness struct {
+	middleware middleware.Middleware
+	httpClient *http.Client
+	steps      []ReplyFunc
+	calls      []Call
+	mu         sync.Mutex
+}
+
+// NewHarness creates a new Harness running the middleware, which logs to a no-op logger.
+func NewHarness(m middleware.Middleware) *Harness {
+	m.SetLogger(&logger.NoOpLogger{})
+
+	return &Harness{
+		middleware: m,
+		httpClient: &http.Client{}, //nolint:exhaustruct
+		steps:      nil,
+		calls:      nil,
+		mu:         sync.Mutex{},
+	}
+}
+
+// Then adds a step replying with the response built by fn.
+func (h *Harness) Then(fn ReplyFunc) *Harness {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.steps = append(h.steps, fn)
+	return h
+}
+
+// ThenReply adds a step replying with the status and body.
+func (h *Harness) ThenReply(status int, body string) *Harness {
+	return h.Then(func(req *http.Request) (*http.Response, error) {
+		return NewResponse(req, status, []byte(body), nil), nil
+	})
+}
+
+// ThenReplyJSON adds a step replying with the status and the JSON encoding of v.
+func (h *Harness) ThenReplyJSON(status int, v interface{}) *Harness {
+	return h.Then(replyJSON(status, v))
+}
+
+// ThenError adds a step failing with err, as a transport error would.
+func (h *Harness) ThenError(err error) *Harness {
+	return h.Then(func(_ *http.Request) (*http.Response, error) {
+		return nil, err
+	})
+}
+
+// Run processes the request with the middleware.
+func (h *Harness) Run(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return h.middleware.Process(ctx, h.httpClient, req, h.next)
+}
+
+// NextCalls returns the requests the middleware passed to next, in the order they were passed.
+func (h *Harness) NextCalls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	calls := make([]Call, len(h.calls))
+	copy(calls, h.calls)
+	return calls
+}
+
+// next records the request, consuming its body, and replies with the step of the call.
+func (h *Harness) next(ctx context.Context, _ *http.Client, req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	h.mu.Lock()
+	h.calls = append(h.calls, Call{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	var step ReplyFunc
+	if len(h.steps) > 0 {
+		step = h.steps[min(len(h.calls), len(h.steps))-1]
+	}
+	h.mu.Unlock()
+
+	if step == nil {
+		return NewResponse(req, http.StatusOK, nil, nil), nil
+	}
+	return step(req.WithContext(ctx))
+}
+
+// contextKey is the context key CheckContract checks the propagation of.
+type contextKey struct{}
+
+// CheckContract runs the middleware returned by newMiddleware against scripted chains and reports
+// the properties of the middleware contract it breaks to t, returning whether it holds them all:
+//   - next receives a context derived from the context of the request;
+//   - next is not called with a live context once the context of the request is canceled;
+//   - every call to next can read the whole body of the request, even after a failed call;
+//   - a response of next is returned with its status and body intact;
+//   - a failure of next is returned as an error, unless a later call to next succeeds.
+//
+// Each property is checked with a new middleware, so state such as an open circuit or a cached
+// response does not carry over between checks.
+func CheckContract(t TestingT, newMiddleware func() middleware.Middleware) bool {
+	t.Helper()
+
+	ok := true
+	for _, check := range []func(TestingT, middleware.Middleware) bool{
+		checkContextPropagation,
+		checkCancellation,
+		checkBodyRereadability,
+		checkResponse,
+		checkError,
+	} {
+		if !check(t, newMiddleware()) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// checkContextPropagation checks that next receives a context derived from the context of the request.
+func checkContextPropagation(t TestingT, m middleware.Middleware) bool {
+	t.Helper()
+
+	var dropped atomic.Bool
+	h := NewHarness(m).Then(func(req *http.Request) (*http.Response, error) {
+		if req.Context().Value(contextKey{}) == nil {
+			dropped.Store(true)
+		}
+		return NewResponse(req, http.StatusOK, nil, nil), nil
+	})
+
+	ctx := context.WithValue(context.Background(), contextKey{}, true)
+	resp, err := h.Run(ctx, newContractRequest(http.MethodGet, ""))
+	closeResponse(resp)
+	if err != nil {
+		t.Errorf("axonettest: %T failed a request next replied to: %v", m, err)
+		return false
+	}
+
+	if dropped.Load() {
+		t.Errorf("axonettest: %T does not pass the context of the request to next", m)
+		return false
+	}
+	return true
+}
+
+// checkCancellation checks that next is not called with a live context once the context of the
+// request is canceled.
+func checkCancellation(t TestingT, m middleware.Middleware) bool {
+	t.Helper()
+
+	var live atomic.Bool
+	h := NewHarness(m).Then(func(req *http.Request) (*http.Response, error) {
+		if req.Context().Err() == nil {
+			live.Store(true)
+		}
+		return NewResponse(req, http.StatusOK, nil, nil), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp, _ := h.Run(ctx, newContractRequest(http.MethodGet, ""))
+	closeResponse(resp)
+
+	if live.Load() {
+		t.Errorf("axonettest: %T calls next with a live context once the request is canceled", m)
+		return false
+	}
+	return true
+}
+
+// checkBodyRereadability checks that every call to next can read the whole body of the request,
+// even after a failed call.
+func checkBodyRereadability(t TestingT, m middleware.Middleware) bool {
+	t.Helper()
+
+	const body = `{"contract": "body"}`
+	h := NewHarness(m).ThenError(ErrScripted).ThenReply(http.StatusOK, "")
+
+	resp, _ := h.Run(context.Background(), newContractRequest(http.MethodPost, body))
+	closeResponse(resp)
+
+	for i, call := range h.NextCalls() {
+		if string(call.Body) != body {
+			t.Errorf("axonettest: %T passes a request to next whose body is %q instead of %q on call %d",
+				m, call.Body, body, i+1)
+			return false
+		}
+	}
+	return true
+}
+
+// checkResponse checks that a response of next is returned with its status and body intact.
+func checkResponse(t TestingT, m middleware.Middleware) bool {
+	t.Helper()
+
+	const body = "contract response"
+	h := NewHarness(m).Then(func(req *http.Request) (*http.Response, error) {
+		return NewResponse(req, http.StatusAccepted, []byte(body), http.Header{"Content-Type": {"text/plain"}}), nil
+	})
+
+	resp, err := h.Run(context.Background(), newContractRequest(http.MethodGet, ""))
+	if err != nil {
+		t.Errorf("axonettest: %T failed a request next replied to: %v", m, err)
+		return false
+	}
+	if resp == nil {
+		t.Errorf("axonettest: %T returned neither a response nor an error", m)
+		return false
+	}
+	defer resp.Body.Close()
+
+	got, err := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode != http.StatusAccepted:
+		t.Errorf("axonettest: %T returned the status %d instead of %d", m, resp.StatusCode, http.StatusAccepted)
+		return false
+	case err != nil || string(got) != body:
+		t.Errorf("axonettest: %T returned the body %q (%v) instead of %q", m, got, err, body)
+		return false
+	}
+	return true
+}
+
+// checkError checks that a failure of next is returned as an error, unless a later call to next succeeds.
+func checkError(t TestingT, m middleware.Middleware) bool {
+	t.Helper()
+
+	h := NewHarness(m).ThenError(ErrScripted)
+
+	resp, err := h.Run(context.Background(), newContractRequest(http.MethodGet, ""))
+	closeResponse(resp)
+	if err == nil && len(h.NextCalls()) > 0 {
+		t.Errorf("axonettest: %T returned no error although every call to next failed", m)
+		return false
+	}
+	return true
+}
+
+// newContractRequest creates a request to the contract checks, with a body restorable with
+// Request.GetBody if not empty, as set by http.NewRequest.
+func newContractRequest(method, body string) *http.Request {
+	var reader io.Reader = http.NoBody
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, "https://axonettest.invalid/contract", reader) //nolint:noctx
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+// closeResponse closes the body of the response, if any.
+func closeResponse(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+}
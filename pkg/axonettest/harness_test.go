@@ -0,0 +1,101 @@
+package axonettest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/axonettest"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RetryMiddleware retries failed requests once, restoring their body.
+type RetryMiddleware struct{}
+
+func (m *RetryMiddleware) Process(ctx context.Context, c *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp, err := next(ctx, c, req)
+	if err == nil {
+		return resp, nil
+	}
+
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return next(ctx, c, retry)
+}
+
+func (m *RetryMiddleware) SetLogger(_ logger.Logger) {}
+
+// CarelessMiddleware retries failed requests once without restoring their body, on a new context,
+// and swallows the errors.
+type CarelessMiddleware struct{}
+
+func (m *CarelessMiddleware) Process(_ context.Context, c *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	ctx := context.Background()
+	resp, err := next(ctx, c, req)
+	if err != nil {
+		resp, _ = next(ctx, c, req)
+	}
+	return resp, nil
+}
+
+func (m *CarelessMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestHarness(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Run a middleware against a script", func(t *testing.T) {
+		t.Parallel()
+
+		h := axonettest.NewHarness(&RetryMiddleware{}).
+			ThenError(axonettest.ErrScripted).
+			ThenReplyJSON(http.StatusOK, User{ID: 1, Name: "Ada"})
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://api.example.com/users/1", nil)
+		require.NoError(t, err)
+
+		resp, err := h.Run(context.Background(), req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, h.NextCalls(), 2)
+
+		// The last step is repeated once the script is exhausted
+		resp, err = h.Run(context.Background(), req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Len(t, h.NextCalls(), 3)
+	})
+
+	t.Run("Hold the middleware contract", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, axonettest.CheckContract(t, func() middleware.Middleware {
+			return &RetryMiddleware{}
+		}))
+	})
+
+	t.Run("Report broken contract properties", func(t *testing.T) {
+		t.Parallel()
+
+		rt := &RecordingT{}
+		assert.False(t, axonettest.CheckContract(rt, func() middleware.Middleware {
+			return &CarelessMiddleware{}
+		}))
+		require.Len(t, rt.errors, 4)
+		assert.Contains(t, rt.errors[0], "does not pass the context of the request to next")
+		assert.Contains(t, rt.errors[1], "calls next with a live context")
+		assert.Contains(t, rt.errors[2], "passes a request to next whose body is")
+		assert.Contains(t, rt.errors[3], "returned no error although every call to next failed")
+	})
+}
@@ -0,0 +1,297 @@
+// Package axonettest provides a programmable mock for unit testing code built on axonet without
+// running a server. A Mock replies to the requests it matches with stubbed responses, records
+// the requests it receives and asserts on them:
+//
+//	mock := axonettest.NewMock()
+//	mock.OnGET("/users/1").ReplyJSON(http.StatusOK, User{ID: 1})
+//
+//	c := client.NewClient(client.WithBaseURL("https://api.example.com"), mock.Option())
+//	// ... exercise the code under test with c
+//
+//	mock.AssertCalled(t, http.MethodGet, "/users/1")
+package axonettest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// ErrNoStub is returned for requests no stub of the Mock replies to.
+var ErrNoStub = errors.New("axonettest: no stub matches the request")
+
+// TestingT is the subset of testing.TB used by the assertions of a Mock.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Call is a request received by a Mock.
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// Mock is a middleware and http.RoundTripper replying to requests with stubbed responses instead
+// of sending them. Stubs are matched in the order they were added, so a stub limited with
+// Stub.Times can be followed by another for the same request.
+type Mock struct {
+	stubs  []*Stub
+	calls  []Call
+	mu     sync.Mutex
+	logger logger.Logger
+}
+
+// NewMock creates a new Mock without stubs.
+func NewMock() *Mock {
+	return &Mock{
+		stubs:  nil,
+		calls:  nil,
+		mu:     sync.Mutex{},
+		logger: &logger.NoOpLogger{},
+	}
+}
+
+// Option returns the option adding the mock to a Client as its innermost middleware, so the
+// requests go through the other middleware of the Client before reaching the mock.
+func (m *Mock) Option() client.Option {
+	return client.WithMiddlewarePriority(math.MinInt, m)
+}
+
+// On adds a stub for the requests with the method, or any method if empty, matching the pattern.
+// The pattern is a path such as "/users/1", optionally with a scheme and host, and query parameters
+// the requests must have. A pattern ending with "*" matches the paths starting with it.
+// The stub replies 200 with an empty body until another reply is set.
+func (m *Mock) On(method, pattern string) *Stub {
+	stub := newStub(method, pattern)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stubs = append(m.stubs, stub)
+	return stub
+}
+
+// OnGET adds a stub for the GET requests matching the pattern.
+func (m *Mock) OnGET(pattern string) *Stub {
+	return m.On(http.MethodGet, pattern)
+}
+
+// OnPOST adds a stub for the POST requests matching the pattern.
+func (m *Mock) OnPOST(pattern string) *Stub {
+	return m.On(http.MethodPost, pattern)
+}
+
+// OnPUT adds a stub for the PUT requests matching the pattern.
+func (m *Mock) OnPUT(pattern string) *Stub {
+	return m.On(http.MethodPut, pattern)
+}
+
+// OnPATCH adds a stub for the PATCH requests matching the pattern.
+func (m *Mock) OnPATCH(pattern string) *Stub {
+	return m.On(http.MethodPatch, pattern)
+}
+
+// OnDELETE adds a stub for the DELETE requests matching the pattern.
+func (m *Mock) OnDELETE(pattern string) *Stub {
+	return m.On(http.MethodDelete, pattern)
+}
+
+// Process replies to the request with the first stub matching it, without calling next.
+func (m *Mock) Process(ctx context.Context, _ *http.Client, req *http.Request, _ middleware.NextFunc) (*http.Response, error) {
+	return m.RoundTrip(req.WithContext(ctx))
+}
+
+// RoundTrip records the request and replies with the first stub matching it, so the mock can
+// also serve as the transport of an http.Client.
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	call, err := m.record(req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	stubs := m.stubs
+	m.mu.Unlock()
+
+	for _, stub := range stubs {
+		reply, delay, ok := stub.take(req)
+		if !ok {
+			continue
+		}
+
+		m.logger.WithFields(
+			logger.String("method", call.Method),
+			logger.String("url", call.URL.String()),
+			logger.String("stub", stub.String()),
+		).Debug("Replying with stub")
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		return reply(req)
+	}
+
+	m.logger.WithFields(
+		logger.String("method", call.Method),
+		logger.String("url", call.URL.String()),
+	).Warn("No stub matches the request")
+	return nil, fmt.Errorf("%w: %s %s", ErrNoStub, call.Method, call.URL)
+}
+
+// SetLogger sets the logger for the middleware.
+func (m *Mock) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Introspect returns the number of stubs and of requests received.
+func (m *Mock) Introspect() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return map[string]interface{}{
+		"stubs": len(m.stubs),
+		"calls": len(m.calls),
+	}
+}
+
+// Calls returns the requests received, in the order they were received.
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	calls := make([]Call, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// CallsTo returns the requests received with the method, or any method if empty, matching
+// the pattern, in the order they were received.
+func (m *Mock) CallsTo(method, pattern string) []Call {
+	stub := newStub(method, pattern)
+
+	var calls []Call
+	for _, call := range m.Calls() {
+		req := &http.Request{Method: call.Method, URL: call.URL, Header: call.Header} //nolint:exhaustruct
+		if stub.matches(req) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset removes the stubs and the recorded requests.
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stubs = nil
+	m.calls = nil
+}
+
+// AssertCalled asserts that a request with the method matching the pattern was received.
+func (m *Mock) AssertCalled(t TestingT, method, pattern string) bool {
+	t.Helper()
+
+	if len(m.CallsTo(method, pattern)) == 0 {
+		t.Errorf("axonettest: expected a call to %s %s, got none", method, pattern)
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled asserts that no request with the method matching the pattern was received.
+func (m *Mock) AssertNotCalled(t TestingT, method, pattern string) bool {
+	t.Helper()
+
+	if n := len(m.CallsTo(method, pattern)); n > 0 {
+		t.Errorf("axonettest: expected no call to %s %s, got %d", method, pattern, n)
+		return false
+	}
+	return true
+}
+
+// AssertNumberOfCalls asserts that n requests with the method matching the pattern were received.
+func (m *Mock) AssertNumberOfCalls(t TestingT, method, pattern string, n int) bool {
+	t.Helper()
+
+	if got := len(m.CallsTo(method, pattern)); got != n {
+		t.Errorf("axonettest: expected %d calls to %s %s, got %d", n, method, pattern, got)
+		return false
+	}
+	return true
+}
+
+// AssertExpectations asserts that every stub was used, as many times as its limit if it has one.
+func (m *Mock) AssertExpectations(t TestingT) bool {
+	t.Helper()
+
+	m.mu.Lock()
+	stubs := m.stubs
+	m.mu.Unlock()
+
+	ok := true
+	for _, stub := range stubs {
+		if !stub.exhausted() {
+			t.Errorf("axonettest: stub %s was called %d times", stub, stub.Calls())
+			ok = false
+		}
+	}
+	return ok
+}
+
+// record adds the request to the received requests, reading its body and replacing it with
+// a copy so stubs can read it again.
+func (m *Mock) record(req *http.Request) (Call, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return Call{}, err //nolint:exhaustruct
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	call := Call{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
+	return call, nil
+}
+
+// sleep waits for the delay, or until the context is done.
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
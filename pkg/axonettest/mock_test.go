@@ -0,0 +1,147 @@
+package axonettest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/axonettest"
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrReset = errors.New("connection reset")
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RecordingT records the failures of assertions.
+type RecordingT struct {
+	errors []string
+}
+
+func (t *RecordingT) Helper() {}
+
+func (t *RecordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMock(t *testing.T) {
+	t.Parallel()
+
+	newClient := func(mock *axonettest.Mock) *client.Client {
+		return client.NewClient(client.WithBaseURL("https://api.example.com"), mock.Option())
+	}
+
+	t.Run("Reply with stubs and assert calls", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.OnGET("/users/1").ReplyJSON(http.StatusOK, User{ID: 1, Name: "Ada"})
+		mock.OnPOST("/users").MatchHeader("X-Token", "secret").Reply(http.StatusCreated, "")
+		c := newClient(mock)
+
+		var user User
+		resp, err := c.NewRequest().URL("/users/1").Result(&user).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Equal(t, User{ID: 1, Name: "Ada"}, user)
+
+		resp, err = c.NewRequest().Method(http.MethodPost).URL("/users").Header("X-Token", "secret").
+			MarshalBody(User{ID: 2, Name: "Grace"}).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		// Requests without a matching stub fail
+		_, err = c.NewRequest().Method(http.MethodDelete).URL("/users/1").Do(context.Background())
+		require.ErrorIs(t, err, axonettest.ErrNoStub)
+
+		calls := mock.CallsTo(http.MethodPost, "/users")
+		require.Len(t, calls, 1)
+		assert.JSONEq(t, `{"id": 2, "name": "Grace"}`, string(calls[0].Body))
+		assert.Len(t, mock.Calls(), 3)
+		assert.True(t, mock.AssertCalled(t, http.MethodGet, "/users/1"))
+		assert.True(t, mock.AssertNumberOfCalls(t, "", "/users*", 3))
+		assert.True(t, mock.AssertExpectations(t))
+
+		rt := &RecordingT{}
+		assert.False(t, mock.AssertNotCalled(rt, http.MethodGet, "/users/1"))
+		assert.False(t, mock.AssertCalled(rt, http.MethodGet, "/users/2"))
+		assert.Len(t, rt.errors, 2)
+	})
+
+	t.Run("Reply in order with limited stubs", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.OnGET("/status").Once().ReplyError(ErrReset)
+		mock.OnGET("/status").Times(2).Reply(http.StatusServiceUnavailable, "")
+		mock.OnGET("/status?verbose=1").Reply(http.StatusOK, "verbose")
+		c := newClient(mock)
+
+		_, err := c.NewRequest().URL("/status").Do(context.Background())
+		require.ErrorIs(t, err, ErrReset)
+		for range 2 {
+			resp, err := c.NewRequest().URL("/status").Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		}
+
+		// The stub with a query parameter only matches requests with it
+		_, err = c.NewRequest().URL("/status").Do(context.Background())
+		require.ErrorIs(t, err, axonettest.ErrNoStub)
+		resp, err := c.NewRequest().URL("/status?verbose=1&lang=en").Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, mock.AssertExpectations(t))
+	})
+
+	t.Run("Delay replies until the request is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.OnGET("/slow").Delay(20 * time.Millisecond)
+		mock.OnGET("/slower").Delay(time.Minute)
+		c := newClient(mock)
+
+		start := time.Now()
+		_, err := c.NewRequest().URL("/slow").Do(context.Background())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = c.NewRequest().URL("/slower").Do(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		rt := &RecordingT{}
+		assert.True(t, mock.AssertExpectations(rt))
+	})
+
+	t.Run("Serve as the transport of an HTTP client", func(t *testing.T) {
+		t.Parallel()
+
+		mock := axonettest.NewMock()
+		mock.On("", "https://api.example.com/health").ReplyHeader("X-Version", "1").Reply(http.StatusNoContent, "")
+
+		httpClient := &http.Client{Transport: mock} //nolint:exhaustruct
+		resp, err := httpClient.Get("https://api.example.com/health")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		_, err = httpClient.Get("https://other.example.com/health") //nolint:bodyclose
+		require.ErrorIs(t, err, axonettest.ErrNoStub)
+
+		rt := &RecordingT{}
+		mock.OnGET("/unused")
+		assert.False(t, mock.AssertExpectations(rt))
+		assert.Len(t, rt.errors, 1)
+	})
+}
@@ -0,0 +1,272 @@
+package axonettest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplyFunc builds the response of a stub to a request.
+type ReplyFunc func(req *http.Request) (*http.Response, error)
+
+// Stub is a programmed reply of a Mock to the requests it matches.
+type Stub struct {
+	method  string
+	pattern *url.URL
+	prefix  bool
+	headers http.Header
+	match   func(req *http.Request) bool
+	reply   ReplyFunc
+	header  http.Header
+	delay   time.Duration
+	times   int
+	calls   int
+	mu      sync.Mutex
+}
+
+// newStub creates a new Stub matching the method and pattern, replying 200 with an empty body.
+func newStub(method, pattern string) *Stub {
+	prefix := strings.HasSuffix(pattern, "*")
+	parsed, err := url.Parse(strings.TrimSuffix(pattern, "*"))
+	if err != nil {
+		parsed = &url.URL{Path: pattern} //nolint:exhaustruct
+	}
+
+	s := &Stub{
+		method:  method,
+		pattern: parsed,
+		prefix:  prefix,
+		headers: make(http.Header),
+		match:   nil,
+		reply:   nil,
+		header:  make(http.Header),
+		delay:   0,
+		times:   0,
+		calls:   0,
+		mu:      sync.Mutex{},
+	}
+	return s.Reply(http.StatusOK, "")
+}
+
+// Reply makes the stub reply with the status and body.
+func (s *Stub) Reply(status int, body string) *Stub {
+	return s.ReplyFunc(func(req *http.Request) (*http.Response, error) {
+		return NewResponse(req, status, []byte(body), nil), nil
+	})
+}
+
+// ReplyJSON makes the stub reply with the status and the JSON encoding of v.
+func (s *Stub) ReplyJSON(status int, v interface{}) *Stub {
+	return s.ReplyFunc(replyJSON(status, v))
+}
+
+// ReplyError makes the stub fail the request with err, as a transport error would.
+func (s *Stub) ReplyError(err error) *Stub {
+	return s.ReplyFunc(func(_ *http.Request) (*http.Response, error) {
+		return nil, err
+	})
+}
+
+// ReplyFunc makes the stub reply with the response built by fn.
+func (s *Stub) ReplyFunc(fn ReplyFunc) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reply = fn
+	return s
+}
+
+// ReplyHeader adds a header to the responses of the stub, whichever reply is set.
+func (s *Stub) ReplyHeader(key, value string) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.header.Add(key, value)
+	return s
+}
+
+// MatchHeader restricts the stub to the requests with the header value.
+func (s *Stub) MatchHeader(key, value string) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.headers.Add(key, value)
+	return s
+}
+
+// Match restricts the stub to the requests fn reports true for, such as those with a given body.
+func (s *Stub) Match(fn func(req *http.Request) bool) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.match = fn
+	return s
+}
+
+// Delay makes the stub wait before replying, or until the request is canceled, to simulate a
+// slow server.
+func (s *Stub) Delay(d time.Duration) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delay = d
+	return s
+}
+
+// Times limits the stub to n replies, after which the requests it matched go to the next
+// matching stub. A stub replies without limit by default.
+func (s *Stub) Times(n int) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.times = n
+	return s
+}
+
+// Once limits the stub to a single reply.
+func (s *Stub) Once() *Stub {
+	return s.Times(1)
+}
+
+// Calls returns the number of requests the stub replied to.
+func (s *Stub) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+// String returns the method and pattern of the stub.
+func (s *Stub) String() string {
+	pattern := s.pattern.String()
+	if s.prefix {
+		pattern += "*"
+	}
+	return s.method + " " + pattern
+}
+
+// take reserves a reply of the stub to the request, returning its reply and delay, or false
+// if the stub does not match the request or has no replies left.
+func (s *Stub) take(req *http.Request) (ReplyFunc, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.matches(req) || (s.times > 0 && s.calls >= s.times) {
+		return nil, 0, false
+	}
+	s.calls++
+
+	reply, header := s.reply, s.header.Clone()
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := reply(req)
+		if resp != nil && len(header) > 0 {
+			if resp.Header == nil {
+				resp.Header = make(http.Header)
+			}
+			for key, values := range header {
+				resp.Header[key] = append(resp.Header[key], values...)
+			}
+		}
+		return resp, err
+	}, s.delay, true
+}
+
+// exhausted reports whether the stub was called as many times as expected, or at least once
+// if it replies without limit.
+func (s *Stub) exhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.times > 0 {
+		return s.calls >= s.times
+	}
+	return s.calls > 0
+}
+
+// matches reports whether the request matches the method, pattern and conditions of the stub.
+func (s *Stub) matches(req *http.Request) bool {
+	if s.method != "" && s.method != req.Method {
+		return false
+	}
+	if !matchURL(s.pattern, s.prefix, req.URL) {
+		return false
+	}
+	for key, values := range s.headers {
+		for _, value := range values {
+			if !slices.Contains(req.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+	return s.match == nil || s.match(req)
+}
+
+// matchURL reports whether the URL matches the pattern. The scheme and host of the pattern are
+// only compared if set, and its query parameters must be present in the URL with the same values.
+func matchURL(pattern *url.URL, prefix bool, u *url.URL) bool {
+	if pattern.Scheme != "" && pattern.Scheme != u.Scheme {
+		return false
+	}
+	if pattern.Host != "" && pattern.Host != u.Host {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if prefix {
+		if !strings.HasPrefix(path, pattern.EscapedPath()) {
+			return false
+		}
+	} else if pattern.EscapedPath() != path && pattern.Path != "" {
+		return false
+	}
+
+	query := u.Query()
+	for key, values := range pattern.Query() {
+		for _, value := range values {
+			if !slices.Contains(query[key], value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// replyJSON returns a reply with the status and the JSON encoding of v.
+func replyJSON(status int, v interface{}) ReplyFunc {
+	body, err := json.Marshal(v)
+	return func(req *http.Request) (*http.Response, error) {
+		if err != nil {
+			return nil, fmt.Errorf("axonettest: encode reply: %w", err)
+		}
+		return NewResponse(req, status, body, http.Header{"Content-Type": {"application/json"}}), nil
+	}
+}
+
+// NewResponse creates a response to the request with the status, body and headers, for replies
+// built with Stub.ReplyFunc.
+func NewResponse(req *http.Request, status int, body []byte, header http.Header) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{ //nolint:exhaustruct
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,183 @@
+// Package backoff provides exponential backoff with jitter, shared by the features that
+// wait between attempts or grow a delay after consecutive failures, such as the retry middleware,
+// the rate limit retries of pagination, the bypass of an unavailable Redis backend and the
+// maintenance windows learned by the maintenance middleware.
+package backoff
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Unlimited is the value of Policy.MaxRetries that retries until the elapsed time or the context ends.
+const Unlimited uint64 = math.MaxUint64
+
+// maxDelay is the longest delay, the largest float64 below math.MaxInt64, so that delays growing
+// past it, even to +Inf, convert to a valid time.Duration.
+const maxDelay float64 = math.MaxInt64 - 1023
+
+// Jitter selects how delays are randomized, so that clients failing together do not retry together.
+type Jitter int
+
+const (
+	// JitterProportional randomizes the delay by up to RandomizationFactor of its value in either direction.
+	JitterProportional Jitter = iota
+	// JitterFull picks the delay uniformly between zero and its value.
+	JitterFull
+	// JitterEqual keeps half of the delay and picks the other half uniformly.
+	JitterEqual
+	// JitterNone does not randomize the delay.
+	JitterNone
+)
+
+// Policy describes how long to wait between attempts.
+type Policy struct {
+	// InitialInterval is the delay before the first retry, before jitter.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay before jitter.
+	MaxInterval time.Duration
+	// Multiplier is the factor the delay grows by after every retry.
+	Multiplier float64
+	// Jitter selects how the delay is randomized.
+	Jitter Jitter
+	// RandomizationFactor is the range of JitterProportional, between 0 and 1.
+	RandomizationFactor float64
+	// MaxRetries is the number of retries after the first attempt, or Unlimited.
+	MaxRetries uint64
+	// MaxElapsedTime stops retrying once it has passed since the first attempt. Zero means no limit.
+	MaxElapsedTime time.Duration
+}
+
+// Exponential returns a policy growing the delay by 1.5 after every retry, randomized by up to 50%,
+// and giving up after 15 minutes.
+func Exponential(initialInterval, maxInterval time.Duration) Policy {
+	return Policy{
+		InitialInterval:     initialInterval,
+		MaxInterval:         maxInterval,
+		Multiplier:          1.5,
+		Jitter:              JitterProportional,
+		RandomizationFactor: 0.5,
+		MaxRetries:          Unlimited,
+		MaxElapsedTime:      15 * time.Minute,
+	}
+}
+
+// Doubling returns a policy doubling the delay after every retry without jitter, up to maxInterval,
+// for delays that must be predictable, such as how long an unavailable dependency is avoided.
+func Doubling(initialInterval, maxInterval time.Duration) Policy {
+	return Policy{
+		InitialInterval:     initialInterval,
+		MaxInterval:         maxInterval,
+		Multiplier:          2,
+		Jitter:              JitterNone,
+		RandomizationFactor: 0,
+		MaxRetries:          Unlimited,
+		MaxElapsedTime:      0,
+	}
+}
+
+// WithMaxRetries returns a copy of the policy limited to the number of retries.
+func (p Policy) WithMaxRetries(maxRetries uint64) Policy {
+	p.MaxRetries = maxRetries
+	return p
+}
+
+// WithJitter returns a copy of the policy using the jitter.
+func (p Policy) WithJitter(jitter Jitter) Policy {
+	p.Jitter = jitter
+	return p
+}
+
+// Delay returns the delay before the given retry, numbered starting at 1. Without MaxInterval,
+// the delay is capped at the longest time.Duration.
+func (p Policy) Delay(retry uint64) time.Duration {
+	delay := float64(p.InitialInterval)
+	if retry > 1 && delay > 0 {
+		delay *= math.Pow(p.Multiplier, float64(retry-1))
+	}
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	delay = min(delay, maxDelay)
+
+	switch p.Jitter {
+	case JitterProportional:
+		delta := p.RandomizationFactor * delay
+		delay = delay - delta + rand.Float64()*(2*delta) //nolint:gosec
+	case JitterFull:
+		delay = rand.Float64() * delay //nolint:gosec
+	case JitterEqual:
+		delay = delay/2 + rand.Float64()*(delay/2) //nolint:gosec
+	case JitterNone:
+	}
+	return time.Duration(min(delay, maxDelay))
+}
+
+// PermanentError wraps an error that must not be retried.
+type PermanentError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps the error so that Retry returns it without retrying.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// NotifyFunc is called with the error of a failed attempt and the delay before the next one.
+type NotifyFunc func(err error, delay time.Duration)
+
+// Retry calls fn until it succeeds, returns a permanent error, the policy gives up or the context
+// ends, waiting between attempts as the policy describes. It returns the last error of fn, unwrapped
+// from PermanentError, or the error of the context. The notify function may be nil.
+func Retry(ctx context.Context, policy Policy, fn func() error, notify NotifyFunc) error {
+	start := time.Now()
+
+	for retry := uint64(1); ; retry++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		var permanent *PermanentError
+		if errors.As(err, &permanent) {
+			return permanent.Err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Give up once the retries or the time are exhausted
+		delay := policy.Delay(retry)
+		if policy.MaxRetries != Unlimited && retry > policy.MaxRetries {
+			return err
+		}
+		if policy.MaxElapsedTime > 0 && time.Since(start)+delay > policy.MaxElapsedTime {
+			return err
+		}
+
+		if notify != nil {
+			notify(err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
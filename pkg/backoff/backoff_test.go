@@ -0,0 +1,121 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/backoff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrFailed = errors.New("simulated failure")
+
+func TestDelay(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Grow exponentially up to the maximum", func(t *testing.T) {
+		t.Parallel()
+
+		policy := backoff.Exponential(100*time.Millisecond, time.Second).WithJitter(backoff.JitterNone)
+		assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+		assert.Equal(t, 150*time.Millisecond, policy.Delay(2))
+		assert.Equal(t, 225*time.Millisecond, policy.Delay(3))
+		assert.Equal(t, time.Second, policy.Delay(20))
+	})
+
+	t.Run("Double without jitter", func(t *testing.T) {
+		t.Parallel()
+
+		policy := backoff.Doubling(time.Second, 5*time.Second)
+		assert.Equal(t, time.Second, policy.Delay(1))
+		assert.Equal(t, 2*time.Second, policy.Delay(2))
+		assert.Equal(t, 4*time.Second, policy.Delay(3))
+		assert.Equal(t, 5*time.Second, policy.Delay(4))
+	})
+
+	t.Run("Cap delays without a maximum", func(t *testing.T) {
+		t.Parallel()
+
+		for _, jitter := range []backoff.Jitter{backoff.JitterNone, backoff.JitterProportional, backoff.JitterFull, backoff.JitterEqual} {
+			policy := backoff.Doubling(time.Second, 0).WithJitter(jitter)
+			assert.Positive(t, policy.Delay(100))
+			assert.Positive(t, policy.Delay(10000))
+		}
+		assert.Equal(t, time.Duration(0), backoff.Doubling(0, 0).Delay(10000))
+	})
+
+	t.Run("Randomize within the jitter range", func(t *testing.T) {
+		t.Parallel()
+
+		policy := backoff.Exponential(time.Second, time.Second)
+		for range 100 {
+			assert.InDelta(t, time.Second, policy.Delay(1), float64(500*time.Millisecond))
+			assert.LessOrEqual(t, policy.WithJitter(backoff.JitterFull).Delay(1), time.Second)
+			assert.GreaterOrEqual(t, policy.WithJitter(backoff.JitterEqual).Delay(1), 500*time.Millisecond)
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	policy := backoff.Exponential(time.Millisecond, 10*time.Millisecond)
+
+	t.Run("Stop after the maximum number of retries", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		var delays []time.Duration
+		err := backoff.Retry(context.Background(), policy.WithMaxRetries(2), func() error {
+			attempts++
+			return ErrFailed
+		}, func(err error, delay time.Duration) {
+			delays = append(delays, delay)
+		})
+		require.ErrorIs(t, err, ErrFailed)
+		assert.Equal(t, 3, attempts)
+		assert.Len(t, delays, 2)
+	})
+
+	t.Run("Stop on success", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := backoff.Retry(context.Background(), policy, func() error {
+			attempts++
+			if attempts < 3 {
+				return ErrFailed
+			}
+			return nil
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Do not retry permanent errors", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := backoff.Retry(context.Background(), policy, func() error {
+			attempts++
+			return backoff.Permanent(ErrFailed)
+		}, nil)
+		require.ErrorIs(t, err, ErrFailed)
+		assert.Equal(t, ErrFailed, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Stop when the context ends", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err := backoff.Retry(ctx, backoff.Exponential(time.Hour, time.Hour), func() error {
+			cancel()
+			return ErrFailed
+		}, nil)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
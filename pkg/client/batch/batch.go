@@ -0,0 +1,174 @@
+// Package batch decodes responses carrying the results of several operations, such as WebDAV
+// 207 Multi-Status responses and JSON batch responses like Microsoft Graph $batch, into typed
+// per-item results and errors.
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+)
+
+var ErrInvalidBatch = errors.New("invalid batch response")
+
+// bodySnippetSize is the number of bytes of the body of a failed item kept in its error.
+const bodySnippetSize = 512
+
+// Item is the result of one operation of a batch response.
+type Item[T any] struct {
+	// ID identifies the operation, such as the id of a $batch request or the href of a WebDAV resource.
+	ID string
+	// Status is the status code of the operation.
+	Status int
+	// Header holds the headers of the operation, if the format has any.
+	Header http.Header
+	// Value is the decoded body of a successful operation.
+	Value T
+	// Err is an *errors.HTTPError for operations with a status other than 2xx, or the error
+	// decoding the body of a successful operation.
+	Err error
+}
+
+// Format names the fields of a JSON batch response holding an array of per-item results.
+type Format struct {
+	// Items is the field holding the array of items, or empty if the response is the array itself.
+	Items string
+	// ID is the field identifying the operation of an item.
+	ID string
+	// Status is the field holding the status code of an item.
+	Status string
+	// Header is the field holding the headers of an item, as an object of strings or string arrays.
+	Header string
+	// Body is the field holding the body of an item.
+	Body string
+}
+
+// Graph is the format of Microsoft Graph $batch responses.
+var Graph = Format{
+	Items:  "responses",
+	ID:     "id",
+	Status: "status",
+	Header: "headers",
+	Body:   "body",
+}
+
+// DecodeJSON decodes a JSON batch response in the format into per-item results, unmarshaling
+// the body of each successful item into a value of type T. It fails with ErrInvalidBatch only if
+// the response itself is malformed; failed items carry their own error.
+func DecodeJSON[T any](data []byte, format Format) ([]Item[T], error) {
+	var rawItems []map[string]json.RawMessage
+	if format.Items == "" {
+		if err := json.Unmarshal(data, &rawItems); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+	} else {
+		var root map[string]json.RawMessage
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+		if err := json.Unmarshal(root[format.Items], &rawItems); err != nil {
+			return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidBatch, format.Items, err)
+		}
+	}
+
+	items := make([]Item[T], 0, len(rawItems))
+	for i, raw := range rawItems {
+		item, err := decodeJSONItem[T](raw, format)
+		if err != nil {
+			return nil, fmt.Errorf("%w: item %d: %w", ErrInvalidBatch, i, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodeJSONItem decodes an item of a JSON batch response.
+func decodeJSONItem[T any](raw map[string]json.RawMessage, format Format) (Item[T], error) {
+	var item Item[T]
+
+	if id, ok := raw[format.ID]; ok {
+		// IDs are strings in most formats, but numbers are accepted as they are
+		if err := json.Unmarshal(id, &item.ID); err != nil {
+			item.ID = string(id)
+		}
+	}
+
+	if err := json.Unmarshal(raw[format.Status], &item.Status); err != nil {
+		return item, fmt.Errorf("field %q: %w", format.Status, err)
+	}
+
+	if header, ok := raw[format.Header]; ok {
+		item.Header = decodeHeader(header)
+	}
+
+	body := raw[format.Body]
+	if !isSuccess(item.Status) {
+		item.Err = newItemError(item, string(body))
+		return item, nil
+	}
+	if len(body) > 0 && string(body) != "null" {
+		item.Err = json.Unmarshal(body, &item.Value)
+	}
+	return item, nil
+}
+
+// decodeHeader decodes headers given as an object of strings or string arrays.
+func decodeHeader(data json.RawMessage) http.Header {
+	header := make(http.Header)
+
+	var values map[string][]string
+	if err := json.Unmarshal(data, &values); err == nil {
+		for key, v := range values {
+			header[http.CanonicalHeaderKey(key)] = v
+		}
+		return header
+	}
+
+	var single map[string]string
+	if err := json.Unmarshal(data, &single); err == nil {
+		for key, v := range single {
+			header.Set(key, v)
+		}
+	}
+	return header
+}
+
+// Values returns the values of the successful items, and the errors of the failed items joined
+// together, each prefixed with the ID of its item.
+func Values[T any](items []Item[T]) ([]T, error) {
+	values := make([]T, 0, len(items))
+	var errs []error
+	for _, item := range items {
+		if item.Err != nil {
+			errs = append(errs, fmt.Errorf("item %q: %w", item.ID, item.Err))
+			continue
+		}
+		values = append(values, item.Value)
+	}
+	return values, errors.Join(errs...)
+}
+
+// newItemError creates the error of an item with a status other than 2xx.
+func newItemError[T any](item Item[T], body string) *clientErrors.HTTPError {
+	if len(body) > bodySnippetSize {
+		body = body[:bodySnippetSize]
+	}
+	return &clientErrors.HTTPError{
+		StatusCode:  item.Status,
+		Status:      strings.TrimSpace(strconv.Itoa(item.Status) + " " + http.StatusText(item.Status)),
+		Header:      item.Header,
+		BodySnippet: body,
+		URL:         "",
+		Method:      "",
+	}
+}
+
+// isSuccess reports whether the status code is 2xx.
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
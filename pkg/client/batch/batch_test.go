@@ -0,0 +1,138 @@
+package batch_test
+
+import (
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/batch"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Name string `json:"displayName"`
+}
+
+type Props struct {
+	DisplayName string `xml:"DAV: displayname"`
+	ETag        string `xml:"DAV: getetag"`
+	Length      int64  `xml:"DAV: getcontentlength"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Decode Microsoft Graph batch responses", func(t *testing.T) {
+		t.Parallel()
+
+		items, err := batch.DecodeJSON[User]([]byte(`{"responses": [
+			{"id": "1", "status": 200, "headers": {"Content-Type": "application/json"}, "body": {"displayName": "Ada"}},
+			{"id": "2", "status": 404, "body": {"error": {"code": "Request_ResourceNotFound"}}},
+			{"id": "3", "status": 204}
+		]}`), batch.Graph)
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+
+		assert.Equal(t, "1", items[0].ID)
+		assert.Equal(t, "Ada", items[0].Value.Name)
+		assert.Equal(t, "application/json", items[0].Header.Get("Content-Type"))
+		require.NoError(t, items[0].Err)
+
+		var httpErr *errors.HTTPError
+		require.ErrorAs(t, items[1].Err, &httpErr)
+		assert.Equal(t, 404, httpErr.StatusCode)
+		assert.Contains(t, httpErr.BodySnippet, "Request_ResourceNotFound")
+		require.ErrorIs(t, items[1].Err, errors.ErrBadStatus)
+
+		require.NoError(t, items[2].Err)
+
+		values, err := batch.Values(items)
+		assert.Len(t, values, 2)
+		require.ErrorIs(t, err, errors.ErrBadStatus)
+		assert.Contains(t, err.Error(), `item "2"`)
+	})
+
+	t.Run("Decode top-level arrays with custom fields", func(t *testing.T) {
+		t.Parallel()
+
+		items, err := batch.DecodeJSON[User]([]byte(`[
+			{"ref": 7, "code": 201, "headers": {"Location": ["/users/7"]}, "result": {"displayName": "Grace"}}
+		]`), batch.Format{Items: "", ID: "ref", Status: "code", Header: "headers", Body: "result"})
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "7", items[0].ID)
+		assert.Equal(t, 201, items[0].Status)
+		assert.Equal(t, "/users/7", items[0].Header.Get("Location"))
+		assert.Equal(t, "Grace", items[0].Value.Name)
+	})
+
+	t.Run("Reject malformed responses", func(t *testing.T) {
+		t.Parallel()
+
+		for _, data := range []string{`{"responses": {}}`, `{"responses": [{"id": "1"}]}`, `not json`} {
+			_, err := batch.DecodeJSON[User]([]byte(data), batch.Graph)
+			require.ErrorIs(t, err, batch.ErrInvalidBatch, data)
+		}
+	})
+}
+
+func TestDecodeMultiStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Decode properties of each resource", func(t *testing.T) {
+		t.Parallel()
+
+		items, err := batch.DecodeMultiStatus[Props]([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:">
+  <D:response>
+    <D:href>/files/report.pdf</D:href>
+    <D:propstat>
+      <D:prop><D:displayname>Report</D:displayname><D:getcontentlength>2048</D:getcontentlength></D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+    <D:propstat>
+      <D:status>HTTP/1.1 200 OK</D:status>
+      <D:prop><D:getetag>"abc"</D:getetag></D:prop>
+    </D:propstat>
+    <D:propstat>
+      <D:prop><D:quota-used-bytes/></D:prop>
+      <D:status>HTTP/1.1 404 Not Found</D:status>
+    </D:propstat>
+  </D:response>
+  <D:response>
+    <D:href>/files/missing</D:href>
+    <D:status>HTTP/1.1 404 Not Found</D:status>
+    <D:responsedescription>No such file</D:responsedescription>
+  </D:response>
+  <D:response>
+    <D:href>/files/locked</D:href>
+    <D:propstat>
+      <D:prop><D:displayname/></D:prop>
+      <D:status>HTTP/1.1 403 Forbidden</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`))
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+
+		assert.Equal(t, "/files/report.pdf", items[0].ID)
+		assert.Equal(t, 200, items[0].Status)
+		require.NoError(t, items[0].Err)
+		assert.Equal(t, Props{DisplayName: "Report", ETag: `"abc"`, Length: 2048}, items[0].Value)
+
+		var httpErr *errors.HTTPError
+		require.ErrorAs(t, items[1].Err, &httpErr)
+		assert.Equal(t, 404, httpErr.StatusCode)
+		assert.Equal(t, "No such file", httpErr.BodySnippet)
+
+		assert.Equal(t, 403, items[2].Status)
+		require.ErrorIs(t, items[2].Err, errors.ErrBadStatus)
+	})
+
+	t.Run("Reject malformed responses", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := batch.DecodeMultiStatus[Props]([]byte(`<D:multistatus xmlns:D="DAV:"><D:response><D:href>/a</D:href><D:status>bad</D:status></D:response></D:multistatus>`))
+		require.ErrorIs(t, err, batch.ErrInvalidBatch)
+	})
+}
@@ -0,0 +1,153 @@
+package batch
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// multistatus is the root element of a WebDAV 207 Multi-Status response.
+type multistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+// davResponse is the result of a resource, with either a status or properties grouped by status.
+type davResponse struct {
+	Href        string        `xml:"DAV: href"`
+	Status      string        `xml:"DAV: status"`
+	Propstats   []davPropstat `xml:"DAV: propstat"`
+	Description string        `xml:"DAV: responsedescription"`
+}
+
+// davPropstat groups properties of a resource sharing a status.
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+// davProp keeps the tokens of a prop element, with their namespaces resolved, so the properties
+// are decoded once the status of their group is known.
+type davProp struct {
+	tokens []xml.Token
+}
+
+// UnmarshalXML records the tokens of the element.
+func (p *davProp) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.tokens = append(p.tokens, start.Copy())
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		p.tokens = append(p.tokens, xml.CopyToken(token))
+	}
+	return nil
+}
+
+// decode decodes the properties into v, keeping the fields already set by other groups.
+func (p *davProp) decode(v interface{}) error {
+	return xml.NewTokenDecoder(&tokenReader{tokens: p.tokens}).Decode(v)
+}
+
+// tokenReader replays recorded tokens.
+type tokenReader struct {
+	tokens []xml.Token
+}
+
+// Token returns the next token, or io.EOF once every token was returned.
+func (r *tokenReader) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return token, nil
+}
+
+// DecodeMultiStatus decodes a WebDAV 207 Multi-Status response into per-resource results,
+// identified by their href. Properties of successful propstat elements are decoded into a value
+// of type T, whose fields match property names such as `xml:"DAV: getetag"`. Properties of
+// other propstat elements, such as properties not found, are left zero. Resources without any
+// successful propstat element fail with an *errors.HTTPError.
+func DecodeMultiStatus[T any](data []byte) ([]Item[T], error) {
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+	}
+
+	items := make([]Item[T], 0, len(ms.Responses))
+	for _, resp := range ms.Responses {
+		item, err := decodeDAVResponse[T](resp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidBatch, resp.Href, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodeDAVResponse decodes the result of a resource.
+func decodeDAVResponse[T any](resp davResponse) (Item[T], error) {
+	item := Item[T]{ID: strings.TrimSpace(resp.Href), Status: 0, Header: nil, Value: *new(T), Err: nil}
+
+	// A status for the whole resource, such as for a resource that was not found
+	if len(resp.Propstats) == 0 {
+		status, err := parseStatusLine(resp.Status)
+		if err != nil {
+			return item, err
+		}
+		item.Status = status
+		if !isSuccess(status) {
+			item.Err = newItemError(item, resp.Description)
+		}
+		return item, nil
+	}
+
+	var failed int
+	for _, propstat := range resp.Propstats {
+		status, err := parseStatusLine(propstat.Status)
+		if err != nil {
+			return item, err
+		}
+		if !isSuccess(status) {
+			if failed == 0 {
+				failed = status
+			}
+			continue
+		}
+
+		if item.Status == 0 {
+			item.Status = status
+		}
+		if err := propstat.Prop.decode(&item.Value); err != nil && item.Err == nil {
+			item.Err = err
+		}
+	}
+
+	if item.Status == 0 {
+		item.Status = failed
+		item.Err = newItemError(item, resp.Description)
+	}
+	return item, nil
+}
+
+// parseStatusLine parses the status code of a status line such as "HTTP/1.1 200 OK".
+func parseStatusLine(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid status %q", line)
+	}
+	status, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid status %q", line)
+	}
+	return status, nil
+}
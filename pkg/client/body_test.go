@@ -0,0 +1,141 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/codec"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyReader(t *testing.T) {
+	t.Parallel()
+
+	// The server echoes the body it received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Stream the body", func(t *testing.T) {
+		t.Parallel()
+
+		// Hide the concrete type so the body is streamed with an unknown length
+		reader := io.MultiReader(strings.NewReader("large "), strings.NewReader("payload"))
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			BodyReader(reader).
+			Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "large payload", string(body))
+	})
+
+	t.Run("Read the body from GetBody", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		req, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			GetBody(func() (io.ReadCloser, error) {
+				calls++
+				return io.NopCloser(strings.NewReader("payload")), nil
+			}).
+			Build(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, req.GetBody)
+
+		// The body is read from a first call, and GetBody returns a new reader
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+
+		again, err := req.GetBody()
+		require.NoError(t, err)
+		body, err = io.ReadAll(again)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Conflict with another body", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			Body([]byte("payload")).
+			BodyReader(strings.NewReader("payload")).
+			Build(context.Background())
+		require.ErrorIs(t, err, errors.ErrBodyMarshalConflict)
+	})
+}
+
+func TestBodyAs(t *testing.T) {
+	t.Parallel()
+
+	// The server echoes the body it received along with its content type
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	type Item struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	t.Run("Marshal with the codec of the content type", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			BodyAs("application/xml", Item{Name: "widget"}).
+			DoResponse(context.Background())
+		require.NoError(t, err)
+
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "<Item><name>widget</name></Item>", body)
+		assert.Equal(t, "application/xml", resp.Header("Content-Type"))
+
+		var item Item
+		require.NoError(t, resp.Decode(&item))
+		assert.Equal(t, "widget", item.Name)
+	})
+
+	t.Run("Unknown content type", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			BodyAs("application/x-unknown", Item{Name: "widget"}).
+			Build(context.Background())
+		require.ErrorIs(t, err, codec.ErrUnknownContentType)
+		require.ErrorIs(t, err, errors.ErrRequestCreation)
+	})
+}
@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ClassifyingMiddleware records the classification of the outcome of every request.
+type ClassifyingMiddleware struct {
+	classes []middleware.Class
+}
+
+func (m *ClassifyingMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	resp, err := next(ctx, httpClient, req)
+	m.classes = append(m.classes, middleware.Classify(ctx, resp, err))
+	return resp, err
+}
+
+func (m *ClassifyingMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestClassifier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default classification", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			status int
+			err    error
+			class  middleware.Class
+		}{
+			{http.StatusOK, nil, middleware.ClassSuccess},
+			{http.StatusNotModified, nil, middleware.ClassSuccess},
+			{http.StatusNotFound, nil, middleware.ClassClientError},
+			{http.StatusTooManyRequests, nil, middleware.ClassFailure},
+			{http.StatusBadGateway, nil, middleware.ClassFailure},
+			{0, fmt.Errorf("%w: reset", errors.ErrNetwork), middleware.ClassFailure},
+			{0, errors.ErrRequestCreation, middleware.ClassPermanentFailure},
+			{0, &errors.OverloadedError{RetryAfter: time.Second, QueueDepth: 1}, middleware.ClassClientError},
+			{0, &errors.HTTPError{StatusCode: http.StatusForbidden}, middleware.ClassClientError},
+			{0, &errors.HTTPError{StatusCode: http.StatusServiceUnavailable}, middleware.ClassFailure},
+		}
+
+		for _, tt := range tests {
+			var resp *http.Response
+			if tt.status != 0 {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			assert.Equal(t, tt.class, middleware.DefaultClassifier(resp, tt.err), "status %d, error %v", tt.status, tt.err)
+		}
+
+		assert.True(t, middleware.ClassClientError.Healthy())
+		assert.False(t, middleware.ClassClientError.Retryable())
+		assert.False(t, middleware.ClassPermanentFailure.Healthy())
+		assert.True(t, middleware.ClassFailure.Retryable())
+	})
+
+	t.Run("Share the classifier of the client", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		}))
+		t.Cleanup(server.Close)
+
+		recorder := &ClassifyingMiddleware{classes: nil}
+		c := NewTestClient(
+			client.WithMiddleware(recorder),
+			client.WithClassifier(func(resp *http.Response, err error) middleware.Class {
+				if resp != nil && resp.StatusCode == http.StatusConflict {
+					return middleware.ClassFailure
+				}
+				return middleware.DefaultClassifier(resp, err)
+			}),
+		)
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, []middleware.Class{middleware.ClassFailure}, recorder.classes)
+	})
+
+	t.Run("Reject a nil classifier", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithClassifier(nil))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+}
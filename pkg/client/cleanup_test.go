@@ -0,0 +1,191 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cleanupRecorder records the cleanups that ran, in order.
+type cleanupRecorder struct {
+	names []string
+	mu    sync.Mutex
+}
+
+func (r *cleanupRecorder) cleanup(name string) func() {
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.names = append(r.names, name)
+	}
+}
+
+func (r *cleanupRecorder) ran() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.names...)
+}
+
+// CleanupMiddleware registers a cleanup before calling the next middleware.
+type CleanupMiddleware struct {
+	name     string
+	recorder *cleanupRecorder
+}
+
+func (m *CleanupMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	middleware.OnCleanup(ctx, m.recorder.cleanup(m.name))
+	return next(ctx, httpClient, req)
+}
+
+func (m *CleanupMiddleware) SetLogger(_ logger.Logger) {}
+
+// OuterMiddleware replaces the outcome of the rest of the chain.
+type OuterMiddleware struct {
+	process func(resp *http.Response, err error) (*http.Response, error)
+}
+
+func (m *OuterMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	return m.process(next(ctx, httpClient, req))
+}
+
+func (m *OuterMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestOnCleanup(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	type FirstMiddleware struct{ CleanupMiddleware }
+	type SecondMiddleware struct{ CleanupMiddleware }
+
+	newChain := func(recorder *cleanupRecorder, outer ...middleware.Middleware) *middleware.Chain {
+		return middleware.NewChain(logger.NewBasicLogger(), append(outer,
+			&FirstMiddleware{CleanupMiddleware{name: "first", recorder: recorder}},
+			&SecondMiddleware{CleanupMiddleware{name: "second", recorder: recorder}},
+		)...)
+	}
+
+	t.Run("Run cleanups in reverse order once the body is closed", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &cleanupRecorder{}
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		resp, err := newChain(recorder).Process(context.Background(), server.Client(), req)
+		require.NoError(t, err)
+		assert.Empty(t, recorder.ran())
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+		assert.Empty(t, recorder.ran())
+
+		require.NoError(t, resp.Body.Close())
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, []string{"second", "first"}, recorder.ran())
+	})
+
+	t.Run("Run cleanups when outer middleware short-circuits", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &cleanupRecorder{}
+		outer := &OuterMiddleware{process: func(resp *http.Response, err error) (*http.Response, error) {
+			return nil, ErrMiddleware
+		}}
+
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		_, err := newChain(recorder, outer).Process(context.Background(), server.Client(), req)
+		require.ErrorIs(t, err, ErrMiddleware)
+		assert.Equal(t, []string{"second", "first"}, recorder.ran())
+	})
+
+	t.Run("Run cleanups when outer middleware panics", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &cleanupRecorder{}
+		outer := &OuterMiddleware{process: func(resp *http.Response, err error) (*http.Response, error) {
+			panic("outer middleware panicked")
+		}}
+
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		assert.PanicsWithValue(t, "outer middleware panicked", func() {
+			_, _ = newChain(recorder, outer).Process(context.Background(), server.Client(), req)
+		})
+		assert.Equal(t, []string{"second", "first"}, recorder.ran())
+	})
+
+	t.Run("Run a cleanup early only once", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &cleanupRecorder{}
+		outer := &OuterMiddleware{process: func(resp *http.Response, err error) (*http.Response, error) {
+			return nil, ErrMiddleware
+		}}
+
+		chain := newChain(recorder, outer)
+		chain.Then(&middlewareFunc{process: func(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+			release := middleware.OnCleanup(ctx, recorder.cleanup("early"))
+			release()
+			return next(ctx, httpClient, req)
+		}})
+
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		_, err := chain.Process(context.Background(), server.Client(), req)
+		require.ErrorIs(t, err, ErrMiddleware)
+		assert.Equal(t, []string{"early", "second", "first"}, recorder.ran())
+	})
+
+	t.Run("Keep running cleanups after a cleanup panics", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &cleanupRecorder{}
+		chain := newChain(recorder)
+		chain.Then(&middlewareFunc{process: func(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+			middleware.OnCleanup(ctx, func() { panic("cleanup panicked") })
+			return nil, ErrMiddleware
+		}})
+
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		_, err := chain.Process(context.Background(), server.Client(), req)
+		require.ErrorIs(t, err, ErrMiddleware)
+		assert.Equal(t, []string{"second", "first"}, recorder.ran())
+	})
+
+	t.Run("Leave cleanups outside of a chain to the caller", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &cleanupRecorder{}
+		release := middleware.OnCleanup(context.Background(), recorder.cleanup("outside"))
+		assert.Empty(t, recorder.ran())
+
+		release()
+		release()
+		assert.Equal(t, []string{"outside"}, recorder.ran())
+	})
+}
+
+// middlewareFunc adapts a function to the Middleware interface.
+type middlewareFunc struct {
+	process func(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error)
+}
+
+func (m *middlewareFunc) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	return m.process(ctx, httpClient, req, next)
+}
+
+func (m *middlewareFunc) SetLogger(_ logger.Logger) {}
@@ -5,17 +5,37 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
+	"github.com/jaxron/axonet/pkg/client/dns"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 )
 
 // Client manages HTTP requests with various middleware options.
 type Client struct {
-	middlewareChain *middleware.Chain
-	httpClient      *http.Client
-	marshalFunc     MarshalFunc
-	unmarshalFunc   UnmarshalFunc
+	middlewareChain  *middleware.Chain
+	httpClient       *http.Client
+	marshalFunc      MarshalFunc
+	unmarshalFunc    UnmarshalFunc
+	redirectPolicy   *RedirectPolicy
+	baseURL          *url.URL
+	errorOnStatus    func(status int) bool
+	contentTypes     []string
+	maxResponseBytes int64
+	protocolOption   string
+	pins             []string
+	resolver         dns.Resolver
+	hooks            []Hooks
+	eventBus         *events.Bus
+	started          []middleware.LifecycleMiddleware
+	lifecycleMu      sync.Mutex
+	err              error
+	conflicts        error
 }
 
 // NewClient creates a new Client instance with default settings.
@@ -28,18 +48,217 @@ func NewClient(opts ...Option) *Client {
 			Jar:           nil,
 			Timeout:       0,
 		},
-		marshalFunc:   json.Marshal,
-		unmarshalFunc: json.Unmarshal,
+		marshalFunc:      json.Marshal,
+		unmarshalFunc:    json.Unmarshal,
+		redirectPolicy:   NewRedirectPolicy(),
+		baseURL:          nil,
+		errorOnStatus:    nil,
+		contentTypes:     nil,
+		maxResponseBytes: 0,
+		protocolOption:   "",
+		pins:             nil,
+		resolver:         nil,
+		hooks:            nil,
+		eventBus:         nil,
+		started:          nil,
+		lifecycleMu:      sync.Mutex{},
+		err:              nil,
+		conflicts:        nil,
 	}
+	client.httpClient.CheckRedirect = client.checkRedirect
 
 	for _, opt := range opts {
 		opt(client)
 	}
 
+	// Requests fail with the composition error rather than misbehaving at runtime
+	client.fail(client.middlewareChain.Validate())
+
+	// Start the background work of middleware once the composition is known to be valid
+	if client.err == nil {
+		client.fail(client.Start(context.Background()))
+	}
+
 	return client
 }
 
+// NewClientE creates a new Client instance like NewClient, but returns the errors found while
+// applying the options and validating the middleware composition instead of deferring them to Err.
+// It also returns errors.ErrOptionConflict for options overriding each other, which NewClient
+// tolerates by keeping the first protocol option, the last flags and the last middleware of a name.
+func NewClientE(opts ...Option) (*Client, error) {
+	client := NewClient(opts...)
+	if err := errors.Join(client.Err(), client.conflicts); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Err returns the errors found while constructing the Client, such as an invalid option or a
+// *middleware.CompositionError when middleware cannot work in the position it was added in.
+// Every request fails with this error.
+func (c *Client) Err() error {
+	return c.err
+}
+
+// fail records an error found while constructing the Client.
+func (c *Client) fail(err error) {
+	c.err = errors.Join(c.err, err)
+}
+
+// conflict records options overriding each other, which only NewClientE reports.
+func (c *Client) conflict(err error) {
+	c.conflicts = errors.Join(c.conflicts, err)
+}
+
 // Do performs an HTTP request with the specified options.
 func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	return c.middlewareChain.Process(ctx, c.httpClient, req)
+	return c.do(ctx, req, nil)
+}
+
+// do performs an HTTP request, merging the given middleware into the client chain for this request only.
+func (c *Client) do(ctx context.Context, req *http.Request, middlewares []middleware.Middleware) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	// Requests may override the size limit of response bodies
+	if _, ok := middleware.MaxResponseBytesFromContext(ctx); !ok && c.maxResponseBytes > 0 {
+		ctx = middleware.WithMaxResponseBytes(ctx, c.maxResponseBytes)
+	}
+
+	// Let middleware cloning the transport know its connections already resolve with the resolver
+	if c.resolver != nil {
+		ctx = middleware.WithResolver(ctx, c.resolver)
+	}
+
+	// Middleware added for the request must work in its position too
+	chain := c.middlewareChain
+	if len(middlewares) > 0 {
+		chain = chain.With(middlewares...)
+		if err := chain.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Requests with their own timeout are not bounded by the timeout of the Client
+	httpClient := c.httpClient
+	if _, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok && httpClient.Timeout > 0 {
+		clone := *httpClient
+		clone.Timeout = 0
+		httpClient = &clone
+	}
+
+	// Publish the events emitted about the request
+	if c.eventBus != nil {
+		ctx = events.WithBus(ctx, c.eventBus)
+	}
+
+	resp, err := c.runHooks(ctx, req, func(ctx context.Context) (*http.Response, error) {
+		return chain.Process(ctx, httpClient, req)
+	})
+
+	// Release the group member once this body is closed, as requests decoding a result replace it
+	if release, ok := ctx.Value(groupReleaseKey{}).(func()); ok && resp != nil && resp.Body != nil {
+		resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	}
+	if err != nil {
+		return resp, err
+	}
+
+	// Surface error statuses sent in trailers once the body is read
+	withTrailerStatus(resp)
+
+	// Turn responses with an error status into errors, keeping the response for inspection
+	if c.errorOnStatus != nil && c.errorOnStatus(resp.StatusCode) {
+		return resp, &StatusError{HTTPError: errors.NewHTTPError(resp), Response: resp}
+	}
+	return resp, nil
+}
+
+// resolveURL resolves a request URL against the base URL of the Client, if any.
+func (c *Client) resolveURL(rawURL string) (string, error) {
+	if c.baseURL == nil {
+		return rawURL, nil
+	}
+
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if ref.Scheme != "" || ref.Host != "" {
+		return rawURL, nil
+	}
+
+	// Append the path to the base path rather than replacing its last segment, keeping escaped
+	// characters such as %2F escaped
+	resolved := c.baseURL.JoinPath(ref.EscapedPath())
+	if ref.Path == "" {
+		resolved.Path, resolved.RawPath = c.baseURL.Path, c.baseURL.RawPath
+	}
+
+	// Keep the query parameters of the base URL along with those of the request URL
+	query := c.baseURL.Query()
+	for key, values := range ref.Query() {
+		query[key] = values
+	}
+	resolved.RawQuery = query.Encode()
+	resolved.Fragment = ref.Fragment
+
+	return resolved.String(), nil
+}
+
+// SetDegraded manually enables or disables degraded mode. While degraded, optional middleware
+// is skipped and middleware such as the cache and rate limiter favor availability over freshness.
+func (c *Client) SetDegraded(enabled bool) {
+	c.middlewareChain.SetDegraded(enabled)
+}
+
+// Degraded reports whether the Client is degraded, either manually or by a degradation source.
+func (c *Client) Degraded() bool {
+	return c.middlewareChain.Degraded()
+}
+
+// SetFlag enables or disables a middleware flag for every request of the Client at runtime.
+func (c *Client) SetFlag(flag middleware.Flag, enabled bool) {
+	c.middlewareChain.Flags().Set(flag, enabled)
+}
+
+// Middleware returns the middleware registered under the name with WithNamedMiddleware.
+func (c *Client) Middleware(name string) (middleware.Middleware, bool) {
+	return c.middlewareChain.Get(name)
+}
+
+// SkipMiddleware returns a copy of ctx skipping the middleware of the same types as the given
+// middleware, such as client.SkipMiddleware(ctx, (*redis.RedisMiddleware)(nil)).
+// Unlike the skip keys of the middleware packages, it works with any middleware.
+func SkipMiddleware(ctx context.Context, middlewares ...middleware.Middleware) context.Context {
+	return middleware.WithSkip(ctx, middlewares...)
+}
+
+// SkipNamed returns a copy of ctx skipping the middleware registered under the given names
+// with WithNamedMiddleware.
+func SkipNamed(ctx context.Context, names ...string) context.Context {
+	return middleware.WithSkipNamed(ctx, names...)
+}
+
+// Snapshot is a JSON-serializable description of the Client configuration and live state.
+type Snapshot struct {
+	Timeout        string                   `json:"timeout"`
+	EarlyRejection bool                     `json:"earlyRejection"`
+	Degraded       bool                     `json:"degraded"`
+	Flags          map[middleware.Flag]bool `json:"flags,omitempty"`
+	Middlewares    []middleware.Snapshot    `json:"middlewares"`
+}
+
+// Introspect returns a snapshot of the chain composition along with the configuration and
+// live state of every middleware that implements middleware.Introspector.
+func (c *Client) Introspect() Snapshot {
+	return Snapshot{
+		Timeout:        c.httpClient.Timeout.String(),
+		EarlyRejection: c.middlewareChain.EarlyRejection(),
+		Degraded:       c.middlewareChain.Degraded(),
+		Flags:          c.middlewareChain.Flags().Values(),
+		Middlewares:    c.middlewareChain.Introspect(),
+	}
 }
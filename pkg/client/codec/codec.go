@@ -0,0 +1,112 @@
+// Package codec provides a registry of codecs that marshal and unmarshal bodies, keyed by
+// content type, so requests and responses can use any registered body format.
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownContentType = errors.New("unknown content type")
+
+// Codec marshals and unmarshals bodies of a single content type.
+type Codec interface {
+	// ContentType returns the media type of the bodies, such as "application/json".
+	ContentType() string
+	// Marshal encodes v into a body.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes the body into v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	codecs   = make(map[string]Codec)
+	codecsMu sync.RWMutex
+)
+
+// JSON and XML are registered by default.
+var (
+	JSON = New("application/json", json.Marshal, json.Unmarshal)
+	XML  = New("application/xml", xml.Marshal, xml.Unmarshal)
+)
+
+func init() {
+	Register(JSON)
+	Register(XML)
+	Register(New("text/xml", xml.Marshal, xml.Unmarshal))
+}
+
+// funcCodec is a Codec built from marshal and unmarshal functions.
+type funcCodec struct {
+	contentType string
+	marshal     func(v interface{}) ([]byte, error)
+	unmarshal   func(data []byte, v interface{}) error
+}
+
+// New creates a Codec for the content type from marshal and unmarshal functions, such as those
+// of a msgpack, CBOR or protobuf library.
+func New(contentType string, marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) Codec {
+	return &funcCodec{
+		contentType: contentType,
+		marshal:     marshal,
+		unmarshal:   unmarshal,
+	}
+}
+
+func (c *funcCodec) ContentType() string                        { return c.contentType }
+func (c *funcCodec) Marshal(v interface{}) ([]byte, error)      { return c.marshal(v) }
+func (c *funcCodec) Unmarshal(data []byte, v interface{}) error { return c.unmarshal(data, v) }
+
+// Register adds a codec to the registry, replacing any codec with the same content type.
+func Register(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[mediaType(codec.ContentType())] = codec
+}
+
+// Lookup returns the codec registered for the content type. Parameters such as the charset are
+// ignored, and structured syntax suffixes fall back to their format, so "application/problem+json"
+// uses the codec of "application/json" unless a codec is registered for it.
+func Lookup(contentType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	mt := mediaType(contentType)
+	if codec, ok := codecs[mt]; ok {
+		return codec, true
+	}
+
+	if i := strings.LastIndexByte(mt, '+'); i >= 0 {
+		codec, ok := codecs["application/"+mt[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// ContentTypes returns the registered content types sorted alphabetically.
+func ContentTypes() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	contentTypes := make([]string, 0, len(codecs))
+	for contentType := range codecs {
+		contentTypes = append(contentTypes, contentType)
+	}
+	slices.Sort(contentTypes)
+	return contentTypes
+}
+
+// mediaType returns the lowercase media type of the content type, without parameters.
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
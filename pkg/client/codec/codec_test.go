@@ -0,0 +1,60 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Item struct {
+	Name  string `json:"name"  xml:"name"`
+	Count int    `json:"count" xml:"count"`
+}
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Round trip the default codecs", func(t *testing.T) {
+		t.Parallel()
+
+		for _, contentType := range []string{"application/json", "application/xml", "text/xml"} {
+			c, ok := codec.Lookup(contentType)
+			require.True(t, ok, contentType)
+
+			data, err := c.Marshal(Item{Name: "widget", Count: 2})
+			require.NoError(t, err, contentType)
+
+			var item Item
+			require.NoError(t, c.Unmarshal(data, &item), contentType)
+			assert.Equal(t, Item{Name: "widget", Count: 2}, item, contentType)
+		}
+	})
+
+	t.Run("Ignore parameters and fall back on suffixes", func(t *testing.T) {
+		t.Parallel()
+
+		c, ok := codec.Lookup("Application/JSON; charset=utf-8")
+		require.True(t, ok)
+		assert.Equal(t, "application/json", c.ContentType())
+
+		c, ok = codec.Lookup("application/problem+json")
+		require.True(t, ok)
+		assert.Equal(t, "application/json", c.ContentType())
+
+		_, ok = codec.Lookup("application/octet-stream")
+		assert.False(t, ok)
+	})
+
+	t.Run("Register a codec", func(t *testing.T) {
+		t.Parallel()
+
+		codec.Register(codec.New("application/vnd.test", json.Marshal, json.Unmarshal))
+
+		_, ok := codec.Lookup("application/vnd.test")
+		require.True(t, ok)
+		assert.Contains(t, codec.ContentTypes(), "application/vnd.test")
+	})
+}
@@ -0,0 +1,130 @@
+// Package compression provides a registry of compression codecs shared by the middleware
+// that compress request bodies, decompress response bodies, and compress cached bodies.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownEncoding = errors.New("unknown content encoding")
+
+// Codec compresses and decompresses data for a single content encoding.
+type Codec interface {
+	// Encoding returns the content encoding token, such as "gzip".
+	Encoding() string
+	// NewReader returns a reader that decompresses data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	// NewWriter returns a writer that compresses data written to w.
+	// Closing the writer flushes any pending data but does not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+var (
+	codecs   = make(map[string]Codec)
+	codecsMu sync.RWMutex
+)
+
+func init() {
+	Register(GzipCodec{})
+	Register(DeflateCodec{})
+}
+
+// Register adds a codec to the registry, replacing any codec with the same encoding.
+func Register(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[strings.ToLower(codec.Encoding())] = codec
+}
+
+// Lookup returns the codec registered for the encoding.
+// Encodings are matched case-insensitively.
+func Lookup(encoding string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[strings.ToLower(strings.TrimSpace(encoding))]
+	return codec, ok
+}
+
+// Encodings returns the registered encodings sorted alphabetically.
+func Encodings() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	encodings := make([]string, 0, len(codecs))
+	for encoding := range codecs {
+		encodings = append(encodings, encoding)
+	}
+	slices.Sort(encodings)
+	return encodings
+}
+
+// Compress compresses data using the codec.
+func Compress(codec Codec, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", codec.Encoding(), err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("%s: %w", codec.Encoding(), err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("%s: %w", codec.Encoding(), err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses data using the codec.
+func Decompress(codec Codec, data []byte) ([]byte, error) {
+	r, err := codec.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", codec.Encoding(), err)
+	}
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", codec.Encoding(), err)
+	}
+
+	return decompressed, nil
+}
+
+// GzipCodec implements the gzip content encoding.
+type GzipCodec struct{}
+
+func (GzipCodec) Encoding() string { return "gzip" }
+
+func (GzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (GzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// DeflateCodec implements the deflate content encoding, which uses the zlib format.
+type DeflateCodec struct{}
+
+func (DeflateCodec) Encoding() string { return "deflate" }
+
+func (DeflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+func (DeflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// sniffSize is the number of bytes of the body used to detect its content type, as many as
+// http.DetectContentType considers.
+const sniffSize = 512
+
+// utf8BOM is the byte order mark some servers start UTF-8 bodies with.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// checkContentType verifies that the Content-Type header of the response and the content type
+// detected from the beginning of its body match one of the expected media types. The body is
+// left readable from the start.
+func checkContentType(resp *http.Response, expected []string) error {
+	if len(expected) == 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+
+	prefix, _ := io.ReadAll(io.LimitReader(resp.Body, sniffSize))
+	resp.Body = &sniffedBody{Reader: io.MultiReader(bytes.NewReader(prefix), resp.Body), Closer: resp.Body}
+
+	contentType := resp.Header.Get("Content-Type")
+	declared := mediaType(contentType)
+	sniffed := sniffMediaType(prefix)
+	if (declared == "" || matchesMediaType(declared, expected)) && (sniffed == "" || matchesMediaType(sniffed, expected)) {
+		return nil
+	}
+
+	err := &errors.ContentTypeError{
+		Expected:    expected,
+		ContentType: contentType,
+		Sniffed:     sniffed,
+		StatusCode:  resp.StatusCode,
+		BodySnippet: string(prefix),
+		URL:         "",
+		Method:      "",
+	}
+	if resp.Request != nil {
+		err.URL = resp.Request.URL.String()
+		err.Method = resp.Request.Method
+	}
+	return err
+}
+
+// sniffMediaType returns the media type detected from the beginning of a body, or an empty string
+// if it could be of any type, such as plain text or unrecognized binary data.
+func sniffMediaType(prefix []byte) string {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(prefix, utf8BOM), " \t\r\n")
+	if len(trimmed) == 0 {
+		return ""
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return "application/json"
+	}
+
+	sniffed := mediaType(http.DetectContentType(trimmed))
+	if sniffed == "text/plain" || sniffed == "application/octet-stream" {
+		return ""
+	}
+	return sniffed
+}
+
+// matchesMediaType reports whether the media type matches one of the expected media types.
+// Expected types may be wildcards such as "text/*", and a type with a structured syntax suffix
+// such as "application/problem+json" matches its base type "application/json", and conversely.
+func matchesMediaType(actual string, expected []string) bool {
+	actualType, actualSub := splitMediaType(actual)
+	return slices.ContainsFunc(expected, func(e string) bool {
+		expectedType, expectedSub := splitMediaType(mediaType(e))
+		switch {
+		case expectedType == "*":
+			return true
+		case expectedType != actualType:
+			return false
+		case expectedSub == "*" || expectedSub == actualSub:
+			return true
+		}
+		return structuredSuffix(actualSub) == expectedSub || structuredSuffix(expectedSub) == actualSub
+	})
+}
+
+// mediaType returns the lowercase media type of a Content-Type value without its parameters,
+// with text/xml treated as application/xml.
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	if mt == "text/xml" {
+		return "application/xml"
+	}
+	return mt
+}
+
+// splitMediaType splits a media type into its type and subtype.
+func splitMediaType(mt string) (string, string) {
+	typ, sub, _ := strings.Cut(mt, "/")
+	return typ, sub
+}
+
+// structuredSuffix returns the structured syntax suffix of a subtype, such as "json" for
+// "problem+json", or an empty string if it has none.
+func structuredSuffix(sub string) string {
+	if i := strings.LastIndexByte(sub, '+'); i >= 0 {
+		return sub[i+1:]
+	}
+	return ""
+}
+
+// sniffedBody is a response body whose beginning was read to detect its content type and is
+// replayed before the rest.
+type sniffedBody struct {
+	io.Reader
+	io.Closer
+}
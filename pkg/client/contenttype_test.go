@@ -0,0 +1,103 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentTypeCheck(t *testing.T) {
+	t.Parallel()
+
+	// The server replies with the content type and body of the path
+	replies := map[string][2]string{
+		"/json":       {"application/json; charset=utf-8", `{"name": "Ada"}`},
+		"/problem":    {"application/problem+json", `{"name": "Ada"}`},
+		"/html":       {"text/html; charset=utf-8", "<!DOCTYPE html><html><body>Service Unavailable</body></html>"},
+		"/mislabeled": {"application/json", "\n  <html><body>Maintenance</body></html>"},
+		"/unlabeled":  {"", `  [{"name": "Ada"}]`},
+		"/xml":        {"text/xml", `<?xml version="1.0"?><user><name>Ada</name></user>`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := replies[r.URL.Path]
+		w.Header()["Content-Type"] = nil
+		if reply[0] != "" {
+			w.Header().Set("Content-Type", reply[0])
+		}
+		_, _ = w.Write([]byte(reply[1]))
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewTestClient(client.WithBaseURL(server.URL), client.WithContentTypeCheck())
+
+	t.Run("Decode responses of the expected content type", func(t *testing.T) {
+		t.Parallel()
+
+		for _, path := range []string{"/json", "/problem"} {
+			var user struct{ Name string }
+			_, err := c.NewRequest().URL(path).Result(&user).Do(context.Background())
+			require.NoError(t, err, path)
+			assert.Equal(t, "Ada", user.Name)
+		}
+
+		// Responses without a content type are sniffed
+		var users []struct{ Name string }
+		_, err := c.NewRequest().URL("/unlabeled").Result(&users).Do(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("Reject responses of another content type", func(t *testing.T) {
+		t.Parallel()
+
+		var user struct{ Name string }
+		resp, err := c.NewRequest().URL("/html").Result(&user).Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrUnexpectedContentType)
+
+		var contentTypeErr *errors.ContentTypeError
+		require.ErrorAs(t, err, &contentTypeErr)
+		assert.Equal(t, []string{"application/json"}, contentTypeErr.Expected)
+		assert.Equal(t, "text/html", contentTypeErr.Sniffed)
+		assert.Equal(t, http.StatusOK, contentTypeErr.StatusCode)
+		assert.Equal(t, http.MethodGet, contentTypeErr.Method)
+		assert.Contains(t, contentTypeErr.BodySnippet, "Service Unavailable")
+
+		// The body is left readable from the start
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, replies["/html"][1], string(body))
+
+		// A body contradicting its Content-Type header is rejected too
+		_, err = c.NewRequest().URL("/mislabeled").Result(&user).Do(context.Background())
+		require.ErrorAs(t, err, &contentTypeErr)
+		assert.Equal(t, "application/json", contentTypeErr.ContentType)
+		assert.Equal(t, "text/html", contentTypeErr.Sniffed)
+	})
+
+	t.Run("Override the expected content types per request", func(t *testing.T) {
+		t.Parallel()
+
+		var raw interface{}
+		_, err := c.NewRequest().URL("/xml").ExpectContentType("application/xml").
+			UnmarshalWith(func(data []byte, v interface{}) error { return nil }).
+			Result(&raw).Do(context.Background())
+		require.NoError(t, err)
+
+		_, err = c.NewRequest().URL("/json").ExpectContentType("text/*").Result(&raw).Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrUnexpectedContentType)
+
+		// Requests without a result or expected types are not checked
+		_, err = c.NewRequest().URL("/html").Do(context.Background())
+		require.NoError(t, err)
+		_, err = c.NewRequest().URL("/html").ExpectContentType().Result(&raw).Do(context.Background())
+		require.Error(t, err)
+		require.NotErrorIs(t, err, errors.ErrUnexpectedContentType)
+	})
+}
@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieJar(t *testing.T) {
+	t.Parallel()
+
+	// The server sets a session cookie on login and echoes the session of other requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.URL.Query().Get("user"), Path: "/"}) //nolint:exhaustruct
+			return
+		}
+		if cookie, err := r.Cookie("session"); err == nil {
+			_, _ = w.Write([]byte(cookie.Value))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	session := func(t *testing.T, rb *client.Request) string {
+		t.Helper()
+
+		resp, err := rb.URL(server.URL + "/me").Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	login := func(t *testing.T, rb *client.Request, user string) {
+		t.Helper()
+
+		resp, err := rb.URL(server.URL + "/login?user=" + user).Do(context.Background())
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	newJar := func(t *testing.T) http.CookieJar {
+		t.Helper()
+
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		return jar
+	}
+
+	t.Run("Capture cookies in the jar of the client", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithCookieJar(newJar(t)))
+		login(t, c.NewRequest(), "ada")
+		assert.Equal(t, "ada", session(t, c.NewRequest()))
+
+		// Clients without a jar do not keep cookies
+		c = NewTestClient()
+		login(t, c.NewRequest(), "ada")
+		assert.Empty(t, session(t, c.NewRequest()))
+	})
+
+	t.Run("Override the jar per request", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithCookieJar(newJar(t)))
+		login(t, c.NewRequest(), "ada")
+
+		grace := newJar(t)
+		login(t, c.NewRequest().CookieJar(grace), "grace")
+		assert.Equal(t, "grace", session(t, c.NewRequest().CookieJar(grace)))
+		assert.Equal(t, "ada", session(t, c.NewRequest()))
+
+		// A nil jar disables cookies for the request
+		assert.Empty(t, session(t, c.NewRequest().CookieJar(nil)))
+	})
+}
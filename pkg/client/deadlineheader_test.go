@@ -0,0 +1,90 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeadlineHeader(t *testing.T) {
+	t.Parallel()
+
+	// The server reports the budget it received, halved, in the same header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen", r.Header.Get(middleware.DefaultDeadlineHeader))
+		if budget, err := middleware.DeadlineMilliseconds.Parse(r.Header.Get(middleware.DefaultDeadlineHeader)); err == nil {
+			w.Header().Set(middleware.DefaultDeadlineHeader, middleware.DeadlineMilliseconds.Format(budget/2))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewTestClient(client.WithBaseURL(server.URL), client.WithDeadlineHeader("", middleware.DeadlineMilliseconds))
+
+	t.Run("Send the remaining time and parse the reported budget", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		resp, err := c.NewRequest().URL("/").Do(ctx)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		sent, err := middleware.DeadlineMilliseconds.Parse(resp.Header.Get("X-Seen"))
+		require.NoError(t, err)
+		assert.Greater(t, sent, time.Second)
+		assert.LessOrEqual(t, sent, 2*time.Second)
+
+		budget, ok := middleware.DeadlineBudgetFromResponse(resp)
+		require.True(t, ok)
+		assert.InDelta(t, sent, budget.Sent, float64(time.Millisecond))
+		require.True(t, budget.HasReported)
+		assert.Equal(t, sent/2, budget.Reported)
+	})
+
+	t.Run("Omit the header without a deadline", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := c.NewRequest().URL("/").Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Empty(t, resp.Header.Get("X-Seen"))
+		_, ok := middleware.DeadlineBudgetFromResponse(resp)
+		assert.False(t, ok)
+	})
+
+	t.Run("Encode and decode the formats", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			format   middleware.DeadlineFormat
+			duration time.Duration
+			value    string
+		}{
+			{middleware.DeadlineMilliseconds, 1500 * time.Millisecond, "1500"},
+			{middleware.DeadlineMilliseconds, time.Microsecond, "1"},
+			{middleware.DeadlineSeconds, 1500 * time.Millisecond, "1.5"},
+			{middleware.DeadlineGRPC, 1500 * time.Nanosecond, "1500n"},
+			{middleware.DeadlineGRPC, 1500 * time.Millisecond, "1500000u"},
+			{middleware.DeadlineGRPC, 10 * time.Minute, "600000m"},
+		} {
+			assert.Equal(t, tc.value, tc.format.Format(tc.duration))
+		}
+
+		d, err := middleware.DeadlineGRPC.Parse("2S")
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Second, d)
+		_, err = middleware.DeadlineGRPC.Parse("2x")
+		require.Error(t, err)
+		_, err = middleware.DeadlineMilliseconds.Parse("-5")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,243 @@
+// Package dns provides resolvers for the connections of the client, including a cache of resolved
+// addresses for workloads dialing the same hosts repeatedly.
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long resolved addresses are reused before the host is resolved again.
+	DefaultTTL = time.Minute
+	// DefaultNegativeTTL is how long a failed lookup is reused before the host is resolved again.
+	DefaultNegativeTTL = 5 * time.Second
+	// LookupTimeout bounds the lookups of a Cache, which outlive the callers waiting for them.
+	LookupTimeout = 30 * time.Second
+)
+
+// Resolver resolves host names to addresses. *net.Resolver implements it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DialFunc dials a network address, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dial wraps a dial function to resolve host names with the resolver, trying each address
+// until one connects. Addresses that are already IP addresses are dialed as they are.
+func Dial(resolver Resolver, dial DialFunc) DialFunc {
+	if dial == nil {
+		dialer := &net.Dialer{} //nolint:exhaustruct
+		dial = dialer.DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true} //nolint:exhaustruct
+		}
+
+		// Try each address until one connects
+		var conn net.Conn
+		for _, ip := range addrs {
+			if conn, err = dial(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// Stats reports the lookups served by a Cache.
+type Stats struct {
+	// Hits is the number of lookups served from resolved addresses.
+	Hits uint64 `json:"hits"`
+	// NegativeHits is the number of lookups served from failed lookups.
+	NegativeHits uint64 `json:"negativeHits"`
+	// Misses is the number of lookups sent to the resolver.
+	Misses uint64 `json:"misses"`
+	// Errors is the number of lookups sent to the resolver that failed.
+	Errors uint64 `json:"errors"`
+	// Entries is the number of hosts currently cached.
+	Entries int `json:"entries"`
+}
+
+// entry is a cached lookup.
+type entry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// lookup is a lookup in progress, shared by concurrent lookups of the same host.
+type lookup struct {
+	done  chan struct{}
+	addrs []string
+	err   error
+}
+
+// Cache is a Resolver caching the lookups of another resolver. Concurrent lookups of a host that
+// is not cached share a single lookup, which a canceled caller does not cancel for the others.
+type Cache struct {
+	resolver     Resolver
+	entries      map[string]entry
+	lookups      map[string]*lookup
+	ttl          time.Duration
+	negativeTTL  time.Duration
+	swept        time.Time
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+	errors       atomic.Uint64
+	mu           sync.Mutex
+}
+
+// NewCache creates a new Cache instance resolving hosts with the resolver, such as net.DefaultResolver.
+func NewCache(resolver Resolver) *Cache {
+	return &Cache{
+		resolver:     resolver,
+		entries:      make(map[string]entry),
+		lookups:      make(map[string]*lookup),
+		ttl:          DefaultTTL,
+		negativeTTL:  DefaultNegativeTTL,
+		swept:        time.Now(),
+		hits:         atomic.Uint64{},
+		negativeHits: atomic.Uint64{},
+		misses:       atomic.Uint64{},
+		errors:       atomic.Uint64{},
+		mu:           sync.Mutex{},
+	}
+}
+
+// SetTTL sets how long resolved addresses are reused. Defaults to 1 minute.
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+}
+
+// SetNegativeTTL sets how long a host that does not exist is reported as such without resolving
+// it again. Defaults to 5 seconds. A TTL of zero disables negative caching.
+func (c *Cache) SetNegativeTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.negativeTTL = ttl
+}
+
+// LookupHost returns the addresses of the host, resolving it if it is not cached or its entry expired.
+func (c *Cache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[host]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		if e.err != nil {
+			c.negativeHits.Add(1)
+			return nil, e.err
+		}
+		c.hits.Add(1)
+		return e.addrs, nil
+	}
+
+	// Join the lookup already in progress, or start one
+	l, ok := c.lookups[host]
+	if !ok {
+		l = &lookup{done: make(chan struct{}), addrs: nil, err: nil}
+		c.lookups[host] = l
+		go c.resolve(context.WithoutCancel(ctx), host, l)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return l.addrs, l.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve performs the lookup of the host shared by the callers waiting for it, and caches its
+// result. The lookup is bounded by LookupTimeout rather than by the caller that started it.
+func (c *Cache) resolve(ctx context.Context, host string, l *lookup) {
+	ctx, cancel := context.WithTimeout(ctx, LookupTimeout)
+	defer cancel()
+
+	c.misses.Add(1)
+	l.addrs, l.err = c.resolver.LookupHost(ctx, host)
+	if l.err != nil {
+		c.errors.Add(1)
+	}
+
+	c.mu.Lock()
+	delete(c.lookups, host)
+	now := time.Now()
+	switch {
+	case l.err == nil:
+		c.entries[host] = entry{addrs: l.addrs, err: nil, expires: now.Add(c.ttl)}
+	case c.negativeTTL > 0 && isNotFound(l.err):
+		c.entries[host] = entry{addrs: nil, err: l.err, expires: now.Add(c.negativeTTL)}
+	default:
+		delete(c.entries, host)
+	}
+	c.sweep(now)
+	c.mu.Unlock()
+	close(l.done)
+}
+
+// sweep evicts the expired entries at most once per TTL, so hosts that are not resolved again do
+// not stay cached forever. It must be called with the lock held.
+func (c *Cache) sweep(now time.Time) {
+	if now.Sub(c.swept) < c.ttl {
+		return
+	}
+	c.swept = now
+
+	for host, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, host)
+		}
+	}
+}
+
+// Flush drops every cached lookup, so hosts are resolved again.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]entry)
+}
+
+// Stats returns the statistics of the lookups served by the cache.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Errors:       c.errors.Load(),
+		Entries:      entries,
+	}
+}
+
+// isNotFound reports whether the lookup failed because the host does not exist, rather than
+// because of a transient failure that should not be cached.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
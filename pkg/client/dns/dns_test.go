@@ -0,0 +1,195 @@
+package dns_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/dns"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// StaticResolver resolves hosts from a fixed table, counting lookups.
+type StaticResolver struct {
+	hosts   map[string][]string
+	lookups atomic.Int64
+	delay   time.Duration
+}
+
+func (r *StaticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups.Add(1)
+	time.Sleep(r.delay)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true} //nolint:exhaustruct
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Cache resolved addresses until they expire", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := &StaticResolver{hosts: map[string][]string{"example.test": {"127.0.0.1"}}}
+		cache := dns.NewCache(resolver)
+		cache.SetTTL(50 * time.Millisecond)
+
+		for range 3 {
+			addrs, err := cache.LookupHost(context.Background(), "example.test")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"127.0.0.1"}, addrs)
+		}
+		assert.Equal(t, int64(1), resolver.lookups.Load())
+
+		time.Sleep(60 * time.Millisecond)
+		_, err := cache.LookupHost(context.Background(), "example.test")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), resolver.lookups.Load())
+		assert.Equal(t, dns.Stats{Hits: 2, NegativeHits: 0, Misses: 2, Errors: 0, Entries: 1}, cache.Stats())
+
+		cache.Flush()
+		assert.Equal(t, 0, cache.Stats().Entries)
+	})
+
+	t.Run("Cache hosts that do not exist", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := &StaticResolver{hosts: nil}
+		cache := dns.NewCache(resolver)
+
+		for range 2 {
+			_, err := cache.LookupHost(context.Background(), "missing.test")
+			var dnsErr *net.DNSError
+			require.ErrorAs(t, err, &dnsErr)
+			assert.True(t, dnsErr.IsNotFound)
+		}
+		assert.Equal(t, int64(1), resolver.lookups.Load())
+		assert.Equal(t, dns.Stats{Hits: 0, NegativeHits: 1, Misses: 1, Errors: 1, Entries: 1}, cache.Stats())
+
+		// Without negative caching, every lookup is sent to the resolver
+		cache = dns.NewCache(resolver)
+		cache.SetNegativeTTL(0)
+		for range 2 {
+			_, err := cache.LookupHost(context.Background(), "missing.test")
+			require.Error(t, err)
+		}
+		assert.Equal(t, int64(3), resolver.lookups.Load())
+	})
+
+	t.Run("Share concurrent lookups of a host", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := &StaticResolver{hosts: map[string][]string{"example.test": {"127.0.0.1"}}, delay: 50 * time.Millisecond}
+		cache := dns.NewCache(resolver)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				addrs, err := cache.LookupHost(context.Background(), "example.test")
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"127.0.0.1"}, addrs)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(1), resolver.lookups.Load())
+	})
+
+	t.Run("Finish shared lookups once their caller is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := &StaticResolver{hosts: map[string][]string{"example.test": {"127.0.0.1"}}, delay: 50 * time.Millisecond}
+		cache := dns.NewCache(resolver)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(5 * time.Millisecond)
+			addrs, err := cache.LookupHost(context.Background(), "example.test")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"127.0.0.1"}, addrs)
+		}()
+
+		_, err := cache.LookupHost(ctx, "example.test")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		wg.Wait()
+
+		// The result of the lookup is cached
+		_, err = cache.LookupHost(context.Background(), "example.test")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), resolver.lookups.Load())
+	})
+
+	t.Run("Evict expired entries", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := &StaticResolver{hosts: map[string][]string{
+			"a.test": {"127.0.0.1"},
+			"b.test": {"127.0.0.2"},
+			"c.test": {"127.0.0.3"},
+		}}
+		cache := dns.NewCache(resolver)
+		cache.SetTTL(20 * time.Millisecond)
+
+		for _, host := range []string{"a.test", "b.test"} {
+			_, err := cache.LookupHost(context.Background(), host)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, cache.Stats().Entries)
+
+		time.Sleep(30 * time.Millisecond)
+		_, err := cache.LookupHost(context.Background(), "c.test")
+		require.NoError(t, err)
+		assert.Equal(t, 1, cache.Stats().Entries)
+	})
+
+	t.Run("Reject a nil resolver", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithResolver(nil))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+
+	t.Run("Resolve hosts of client requests", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Connection", "close")
+		}))
+		t.Cleanup(server.Close)
+
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		resolver := &StaticResolver{hosts: map[string][]string{"example.test": {"127.0.0.1"}}}
+		cache := dns.NewCache(resolver)
+		c, err := client.NewClientE(client.WithResolver(cache))
+		require.NoError(t, err)
+
+		for range 3 {
+			resp, err := c.NewRequest().URL("http://example.test:" + serverURL.Port()).Do(context.Background())
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+		assert.Equal(t, int64(1), resolver.lookups.Load())
+		assert.Equal(t, uint64(2), cache.Stats().Hits)
+	})
+}
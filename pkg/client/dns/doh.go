@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Public DNS-over-HTTPS endpoints. They are addressed by IP, so they are reachable without
+// resolving their own host name with plain DNS.
+const (
+	Cloudflare = "https://1.1.1.1/dns-query"
+	Google     = "https://8.8.8.8/dns-query"
+)
+
+// dohContentType is the media type of DNS messages sent over HTTPS (RFC 8484).
+const dohContentType = "application/dns-message"
+
+// maxMessageSize is the largest DNS message read from a response.
+const maxMessageSize = 65535
+
+// DNS record types and response codes used by the resolver.
+const (
+	typeA      = 1
+	typeAAAA   = 28
+	classINET  = 1
+	rcodeNXDom = 3
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+// DoHResolver is a Resolver sending queries to a DNS-over-HTTPS endpoint (RFC 8484), for networks
+// where plain DNS is blocked or monitored.
+type DoHResolver struct {
+	endpoint   string
+	httpClient *http.Client
+	mu         sync.RWMutex
+}
+
+// NewDoH creates a new DoHResolver instance querying the endpoint, such as Cloudflare, Google or the
+// URL of a custom server. The host of a custom endpoint is resolved with the system resolver unless
+// it is an IP address.
+func NewDoH(endpoint string) *DoHResolver {
+	return &DoHResolver{
+		endpoint: endpoint,
+		httpClient: &http.Client{
+			Transport:     http.DefaultTransport,
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       10 * time.Second,
+		},
+		mu: sync.RWMutex{},
+	}
+}
+
+// SetHTTPClient sets the HTTP client sending the queries, such as one trusting a private CA.
+// It must not resolve hosts with this resolver.
+func (r *DoHResolver) SetHTTPClient(httpClient *http.Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.httpClient = httpClient
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of the host. Hosts that do not exist fail with a
+// *net.DNSError reporting IsNotFound, so a Cache remembers them.
+func (r *DoHResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	// Query both record types concurrently
+	var (
+		wg      sync.WaitGroup
+		results [2][]string
+		errs    [2]error
+	)
+	for i, qtype := range []uint16{typeA, typeAAAA} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = r.query(ctx, host, qtype)
+		}()
+	}
+	wg.Wait()
+
+	addrs := slices.Concat(results[0], results[1])
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, Server: r.endpoint, IsNotFound: true} //nolint:exhaustruct
+}
+
+// query sends a query for the records of the type and returns their addresses.
+func (r *DoHResolver) query(ctx context.Context, host string, qtype uint16) ([]string, error) {
+	r.mu.RLock()
+	endpoint, httpClient := r.endpoint, r.httpClient
+	r.mu.RUnlock()
+
+	dnsError := func(format string, args ...interface{}) error {
+		return &net.DNSError{Err: fmt.Sprintf(format, args...), Name: host, Server: endpoint, IsTemporary: true} //nolint:exhaustruct
+	}
+
+	msg, err := packQuery(host, qtype)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: host, Server: endpoint} //nolint:exhaustruct
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: host, Server: endpoint} //nolint:exhaustruct
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, dnsError("%v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, dnsError("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+	if err != nil {
+		return nil, dnsError("%v", err)
+	}
+
+	addrs, rcode, err := unpackAnswers(body, qtype)
+	switch {
+	case err != nil:
+		return nil, dnsError("%v", err)
+	case rcode == rcodeNXDom:
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: endpoint, IsNotFound: true} //nolint:exhaustruct
+	case rcode != 0:
+		return nil, dnsError("server failure, response code %d", rcode)
+	}
+	return addrs, nil
+}
+
+// packQuery encodes a recursive query for the records of the type of the host.
+func packQuery(host string, qtype uint16) ([]byte, error) {
+	// Header with ID 0, as recommended for HTTP caching, and recursion desired
+	msg := []byte{0, 0, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid host name %q", host)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classINET)
+	return msg, nil
+}
+
+// unpackAnswers decodes a response, returning the addresses of the answers of the type and the
+// response code.
+func unpackAnswers(msg []byte, qtype uint16) ([]string, int, error) {
+	if len(msg) < 12 {
+		return nil, 0, errMalformed
+	}
+	rcode := int(msg[3] & 0x0f)
+	questions := int(binary.BigEndian.Uint16(msg[4:6]))
+	answers := int(binary.BigEndian.Uint16(msg[6:8]))
+
+	offset := 12
+	for range questions {
+		var err error
+		if offset, err = skipName(msg, offset); err != nil {
+			return nil, 0, err
+		}
+		offset += 4
+	}
+
+	var addrs []string
+	for range answers {
+		var err error
+		if offset, err = skipName(msg, offset); err != nil {
+			return nil, 0, err
+		}
+		if offset+10 > len(msg) {
+			return nil, 0, errMalformed
+		}
+		rtype := binary.BigEndian.Uint16(msg[offset:])
+		length := int(binary.BigEndian.Uint16(msg[offset+8:]))
+		offset += 10
+		if offset+length > len(msg) {
+			return nil, 0, errMalformed
+		}
+
+		// Skip other records, such as the CNAME records leading to the addresses
+		data := msg[offset : offset+length]
+		if rtype == qtype && (length == net.IPv4len || length == net.IPv6len) {
+			addrs = append(addrs, net.IP(data).String())
+		}
+		offset += length
+	}
+	return addrs, rcode, nil
+}
+
+// skipName returns the offset following the possibly compressed name at offset.
+func skipName(msg []byte, offset int) (int, error) {
+	for offset < len(msg) {
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			// A pointer ends the name
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+	return 0, errMalformed
+}
@@ -0,0 +1,133 @@
+package dns_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewDoHServer starts a DNS-over-HTTPS server answering queries from a fixed table of records by
+// host and record type, and NXDOMAIN for unknown hosts.
+func NewDoHServer(t *testing.T, records map[string]map[uint16][]net.IP) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		query, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Read the question following the header
+		var labels []string
+		offset := 12
+		for query[offset] != 0 {
+			length := int(query[offset])
+			labels = append(labels, string(query[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+		qtype := binary.BigEndian.Uint16(query[offset+1:])
+		question := query[12 : offset+5]
+
+		hostRecords, ok := records[strings.Join(labels, ".")]
+		ips := hostRecords[qtype]
+
+		// Answer with a header, the question, then the records pointing to the name of the question
+		resp := []byte{0, 0, 0x81, 0x80, 0, 1, 0, byte(len(ips)), 0, 0, 0, 0}
+		if !ok {
+			resp[3] |= 3
+		}
+		resp = append(resp, question...)
+		for _, ip := range ips {
+			data := ip.To4()
+			if qtype == 28 {
+				data = ip.To16()
+			}
+			resp = append(resp, 0xc0, 12)
+			resp = binary.BigEndian.AppendUint16(resp, qtype)
+			resp = append(resp, 0, 1, 0, 0, 1, 0)
+			resp = binary.BigEndian.AppendUint16(resp, uint16(len(data))) //nolint:gosec
+			resp = append(resp, data...)
+		}
+
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDoHResolver(t *testing.T) {
+	t.Parallel()
+
+	server := NewDoHServer(t, map[string]map[uint16][]net.IP{
+		"example.test": {
+			1:  {net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")},
+			28: {net.ParseIP("2001:db8::1")},
+		},
+		"v4only.test": {1: {net.ParseIP("192.0.2.3")}},
+	})
+
+	newResolver := func() *dns.DoHResolver {
+		resolver := dns.NewDoH(server.URL + "/dns-query")
+		resolver.SetHTTPClient(server.Client())
+		return resolver
+	}
+
+	t.Run("Resolve IPv4 and IPv6 addresses", func(t *testing.T) {
+		t.Parallel()
+
+		addrs, err := newResolver().LookupHost(context.Background(), "example.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"}, addrs)
+
+		addrs, err = newResolver().LookupHost(context.Background(), "v4only.test.")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.3"}, addrs)
+	})
+
+	t.Run("Report hosts that do not exist", func(t *testing.T) {
+		t.Parallel()
+
+		cache := dns.NewCache(newResolver())
+		for range 2 {
+			_, err := cache.LookupHost(context.Background(), "missing.test")
+			var dnsErr *net.DNSError
+			require.ErrorAs(t, err, &dnsErr)
+			assert.True(t, dnsErr.IsNotFound)
+		}
+		assert.Equal(t, uint64(1), cache.Stats().NegativeHits)
+	})
+
+	t.Run("Report failing endpoints as temporary", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := dns.NewDoH(server.URL + "/dns-query")
+		_, err := resolver.LookupHost(context.Background(), "example.test")
+		var dnsErr *net.DNSError
+		require.ErrorAs(t, err, &dnsErr) // The default client does not trust the test server
+		assert.True(t, dnsErr.IsTemporary)
+		assert.False(t, dnsErr.IsNotFound)
+	})
+
+	t.Run("Return IP addresses as they are", func(t *testing.T) {
+		t.Parallel()
+
+		addrs, err := dns.NewDoH("https://invalid.test/dns-query").LookupHost(context.Background(), "192.0.2.9")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.9"}, addrs)
+	})
+}
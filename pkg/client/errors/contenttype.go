@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentTypeError is returned when the response to decode has another content type than the
+// decoder expects, such as an HTML error page where JSON was expected. It matches
+// ErrUnexpectedContentType with errors.Is.
+type ContentTypeError struct {
+	// Expected are the media types the decoder accepts.
+	Expected []string
+	// ContentType is the Content-Type header of the response, which may be empty.
+	ContentType string
+	// Sniffed is the media type detected from the beginning of the body, or empty if unknown.
+	Sniffed     string
+	StatusCode  int
+	BodySnippet string
+	URL         string
+	Method      string
+}
+
+// Error implements the error interface.
+func (e *ContentTypeError) Error() string {
+	got := e.ContentType
+	if got == "" {
+		got = "no content type"
+	}
+	if e.Sniffed != "" {
+		got = fmt.Sprintf("%s (body looks like %s)", got, e.Sniffed)
+	}
+	return fmt.Sprintf("%s: %s %s: got %s, expected %s",
+		ErrUnexpectedContentType, e.Method, e.URL, got, strings.Join(e.Expected, " or "))
+}
+
+// Unwrap returns ErrUnexpectedContentType.
+func (e *ContentTypeError) Unwrap() error {
+	return ErrUnexpectedContentType
+}
@@ -11,10 +11,39 @@ var (
 
 	ErrRequestCreation     = errors.New("request creation error")
 	ErrBodyMarshalConflict = errors.New("body and marshal body conflict")
+	ErrBodyTransform       = errors.New("body transformation error")
 
 	ErrNetwork   = errors.New("network error")
 	ErrTimeout   = errors.New("timeout error")
 	ErrBadStatus = errors.New("bad status code")
+
+	ErrResponseTooLarge = errors.New("response body too large")
+	ErrTrailerStatus    = errors.New("error status in trailers")
+	ErrPathNotFound     = errors.New("path not found in response")
+
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+
+	ErrTooManyRedirects   = errors.New("too many redirects")
+	ErrRedirectNotAllowed = errors.New("redirect not allowed")
+
+	ErrInvalidOption       = errors.New("invalid option")
+	ErrOptionConflict      = errors.New("conflicting options")
+	ErrMissingPrerequisite = errors.New("missing prerequisite")
+	ErrInvalidComposition  = errors.New("invalid middleware composition")
+
+	ErrDeadlineUnreachable = errors.New("deadline cannot be met")
+	ErrOverloaded          = errors.New("overloaded")
+	ErrMaintenance         = errors.New("upstream under maintenance")
+
+	ErrGroupCanceled = errors.New("request group canceled")
+	ErrPreconnect    = errors.New("preconnect failed")
+	ErrLifecycle     = errors.New("middleware lifecycle error")
+
+	ErrCertificatePin    = errors.New("certificate pin mismatch")
+	ErrClientCertificate = errors.New("client certificate cannot be presented")
+
+	ErrSignatureInvalid = errors.New("invalid URL signature")
+	ErrSignatureExpired = errors.New("URL signature expired")
 )
 
 // IsTemporary returns true if the error is considered temporary and can be retried.
@@ -29,3 +58,13 @@ func IsTemporary(err error) bool {
 func Is(err, target error) bool {
 	return errors.Is(err, target)
 }
+
+// As finds the first error in err's chain that matches target, and if so, sets target to that error value.
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// Join returns an error wrapping the given errors, discarding nil errors.
+func Join(errs ...error) error {
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// bodySnippetSize is the number of bytes of the response body kept in an HTTPError.
+const bodySnippetSize = 512
+
+// HTTPError describes a response with an unexpected status code.
+// It matches ErrBadStatus with errors.Is, and *ProblemDetails with errors.As if the response
+// has a problem details body.
+type HTTPError struct {
+	StatusCode  int
+	Status      string
+	Header      http.Header
+	BodySnippet string
+	URL         string
+	Method      string
+	// Problem is the decoded problem details body of the response, or nil if it has none.
+	Problem *ProblemDetails
+}
+
+// NewHTTPError creates a new HTTPError describing the response. The beginning of the body is
+// kept as a snippet, problem details bodies are decoded, and the body is left readable from the start.
+func NewHTTPError(resp *http.Response) *HTTPError {
+	err := &HTTPError{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		Header:      resp.Header.Clone(),
+		BodySnippet: "",
+		URL:         "",
+		Method:      "",
+		Problem:     nil,
+	}
+
+	// Responses served by middleware, such as a cache, may have no request
+	if resp.Request != nil {
+		err.URL = resp.Request.URL.String()
+		err.Method = resp.Request.Method
+	}
+
+	if resp.Body != nil && resp.Body != http.NoBody {
+		// Problem details are read whole to be decoded
+		problem := IsProblem(resp.Header.Get("Content-Type"))
+		limit := int64(bodySnippetSize)
+		if problem {
+			limit = maxProblemSize
+		}
+
+		head, _ := io.ReadAll(io.LimitReader(resp.Body, limit))
+		err.BodySnippet = string(head[:min(len(head), bodySnippetSize)])
+		if problem {
+			err.Problem = parseProblem(head, resp.StatusCode)
+		}
+		resp.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(head), resp.Body), Closer: resp.Body}
+	}
+
+	return err
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("%s: %d", ErrBadStatus, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s %s: %d", ErrBadStatus, e.Method, e.URL, e.StatusCode)
+}
+
+// Unwrap returns ErrBadStatus, and the problem details of the response if any.
+func (e *HTTPError) Unwrap() []error {
+	if e.Problem != nil {
+		return []error{ErrBadStatus, e.Problem}
+	}
+	return []error{ErrBadStatus}
+}
+
+// prefixedBody is a response body whose beginning was read and is replayed before the rest.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,22 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// ResponseTooLargeError is returned when reading a response body beyond its size limit.
+// It matches ErrResponseTooLarge with errors.Is.
+type ResponseTooLargeError struct {
+	Limit int64
+	URL   string
+}
+
+// Error implements the error interface.
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %s exceeds %d bytes", ErrResponseTooLarge, e.URL, e.Limit)
+}
+
+// Unwrap returns ErrResponseTooLarge.
+func (e *ResponseTooLargeError) Unwrap() error {
+	return ErrResponseTooLarge
+}
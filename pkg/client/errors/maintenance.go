@@ -0,0 +1,29 @@
+package errors
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaintenanceError is returned when a request is rejected because its host announced planned
+// maintenance. It matches ErrMaintenance with errors.Is.
+type MaintenanceError struct {
+	// Host is the host under maintenance.
+	Host string
+	// Until is when the maintenance window is expected to end.
+	Until time.Time
+	// RetryAfter is the time left until the end of the maintenance window.
+	RetryAfter time.Duration
+	// Reason describes the signal that announced the maintenance.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *MaintenanceError) Error() string {
+	return fmt.Sprintf("%s: %s (%s), retry after %s", ErrMaintenance, e.Host, e.Reason, e.RetryAfter)
+}
+
+// Unwrap returns ErrMaintenance.
+func (e *MaintenanceError) Unwrap() error {
+	return ErrMaintenance
+}
@@ -0,0 +1,26 @@
+package errors
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverloadedError is returned when a limiter rejects a request because its queue is full.
+// It matches ErrOverloaded with errors.Is.
+type OverloadedError struct {
+	// RetryAfter is the suggested delay before trying again, derived from the queue depth
+	// and the rate the limiter serves requests at.
+	RetryAfter time.Duration
+	// QueueDepth is the number of requests queued when the request was rejected.
+	QueueDepth int
+}
+
+// Error implements the error interface.
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("%s: %d requests queued, retry after %s", ErrOverloaded, e.QueueDepth, e.RetryAfter)
+}
+
+// Unwrap returns ErrOverloaded.
+func (e *OverloadedError) Unwrap() error {
+	return ErrOverloaded
+}
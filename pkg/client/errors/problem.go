@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// maxProblemSize is the number of bytes of a response body read to decode its problem details.
+const maxProblemSize = 64 << 10
+
+// ProblemContentType is the media type of problem details documents.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is the RFC 7807 problem details document of an error response, such as
+// {"type": "https://example.com/probs/out-of-credit", "title": "You do not have enough credit."}.
+// It is found with errors.As on the errors of responses with a problem details body.
+type ProblemDetails struct {
+	// Type is a URI identifying the problem type, "about:blank" if the document has none.
+	Type string `json:"type"`
+	// Title is a short summary of the problem type.
+	Title string `json:"title"`
+	// Status is the status code of the response, as set by the server.
+	Status int `json:"status"`
+	// Detail is an explanation specific to this occurrence of the problem.
+	Detail string `json:"detail"`
+	// Instance is a URI identifying this occurrence of the problem.
+	Instance string `json:"instance"`
+	// Extensions holds the other members of the document, such as a list of invalid parameters.
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the members of the document, keeping the unknown ones as extensions.
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type members ProblemDetails
+	if err := json.Unmarshal(data, (*members)(p)); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, member := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(all, member)
+	}
+	if len(all) > 0 {
+		p.Extensions = all
+	}
+	return nil
+}
+
+// Extension decodes the extension member into v, reporting whether the document has it.
+func (p *ProblemDetails) Extension(name string, v interface{}) (bool, error) {
+	raw, ok := p.Extensions[name]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Error implements the error interface.
+func (p *ProblemDetails) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		return fmt.Sprintf("%s (%d)", title, p.Status)
+	}
+	return fmt.Sprintf("%s (%d): %s", title, p.Status, p.Detail)
+}
+
+// IsProblem reports whether the Content-Type value is the problem details media type.
+func IsProblem(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == ProblemContentType
+}
+
+// parseProblem decodes a problem details document, completing the members the server left out.
+// It returns nil if the body is not a valid document.
+func parseProblem(body []byte, status int) *ProblemDetails {
+	var problem ProblemDetails
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return nil
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Status == 0 {
+		problem.Status = status
+	}
+	return &problem
+}
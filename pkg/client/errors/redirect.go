@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TooManyRedirectsError is returned when a request is redirected more times than allowed.
+// It matches ErrTooManyRedirects and ErrPermanent with errors.Is, so the request is not retried.
+type TooManyRedirectsError struct {
+	// Max is the number of redirects that were allowed.
+	Max int
+	// Via are the URLs visited, from the original request to the redirect that was not followed.
+	Via []string
+}
+
+// Error implements the error interface.
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("%s: stopped after %d redirects: %s", ErrTooManyRedirects, e.Max, strings.Join(e.Via, " -> "))
+}
+
+// Unwrap returns ErrTooManyRedirects and ErrPermanent.
+func (e *TooManyRedirectsError) Unwrap() []error {
+	return []error{ErrTooManyRedirects, ErrPermanent}
+}
+
+// RedirectNotAllowedError is returned when a redirect is rejected by the redirect policy, such as
+// a redirect to another host. It matches ErrRedirectNotAllowed and ErrPermanent with errors.Is.
+type RedirectNotAllowedError struct {
+	// From is the URL of the original request.
+	From string
+	// To is the URL the redirect pointed to.
+	To string
+}
+
+// Error implements the error interface.
+func (e *RedirectNotAllowedError) Error() string {
+	return fmt.Sprintf("%s: %s leaves the host of %s", ErrRedirectNotAllowed, e.To, e.From)
+}
+
+// Unwrap returns ErrRedirectNotAllowed and ErrPermanent.
+func (e *RedirectNotAllowedError) Unwrap() []error {
+	return []error{ErrRedirectNotAllowed, ErrPermanent}
+}
@@ -0,0 +1,26 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PinError is returned when no certificate presented by a server matches the pinned public keys.
+// It matches ErrCertificatePin and ErrPermanent with errors.Is, so the request is not retried.
+type PinError struct {
+	// Host is the server name the connection was made to.
+	Host string
+	// Presented are the pins of the certificates presented by the server, as "sha256/" followed
+	// by the base64 SHA-256 hash of their public key.
+	Presented []string
+}
+
+// Error implements the error interface.
+func (e *PinError) Error() string {
+	return fmt.Sprintf("%s: %s presented %s", ErrCertificatePin, e.Host, strings.Join(e.Presented, ", "))
+}
+
+// Unwrap returns ErrCertificatePin and ErrPermanent.
+func (e *PinError) Unwrap() []error {
+	return []error{ErrCertificatePin, ErrPermanent}
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// gRPC status codes that are worth retrying.
+const (
+	grpcDeadlineExceeded  = 4
+	grpcResourceExhausted = 8
+	grpcAborted           = 10
+	grpcUnavailable       = 14
+)
+
+// TrailerStatusError is returned when reading a response body whose trailers carry an error
+// status, such as a non-zero grpc-status of a gRPC or gRPC-Web response. It matches
+// ErrTrailerStatus with errors.Is, along with ErrTimeout or ErrTemporary for the gRPC codes worth
+// retrying and ErrPermanent for the others.
+type TrailerStatusError struct {
+	// Code is the gRPC status code.
+	Code int
+	// Message is the decoded grpc-message.
+	Message string
+	URL     string
+	Method  string
+}
+
+// Error implements the error interface.
+func (e *TrailerStatusError) Error() string {
+	return fmt.Sprintf("%s: %s %s: code %d: %s", ErrTrailerStatus, e.Method, e.URL, e.Code, e.Message)
+}
+
+// Unwrap returns ErrTrailerStatus and the error describing whether the request can be retried.
+func (e *TrailerStatusError) Unwrap() []error {
+	switch e.Code {
+	case grpcDeadlineExceeded:
+		return []error{ErrTrailerStatus, ErrTimeout}
+	case grpcResourceExhausted, grpcAborted, grpcUnavailable:
+		return []error{ErrTrailerStatus, ErrTemporary}
+	default:
+		return []error{ErrTrailerStatus, ErrPermanent}
+	}
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Bus delivers the events published to it to its subscribers, in the order they subscribed.
+// Handlers are called synchronously by the request emitting the event, so they must not block.
+type Bus struct {
+	subscribers []*subscriber
+	mu          sync.RWMutex
+}
+
+// subscriber is a handler subscribed to a Bus.
+type subscriber struct {
+	handle Handler
+}
+
+// NewBus creates a new Bus instance.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: nil,
+		mu:          sync.RWMutex{},
+	}
+}
+
+// SubscribeAll subscribes fn to every event published to the bus. The returned function
+// unsubscribes it.
+func (b *Bus) SubscribeAll(fn Handler) func() {
+	sub := &subscriber{handle: fn}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, sub)
+	return func() { b.unsubscribe(sub) }
+}
+
+// Subscribe subscribes fn to the events of type T published to the bus, such as RetryScheduled.
+// The returned function unsubscribes it.
+func Subscribe[T Event](b *Bus, fn func(ctx context.Context, e T)) func() {
+	return b.SubscribeAll(func(ctx context.Context, e Event) {
+		if typed, ok := e.(T); ok {
+			fn(ctx, typed)
+		}
+	})
+}
+
+// Publish delivers the event to the subscribers of the bus.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		sub.handle(ctx, e)
+	}
+}
+
+// unsubscribe removes the subscriber from the bus.
+func (b *Bus) unsubscribe(sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Copy the subscribers, as publishers may be iterating over them
+	subscribers := make([]*subscriber, 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		if s != sub {
+			subscribers = append(subscribers, s)
+		}
+	}
+	b.subscribers = subscribers
+}
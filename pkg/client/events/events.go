@@ -0,0 +1,163 @@
+// Package events defines the events emitted by the middleware chain and the built-in middleware,
+// such as retries, cache lookups and proxy rotations, and a Bus delivering them to typed handlers.
+package events
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+)
+
+// Names of the events emitted by this module.
+const (
+	NameRetryScheduled      = "retry"
+	NameCacheHit            = "cache.hit"
+	NameCacheMiss           = "cache.miss"
+	NameBreakerStateChanged = "breaker.state_changed"
+	NameProxyRotated        = "proxy.rotated"
+	NameMiddlewareSkipped   = "middleware.skipped"
+)
+
+// Event is an event emitted about a request. Its fields describe it in log entries and spans.
+type Event interface {
+	Name() string
+	Fields() []logger.Field
+}
+
+// RetryScheduled is emitted before a failed attempt is retried.
+type RetryScheduled struct {
+	// Attempt is the number of the failed attempt.
+	Attempt int
+	// Err is the error of the failed attempt.
+	Err error
+	// Delay is the time before the next attempt.
+	Delay time.Duration
+}
+
+func (e RetryScheduled) Name() string { return NameRetryScheduled }
+
+func (e RetryScheduled) Fields() []logger.Field {
+	return []logger.Field{
+		logger.Int("attempt", e.Attempt),
+		logger.Any("error", e.Err),
+		logger.Duration("retry_in", e.Delay),
+	}
+}
+
+// CacheHit is emitted when a response is served from a cache.
+type CacheHit struct {
+	// Key is the cache key of the request.
+	Key string
+	// Stale reports whether the response is past its expiration.
+	Stale bool
+}
+
+func (e CacheHit) Name() string { return NameCacheHit }
+
+func (e CacheHit) Fields() []logger.Field {
+	fields := []logger.Field{logger.String("key", e.Key)}
+	if e.Stale {
+		fields = append(fields, logger.Bool("stale", true))
+	}
+	return fields
+}
+
+// CacheMiss is emitted when a cache has no usable response for the request.
+type CacheMiss struct {
+	// Key is the cache key of the request.
+	Key string
+	// Reason is why a cached response was not usable, such as "stale", or empty if there was none.
+	Reason string
+}
+
+func (e CacheMiss) Name() string { return NameCacheMiss }
+
+func (e CacheMiss) Fields() []logger.Field {
+	fields := []logger.Field{logger.String("key", e.Key)}
+	if e.Reason != "" {
+		fields = append(fields, logger.String("reason", e.Reason))
+	}
+	return fields
+}
+
+// BreakerStateChanged is emitted when a circuit breaker changes state while processing a request,
+// such as from "closed" to "open".
+type BreakerStateChanged struct {
+	From string
+	To   string
+}
+
+func (e BreakerStateChanged) Name() string { return NameBreakerStateChanged }
+
+func (e BreakerStateChanged) Fields() []logger.Field {
+	return []logger.Field{logger.String("from", e.From), logger.String("to", e.To)}
+}
+
+// ProxyRotated is emitted when a proxy is selected for a request.
+type ProxyRotated struct {
+	Proxy *url.URL
+}
+
+func (e ProxyRotated) Name() string { return NameProxyRotated }
+
+func (e ProxyRotated) Fields() []logger.Field {
+	return []logger.Field{logger.String("proxy", e.Proxy.Redacted())}
+}
+
+// MiddlewareSkipped is emitted when the chain skips a middleware for a request.
+type MiddlewareSkipped struct {
+	// Index is the position of the middleware in the chain.
+	Index int
+	// Middleware is the type of the middleware.
+	Middleware string
+}
+
+func (e MiddlewareSkipped) Name() string { return NameMiddlewareSkipped }
+
+func (e MiddlewareSkipped) Fields() []logger.Field {
+	return []logger.Field{logger.Int("index", e.Index), logger.String("middleware", e.Middleware)}
+}
+
+// New returns an event with the name and fields, for events without a type of their own.
+func New(name string, fields ...logger.Field) Event {
+	return event{name: name, fields: fields}
+}
+
+// event is an event created with New.
+type event struct {
+	name   string
+	fields []logger.Field
+}
+
+func (e event) Name() string { return e.name }
+
+func (e event) Fields() []logger.Field { return e.fields }
+
+// Handler receives the events emitted about a request.
+type Handler func(ctx context.Context, e Event)
+
+// handlersKey is the context key used to store the handlers of a request.
+type handlersKey struct{}
+
+// WithHandler returns a copy of ctx passing the events emitted about the request to fn, after the
+// handlers already stored in ctx.
+func WithHandler(ctx context.Context, fn Handler) context.Context {
+	handlers, _ := ctx.Value(handlersKey{}).([]Handler)
+	return context.WithValue(ctx, handlersKey{}, append(slices.Clip(handlers), fn))
+}
+
+// WithBus returns a copy of ctx publishing the events emitted about the request to the bus.
+func WithBus(ctx context.Context, bus *Bus) context.Context {
+	return WithHandler(ctx, bus.Publish)
+}
+
+// Publish emits the event about the request to the handlers stored in ctx.
+func Publish(ctx context.Context, e Event) {
+	handlers, _ := ctx.Value(handlersKey{}).([]Handler)
+	for _, fn := range handlers {
+		fn(ctx, e)
+	}
+}
@@ -0,0 +1,72 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Deliver typed events to subscribers", func(t *testing.T) {
+		t.Parallel()
+
+		bus := events.NewBus()
+		var (
+			retries []events.RetryScheduled
+			names   []string
+		)
+		events.Subscribe(bus, func(ctx context.Context, e events.RetryScheduled) {
+			retries = append(retries, e)
+		})
+		bus.SubscribeAll(func(ctx context.Context, e events.Event) {
+			names = append(names, e.Name())
+		})
+
+		skipped := &IntrospectMiddleware{}
+		c := client.NewClient(
+			client.WithEventBus(bus),
+			client.WithMiddleware(skipped),
+			client.WithMiddleware(&EventMiddleware{}),
+		)
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background(), client.WithSkipMiddleware(skipped))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Len(t, retries, 1)
+		assert.Equal(t, 1, retries[0].Attempt)
+		assert.Equal(t, []string{events.NameMiddlewareSkipped, events.NameCacheMiss, events.NameRetryScheduled}, names)
+	})
+
+	t.Run("Stop delivering events once unsubscribed", func(t *testing.T) {
+		t.Parallel()
+
+		bus := events.NewBus()
+		var misses int
+		unsubscribe := events.Subscribe(bus, func(ctx context.Context, e events.CacheMiss) {
+			misses++
+		})
+
+		c := client.NewClient(client.WithEventBus(bus), client.WithMiddleware(&EventMiddleware{}))
+		for range 2 {
+			resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+			require.NoError(t, err)
+			resp.Body.Close()
+			unsubscribe()
+		}
+		assert.Equal(t, 1, misses)
+	})
+}
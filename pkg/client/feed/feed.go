@@ -0,0 +1,208 @@
+// Package feed fetches and parses RSS and Atom feeds.
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+)
+
+var ErrInvalidFeed = errors.New("invalid feed")
+
+// Feed is a parsed RSS or Atom feed.
+type Feed struct {
+	Title   string
+	Link    string
+	Entries []Entry
+}
+
+// Entry is an item of an RSS feed or an entry of an Atom feed.
+type Entry struct {
+	ID        string
+	Title     string
+	Link      string
+	Summary   string
+	Published time.Time
+	Updated   time.Time
+}
+
+// rssFeed is the root element of an RSS 2.0 feed.
+type rssFeed struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Link  string `xml:"link"`
+		Items []struct {
+			GUID        string `xml:"guid"`
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// atomLink is a link element of an Atom feed.
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// atomFeed is the root element of an Atom feed.
+type atomFeed struct {
+	Title   string     `xml:"title"`
+	Links   []atomLink `xml:"link"`
+	Entries []struct {
+		ID        string     `xml:"id"`
+		Title     string     `xml:"title"`
+		Links     []atomLink `xml:"link"`
+		Summary   string     `xml:"summary"`
+		Content   string     `xml:"content"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// Fetch fetches the feed at url using the client and parses it.
+func Fetch(ctx context.Context, c *client.Client, url string) (*Feed, error) {
+	resp, err := c.NewRequest().
+		Method(http.MethodGet).
+		URL(url).
+		Do(ctx)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, clientErrors.NewHTTPError(resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse parses an RSS 2.0 or Atom feed.
+func Parse(data []byte) (*Feed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	default:
+		return nil, fmt.Errorf("%w: unexpected root element %q", ErrInvalidFeed, root)
+	}
+}
+
+// parseRSS parses an RSS 2.0 feed.
+func parseRSS(data []byte) (*Feed, error) {
+	var rss rssFeed
+	if err := xml.Unmarshal(data, &rss); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFeed, err)
+	}
+
+	feed := &Feed{
+		Title:   strings.TrimSpace(rss.Channel.Title),
+		Link:    strings.TrimSpace(rss.Channel.Link),
+		Entries: make([]Entry, 0, len(rss.Channel.Items)),
+	}
+	for _, item := range rss.Channel.Items {
+		published := parseTime(item.PubDate)
+		feed.Entries = append(feed.Entries, Entry{
+			ID:        strings.TrimSpace(item.GUID),
+			Title:     strings.TrimSpace(item.Title),
+			Link:      strings.TrimSpace(item.Link),
+			Summary:   strings.TrimSpace(item.Description),
+			Published: published,
+			Updated:   published,
+		})
+	}
+
+	return feed, nil
+}
+
+// parseAtom parses an Atom feed.
+func parseAtom(data []byte) (*Feed, error) {
+	var atom atomFeed
+	if err := xml.Unmarshal(data, &atom); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFeed, err)
+	}
+
+	feed := &Feed{
+		Title:   strings.TrimSpace(atom.Title),
+		Link:    alternateLink(atom.Links),
+		Entries: make([]Entry, 0, len(atom.Entries)),
+	}
+	for _, entry := range atom.Entries {
+		summary := entry.Summary
+		if summary == "" {
+			summary = entry.Content
+		}
+
+		feed.Entries = append(feed.Entries, Entry{
+			ID:        strings.TrimSpace(entry.ID),
+			Title:     strings.TrimSpace(entry.Title),
+			Link:      alternateLink(entry.Links),
+			Summary:   strings.TrimSpace(summary),
+			Published: parseTime(entry.Published),
+			Updated:   parseTime(entry.Updated),
+		})
+	}
+
+	return feed, nil
+}
+
+// alternateLink returns the alternate link, which is the default when no rel is given.
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// rootElement returns the local name of the root element of the XML document.
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidFeed, err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// parseTime parses the date formats used by RSS and Atom, returning the zero time if it is invalid.
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,97 @@
+package feed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/feed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parse RSS feed", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := feed.Parse([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Example</title>
+    <link>https://example.com</link>
+    <item>
+      <guid>1</guid>
+      <title>First post</title>
+      <link>https://example.com/1</link>
+      <description>Hello</description>
+      <pubDate>Tue, 02 Jan 2024 15:04:05 +0000</pubDate>
+    </item>
+  </channel>
+</rss>`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Example", f.Title)
+		assert.Equal(t, "https://example.com", f.Link)
+		require.Len(t, f.Entries, 1)
+		assert.Equal(t, "1", f.Entries[0].ID)
+		assert.Equal(t, "First post", f.Entries[0].Title)
+		assert.Equal(t, "https://example.com/1", f.Entries[0].Link)
+		assert.Equal(t, "Hello", f.Entries[0].Summary)
+		assert.True(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC).Equal(f.Entries[0].Published))
+	})
+
+	t.Run("Parse Atom feed", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := feed.Parse([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <link rel="self" href="https://example.com/feed.xml"/>
+  <link href="https://example.com"/>
+  <entry>
+    <id>urn:1</id>
+    <title>First post</title>
+    <link rel="alternate" href="https://example.com/1"/>
+    <content>Hello</content>
+    <published>2024-01-02T15:04:05Z</published>
+    <updated>2024-01-03T15:04:05Z</updated>
+  </entry>
+</feed>`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Example", f.Title)
+		assert.Equal(t, "https://example.com", f.Link)
+		require.Len(t, f.Entries, 1)
+		assert.Equal(t, "urn:1", f.Entries[0].ID)
+		assert.Equal(t, "https://example.com/1", f.Entries[0].Link)
+		assert.Equal(t, "Hello", f.Entries[0].Summary)
+		assert.Equal(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), f.Entries[0].Published)
+		assert.Equal(t, time.Date(2024, 1, 3, 15, 4, 5, 0, time.UTC), f.Entries[0].Updated)
+	})
+
+	t.Run("Reject unknown documents", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := feed.Parse([]byte(`<html></html>`))
+		require.ErrorIs(t, err, feed.ErrInvalidFeed)
+	})
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Example</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	f, err := feed.Fetch(context.Background(), client.NewClient(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "Example", f.Title)
+	assert.Empty(t, f.Entries)
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formContentType is the content type of form-encoded bodies.
+const formContentType = "application/x-www-form-urlencoded"
+
+// FormBody sets the form-encoded values as the body of the request, along with its content type.
+func (rb *Request) FormBody(values url.Values) *Request {
+	rb.body = []byte(values.Encode())
+	rb.header.Set("Content-Type", formContentType)
+	return rb
+}
+
+// FormStruct sets the body of the request to the form encoding of a struct, along with its content type.
+// Fields are named by their "form" tag, such as `form:"name,omitempty"`, or by their name otherwise,
+// and fields tagged with "-" are skipped. Slices produce a value per element.
+func (rb *Request) FormStruct(v interface{}) *Request {
+	rb.marshalBody = v
+	rb.marshalFunc = MarshalForm
+	rb.header.Set("Content-Type", formContentType)
+	return rb
+}
+
+// MarshalForm is a MarshalFunc that form-encodes a struct as described by Request.FormStruct.
+// It also accepts url.Values and maps of strings.
+func MarshalForm(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case url.Values:
+		return []byte(v.Encode()), nil
+	case map[string]string:
+		values := make(url.Values, len(v))
+		for key, value := range v {
+			values.Set(key, value)
+		}
+		return []byte(values.Encode()), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form: cannot encode %T, expected a struct", v)
+	}
+
+	values := make(url.Values)
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		value := rv.Field(i)
+		if opts == "omitempty" && value.IsZero() {
+			continue
+		}
+
+		// Slices produce a value per element, except byte slices
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+			for j := range value.Len() {
+				s, err := formValue(value.Index(j))
+				if err != nil {
+					return nil, fmt.Errorf("form: field %s: %w", field.Name, err)
+				}
+				values.Add(name, s)
+			}
+			continue
+		}
+
+		s, err := formValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("form: field %s: %w", field.Name, err)
+		}
+		values.Add(name, s)
+	}
+
+	return []byte(values.Encode()), nil
+}
+
+// formValue returns the form encoding of a single value.
+func formValue(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch i := v.Interface().(type) {
+	case time.Time:
+		return i.Format(time.RFC3339), nil
+	case encoding.TextMarshaler:
+		text, err := i.MarshalText()
+		return string(text), err
+	case fmt.Stringer:
+		return i.String(), nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		return string(v.Bytes()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
@@ -0,0 +1,94 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormBody(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Encode values", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			FormBody(url.Values{"user": {"jane doe"}, "tag": {"a", "b"}}).
+			DoResponse(context.Background())
+		require.NoError(t, err)
+
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "tag=a&tag=b&user=jane+doe", body)
+		assert.Equal(t, "application/x-www-form-urlencoded", resp.Header("X-Content-Type"))
+	})
+
+	t.Run("Encode structs", func(t *testing.T) {
+		t.Parallel()
+
+		type Form struct {
+			User     string    `form:"user"`
+			Age      int       `form:"age"`
+			Admin    bool      `form:"admin"`
+			Tags     []string  `form:"tag"`
+			Comment  string    `form:"comment,omitempty"`
+			Secret   string    `form:"-"`
+			Since    time.Time `form:"since"`
+			Score    float64
+			internal string
+		}
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			FormStruct(&Form{
+				User:     "jane",
+				Age:      30,
+				Admin:    true,
+				Tags:     []string{"a", "b"},
+				Comment:  "",
+				Secret:   "hidden",
+				Since:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Score:    1.5,
+				internal: "hidden",
+			}).
+			DoResponse(context.Background())
+		require.NoError(t, err)
+
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "Score=1.5&admin=true&age=30&since=2024-01-02T03%3A04%3A05Z&tag=a&tag=b&user=jane", body)
+		assert.Equal(t, "application/x-www-form-urlencoded", resp.Header("X-Content-Type"))
+	})
+
+	t.Run("Reject values that are not structs", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			FormStruct([]string{"a"}).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrRequestCreation)
+	})
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// GroupStats reports the requests of a Group.
+type GroupStats struct {
+	// Requests is the number of requests started in the group.
+	Requests int
+	// InFlight is the number of requests still running or with a response body not yet closed.
+	InFlight int
+	// Succeeded is the number of requests that returned a response without an error.
+	Succeeded int
+	// Failed is the number of requests that returned an error, including canceled requests.
+	Failed int
+	// Canceled is the number of requests that failed because the group was canceled.
+	Canceled int
+}
+
+// Group runs requests sharing a cancellation, such as all the requests needed to render a page.
+// Canceling the group aborts every request in flight, including the reading of response bodies,
+// and fails the requests started afterwards with ErrGroupCanceled.
+type Group struct {
+	client *Client
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	stats  GroupStats
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewGroup creates a new Group of requests sent by the client. The group is canceled when ctx is.
+func (c *Client) NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{
+		client: c,
+		ctx:    ctx,
+		cancel: cancel,
+		stats:  GroupStats{Requests: 0, InFlight: 0, Succeeded: 0, Failed: 0, Canceled: 0},
+		wg:     sync.WaitGroup{},
+		mu:     sync.Mutex{},
+	}
+}
+
+// NewRequest creates a new Request belonging to the group.
+func (g *Group) NewRequest() *Request {
+	rb := g.client.NewRequest()
+	rb.group = g
+	return rb
+}
+
+// Do executes the request as a member of the group.
+func (g *Group) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return g.run(ctx, func(ctx context.Context) (*http.Response, error) {
+		return g.client.Do(ctx, req)
+	})
+}
+
+// Context returns the context of the group, done once the group is canceled.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Cancel aborts the requests in flight and fails the requests started afterwards.
+func (g *Group) Cancel() {
+	g.cancel(errors.ErrGroupCanceled)
+}
+
+// Wait blocks until no request of the group is in flight, that is until every request has
+// returned and every response body has been closed.
+func (g *Group) Wait() {
+	g.wg.Wait()
+}
+
+// Stats returns the statistics of the requests of the group.
+func (g *Group) Stats() GroupStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.stats
+}
+
+// groupReleaseKey is the context key used to store the function releasing a group member.
+type groupReleaseKey struct{}
+
+// run executes fn with a context canceled when either ctx or the group is, keeping the request
+// in flight until the response body returned by the client is closed, even if the request
+// replaces it once decoded.
+func (g *Group) run(ctx context.Context, fn func(context.Context) (*http.Response, error)) (*http.Response, error) {
+	g.mu.Lock()
+	g.stats.Requests++
+	if err := g.ctx.Err(); err != nil {
+		g.stats.Failed++
+		g.stats.Canceled++
+		g.mu.Unlock()
+		return nil, canceledError(context.Cause(g.ctx))
+	}
+	g.stats.InFlight++
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(g.ctx, func() {
+		cancel(context.Cause(g.ctx))
+	})
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			stop()
+			cancel(nil)
+
+			g.mu.Lock()
+			g.stats.InFlight--
+			g.mu.Unlock()
+			g.wg.Done()
+		})
+	}
+
+	resp, err := fn(context.WithValue(ctx, groupReleaseKey{}, release))
+
+	g.mu.Lock()
+	switch {
+	case err == nil:
+		g.stats.Succeeded++
+	case g.ctx.Err() != nil:
+		g.stats.Failed++
+		g.stats.Canceled++
+		err = canceledError(err)
+	default:
+		g.stats.Failed++
+	}
+	g.mu.Unlock()
+
+	// The client releases the member when the body it returned is closed
+	if resp == nil || resp.Body == nil {
+		release()
+	}
+	return resp, err
+}
+
+// canceledError marks err as caused by the cancellation of the group.
+func canceledError(err error) error {
+	if errors.Is(err, errors.ErrGroupCanceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errors.ErrGroupCanceled, err)
+}
+
+// releaseOnClose is a response body that releases its group member when closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the body and releases the group member.
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,131 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Aggregate the stats of the requests", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		t.Cleanup(server.Close)
+
+		group := NewTestClient().NewGroup(context.Background())
+
+		resp, err := group.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, group.Stats().InFlight)
+		resp.Body.Close()
+
+		_, err = group.NewRequest().URL("://invalid").Do(context.Background())
+		require.Error(t, err)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err = group.Do(context.Background(), req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		group.Wait()
+		assert.Equal(t, client.GroupStats{Requests: 3, InFlight: 0, Succeeded: 2, Failed: 1, Canceled: 0}, group.Stats())
+	})
+
+	t.Run("Release members decoding a result", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"axonet"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		group := NewTestClient().NewGroup(context.Background())
+
+		var result map[string]string
+		_, err := group.NewRequest().URL(server.URL).Result(&result).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "axonet", result["name"])
+
+		// The body was read and closed to decode the result, so the member is released
+		group.Wait()
+		assert.Equal(t, 0, group.Stats().InFlight)
+	})
+
+	t.Run("Cancel the requests in flight", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{}, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			<-r.Context().Done()
+		}))
+		t.Cleanup(server.Close)
+
+		group := NewTestClient().NewGroup(context.Background())
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = group.NewRequest().URL(server.URL).Do(context.Background())
+			}()
+		}
+		<-started
+		<-started
+
+		group.Cancel()
+		wg.Wait()
+		for _, err := range errs {
+			require.ErrorIs(t, err, errors.ErrGroupCanceled)
+		}
+		require.ErrorIs(t, group.Context().Err(), context.Canceled)
+
+		// Requests started after the cancellation fail immediately
+		_, err := group.NewRequest().URL(server.URL).Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrGroupCanceled)
+
+		group.Wait()
+		assert.Equal(t, client.GroupStats{Requests: 3, InFlight: 0, Succeeded: 0, Failed: 3, Canceled: 3}, group.Stats())
+	})
+
+	t.Run("Cancel the reading of response bodies", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("partial"))
+			w.(http.Flusher).Flush() //nolint:forcetypeassert
+			<-r.Context().Done()
+		}))
+		t.Cleanup(server.Close)
+
+		parent, cancel := context.WithCancel(context.Background())
+		group := NewTestClient().NewGroup(parent)
+
+		resp, err := group.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Canceling the parent context cancels the group
+		time.AfterFunc(50*time.Millisecond, cancel)
+		buf := make([]byte, 64)
+		for err == nil {
+			_, err = resp.Body.Read(buf)
+		}
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
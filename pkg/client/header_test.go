@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/textproto"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawHeader(t *testing.T) {
+	t.Parallel()
+
+	// The server reads the raw request, as http.Server canonicalizes header names
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := textproto.NewReader(bufio.NewReader(conn))
+		var head []string
+		for {
+			line, err := reader.ReadLine()
+			if err != nil || line == "" {
+				break
+			}
+			head = append(head, line)
+		}
+		lines <- head
+
+		_, _ = conn.Write([]byte("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n"))
+	}()
+
+	c, err := client.NewClientE(client.WithHTTP1())
+	require.NoError(t, err)
+
+	resp, err := c.NewRequest().
+		Method(http.MethodPost).
+		URL("http://"+listener.Addr().String()).
+		RawHeader("SOAPAction", "urn:example#Get").
+		RawHeader("X-EBAY-API-SITEID", "0").
+		Header("content-type", "text/xml").
+		Do(context.Background())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	head := <-lines
+	assert.Contains(t, head, "SOAPAction: urn:example#Get")
+	assert.Contains(t, head, "X-EBAY-API-SITEID: 0")
+	assert.Contains(t, head, "Content-Type: text/xml")
+}
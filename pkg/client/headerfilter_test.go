@@ -0,0 +1,52 @@
+package client_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderFilter(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{
+		"Content-Type":          {"application/json"},
+		"Set-Cookie":            {"session=secret", "tracking=1"},
+		"X-Api-Key":             {"key"},
+		"X-Ratelimit-Remaining": {"10"},
+		"X-Request-Id":          {"abc"},
+	}
+
+	t.Run("Drop sensitive headers by default", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.Header{
+			"Content-Type":          {"application/json"},
+			"X-Ratelimit-Remaining": {"10"},
+			"X-Request-Id":          {"abc"},
+		}, middleware.NewHeaderFilter().Filter(header))
+
+		// The original header is left untouched
+		assert.Len(t, header["Set-Cookie"], 2)
+		assert.Nil(t, middleware.NewHeaderFilter().Filter(nil))
+	})
+
+	t.Run("Configure the rules", func(t *testing.T) {
+		t.Parallel()
+
+		filter := middleware.NewHeaderFilter().Allow("content-type", "X-RateLimit-*", "Set-Cookie")
+		assert.Equal(t, http.Header{
+			"Content-Type":          {"application/json"},
+			"X-Ratelimit-Remaining": {"10"},
+		}, filter.Filter(header))
+
+		filter = middleware.NewHeaderFilter().Drop("X-Request-Id")
+		assert.Equal(t, []string{"session=secret", "tracking=1"}, filter.Filter(header)["Set-Cookie"])
+		assert.NotContains(t, filter.Filter(header), "X-Request-Id")
+
+		// Allowing no header keeps every header again
+		assert.Len(t, filter.Allow("Content-Type").Allow().Filter(header), 4)
+	})
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// Hooks are functions called at well-defined points of the requests of a Client, to add
+// instrumentation without writing a middleware. Nil hooks are skipped, and hooks must not block.
+type Hooks struct {
+	// OnRequest is called before the request enters the middleware chain.
+	OnRequest func(ctx context.Context, req *http.Request)
+	// OnResponse is called once the chain returns a response, before its body is read.
+	OnResponse func(ctx context.Context, req *http.Request, resp *http.Response, elapsed time.Duration)
+	// OnError is called once the chain returns an error.
+	OnError func(ctx context.Context, req *http.Request, err error, elapsed time.Duration)
+	// OnRetry is called before the retry middleware retries a failed attempt.
+	OnRetry func(ctx context.Context, req *http.Request, attempt int, err error, delay time.Duration)
+	// OnCacheHit is called when a cache middleware serves the response.
+	OnCacheHit func(ctx context.Context, req *http.Request)
+	// OnCacheMiss is called when a cache middleware has no usable response for the request.
+	OnCacheMiss func(ctx context.Context, req *http.Request)
+	// OnEvent is called with every event emitted by the chain and middleware with events.Publish,
+	// including those of the hooks above.
+	OnEvent func(ctx context.Context, req *http.Request, name string, fields []logger.Field)
+}
+
+// WithHooks adds hooks called during the requests of the Client. Hooks added by several calls
+// are called in the order they were added.
+func WithHooks(hooks Hooks) Option {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, hooks)
+	}
+}
+
+// runHooks calls the request hooks, then processes the request with fn, reporting the events of
+// middleware and the outcome to the hooks.
+func (c *Client) runHooks(ctx context.Context, req *http.Request, fn func(ctx context.Context) (*http.Response, error)) (*http.Response, error) {
+	if len(c.hooks) == 0 {
+		return fn(ctx)
+	}
+
+	for _, hooks := range c.hooks {
+		if hooks.OnRequest != nil {
+			hooks.OnRequest(ctx, req)
+		}
+	}
+
+	start := time.Now()
+	resp, err := fn(middleware.WithEventFunc(ctx, func(name string, fields ...logger.Field) {
+		for _, hooks := range c.hooks {
+			hooks.event(ctx, req, name, fields)
+		}
+	}))
+	elapsed := time.Since(start)
+
+	for _, hooks := range c.hooks {
+		switch {
+		case err != nil && hooks.OnError != nil:
+			hooks.OnError(ctx, req, err, elapsed)
+		case err == nil && hooks.OnResponse != nil:
+			hooks.OnResponse(ctx, req, resp, elapsed)
+		}
+	}
+	return resp, err
+}
+
+// event calls the hooks of an event reported by middleware.
+func (h Hooks) event(ctx context.Context, req *http.Request, name string, fields []logger.Field) {
+	switch {
+	case name == middleware.EventRetry && h.OnRetry != nil:
+		var (
+			attempt int
+			err     error
+			delay   time.Duration
+		)
+		for _, field := range fields {
+			switch field.Key {
+			case "attempt":
+				attempt, _ = field.Value.(int)
+			case "error":
+				err, _ = field.Value.(error)
+			case "retry_in":
+				delay, _ = field.Value.(time.Duration)
+			}
+		}
+		h.OnRetry(ctx, req, attempt, err, delay)
+	case name == middleware.EventCacheHit && h.OnCacheHit != nil:
+		h.OnCacheHit(ctx, req)
+	case name == middleware.EventCacheMiss && h.OnCacheMiss != nil:
+		h.OnCacheMiss(ctx, req)
+	}
+
+	if h.OnEvent != nil {
+		h.OnEvent(ctx, req, name, fields)
+	}
+}
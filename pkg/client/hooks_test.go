@@ -0,0 +1,98 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// EventMiddleware reports a cache miss and a retry before passing the request on.
+type EventMiddleware struct{}
+
+func (m *EventMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	events.Publish(ctx, events.CacheMiss{Key: "k", Reason: ""})
+	events.Publish(ctx, events.RetryScheduled{Attempt: 1, Err: errors.ErrTemporary, Delay: time.Second})
+	return next(ctx, httpClient, req)
+}
+
+func (m *EventMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Call the hooks in order", func(t *testing.T) {
+		t.Parallel()
+
+		var calls []string
+		c := client.NewClient(
+			client.WithMiddleware(&EventMiddleware{}),
+			client.WithHooks(client.Hooks{
+				OnRequest: func(ctx context.Context, req *http.Request) {
+					calls = append(calls, "request "+req.Method)
+				},
+				OnCacheMiss: func(ctx context.Context, req *http.Request) {
+					calls = append(calls, "cache miss")
+				},
+				OnRetry: func(ctx context.Context, req *http.Request, attempt int, err error, delay time.Duration) {
+					assert.ErrorIs(t, err, errors.ErrTemporary)
+					assert.Equal(t, time.Second, delay)
+					calls = append(calls, "retry "+strconv.Itoa(attempt))
+				},
+				OnResponse: func(ctx context.Context, req *http.Request, resp *http.Response, elapsed time.Duration) {
+					assert.Positive(t, elapsed)
+					calls = append(calls, "response "+resp.Status)
+				},
+			}),
+			client.WithHooks(client.Hooks{
+				OnEvent: func(ctx context.Context, req *http.Request, name string, fields []logger.Field) {
+					calls = append(calls, "event "+name)
+				},
+			}),
+		)
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, []string{
+			"request GET",
+			"cache miss", "event cache.miss",
+			"retry 1", "event retry",
+			"response 202 Accepted",
+		}, calls)
+	})
+
+	t.Run("Call the error hook", func(t *testing.T) {
+		t.Parallel()
+
+		var hookErr error
+		c := client.NewClient(client.WithHooks(client.Hooks{
+			OnError: func(ctx context.Context, req *http.Request, err error, elapsed time.Duration) {
+				hookErr = err
+			},
+			OnResponse: func(ctx context.Context, req *http.Request, resp *http.Response, elapsed time.Duration) {
+				t.Error("unexpected response")
+			},
+		}))
+
+		_, err := c.NewRequest().URL("http://127.0.0.1:1").Do(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, err, hookErr)
+	})
+}
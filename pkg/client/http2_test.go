@@ -0,0 +1,114 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TrustMiddleware makes the transport of the client trust the certificate of a test server.
+type TrustMiddleware struct {
+	config *tls.Config
+}
+
+func (m *TrustMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	transport := httpClient.Transport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = m.config
+	return next(ctx, &http.Client{Transport: transport}, req) //nolint:exhaustruct
+}
+
+func (m *TrustMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestHTTP2(t *testing.T) {
+	t.Parallel()
+
+	protoHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+
+	proto := func(t *testing.T, c *client.Client, url string) string {
+		t.Helper()
+
+		resp, err := c.NewRequest().URL(url).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("Use cleartext HTTP/2 with h2c", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewUnstartedServer(protoHandler)
+		server.Config.Protocols = new(http.Protocols)
+		server.Config.Protocols.SetHTTP1(true)
+		server.Config.Protocols.SetUnencryptedHTTP2(true)
+		server.Start()
+		t.Cleanup(server.Close)
+
+		c, err := client.NewClientE(
+			client.WithH2C(),
+			client.WithHTTP2Config(http.HTTP2Config{
+				MaxConcurrentStreams: 10,
+				SendPingTimeout:      time.Second,
+				PingTimeout:          time.Second,
+			}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", proto(t, c, server.URL))
+
+		// Without h2c, cleartext requests use HTTP/1.1
+		assert.Equal(t, "HTTP/1.1", proto(t, NewTestClient(), server.URL))
+	})
+
+	t.Run("Force HTTP/2", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewUnstartedServer(protoHandler)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		t.Cleanup(server.Close)
+
+		trust := &TrustMiddleware{config: server.Client().Transport.(*http.Transport).TLSClientConfig} //nolint:forcetypeassert
+		c, err := client.NewClientE(client.WithHTTP2(), client.WithMiddleware(trust))
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", proto(t, c, server.URL))
+
+		// Servers supporting HTTP/1.1 only are not used
+		http1 := httptest.NewTLSServer(protoHandler)
+		t.Cleanup(http1.Close)
+
+		trust = &TrustMiddleware{config: http1.Client().Transport.(*http.Transport).TLSClientConfig} //nolint:forcetypeassert
+		c, err = client.NewClientE(client.WithHTTP2(), client.WithMiddleware(trust))
+		require.NoError(t, err)
+		_, err = c.NewRequest().URL(http1.URL).Do(context.Background())
+		require.Error(t, err)
+	})
+
+	t.Run("Report HTTP/2 options conflicting with WithHTTP1", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithHTTP1(), client.WithH2C())
+		require.ErrorIs(t, err, errors.ErrOptionConflict)
+		assert.Contains(t, err.Error(), "WithH2C: conflicts with WithHTTP1")
+
+		_, err = client.NewClientE(client.WithHTTP2Config(http.HTTP2Config{}), client.WithHTTP1()) //nolint:exhaustruct
+		require.ErrorIs(t, err, errors.ErrOptionConflict)
+
+		_, err = client.NewClientE(client.WithHTTP2(), client.WithH2C(), client.WithHTTP2Config(http.HTTP2Config{})) //nolint:exhaustruct
+		require.NoError(t, err)
+	})
+}
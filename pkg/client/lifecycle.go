@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// Start starts the middleware of the Client implementing middleware.LifecycleMiddleware that are
+// not started yet, in the order of the chain. NewClient calls it, so it is only needed after Stop.
+// If a middleware fails to start, those started by the call are stopped again, and the error
+// matches errors.ErrLifecycle.
+func (c *Client) Start(ctx context.Context) error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	var started []middleware.LifecycleMiddleware
+	for _, m := range c.middlewareChain.Middlewares() {
+		lm, ok := m.(middleware.LifecycleMiddleware)
+		if !ok || slices.Contains(c.started, lm) {
+			continue
+		}
+
+		if err := lm.Start(ctx); err != nil {
+			return errors.Join(
+				fmt.Errorf("%w: starting %T: %w", errors.ErrLifecycle, m, err),
+				stopMiddleware(ctx, started),
+			)
+		}
+		started = append(started, lm)
+	}
+
+	c.started = append(c.started, started...)
+	return nil
+}
+
+// Stop stops the started lifecycle middleware in reverse order, letting them drain their pending
+// work, such as cache writes, until ctx is done. Requests sent afterwards are still processed,
+// without the background work of the stopped middleware. Errors match errors.ErrLifecycle.
+func (c *Client) Stop(ctx context.Context) error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	started := c.started
+	c.started = nil
+	return stopMiddleware(ctx, started)
+}
+
+// stopMiddleware stops the middleware in reverse order, joining their errors.
+func stopMiddleware(ctx context.Context, started []middleware.LifecycleMiddleware) error {
+	var errs []error
+	for _, m := range slices.Backward(started) {
+		if err := m.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%w: stopping %T: %w", errors.ErrLifecycle, m, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrStartFailed = errors.New("start failed")
+
+// LifecycleMiddleware records its starts and stops in a shared log.
+type LifecycleMiddleware struct {
+	IntrospectMiddleware
+
+	name     string
+	log      *[]string
+	startErr error
+}
+
+func (m *LifecycleMiddleware) Start(_ context.Context) error {
+	*m.log = append(*m.log, "start "+m.name)
+	return m.startErr
+}
+
+func (m *LifecycleMiddleware) Stop(_ context.Context) error {
+	*m.log = append(*m.log, "stop "+m.name)
+	return nil
+}
+
+// InnerLifecycleMiddleware is a second lifecycle middleware type, as a chain holds one middleware per type.
+type InnerLifecycleMiddleware struct{ LifecycleMiddleware }
+
+func TestLifecycle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Start middleware on creation and stop them in reverse order", func(t *testing.T) {
+		t.Parallel()
+
+		var log []string
+		c := client.NewClient(
+			client.WithMiddleware(&LifecycleMiddleware{name: "outer", log: &log}),
+			client.WithMiddleware(&IntrospectMiddleware{}),
+			client.WithMiddleware(&InnerLifecycleMiddleware{LifecycleMiddleware{name: "inner", log: &log}}),
+		)
+		require.NoError(t, c.Err())
+		assert.Equal(t, []string{"start outer", "start inner"}, log)
+
+		require.NoError(t, c.Stop(context.Background()))
+		require.NoError(t, c.Stop(context.Background()))
+		assert.Equal(t, []string{"start outer", "start inner", "stop inner", "stop outer"}, log)
+
+		// Stopped middleware are started again
+		require.NoError(t, c.Start(context.Background()))
+		require.NoError(t, c.Start(context.Background()))
+		assert.Len(t, log, 6)
+	})
+
+	t.Run("Stop started middleware when one fails to start", func(t *testing.T) {
+		t.Parallel()
+
+		var log []string
+		c := client.NewClient(
+			client.WithMiddleware(&LifecycleMiddleware{name: "outer", log: &log}),
+			client.WithMiddleware(&InnerLifecycleMiddleware{LifecycleMiddleware{name: "inner", log: &log, startErr: ErrStartFailed}}),
+		)
+		require.ErrorIs(t, c.Err(), clientErrors.ErrLifecycle)
+		require.ErrorIs(t, c.Err(), ErrStartFailed)
+		assert.Equal(t, []string{"start outer", "start inner", "stop outer"}, log)
+
+		require.NoError(t, c.Stop(context.Background()))
+		assert.Len(t, log, 3)
+	})
+}
@@ -0,0 +1,109 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("a", 100)
+
+	// The server streams the body without a Content-Length, unless asked to declare it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("declared") {
+			w.Header().Set("Content-Length", "100")
+		} else {
+			w.(http.Flusher).Flush()
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Read a body within the limit", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient(client.WithMaxResponseBytes(100)).NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(b))
+	})
+
+	t.Run("Fail reading beyond the limit", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient(client.WithMaxResponseBytes(10)).NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+		assert.Len(t, b, 10)
+
+		var tooLarge *errors.ResponseTooLargeError
+		require.ErrorAs(t, err, &tooLarge)
+		assert.Equal(t, int64(10), tooLarge.Limit)
+	})
+
+	t.Run("Fail before unmarshaling", func(t *testing.T) {
+		t.Parallel()
+
+		var result string
+		_, err := NewTestClient(client.WithMaxResponseBytes(10)).NewRequest().
+			URL(server.URL).
+			Result(&result).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+	})
+
+	t.Run("Fail immediately on a declared length", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient(client.WithMaxResponseBytes(10)).NewRequest().
+			URL(server.URL + "?declared").
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("Override the limit per request", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithMaxResponseBytes(10))
+
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background(), client.WithResponseLimit(0))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Len(t, b, 100)
+
+		_, err = NewTestClient().NewRequest().
+			URL(server.URL + "?declared").
+			MaxResponseBytes(50).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+	})
+
+	t.Run("Reject a negative limit", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithMaxResponseBytes(-1))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Entry is a log entry captured by a RecordingLogger.
+type Entry struct {
+	Level   string
+	Message string
+	Fields  map[string]interface{}
+}
+
+// recording holds the entries shared by a RecordingLogger and the loggers derived from it.
+type recording struct {
+	entries []Entry
+	mu      sync.Mutex
+}
+
+// RecordingLogger is a logger that keeps every entry in memory, so tests can assert the
+// messages and fields emitted by the client and its middleware.
+type RecordingLogger struct {
+	recording *recording
+	fields    []Field
+}
+
+// NewRecordingLogger creates a new RecordingLogger instance.
+func NewRecordingLogger() *RecordingLogger {
+	return &RecordingLogger{
+		recording: &recording{
+			entries: nil,
+			mu:      sync.Mutex{},
+		},
+		fields: nil,
+	}
+}
+
+// Entries returns the entries logged so far, in order.
+func (l *RecordingLogger) Entries() []Entry {
+	l.recording.mu.Lock()
+	defer l.recording.mu.Unlock()
+
+	return slices.Clone(l.recording.entries)
+}
+
+// Find returns the entries logged so far with the message.
+func (l *RecordingLogger) Find(msg string) []Entry {
+	var entries []Entry
+	for _, entry := range l.Entries() {
+		if entry.Message == msg {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (l *RecordingLogger) log(level, msg string) {
+	fields := make(map[string]interface{}, len(l.fields))
+	for _, f := range l.fields {
+		fields[f.Key] = f.Value
+	}
+
+	l.recording.mu.Lock()
+	defer l.recording.mu.Unlock()
+
+	l.recording.entries = append(l.recording.entries, Entry{Level: level, Message: msg, Fields: fields})
+}
+
+func (l *RecordingLogger) Debug(msg string) { l.log("DEBUG", msg) }
+func (l *RecordingLogger) Info(msg string)  { l.log("INFO", msg) }
+func (l *RecordingLogger) Warn(msg string)  { l.log("WARN", msg) }
+func (l *RecordingLogger) Error(msg string) { l.log("ERROR", msg) }
+func (l *RecordingLogger) Debugf(format string, args ...interface{}) {
+	l.log("DEBUG", fmt.Sprintf(format, args...))
+}
+func (l *RecordingLogger) Infof(format string, args ...interface{}) {
+	l.log("INFO", fmt.Sprintf(format, args...))
+}
+func (l *RecordingLogger) Warnf(format string, args ...interface{}) {
+	l.log("WARN", fmt.Sprintf(format, args...))
+}
+func (l *RecordingLogger) Errorf(format string, args ...interface{}) {
+	l.log("ERROR", fmt.Sprintf(format, args...))
+}
+
+func (l *RecordingLogger) WithFields(fields ...Field) Logger {
+	return &RecordingLogger{
+		recording: l.recording,
+		fields:    append(slices.Clone(l.fields), fields...),
+	}
+}
@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values masked by a Redactor by default.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedHeaders are the headers whose values are redacted by default.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// DefaultRedactedParams are the query parameters whose values are redacted by default.
+var DefaultRedactedParams = []string{
+	"access_token", "api_key", "apikey", "client_secret", "password", "secret", "signature", "token",
+}
+
+// Redactor masks credentials in log messages and fields: the values of fields named after a
+// redacted header or query parameter, the redacted query parameters of URLs, the values of
+// redacted headers written as "Name: value", such as in curl commands, and URL passwords.
+type Redactor struct {
+	headers      map[string]struct{}
+	params       map[string]struct{}
+	headerValues *regexp.Regexp
+	paramValues  *regexp.Regexp
+	mask         func(value string) string
+	mu           sync.RWMutex
+}
+
+// userinfo matches the password of URLs.
+var userinfo = regexp.MustCompile(`(://[^/@:\s]*):[^/@\s]*@`)
+
+// NewRedactor creates a new Redactor redacting the DefaultRedactedHeaders and
+// DefaultRedactedParams, replacing their values with Redacted.
+func NewRedactor() *Redactor {
+	r := &Redactor{
+		headers:      nil,
+		params:       nil,
+		headerValues: nil,
+		paramValues:  nil,
+		mask:         func(string) string { return Redacted },
+		mu:           sync.RWMutex{},
+	}
+	r.SetHeaders(DefaultRedactedHeaders...)
+	r.SetParams(DefaultRedactedParams...)
+	return r
+}
+
+// SetHeaders sets the headers whose values are redacted, replacing the defaults.
+func (r *Redactor) SetHeaders(headers ...string) {
+	set := make(map[string]struct{}, len(headers))
+	quoted := make([]string, 0, len(headers))
+	for _, header := range headers {
+		set[textproto.CanonicalMIMEHeaderKey(header)] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(header))
+	}
+
+	var pattern *regexp.Regexp
+	if len(headers) > 0 {
+		pattern = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)(\s*:\s*)([^'"\r\n]*)`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.headers = set
+	r.headerValues = pattern
+}
+
+// SetParams sets the query parameters whose values are redacted, replacing the defaults.
+// Parameter names are case-sensitive, as in URLs.
+func (r *Redactor) SetParams(params ...string) {
+	set := make(map[string]struct{}, len(params))
+	quoted := make([]string, 0, len(params))
+	for _, param := range params {
+		set[param] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(url.QueryEscape(param)))
+	}
+
+	var pattern *regexp.Regexp
+	if len(params) > 0 {
+		pattern = regexp.MustCompile(`([?&;])(` + strings.Join(quoted, "|") + `)=([^&;#\s'"]*)`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.params = set
+	r.paramValues = pattern
+}
+
+// SetMask sets the function masking redacted values, such as to keep the last characters of
+// tokens to tell them apart. Defaults to replacing values with Redacted.
+func (r *Redactor) SetMask(mask func(value string) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mask = mask
+}
+
+// KeepLast returns a mask keeping the last n characters of values longer than 2n characters.
+func KeepLast(n int) func(value string) string {
+	return func(value string) string {
+		if len(value) <= 2*n {
+			return Redacted
+		}
+		return Redacted + value[len(value)-n:]
+	}
+}
+
+// String returns the string with its credentials masked.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.redact(s)
+}
+
+// Field returns the field with its credentials masked. String, URL, error and header values are
+// redacted, and the whole value of fields named after a redacted header or query parameter is
+// masked.
+func (r *Redactor) Field(field Field) Field {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, header := r.headers[textproto.CanonicalMIMEHeaderKey(field.Key)]
+	_, param := r.params[field.Key]
+	sensitive := header || param
+
+	switch value := field.Value.(type) {
+	case string:
+		if sensitive {
+			return Field{Key: field.Key, Value: r.maskValue(value)}
+		}
+		return Field{Key: field.Key, Value: r.redact(value)}
+	case *url.URL:
+		if value != nil {
+			return Field{Key: field.Key, Value: r.redact(value.String())}
+		}
+	case error:
+		if value != nil {
+			return Field{Key: field.Key, Value: r.redact(value.Error())}
+		}
+	case http.Header:
+		redacted := make(http.Header, len(value))
+		for key, values := range value {
+			_, ok := r.headers[textproto.CanonicalMIMEHeaderKey(key)]
+			for _, v := range values {
+				if ok {
+					v = r.maskValue(v)
+				}
+				redacted[key] = append(redacted[key], v)
+			}
+		}
+		return Field{Key: field.Key, Value: redacted}
+	default:
+		if sensitive {
+			return Field{Key: field.Key, Value: r.maskValue(fmt.Sprint(value))}
+		}
+	}
+	return field
+}
+
+// maskValue masks the value, leaving values already redacted, such as by the dump middleware, as
+// they are. It must be called with the read lock held.
+func (r *Redactor) maskValue(value string) string {
+	if strings.HasPrefix(value, Redacted) {
+		return value
+	}
+	return r.mask(value)
+}
+
+// redact masks the credentials in the string. It must be called with the read lock held.
+func (r *Redactor) redact(s string) string {
+	if r.paramValues != nil {
+		s = r.paramValues.ReplaceAllStringFunc(s, func(match string) string {
+			groups := r.paramValues.FindStringSubmatch(match)
+			return groups[1] + groups[2] + "=" + r.maskValue(groups[3])
+		})
+	}
+	if r.headerValues != nil {
+		s = r.headerValues.ReplaceAllStringFunc(s, func(match string) string {
+			groups := r.headerValues.FindStringSubmatch(match)
+			return groups[1] + groups[2] + r.maskValue(groups[3])
+		})
+	}
+	return userinfo.ReplaceAllString(s, "${1}:xxxxx@")
+}
+
+// redactingLogger masks the credentials in the messages and fields of another logger.
+type redactingLogger struct {
+	logger   Logger
+	redactor *Redactor
+}
+
+// NewRedactingLogger returns a logger masking the credentials in the messages and fields logged
+// with the redactor before passing them to the logger.
+func NewRedactingLogger(l Logger, r *Redactor) Logger {
+	if existing, ok := l.(*redactingLogger); ok {
+		l = existing.logger
+	}
+	return &redactingLogger{logger: l, redactor: r}
+}
+
+func (l *redactingLogger) Debug(msg string) { l.logger.Debug(l.redactor.String(msg)) }
+func (l *redactingLogger) Info(msg string)  { l.logger.Info(l.redactor.String(msg)) }
+func (l *redactingLogger) Warn(msg string)  { l.logger.Warn(l.redactor.String(msg)) }
+func (l *redactingLogger) Error(msg string) { l.logger.Error(l.redactor.String(msg)) }
+
+func (l *redactingLogger) Debugf(format string, args ...interface{}) {
+	l.Debug(fmt.Sprintf(format, args...))
+}
+
+func (l *redactingLogger) Infof(format string, args ...interface{}) {
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+func (l *redactingLogger) Warnf(format string, args ...interface{}) {
+	l.Warn(fmt.Sprintf(format, args...))
+}
+
+func (l *redactingLogger) Errorf(format string, args ...interface{}) {
+	l.Error(fmt.Sprintf(format, args...))
+}
+
+func (l *redactingLogger) WithFields(fields ...Field) Logger {
+	redacted := make([]Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = l.redactor.Field(field)
+	}
+	return &redactingLogger{logger: l.logger.WithFields(redacted...), redactor: l.redactor}
+}
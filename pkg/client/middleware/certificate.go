@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// certificateKey identifies a transport by the transport it was cloned from and the leaf of the
+// client certificate it presents.
+type certificateKey struct {
+	base *http.Transport
+	leaf [sha256.Size]byte
+}
+
+// certificateTransports keeps one transport per client certificate, so connections presenting a
+// certificate are reused by requests selecting the same certificate and never by others.
+type certificateTransports struct {
+	transports map[certificateKey]*http.Transport
+	mu         sync.Mutex
+}
+
+// newCertificateTransports creates a new certificateTransports instance.
+func newCertificateTransports() *certificateTransports {
+	return &certificateTransports{
+		transports: make(map[certificateKey]*http.Transport),
+		mu:         sync.Mutex{},
+	}
+}
+
+// client returns a copy of httpClient whose transport presents the client certificate selected for
+// the request, or httpClient itself if no certificate is selected. It fails with
+// errors.ErrClientCertificate if the transport cannot be cloned to present the certificate.
+func (t *certificateTransports) client(ctx context.Context, httpClient *http.Client) (*http.Client, error) {
+	cert, ok := ClientCertificateFromContext(ctx)
+	if !ok || len(cert.Certificate) == 0 {
+		return httpClient, nil
+	}
+
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	base, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("%w: transport %T cannot be cloned", errors.ErrClientCertificate, transport)
+	}
+
+	clone := *httpClient
+	clone.Transport = t.get(base, cert)
+	return &clone, nil
+}
+
+// get returns the transport presenting the certificate, cloning the base transport on first use.
+func (t *certificateTransports) get(base *http.Transport, cert *tls.Certificate) *http.Transport {
+	key := certificateKey{base: base, leaf: sha256.Sum256(cert.Certificate[0])}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if transport, ok := t.transports[key]; ok {
+		return transport
+	}
+
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{} //nolint:exhaustruct,gosec
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	transport.TLSClientConfig.GetClientCertificate = nil
+
+	t.transports[key] = transport
+	return transport
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// Class is the outcome of a request as seen by the middleware that track the health of upstreams
+// or retry requests, such as the circuit breaker, retry and error budget middleware.
+type Class int
+
+const (
+	// ClassSuccess is a request that succeeded.
+	ClassSuccess Class = iota
+	// ClassClientError is a request rejected by a healthy upstream, which is not worth retrying.
+	ClassClientError
+	// ClassFailure is a temporary failure of the upstream, which is worth retrying.
+	ClassFailure
+	// ClassPermanentFailure is a failure that is not worth retrying.
+	ClassPermanentFailure
+)
+
+// String returns the name of the class.
+func (c Class) String() string {
+	switch c {
+	case ClassSuccess:
+		return "success"
+	case ClassClientError:
+		return "client_error"
+	case ClassFailure:
+		return "failure"
+	case ClassPermanentFailure:
+		return "permanent_failure"
+	default:
+		return "unknown"
+	}
+}
+
+// Healthy reports whether the outcome leaves the upstream healthy.
+func (c Class) Healthy() bool {
+	return c == ClassSuccess || c == ClassClientError
+}
+
+// Retryable reports whether the request is worth retrying.
+func (c Class) Retryable() bool {
+	return c == ClassFailure
+}
+
+// Classifier classifies the response and error returned for a request. The response may be nil.
+type Classifier func(resp *http.Response, err error) Class
+
+// DefaultClassifier classifies 5xx and 429 Too Many Requests responses and temporary errors as
+// failures, other 4xx responses and requests rejected with errors.ErrOverloaded or
+// errors.ErrMaintenance as client errors, and other errors as permanent failures.
+// The status of an *errors.HTTPError is classified like the status of a response.
+func DefaultClassifier(resp *http.Response, err error) Class {
+	if resp != nil {
+		if class, ok := classifyStatus(resp.StatusCode); ok {
+			return class
+		}
+	}
+
+	if err == nil {
+		return ClassSuccess
+	}
+
+	// Requests rejected by a local limiter or during planned maintenance say nothing about the
+	// health of the upstream
+	if errors.Is(err, errors.ErrOverloaded) || errors.Is(err, errors.ErrMaintenance) {
+		return ClassClientError
+	}
+
+	var httpErr *errors.HTTPError
+	if errors.As(err, &httpErr) {
+		if class, ok := classifyStatus(httpErr.StatusCode); ok {
+			return class
+		}
+	}
+	if errors.IsTemporary(err) {
+		return ClassFailure
+	}
+	return ClassPermanentFailure
+}
+
+// classifyStatus classifies an error status, reporting false for other statuses.
+func classifyStatus(status int) (Class, bool) {
+	switch {
+	case status >= http.StatusInternalServerError, status == http.StatusTooManyRequests:
+		return ClassFailure, true
+	case status >= http.StatusBadRequest:
+		return ClassClientError, true
+	default:
+		return 0, false
+	}
+}
+
+// classifierKey is the context key used to store the classifier of the chain processing a request.
+type classifierKey struct{}
+
+// Classify classifies the outcome of a request with the classifier of the chain processing it,
+// or with DefaultClassifier if there is none.
+func Classify(ctx context.Context, resp *http.Response, err error) Class {
+	if classifier, ok := ctx.Value(classifierKey{}).(Classifier); ok && classifier != nil {
+		return classifier(resp, err)
+	}
+	return DefaultClassifier(resp, err)
+}
+
+// SetClassifier sets the classifier shared by the middleware of the chain, so that they agree on
+// what counts as a failure. A nil classifier restores DefaultClassifier.
+func (c *Chain) SetClassifier(classifier Classifier) {
+	c.classifier = classifier
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+)
+
+// cleanupKey is the context key used to store the cleanups of a request.
+type cleanupKey struct{}
+
+// cleanup is a cleanup callback running at most once.
+type cleanup struct {
+	fn   func()
+	once sync.Once
+}
+
+// run runs the callback unless it already ran.
+func (c *cleanup) run() {
+	c.once.Do(c.fn)
+}
+
+// cleanupStack holds the cleanups registered while a request runs through the chain.
+type cleanupStack struct {
+	cleanups []*cleanup
+	done     bool
+	mu       sync.Mutex
+}
+
+// OnCleanup registers fn to run once the request processed with ctx is over, for resources held
+// past the return of the middleware, such as a checked-out identity or a semaphore slot held until
+// the response is read. The chain guarantees fn runs exactly once, even when outer middleware
+// short-circuits or panics:
+//   - if the chain returns an error or panics, before Process returns or the panic propagates;
+//   - if the chain returns a response, when its body is closed.
+//
+// Cleanups run in the reverse order of their registration. The returned function runs fn early,
+// such as once the middleware no longer needs the resource. Outside of a chain, or once the request
+// is over, fn is not registered and the caller must run the returned function itself.
+func OnCleanup(ctx context.Context, fn func()) func() {
+	c := &cleanup{fn: fn, once: sync.Once{}}
+
+	stack, ok := ctx.Value(cleanupKey{}).(*cleanupStack)
+	if !ok {
+		return c.run
+	}
+
+	stack.mu.Lock()
+	defer stack.mu.Unlock()
+
+	if !stack.done {
+		stack.cleanups = append(stack.cleanups, c)
+	}
+	return c.run
+}
+
+// empty reports whether no cleanup is registered.
+func (s *cleanupStack) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.cleanups) == 0
+}
+
+// runCleanups runs the cleanups of the request that did not run yet. A panicking cleanup is
+// logged and does not prevent the others from running.
+func (c *Chain) runCleanups(stack *cleanupStack) {
+	stack.mu.Lock()
+	cleanups := stack.cleanups
+	stack.cleanups = nil
+	stack.done = true
+	stack.mu.Unlock()
+
+	for _, cleanup := range slices.Backward(cleanups) {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					c.logger.WithFields(logger.String("panic", fmt.Sprint(r))).Error("Cleanup panicked")
+				}
+			}()
+			cleanup.run()
+		}()
+	}
+}
+
+// cleanupBody is a response body running the cleanups of its request when closed.
+type cleanupBody struct {
+	io.ReadCloser
+	close func()
+	once  sync.Once
+}
+
+// Close closes the body and runs the cleanups.
+func (b *cleanupBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.close)
+	return err
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+
+	"github.com/jaxron/axonet/pkg/client/dns"
+	"github.com/jaxron/axonet/pkg/client/events"
+	"github.com/jaxron/axonet/pkg/client/logger"
+)
+
+// attemptKey is the context key used to store the current retry attempt.
+type attemptKey struct{}
+
+// WithAttempt returns a copy of ctx carrying the given retry attempt number.
+// Attempts are numbered starting at 1.
+func WithAttempt(ctx context.Context, attempt uint64) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the retry attempt stored in ctx.
+// It returns 0 if the request is not being processed by a retrying middleware.
+func AttemptFromContext(ctx context.Context) uint64 {
+	if attempt, ok := ctx.Value(attemptKey{}).(uint64); ok {
+		return attempt
+	}
+	return 0
+}
+
+// Priority indicates how important a request is relative to other requests.
+// Middleware that shed or queue traffic use it to protect critical requests.
+type Priority int
+
+const (
+	// PriorityLow is used for background work that may be dropped under pressure.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the default priority for requests.
+	PriorityNormal
+	// PriorityHigh is used for requests that should be favored over normal traffic.
+	PriorityHigh
+	// PriorityCritical is used for requests that must never be shed.
+	PriorityCritical
+)
+
+// priorityKey is the context key used to store the request priority.
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx carrying the given request priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the request priority stored in ctx.
+// It returns PriorityNormal if no priority is set.
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// maxResponseBytesKey is the context key used to store the size limit of response bodies.
+type maxResponseBytesKey struct{}
+
+// WithMaxResponseBytes returns a copy of ctx limiting response bodies to n bytes.
+// A limit of zero or less disables the limit.
+func WithMaxResponseBytes(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxResponseBytesKey{}, n)
+}
+
+// MaxResponseBytesFromContext returns the size limit of response bodies stored in ctx, if any.
+func MaxResponseBytesFromContext(ctx context.Context) (int64, bool) {
+	n, ok := ctx.Value(maxResponseBytesKey{}).(int64)
+	return n, ok
+}
+
+// clientCertificateKey is the context key used to store the client certificate of a request.
+type clientCertificateKey struct{}
+
+// WithClientCertificate returns a copy of ctx presenting the certificate to servers requesting
+// a client certificate (mTLS), overriding the certificates of the transport.
+func WithClientCertificate(ctx context.Context, cert *tls.Certificate) context.Context {
+	return context.WithValue(ctx, clientCertificateKey{}, cert)
+}
+
+// ClientCertificateFromContext returns the client certificate stored in ctx, if any.
+func ClientCertificateFromContext(ctx context.Context) (*tls.Certificate, bool) {
+	cert, ok := ctx.Value(clientCertificateKey{}).(*tls.Certificate)
+	return cert, ok && cert != nil
+}
+
+// cookieJarKey is the context key used to store the cookie jar of a request.
+type cookieJarKey struct{}
+
+// cookieJar wraps the cookie jar of a request, so a nil jar can be stored.
+type cookieJar struct {
+	jar http.CookieJar
+}
+
+// WithCookieJar returns a copy of ctx using the jar for the request instead of the jar of the
+// client. A nil jar disables cookie handling for the request.
+func WithCookieJar(ctx context.Context, jar http.CookieJar) context.Context {
+	return context.WithValue(ctx, cookieJarKey{}, cookieJar{jar: jar})
+}
+
+// CookieJarFromContext returns the cookie jar stored in ctx, if any. The jar may be nil.
+func CookieJarFromContext(ctx context.Context) (http.CookieJar, bool) {
+	value, ok := ctx.Value(cookieJarKey{}).(cookieJar)
+	return value.jar, ok
+}
+
+// resolverKey is the context key used to store the resolver of the client.
+type resolverKey struct{}
+
+// WithResolver returns a copy of ctx carrying the resolver the client connects to hosts with,
+// so middleware dialing with transports derived from the client's resolves hosts the same way.
+func WithResolver(ctx context.Context, resolver dns.Resolver) context.Context {
+	return context.WithValue(ctx, resolverKey{}, resolver)
+}
+
+// ResolverFromContext returns the resolver stored in ctx, if any.
+func ResolverFromContext(ctx context.Context) (dns.Resolver, bool) {
+	resolver, ok := ctx.Value(resolverKey{}).(dns.Resolver)
+	return resolver, ok
+}
+
+// proxyKey is the context key used to store the proxy selected for a request.
+type proxyKey struct{}
+
+// WithProxy returns a copy of ctx carrying the proxy selected for the request.
+func WithProxy(ctx context.Context, proxy *url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+// ProxyFromContext returns the proxy selected for the request, if any.
+func ProxyFromContext(ctx context.Context) (*url.URL, bool) {
+	proxy, ok := ctx.Value(proxyKey{}).(*url.URL)
+	return proxy, ok && proxy != nil
+}
+
+// Events reported by the middleware of this module. The events package defines their types.
+const (
+	// EventRetry is reported before a failed attempt is retried, with the "attempt" number, the
+	// "error" of the attempt and the delay before the next one as "retry_in".
+	EventRetry = events.NameRetryScheduled
+	// EventCacheHit is reported when a response is served from a cache.
+	EventCacheHit = events.NameCacheHit
+	// EventCacheMiss is reported when a cache has no usable response for the request.
+	EventCacheMiss = events.NameCacheMiss
+)
+
+// EventFunc receives an event reported by a middleware about a request, such as a retry or a
+// cache lookup, with fields describing it.
+type EventFunc func(name string, fields ...logger.Field)
+
+// WithEventFunc returns a copy of ctx passing the events reported about the request to fn, after
+// the functions already stored in ctx. Tracing middleware use it to annotate their spans.
+func WithEventFunc(ctx context.Context, fn EventFunc) context.Context {
+	return events.WithHandler(ctx, func(_ context.Context, e events.Event) {
+		fn(e.Name(), e.Fields()...)
+	})
+}
+
+// RecordEvent reports an event about the request to the event functions stored in ctx. Typed
+// events are emitted with events.Publish instead.
+func RecordEvent(ctx context.Context, name string, fields ...logger.Field) {
+	events.Publish(ctx, events.New(name, fields...))
+}
+
+// LogFields returns the fields describing the attempt of the request, so that every log entry
+// about it carries the same keys: "attempt" once retried and "proxy" once a proxy is selected.
+func LogFields(ctx context.Context) []logger.Field {
+	var fields []logger.Field
+	if attempt := AttemptFromContext(ctx); attempt > 0 {
+		fields = append(fields, logger.Int("attempt", int(attempt)))
+	}
+	if proxy, ok := ProxyFromContext(ctx); ok {
+		fields = append(fields, logger.String("proxy", proxy.Redacted()))
+	}
+	return fields
+}
+
+// skipSet holds the middleware skipped for a request.
+type skipSet struct {
+	types map[reflect.Type]struct{}
+	names map[string]struct{}
+}
+
+// skipKey is the context key used to store the middleware skipped for a request.
+type skipKey struct{}
+
+// WithSkip returns a copy of ctx skipping the middleware of the same types as the given middleware.
+// Any value of the type can be used, such as a nil pointer.
+func WithSkip(ctx context.Context, middlewares ...Middleware) context.Context {
+	skip := skipFromContext(ctx)
+	for _, m := range middlewares {
+		skip.types[reflect.TypeOf(m)] = struct{}{}
+	}
+	return context.WithValue(ctx, skipKey{}, skip)
+}
+
+// WithSkipNamed returns a copy of ctx skipping the middleware registered under the given names.
+func WithSkipNamed(ctx context.Context, names ...string) context.Context {
+	skip := skipFromContext(ctx)
+	for _, name := range names {
+		skip.names[name] = struct{}{}
+	}
+	return context.WithValue(ctx, skipKey{}, skip)
+}
+
+// skipFromContext returns a copy of the middleware skipped for the request.
+func skipFromContext(ctx context.Context) skipSet {
+	skip, ok := ctx.Value(skipKey{}).(skipSet)
+	if !ok {
+		return skipSet{
+			types: make(map[reflect.Type]struct{}),
+			names: make(map[string]struct{}),
+		}
+	}
+	return skipSet{
+		types: maps.Clone(skip.types),
+		names: maps.Clone(skip.names),
+	}
+}
+
+// isSkipped reports whether the middleware is skipped for the request.
+func (c *Chain) isSkipped(ctx context.Context, m Middleware) bool {
+	skip, ok := ctx.Value(skipKey{}).(skipSet)
+	if !ok {
+		return false
+	}
+	if _, ok := skip.types[reflect.TypeOf(m)]; ok {
+		return true
+	}
+	if name := c.nameOf(m); name != "" {
+		_, ok := skip.names[name]
+		return ok
+	}
+	return false
+}
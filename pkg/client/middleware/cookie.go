@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SetCookies adds the cookies to the request, replacing the cookies of the same names already on
+// it, so middleware setting cookies can run again for the same request, such as on retries,
+// without sending duplicates.
+func SetCookies(req *http.Request, cookies ...*http.Cookie) {
+	if len(cookies) == 0 {
+		return
+	}
+
+	existing := slices.DeleteFunc(req.Cookies(), func(cookie *http.Cookie) bool {
+		return slices.ContainsFunc(cookies, func(c *http.Cookie) bool { return c.Name == cookie.Name })
+	})
+
+	values := make([]string, 0, len(existing)+len(cookies))
+	for _, cookie := range append(existing, cookies...) {
+		if value := (&http.Cookie{Name: cookie.Name, Value: cookie.Value, Quoted: cookie.Quoted}).String(); value != "" { //nolint:exhaustruct
+			values = append(values, value)
+		}
+	}
+	req.Header.Set("Cookie", strings.Join(values, "; "))
+}
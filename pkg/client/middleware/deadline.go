@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+)
+
+// latencyWeight is the weight given to the newest sample in the latency moving average.
+const latencyWeight = 0.2
+
+// CostEstimator is implemented by middleware that can estimate how long they will delay a request,
+// such as the time a rate limiter would make the request wait. Estimates must not consume capacity.
+type CostEstimator interface {
+	EstimateCost(req *http.Request) time.Duration
+}
+
+// latencyStats tracks the typical latency of requests per host using a moving average.
+type latencyStats struct {
+	averages map[string]time.Duration
+	mu       sync.RWMutex
+}
+
+// newLatencyStats creates a new latencyStats instance.
+func newLatencyStats() *latencyStats {
+	return &latencyStats{
+		averages: make(map[string]time.Duration),
+		mu:       sync.RWMutex{},
+	}
+}
+
+// observe records the latency of a completed request to the host.
+func (s *latencyStats) observe(host string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	average, ok := s.averages[host]
+	if !ok {
+		s.averages[host] = latency
+		return
+	}
+	s.averages[host] = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(average))
+}
+
+// typical returns the typical latency of requests to the host, or 0 if no requests were observed.
+func (s *latencyStats) typical(host string) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.averages[host]
+}
+
+// SetEarlyRejection enables or disables rejecting requests whose deadline cannot be met.
+func (c *Chain) SetEarlyRejection(enabled bool) {
+	c.earlyRejection = enabled
+}
+
+// EarlyRejection reports whether requests whose deadline cannot be met are rejected.
+func (c *Chain) EarlyRejection() bool {
+	return c.earlyRejection
+}
+
+// checkDeadline returns an error if the context deadline is earlier than the estimated
+// minimum time needed to complete the request.
+func (c *Chain) checkDeadline(ctx context.Context, req *http.Request) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	// Sum the costs of all middleware that can estimate them, plus the typical latency of the host
+	estimate := c.latency.typical(req.URL.Host)
+	for _, m := range c.middlewares {
+		if estimator, ok := m.(CostEstimator); ok {
+			estimate += estimator.EstimateCost(req)
+		}
+	}
+
+	remaining := time.Until(deadline)
+	if remaining >= estimate {
+		return nil
+	}
+
+	c.logger.WithFields(
+		logger.String("url", req.URL.String()),
+		logger.Duration("remaining", remaining),
+		logger.Duration("estimate", estimate),
+	).Debug("Request rejected early")
+
+	return fmt.Errorf("%w: %w", errors.ErrTimeout, errors.ErrDeadlineUnreachable)
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDeadlineHeader is the header carrying the remaining time of requests by default.
+const DefaultDeadlineHeader = "X-Request-Timeout"
+
+// DeadlineFormat is the encoding of the remaining time of a request in a header.
+type DeadlineFormat int
+
+const (
+	// DeadlineMilliseconds encodes the remaining time as an integer number of milliseconds, such as "1500".
+	DeadlineMilliseconds DeadlineFormat = iota
+	// DeadlineSeconds encodes the remaining time as a decimal number of seconds, such as "1.5".
+	DeadlineSeconds
+	// DeadlineGRPC encodes the remaining time like the grpc-timeout header, as at most 8 digits
+	// followed by a unit among H, M, S, m, u and n, such as "1500m".
+	DeadlineGRPC
+)
+
+// grpcUnits are the units of the grpc-timeout header, from the finest.
+var grpcUnits = []struct {
+	unit     byte
+	duration time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// grpcMaxValue is the largest value of the grpc-timeout header, which has at most 8 digits.
+const grpcMaxValue = 1e8 - 1
+
+// Format encodes the duration in the format. Positive durations too short for the format are
+// rounded up to its smallest unit rather than sent as zero.
+func (f DeadlineFormat) Format(d time.Duration) string {
+	switch f {
+	case DeadlineSeconds:
+		ms := max((d+time.Millisecond-1)/time.Millisecond, 1)
+		return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+	case DeadlineGRPC:
+		for _, u := range grpcUnits {
+			if value := (d + u.duration - 1) / u.duration; value <= grpcMaxValue {
+				return strconv.FormatInt(int64(max(value, 1)), 10) + string(u.unit)
+			}
+		}
+		return strconv.FormatInt(grpcMaxValue, 10) + "H"
+	default:
+		return strconv.FormatInt(int64(max((d+time.Millisecond-1)/time.Millisecond, 1)), 10)
+	}
+}
+
+// Parse decodes a duration encoded in the format.
+func (f DeadlineFormat) Parse(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	switch f {
+	case DeadlineSeconds:
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, fmt.Errorf("invalid deadline %q", value)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	case DeadlineGRPC:
+		if len(value) < 2 || len(value) > 9 {
+			return 0, fmt.Errorf("invalid deadline %q", value)
+		}
+		n, err := strconv.ParseUint(value[:len(value)-1], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid deadline %q", value)
+		}
+		for _, u := range grpcUnits {
+			if u.unit == value[len(value)-1] {
+				return time.Duration(n) * u.duration, nil
+			}
+		}
+		return 0, fmt.Errorf("invalid deadline unit %q", value)
+	default:
+		ms, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid deadline %q", value)
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+}
+
+// deadlineBudgetKey is the context key used to store the deadline budget of a request.
+type deadlineBudgetKey struct{}
+
+// DeadlineBudget is the time budget of a request sent with the deadline header, and the budget
+// the server reported in the same header of its response, for budget accounting.
+type DeadlineBudget struct {
+	// Sent is the remaining time of the request when it was sent.
+	Sent time.Duration
+	// Reported is the value of the header in the response, such as the budget the server had
+	// left or used, depending on the server. It is zero if HasReported is false.
+	Reported time.Duration
+	// HasReported reports whether the response had a valid deadline header.
+	HasReported bool
+}
+
+// DeadlineBudgetFromResponse returns the deadline budget of the request of the response, if it
+// was sent with the deadline header set with SetDeadlineHeader.
+func DeadlineBudgetFromResponse(resp *http.Response) (DeadlineBudget, bool) {
+	if resp == nil || resp.Request == nil {
+		return DeadlineBudget{}, false //nolint:exhaustruct
+	}
+	budget, ok := resp.Request.Context().Value(deadlineBudgetKey{}).(*DeadlineBudget)
+	if !ok {
+		return DeadlineBudget{}, false //nolint:exhaustruct
+	}
+	return *budget, true
+}
+
+// SetDeadlineHeader makes the chain send the remaining time of requests with a context deadline
+// in the header, measured right before they are sent, so servers can stop working on requests
+// the client no longer waits for. An empty name disables the header.
+func (c *Chain) SetDeadlineHeader(name string, format DeadlineFormat) {
+	c.deadlineHeader = name
+	c.deadlineFormat = format
+}
+
+// withDeadlineHeader returns a copy of the request carrying the remaining time of its context in
+// the deadline header, and the context recording the budget, if the header is enabled and the
+// context has a deadline. The request is returned unchanged otherwise.
+func (c *Chain) withDeadlineHeader(ctx context.Context, req *http.Request) (context.Context, *http.Request, *DeadlineBudget) {
+	if c.deadlineHeader == "" {
+		return ctx, req, nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, req, nil
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return ctx, req, nil
+	}
+
+	budget := &DeadlineBudget{Sent: remaining, Reported: 0, HasReported: false}
+	ctx = context.WithValue(ctx, deadlineBudgetKey{}, budget)
+
+	// Clone the request, as middleware may send it several times or concurrently
+	req = req.Clone(ctx)
+	req.Header.Set(c.deadlineHeader, c.deadlineFormat.Format(remaining))
+	return ctx, req, budget
+}
+
+// reportDeadlineBudget records the deadline header of the response in the budget of its request.
+func (c *Chain) reportDeadlineBudget(budget *DeadlineBudget, resp *http.Response) {
+	if budget == nil {
+		return
+	}
+	value := resp.Header.Get(c.deadlineHeader)
+	if value == "" {
+		return
+	}
+	if reported, err := c.deadlineFormat.Parse(value); err == nil {
+		budget.Reported = reported
+		budget.HasReported = true
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jaxron/axonet/pkg/client/logger"
+)
+
+// DegradationSource is implemented by middleware that can detect an upstream incident,
+// such as the error budget middleware once a host's budget is nearly exhausted.
+type DegradationSource interface {
+	Degraded() bool
+}
+
+// degradation holds the degraded mode state shared by a chain and the copies derived from it.
+type degradation struct {
+	manual   atomic.Bool
+	sources  []DegradationSource
+	optional map[Middleware]struct{}
+	mu       sync.RWMutex
+}
+
+// newDegradation creates a new degradation instance.
+func newDegradation() *degradation {
+	return &degradation{
+		manual:   atomic.Bool{},
+		sources:  nil,
+		optional: make(map[Middleware]struct{}),
+		mu:       sync.RWMutex{},
+	}
+}
+
+// degradedKey is the context key used to mark requests processed in degraded mode.
+type degradedKey struct{}
+
+// WithDegraded returns a copy of ctx marking the request as processed in degraded mode.
+func WithDegraded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, degradedKey{}, true)
+}
+
+// DegradedFromContext reports whether the request is processed in degraded mode.
+// Middleware use it to serve stale data or reduce their throughput during upstream incidents.
+func DegradedFromContext(ctx context.Context) bool {
+	degraded, ok := ctx.Value(degradedKey{}).(bool)
+	return ok && degraded
+}
+
+// ThenOptional adds middleware to the chain that is skipped while the chain is degraded.
+func (c *Chain) ThenOptional(middlewares ...Middleware) {
+	c.degradation.mu.Lock()
+	defer c.degradation.mu.Unlock()
+
+	for _, m := range middlewares {
+		c.addOrReplace(m)
+		c.degradation.optional[m] = struct{}{}
+	}
+}
+
+// AddDegradationSource makes the chain degraded whenever the source reports an incident.
+func (c *Chain) AddDegradationSource(source DegradationSource) {
+	c.degradation.mu.Lock()
+	defer c.degradation.mu.Unlock()
+
+	c.degradation.sources = append(c.degradation.sources, source)
+}
+
+// SetDegraded manually enables or disables degraded mode.
+func (c *Chain) SetDegraded(enabled bool) {
+	if c.degradation.manual.Swap(enabled) != enabled {
+		c.logger.WithFields(logger.Bool("degraded", enabled)).Info("Degraded mode toggled")
+	}
+}
+
+// Degraded reports whether degraded mode is enabled manually or by a degradation source.
+func (c *Chain) Degraded() bool {
+	if c.degradation.manual.Load() {
+		return true
+	}
+
+	c.degradation.mu.RLock()
+	defer c.degradation.mu.RUnlock()
+
+	for _, source := range c.degradation.sources {
+		if source.Degraded() {
+			return true
+		}
+	}
+	return false
+}
+
+// isOptional reports whether the middleware is skipped while the chain is degraded.
+func (c *Chain) isOptional(m Middleware) bool {
+	c.degradation.mu.RLock()
+	defer c.degradation.mu.RUnlock()
+
+	_, ok := c.degradation.optional[m]
+	return ok
+}
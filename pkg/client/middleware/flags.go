@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// Flag is a capability of a middleware that can be enabled per request or globally at runtime,
+// such as "retry.aggressive" or "cache.stale-ok". Flags are disabled unless enabled.
+type Flag string
+
+var (
+	registeredFlags   = make(map[Flag]string)
+	registeredFlagsMu sync.RWMutex
+)
+
+// RegisterFlag registers a flag supported by a middleware along with a description of its effect.
+// Middleware packages register their flags in an init function.
+func RegisterFlag(flag Flag, description string) {
+	registeredFlagsMu.Lock()
+	defer registeredFlagsMu.Unlock()
+
+	registeredFlags[flag] = description
+}
+
+// RegisteredFlags returns the registered flags and their descriptions.
+func RegisteredFlags() map[Flag]string {
+	registeredFlagsMu.RLock()
+	defer registeredFlagsMu.RUnlock()
+
+	return maps.Clone(registeredFlags)
+}
+
+// FlagSet holds the global state of flags. It is safe for concurrent use and cheap to read,
+// as updates replace the whole set.
+type FlagSet struct {
+	values atomic.Pointer[map[Flag]bool]
+	mu     sync.Mutex
+}
+
+// NewFlagSet creates a new FlagSet instance with every flag disabled.
+func NewFlagSet() *FlagSet {
+	s := &FlagSet{
+		values: atomic.Pointer[map[Flag]bool]{},
+		mu:     sync.Mutex{},
+	}
+	s.values.Store(&map[Flag]bool{})
+	return s
+}
+
+// Set enables or disables a flag.
+func (s *FlagSet) Set(flag Flag, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := maps.Clone(*s.values.Load())
+	values[flag] = enabled
+	s.values.Store(&values)
+}
+
+// Replace replaces the state of every flag.
+func (s *FlagSet) Replace(values map[Flag]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values = maps.Clone(values)
+	if values == nil {
+		values = make(map[Flag]bool)
+	}
+	s.values.Store(&values)
+}
+
+// Enabled reports whether the flag is enabled.
+func (s *FlagSet) Enabled(flag Flag) bool {
+	return (*s.values.Load())[flag]
+}
+
+// Values returns the state of every flag that was set.
+func (s *FlagSet) Values() map[Flag]bool {
+	return maps.Clone(*s.values.Load())
+}
+
+// flagSetKey is the context key used to store the flags of the chain processing a request.
+type flagSetKey struct{}
+
+// flagKey is the context key used to store the state of a flag for a single request.
+type flagKey struct {
+	flag Flag
+}
+
+// WithFlag returns a copy of ctx enabling or disabling the flag for the request,
+// overriding its global state.
+func WithFlag(ctx context.Context, flag Flag, enabled bool) context.Context {
+	return context.WithValue(ctx, flagKey{flag: flag}, enabled)
+}
+
+// FlagEnabled reports whether the flag is enabled for the request, either for the request
+// itself or globally for the chain processing it.
+func FlagEnabled(ctx context.Context, flag Flag) bool {
+	if enabled, ok := ctx.Value(flagKey{flag: flag}).(bool); ok {
+		return enabled
+	}
+	if flags, ok := ctx.Value(flagSetKey{}).(*FlagSet); ok {
+		return flags.Enabled(flag)
+	}
+	return false
+}
+
+// SetFlags sets the flags of the chain, which may be shared with other chains.
+func (c *Chain) SetFlags(flags *FlagSet) {
+	c.flags = flags
+}
+
+// Flags returns the flags of the chain.
+func (c *Chain) Flags() *FlagSet {
+	return c.flags
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SensitiveHeaders are the headers dropped by default by HeaderFilter, which carry credentials or
+// session state that must not be persisted.
+var SensitiveHeaders = []string{
+	"Set-Cookie", "Set-Cookie2", "Cookie", "Authorization", "Proxy-Authorization",
+	"X-Api-Key", "X-Auth-Token", "X-Csrf-Token", "X-Amz-Security-Token",
+}
+
+// HeaderFilter selects the headers of responses persisted by middleware, such as the cache, so
+// stores stay free of secrets. Its rules are set with chained setters:
+//
+//	middleware.NewHeaderFilter().
+//		Allow("Content-Type", "Cache-Control", "ETag", "X-RateLimit-*").
+//		Drop(append(middleware.SensitiveHeaders, "X-Request-Id")...)
+type HeaderFilter struct {
+	allow []string
+	drop  []string
+}
+
+// NewHeaderFilter creates a new HeaderFilter keeping every header but the SensitiveHeaders.
+func NewHeaderFilter() *HeaderFilter {
+	return &HeaderFilter{
+		allow: nil,
+		drop:  slices.Clone(SensitiveHeaders),
+	}
+}
+
+// Allow replaces the headers kept, so other headers are dropped. Patterns ending with "*" match
+// headers starting with the rest of the pattern, and names are matched case-insensitively.
+// Calling it without patterns keeps every header again. Dropped headers are never kept.
+func (f *HeaderFilter) Allow(patterns ...string) *HeaderFilter {
+	f.allow = nil
+	if len(patterns) > 0 {
+		f.allow = slices.Clone(patterns)
+	}
+	return f
+}
+
+// Drop replaces the headers dropped, matched like those of Allow.
+func (f *HeaderFilter) Drop(patterns ...string) *HeaderFilter {
+	f.drop = slices.Clone(patterns)
+	return f
+}
+
+// Filter returns a copy of the header with the headers to drop removed.
+func (f *HeaderFilter) Filter(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+
+	filtered := make(http.Header, len(header))
+	for name, values := range header {
+		if f.keeps(name) {
+			filtered[name] = slices.Clone(values)
+		}
+	}
+	return filtered
+}
+
+// keeps reports whether the header is kept.
+func (f *HeaderFilter) keeps(name string) bool {
+	if matchHeader(f.drop, name) {
+		return false
+	}
+	return f.allow == nil || matchHeader(f.allow, name)
+}
+
+// matchHeader reports whether the header name matches one of the patterns, case-insensitively.
+func matchHeader(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"reflect"
+)
+
+// Introspector is implemented by middleware that can report their configuration and live state.
+// The returned values must be JSON-serializable.
+type Introspector interface {
+	Introspect() map[string]interface{}
+}
+
+// Snapshot describes a middleware in the chain.
+type Snapshot struct {
+	Index    int                    `json:"index"`
+	Name     string                 `json:"name,omitempty"`
+	Type     string                 `json:"type"`
+	Priority int                    `json:"priority,omitempty"`
+	State    map[string]interface{} `json:"state,omitempty"`
+}
+
+// Introspect returns a snapshot of every middleware in the chain, in order.
+func (c *Chain) Introspect() []Snapshot {
+	snapshots := make([]Snapshot, 0, len(c.middlewares))
+	for i, m := range c.middlewares {
+		snapshot := Snapshot{
+			Index:    i,
+			Name:     c.nameOf(m),
+			Type:     reflect.TypeOf(m).String(),
+			Priority: c.priorities[i],
+			State:    nil,
+		}
+		if introspector, ok := m.(Introspector); ok {
+			snapshot.State = introspector.Introspect()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
+// nameOf returns the name the middleware was registered under, if any.
+func (c *Chain) nameOf(m Middleware) string {
+	for name, named := range c.names {
+		if Same(named, m) {
+			return name
+		}
+	}
+	return ""
+}
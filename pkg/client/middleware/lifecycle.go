@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"context"
+)
+
+// LifecycleMiddleware is implemented by middleware running background work, such as health
+// checks, cache writers or pool refreshers. The Client starts them when it is created and stops
+// them with Client.Stop, so clients created per test or per tenant leak no goroutines.
+type LifecycleMiddleware interface {
+	// Start starts the background work of the middleware.
+	Start(ctx context.Context) error
+	// Stop stops the background work, draining the pending work until ctx is done.
+	Stop(ctx context.Context) error
+}
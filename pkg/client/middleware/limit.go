@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"io"
+)
+
+// LimitBody returns the body failing with err once more than limit bytes are read from it, such
+// as for middleware bounding the decompressed size of a body by MaxResponseBytesFromContext.
+func LimitBody(body io.ReadCloser, limit int64, err error) io.ReadCloser {
+	return &limitedBody{ReadCloser: body, remaining: limit, err: err}
+}
+
+// limitedBody is a response body failing with err once more than its limit is read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+// Read reads the body, returning err instead of the bytes beyond the limit.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+
+	// Read one byte past the limit to tell a body of exactly the limit from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = -1
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
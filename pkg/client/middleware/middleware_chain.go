@@ -3,25 +3,55 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"reflect"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 )
 
 // Chain represents a chain of middleware.
 type Chain struct {
-	middlewares []Middleware
-	logger      logger.Logger
+	middlewares    []Middleware
+	names          map[string]Middleware
+	priorities     []int
+	logger         logger.Logger
+	baseLogger     logger.Logger
+	latency        *latencyStats
+	certificates   *certificateTransports
+	degradation    *degradation
+	flags          *FlagSet
+	constraints    []orderConstraint
+	classifier     Classifier
+	earlyRejection bool
+	deadlineHeader string
+	deadlineFormat DeadlineFormat
+	redactor       *logger.Redactor
 }
 
 // NewChain creates a new middleware chain.
 func NewChain(logger logger.Logger, middlewares ...Middleware) *Chain {
 	return &Chain{
-		middlewares: middlewares,
-		logger:      logger,
+		middlewares:    middlewares,
+		names:          make(map[string]Middleware),
+		priorities:     make([]int, len(middlewares)),
+		logger:         logger,
+		baseLogger:     logger,
+		latency:        newLatencyStats(),
+		certificates:   newCertificateTransports(),
+		degradation:    newDegradation(),
+		flags:          NewFlagSet(),
+		constraints:    nil,
+		classifier:     nil,
+		earlyRejection: false,
+		deadlineHeader: "",
+		deadlineFormat: DeadlineMilliseconds,
+		redactor:       nil,
 	}
 }
 
@@ -35,21 +65,196 @@ func (c *Chain) Middlewares() []Middleware {
 	return append([]Middleware(nil), c.middlewares...)
 }
 
-// Then adds middleware to the chain, replacing any existing middleware of the same type.
+// Then adds middleware to the chain with the default priority of 0, replacing any existing
+// middleware of the same type.
 func (c *Chain) Then(middlewares ...Middleware) {
 	for _, m := range middlewares {
 		c.addOrReplace(m)
 	}
 }
 
+// ThenWithPriority adds middleware to the chain with the given priority, replacing any existing
+// middleware of the same type. Middleware with a higher priority runs earlier, regardless of the
+// order it was added in, and middleware with the same priority runs in the order it was added in.
+func (c *Chain) ThenWithPriority(priority int, middlewares ...Middleware) {
+	for _, m := range middlewares {
+		c.add(m, priority)
+	}
+}
+
+// ThenNamed adds middleware to the chain under a name, replacing any existing middleware of the same type.
+// The middleware can later be retrieved with Get.
+func (c *Chain) ThenNamed(name string, m Middleware) {
+	c.addOrReplace(m)
+	c.names[name] = m
+}
+
+// Get returns the middleware registered under the name, if it is still part of the chain.
+func (c *Chain) Get(name string) (Middleware, bool) {
+	m, ok := c.names[name]
+	if !ok {
+		return nil, false
+	}
+
+	for _, existing := range c.middlewares {
+		if Same(existing, m) {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// Remove removes the middleware from the chain.
+// It reports whether the middleware was part of the chain.
+func (c *Chain) Remove(m Middleware) bool {
+	for i, existing := range c.middlewares {
+		if Same(existing, m) {
+			c.removeAt(i)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveByType removes the middleware of the given type from the chain.
+// It reports whether a middleware of that type was part of the chain.
+func (c *Chain) RemoveByType(t reflect.Type) bool {
+	if i := c.indexOf(t); i >= 0 {
+		c.removeAt(i)
+		return true
+	}
+	return false
+}
+
+// InsertBefore adds middleware to the chain immediately before the middleware of the target type,
+// with the same priority. Any existing middleware of the same type as m is removed first.
+// It reports whether a middleware of the target type was part of the chain.
+func (c *Chain) InsertBefore(target reflect.Type, m Middleware) bool {
+	return c.insert(target, m, 0)
+}
+
+// InsertAfter adds middleware to the chain immediately after the middleware of the target type,
+// with the same priority. Any existing middleware of the same type as m is removed first.
+// It reports whether a middleware of the target type was part of the chain.
+func (c *Chain) InsertAfter(target reflect.Type, m Middleware) bool {
+	return c.insert(target, m, 1)
+}
+
+// insert adds middleware at the given offset from the middleware of the target type.
+func (c *Chain) insert(target reflect.Type, m Middleware, offset int) bool {
+	if reflect.TypeOf(m) == target || c.indexOf(target) < 0 {
+		return false
+	}
+	c.RemoveByType(reflect.TypeOf(m))
+
+	index := c.indexOf(target)
+	m.SetLogger(c.logger)
+	c.insertAt(index+offset, m, c.priorities[index])
+	return true
+}
+
+// insertAt inserts the middleware with its priority at the index. The slices are clipped first,
+// since copies of the chain may share their backing arrays.
+func (c *Chain) insertAt(index int, m Middleware, priority int) {
+	c.middlewares = slices.Insert(slices.Clip(c.middlewares), index, m)
+	c.priorities = slices.Insert(slices.Clip(c.priorities), index, priority)
+}
+
+// removeAt removes the middleware and its priority at the index without modifying the backing
+// arrays, which copies of the chain may share.
+func (c *Chain) removeAt(index int) {
+	c.middlewares = append(c.middlewares[:index:index], c.middlewares[index+1:]...)
+	c.priorities = append(c.priorities[:index:index], c.priorities[index+1:]...)
+}
+
+// indexOf returns the index of the middleware of the given type, or -1 if there is none.
+func (c *Chain) indexOf(t reflect.Type) int {
+	return slices.IndexFunc(c.middlewares, func(m Middleware) bool {
+		return reflect.TypeOf(m) == t
+	})
+}
+
+// With returns a copy of the chain with additional middleware, replacing any existing middleware
+// of the same type. The copy shares the logger, latency statistics, degraded mode and flags of the chain,
+// which is left unchanged.
+func (c *Chain) With(middlewares ...Middleware) *Chain {
+	chain := &Chain{
+		middlewares:    c.Middlewares(),
+		names:          maps.Clone(c.names),
+		priorities:     slices.Clone(c.priorities),
+		logger:         c.logger,
+		baseLogger:     c.baseLogger,
+		latency:        c.latency,
+		certificates:   c.certificates,
+		degradation:    c.degradation,
+		flags:          c.flags,
+		constraints:    c.constraints,
+		classifier:     c.classifier,
+		earlyRejection: c.earlyRejection,
+		deadlineHeader: c.deadlineHeader,
+		deadlineFormat: c.deadlineFormat,
+		redactor:       c.redactor,
+	}
+	chain.Then(middlewares...)
+	return chain
+}
+
 // Process runs the request through all middleware in the chain.
 func (c *Chain) Process(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
-	// If no middlewares are defined, perform the request immediately
+	// Fail fast if the deadline clearly cannot be met
+	if c.earlyRejection {
+		if err := c.checkDeadline(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	// Let middleware evaluate the flags of the chain
+	ctx = context.WithValue(ctx, flagSetKey{}, c.flags)
+
+	// Let middleware classify outcomes the same way
+	if c.classifier != nil {
+		ctx = context.WithValue(ctx, classifierKey{}, c.classifier)
+	}
+
+	// Let middleware know the request is processed in degraded mode
+	if c.Degraded() {
+		ctx = WithDegraded(ctx)
+	}
+
+	// Run the cleanups registered by middleware once the request is over, even if it panics
+	stack := &cleanupStack{cleanups: nil, done: false, mu: sync.Mutex{}}
+	ctx = context.WithValue(ctx, cleanupKey{}, stack)
+
+	completed := false
+	defer func() {
+		if !completed {
+			c.runCleanups(stack)
+		}
+	}()
+
+	var (
+		resp *http.Response
+		err  error
+	)
 	if len(c.middlewares) == 0 {
-		return c.performRequest(ctx, httpClient, req)
+		// If no middlewares are defined, perform the request immediately
+		resp, err = c.performRequest(ctx, httpClient, req)
+	} else {
+		resp, err = c.processMiddleware(ctx, httpClient, req, 0)
 	}
+	completed = true
 
-	return c.processMiddleware(ctx, httpClient, req, 0)
+	// Hold the cleanups until the body is closed, unless there is no body to read
+	if err != nil || resp == nil || resp.Body == nil || stack.empty() {
+		c.runCleanups(stack)
+		return resp, err
+	}
+	resp.Body = &cleanupBody{
+		ReadCloser: resp.Body,
+		close:      func() { c.runCleanups(stack) },
+		once:       sync.Once{},
+	}
+	return resp, nil
 }
 
 // processMiddleware recursively applies each middleware in the chain.
@@ -62,6 +267,14 @@ func (c *Chain) processMiddleware(ctx context.Context, httpClient *http.Client,
 	start := time.Now()
 	middleware := c.middlewares[index]
 
+	// Skip middleware disabled for the request, and optional middleware while degraded
+	if c.isSkipped(ctx, middleware) || (DegradedFromContext(ctx) && c.isOptional(middleware)) {
+		skipped := events.MiddlewareSkipped{Index: index, Middleware: reflect.TypeOf(middleware).String()}
+		c.logger.WithFields(skipped.Fields()...).Debug("Middleware skipped")
+		events.Publish(ctx, skipped)
+		return c.processMiddleware(ctx, httpClient, req, index+1)
+	}
+
 	// Otherwise, apply the middleware and continue
 	resp, err := middleware.Process(ctx, httpClient, req, func(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
 		c.logger.WithFields(
@@ -78,19 +291,39 @@ func (c *Chain) processMiddleware(ctx context.Context, httpClient *http.Client,
 // performRequest executes the actual HTTP request.
 func (c *Chain) performRequest(ctx context.Context, httpClient *http.Client, req *http.Request) (*http.Response, error) {
 	start := time.Now()
+	log := c.logger.WithFields(LogFields(ctx)...)
 
 	// Log the request details
-	c.logger.WithFields(
+	log.WithFields(
 		logger.String("method", req.Method),
 		logger.String("url", req.URL.String()),
 		logger.Int("len_headers", len(req.Header)),
 	).Debug("Request started")
 
-	// Send the request
-	resp, err := httpClient.Do(req.WithContext(ctx))
+	// Send the request, or its raw bytes if the caller constructed them
+	var (
+		resp *http.Response
+		err  error
+	)
+	httpClient, err = c.certificates.client(ctx, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if jar, ok := CookieJarFromContext(ctx); ok {
+		clone := *httpClient
+		clone.Jar = jar
+		httpClient = &clone
+	}
+	var budget *DeadlineBudget
+	if raw, ok := RawRequestFromContext(ctx); ok {
+		resp, err = sendRaw(ctx, httpClient, req, raw)
+	} else {
+		ctx, req, budget = c.withDeadlineHeader(ctx, req)
+		resp, err = httpClient.Do(req.WithContext(ctx))
+	}
 	duration := time.Since(start)
 	if err != nil {
-		c.logger.WithFields(
+		log.WithFields(
 			logger.String("error", err.Error()),
 			logger.Duration("duration", duration),
 		).Debug("Request failed")
@@ -101,8 +334,23 @@ func (c *Chain) performRequest(ctx context.Context, httpClient *http.Client, req
 		return nil, fmt.Errorf("%w: %w", errors.ErrNetwork, err)
 	}
 
+	// Record the latency for deadline estimates
+	c.latency.observe(req.URL.Host, duration)
+	c.reportDeadlineBudget(budget, resp)
+
+	// Bound the body before any middleware reads it
+	if limit, ok := MaxResponseBytesFromContext(ctx); ok && limit > 0 {
+		tooLarge := &errors.ResponseTooLargeError{Limit: limit, URL: req.URL.String()}
+		if resp.ContentLength > limit {
+			resp.Body.Close()
+			log.WithFields(logger.Int("status", resp.StatusCode)).Debug("Response too large")
+			return nil, tooLarge
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit, err: tooLarge}
+	}
+
 	// Log the response details
-	c.logger.WithFields(
+	log.WithFields(
 		logger.Int("status", resp.StatusCode),
 		logger.Int("len_headers", len(resp.Header)),
 		logger.Duration("duration", duration),
@@ -111,23 +359,64 @@ func (c *Chain) performRequest(ctx context.Context, httpClient *http.Client, req
 	return resp, nil
 }
 
-// addOrReplace adds a new middleware or replaces an existing one of the same type.
+// addOrReplace adds a new middleware or replaces an existing one of the same type, with the default priority.
 func (c *Chain) addOrReplace(m Middleware) {
-	for i, existing := range c.middlewares {
-		if reflect.TypeOf(existing) == reflect.TypeOf(m) {
+	c.add(m, 0)
+}
+
+// add adds a new middleware or replaces an existing one of the same type, keeping the chain
+// ordered by priority. A replacement with the same priority keeps the position of the existing middleware.
+func (c *Chain) add(m Middleware, priority int) {
+	m.SetLogger(c.logger)
+
+	if i := c.indexOf(reflect.TypeOf(m)); i >= 0 {
+		if c.priorities[i] == priority {
 			c.middlewares[i] = m
-			m.SetLogger(c.logger)
 			return
 		}
+		c.removeAt(i)
 	}
-	c.middlewares = append(c.middlewares, m)
-	m.SetLogger(c.logger)
+
+	// Insert after every middleware with the same or a higher priority
+	index := len(c.middlewares)
+	for i, existing := range c.priorities {
+		if existing < priority {
+			index = i
+			break
+		}
+	}
+	c.insertAt(index, m, priority)
+}
+
+// Same reports whether a and b are the same middleware. Middleware of a type that cannot be
+// compared, such as a struct holding a slice, is identified by its type, since a chain holds at
+// most one middleware of each type.
+func Same(a, b Middleware) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if !reflect.ValueOf(a).Comparable() || !reflect.ValueOf(b).Comparable() {
+		return true
+	}
+	return a == b
 }
 
-// SetLogger updates the logger for all middleware in the chain.
+// SetLogger updates the logger for all middleware in the chain. The logger masks credentials
+// with the redactor of the chain, if any.
 func (c *Chain) SetLogger(l logger.Logger) {
+	c.baseLogger = l
+	if c.redactor != nil {
+		l = logger.NewRedactingLogger(l, c.redactor)
+	}
 	for _, m := range c.middlewares {
 		m.SetLogger(l)
 	}
 	c.logger = l
 }
+
+// SetRedactor makes the chain and its middleware mask credentials in the messages and fields they
+// log with the redactor, such as the Authorization header and the tokens in URLs.
+func (c *Chain) SetRedactor(r *logger.Redactor) {
+	c.redactor = r
+	c.SetLogger(c.baseLogger)
+}
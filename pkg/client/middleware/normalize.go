@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// TrackingParams are the query parameters dropped by default by URLNormalizer, which carry
+// attribution data without changing the response. Patterns ending with "*" match prefixes.
+var TrackingParams = []string{"utm_*", "gclid", "dclid", "fbclid", "msclkid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi"}
+
+// URLNormalizer rewrites request URLs before middleware derive keys from them, such as the cache
+// and single flight middleware, so semantically identical URLs share entries. Its rules are set
+// with chained setters:
+//
+//	middleware.NewURLNormalizer().
+//		SortQuery(false).
+//		DropParams(append(middleware.TrackingParams, "ref")...)
+type URLNormalizer struct {
+	sortQuery     bool
+	normalizeHost bool
+	dropParams    []string
+}
+
+// NewURLNormalizer creates a new URLNormalizer sorting query parameters, lowercasing hosts,
+// removing default ports and dropping the TrackingParams.
+func NewURLNormalizer() *URLNormalizer {
+	return &URLNormalizer{
+		sortQuery:     true,
+		normalizeHost: true,
+		dropParams:    slices.Clone(TrackingParams),
+	}
+}
+
+// SortQuery sets whether query parameters are sorted by name. Values of the same parameter keep
+// their order, which may be significant.
+func (n *URLNormalizer) SortQuery(enabled bool) *URLNormalizer {
+	n.sortQuery = enabled
+	return n
+}
+
+// NormalizeHost sets whether schemes and hosts are lowercased and default ports removed.
+func (n *URLNormalizer) NormalizeHost(enabled bool) *URLNormalizer {
+	n.normalizeHost = enabled
+	return n
+}
+
+// DropParams replaces the query parameters dropped from URLs. Patterns ending with "*" match
+// parameters starting with the rest of the pattern, such as "utm_*".
+func (n *URLNormalizer) DropParams(patterns ...string) *URLNormalizer {
+	n.dropParams = slices.Clone(patterns)
+	return n
+}
+
+// Normalize returns the normalized form of the URL. The fragment, which is never sent, is removed.
+func (n *URLNormalizer) Normalize(u *url.URL) string {
+	normalized := *u
+	normalized.Fragment, normalized.RawFragment = "", ""
+
+	if n.normalizeHost {
+		normalized.Scheme = strings.ToLower(normalized.Scheme)
+		normalized.Host = strings.ToLower(normalized.Host)
+		if port := normalized.Port(); (port == "80" && normalized.Scheme == "http") || (port == "443" && normalized.Scheme == "https") {
+			normalized.Host = strings.TrimSuffix(normalized.Host, ":"+port)
+		}
+	}
+
+	if normalized.RawQuery != "" && (n.sortQuery || len(n.dropParams) > 0) {
+		normalized.RawQuery = n.normalizeQuery(normalized.RawQuery)
+	}
+	return normalized.String()
+}
+
+// normalizeQuery drops and sorts the parameters of the raw query, keeping their encoding.
+func (n *URLNormalizer) normalizeQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !n.dropped(name) {
+			kept = append(kept, param)
+		}
+	}
+
+	if n.sortQuery {
+		slices.SortStableFunc(kept, func(a, b string) int {
+			nameA, _, _ := strings.Cut(a, "=")
+			nameB, _, _ := strings.Cut(b, "=")
+			return strings.Compare(nameA, nameB)
+		})
+	}
+	return strings.Join(kept, "&")
+}
+
+// dropped reports whether the query parameter matches one of the patterns to drop.
+func (n *URLNormalizer) dropped(name string) bool {
+	for _, pattern := range n.dropParams {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// WarmFunc opens connections to the hosts being preconnected with the HTTP client.
+type WarmFunc func(ctx context.Context, httpClient *http.Client) error
+
+// Preconnector is implemented by middleware sending requests with HTTP clients of their own, such
+// as through proxies, so Client.Preconnect warms the connections their requests will use.
+// Preconnect calls warm with each of those clients, derived from httpClient like in Process.
+type Preconnector interface {
+	Preconnect(ctx context.Context, httpClient *http.Client, warm WarmFunc) error
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// rawRequestKey is the context key used to store the raw bytes of a request.
+type rawRequestKey struct{}
+
+// WithRawRequest returns a copy of ctx sending the raw bytes of an HTTP/1.1 request instead of
+// the request built by Go, for servers that break on Go's request normalization. The request still
+// runs through the chain, so middleware such as the proxy middleware apply to the connection.
+func WithRawRequest(ctx context.Context, raw []byte) context.Context {
+	return context.WithValue(ctx, rawRequestKey{}, raw)
+}
+
+// RawRequestFromContext returns the raw bytes of the request, if it is sent as a raw request.
+func RawRequestFromContext(ctx context.Context) ([]byte, bool) {
+	raw, ok := ctx.Value(rawRequestKey{}).([]byte)
+	return raw, ok
+}
+
+// sendRaw sends the raw request over a new connection to the host of req, through the proxy of
+// the transport of httpClient if any, and parses the response. The connection is closed with the body.
+func sendRaw(ctx context.Context, httpClient *http.Client, req *http.Request, raw []byte) (*http.Response, error) {
+	transport, _ := httpClient.Transport.(*http.Transport)
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport) //nolint:forcetypeassert
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dialer := &net.Dialer{} //nolint:exhaustruct
+		dial = dialer.DialContext
+	}
+
+	addr := canonicalAddr(req.URL)
+
+	// Connect to the proxy selected for the request, if any
+	var proxy *url.URL
+	if transport.Proxy != nil {
+		var err error
+		if proxy, err = transport.Proxy(req); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if proxy != nil {
+		conn, err = dial(ctx, "tcp", canonicalAddr(proxy))
+		if err == nil {
+			err = connectTunnel(conn, proxy, addr)
+		}
+	} else {
+		conn, err = dial(ctx, "tcp", addr)
+	}
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, err
+	}
+
+	// Stop blocking on the connection once the request is canceled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	if req.URL.Scheme == "https" {
+		config := &tls.Config{} //nolint:exhaustruct,gosec
+		if transport.TLSClientConfig != nil {
+			config = transport.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = req.URL.Hostname()
+		}
+		config.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			stop()
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if _, err := conn.Write(raw); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	resp.Body = &connBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+	return resp, nil
+}
+
+// connectTunnel asks the proxy to open a tunnel to addr over conn.
+func connectTunnel(conn net.Conn, proxy *url.URL, addr string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		fmt.Fprintf(&buf, "Proxy-Authorization: Basic %s\r\n", credentials)
+	}
+	buf.WriteString("\r\n")
+
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	// The target sends nothing before the request, so no byte of the tunnel is buffered away
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect}) //nolint:exhaustruct
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused the tunnel to %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// canonicalAddr returns the host and port of the URL, defaulting to the port of its scheme.
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// connBody is the body of a raw response, closing its connection when closed.
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+// Close closes the body and its connection.
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	b.conn.Close()
+	return err
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header marking requests of non-idempotent methods as safe to send
+// again, as the server recognizes the repeated requests by their key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// noReplayKey is the context key used to forbid sending a request more than once.
+type noReplayKey struct{}
+
+// WithoutReplay returns a copy of ctx forbidding middleware declaring ReplaysRequest to send
+// the request again once it was sent, even if its method is idempotent.
+func WithoutReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noReplayKey{}, true)
+}
+
+// CanReplay reports whether middleware may send the request again, such as after a response
+// rejecting it. Requests can be replayed if replays were not forbidden with WithoutReplay, their
+// body can be read again with GetBody, and either their method is idempotent as defined by
+// RFC 9110 or they carry an IdempotencyKeyHeader.
+func CanReplay(ctx context.Context, req *http.Request) bool {
+	if noReplay, ok := ctx.Value(noReplayKey{}).(bool); ok && noReplay {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return req.Header.Get(IdempotencyKeyHeader) != ""
+	}
+}
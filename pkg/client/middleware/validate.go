@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// Capability describes how a middleware handles requests, so that the chain can detect
+// middleware added in a position where it cannot work.
+type Capability int
+
+const (
+	// ReadsBody is declared by middleware that reads the request body, such as to derive a key.
+	ReadsBody Capability = iota
+	// ConsumesBody is declared by middleware that reads the request body without restoring it,
+	// leaving an empty body to the middleware after it.
+	ConsumesBody
+	// RewindsBody is declared by middleware that restores the request body after reading it,
+	// making it readable again by the middleware after it.
+	RewindsBody
+	// ReplaysRequest is declared by middleware that may send the request more than once, such as retry.
+	ReplaysRequest
+	// ReadsResponseBody is declared by middleware that inspects the content of the response body,
+	// such as to detect a challenge page, and must see it as the caller does.
+	ReadsResponseBody
+	// DecodesResponseBody is declared by middleware that replaces the response body with its decoded
+	// content, such as decompression. Middleware running after it sees the body still encoded.
+	DecodesResponseBody
+	// SharesResponses is declared by middleware that let concurrent identical requests share the
+	// response of a single request, such as single flight.
+	SharesResponses
+	// NumbersAttempts is declared by middleware replaying requests that store the attempt number
+	// with WithAttempt, so middleware after it can tell the attempts apart.
+	NumbersAttempts
+)
+
+// Describer is implemented by middleware that declare their capabilities.
+type Describer interface {
+	Capabilities() []Capability
+}
+
+// orderConstraint requires the middleware of one type to run before the middleware of another.
+type orderConstraint struct {
+	outer reflect.Type
+	inner reflect.Type
+}
+
+// CompositionIssue describes a middleware that cannot work in its position in the chain.
+type CompositionIssue struct {
+	Index      int    `json:"index"`
+	Middleware string `json:"middleware"`
+	Reason     string `json:"reason"`
+}
+
+// Error implements the error interface.
+func (i CompositionIssue) Error() string {
+	return fmt.Sprintf("%s at index %d: %s", i.Middleware, i.Index, i.Reason)
+}
+
+// CompositionError reports every issue found in the composition of a chain.
+// It matches errors.ErrInvalidComposition with errors.Is.
+type CompositionError struct {
+	Issues []CompositionIssue `json:"issues"`
+}
+
+// Error implements the error interface.
+func (e *CompositionError) Error() string {
+	issues := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		issues = append(issues, issue.Error())
+	}
+	return fmt.Sprintf("%s: %s", errors.ErrInvalidComposition, strings.Join(issues, "; "))
+}
+
+// Unwrap returns errors.ErrInvalidComposition followed by every issue.
+func (e *CompositionError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Issues)+1)
+	errs = append(errs, errors.ErrInvalidComposition)
+	for _, issue := range e.Issues {
+		errs = append(errs, issue)
+	}
+	return errs
+}
+
+// RequireOrder requires the middleware of the same type as outer to run before the middleware of
+// the same type as inner whenever both are part of the chain, such as a retry wrapping a cache.
+// Any value of the types can be used, such as a nil pointer. The constraint is checked by Validate.
+func (c *Chain) RequireOrder(outer, inner Middleware) {
+	c.constraints = append(c.constraints, orderConstraint{
+		outer: reflect.TypeOf(outer),
+		inner: reflect.TypeOf(inner),
+	})
+}
+
+// Validate checks that every middleware can work in its position in the chain, based on the
+// capabilities declared by middleware implementing Describer and the constraints added with
+// RequireOrder. It returns a *CompositionError listing every issue found, or nil.
+func (c *Chain) Validate() error {
+	var issues []CompositionIssue
+
+	// Middleware reading the body must not run after middleware consuming it
+	consumer, decoder, replayer := -1, -1, -1
+	for i, m := range c.middlewares {
+		capabilities := capabilitiesOf(m)
+		if consumer >= 0 && (capabilities[ReadsBody] || capabilities[ReplaysRequest]) {
+			issues = append(issues, CompositionIssue{
+				Index:      i,
+				Middleware: reflect.TypeOf(m).String(),
+				Reason: fmt.Sprintf("needs the request body, which %s at index %d consumes without rewinding",
+					reflect.TypeOf(c.middlewares[consumer]), consumer),
+			})
+		}
+
+		switch {
+		case capabilities[ConsumesBody]:
+			consumer = i
+		case capabilities[RewindsBody]:
+			consumer = -1
+		}
+
+		// Middleware inspecting the response body must run before the body is decoded for the caller
+		if decoder >= 0 && capabilities[ReadsResponseBody] {
+			issues = append(issues, CompositionIssue{
+				Index:      i,
+				Middleware: reflect.TypeOf(m).String(),
+				Reason: fmt.Sprintf("reads the response body, which %s at index %d only decodes afterwards",
+					reflect.TypeOf(c.middlewares[decoder]), decoder),
+			})
+		}
+		if decoder < 0 && capabilities[DecodesResponseBody] {
+			decoder = i
+		}
+
+		// Middleware sharing responses must tell the attempts apart, or a replayed request would
+		// join the flight of an earlier attempt and receive its failure
+		if replayer >= 0 && capabilities[SharesResponses] {
+			issues = append(issues, CompositionIssue{
+				Index:      i,
+				Middleware: reflect.TypeOf(m).String(),
+				Reason: fmt.Sprintf("shares responses between requests, which %s at index %d replays without numbering attempts",
+					reflect.TypeOf(c.middlewares[replayer]), replayer),
+			})
+		}
+		if replayer < 0 && capabilities[ReplaysRequest] && !capabilities[NumbersAttempts] {
+			replayer = i
+		}
+	}
+
+	// Middleware must respect the configured order
+	for _, constraint := range c.constraints {
+		outer, inner := c.indexOf(constraint.outer), c.indexOf(constraint.inner)
+		if outer < 0 || inner < 0 || outer < inner {
+			continue
+		}
+		issues = append(issues, CompositionIssue{
+			Index:      outer,
+			Middleware: constraint.outer.String(),
+			Reason:     fmt.Sprintf("must run before %s at index %d", constraint.inner, inner),
+		})
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+	return &CompositionError{Issues: issues}
+}
+
+// capabilitiesOf returns the capabilities declared by the middleware.
+func capabilitiesOf(m Middleware) map[Capability]bool {
+	capabilities := make(map[Capability]bool)
+	if describer, ok := m.(Describer); ok {
+		for _, capability := range describer.Capabilities() {
+			capabilities[capability] = true
+		}
+	}
+	return capabilities
+}
@@ -0,0 +1,121 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewClientCertificate creates a self-signed client certificate with the common name.
+func NewClientCertificate(t *testing.T, name string) *tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name}, //nolint:exhaustruct
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key} //nolint:exhaustruct
+}
+
+// RoundTripperFunc is an http.RoundTripper calling the function.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	// The server answers with the common name of the client certificate
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert} //nolint:exhaustruct,gosec
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	c, err := client.NewClientE(client.WithTLSConfig(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12})) //nolint:exhaustruct
+	require.NoError(t, err)
+
+	tenantA := NewClientCertificate(t, "tenant-a")
+	tenantB := NewClientCertificate(t, "tenant-b")
+
+	name := func(t *testing.T, ctx context.Context, rb *client.Request, opts ...client.RequestOption) string {
+		t.Helper()
+
+		resp, err := rb.URL(server.URL).Do(ctx, opts...)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("Select the certificate per request", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "tenant-a", name(t, context.Background(), c.NewRequest().ClientCertificate(tenantA)))
+		assert.Equal(t, "tenant-b", name(t, context.Background(), c.NewRequest(), client.WithClientCertificate(tenantB)))
+
+		// Connections are not shared between certificates
+		assert.Equal(t, "tenant-a", name(t, context.Background(), c.NewRequest().ClientCertificate(tenantA)))
+	})
+
+	t.Run("Select the certificate from the context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := middleware.WithClientCertificate(context.Background(), tenantB)
+		assert.Equal(t, "tenant-b", name(t, ctx, c.NewRequest()))
+	})
+
+	t.Run("Fail if the transport cannot present the certificate", func(t *testing.T) {
+		t.Parallel()
+
+		httpClient := &http.Client{Transport: RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			t.Fatal("Request should not be sent")
+			return nil, nil
+		})}
+		chain := middleware.NewChain(logger.NewBasicLogger())
+
+		ctx := middleware.WithClientCertificate(context.Background(), tenantA)
+		_, err := chain.Process(ctx, httpClient, httptest.NewRequest(http.MethodGet, server.URL, nil))
+		require.ErrorIs(t, err, errors.ErrClientCertificate)
+	})
+
+	t.Run("Fail without a certificate", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// escapeQuotes escapes the quotes and backslashes of a parameter of the Content-Disposition header.
+var escapeQuotes = strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace
+
+// multipartPart is a part of a multipart body.
+type multipartPart struct {
+	header textproto.MIMEHeader
+	value  string
+	reader io.Reader
+}
+
+// Multipart describes a multipart/form-data body. Its parts are streamed when the request is
+// sent rather than buffered in memory, so the readers of file parts are read only once and the
+// body cannot be sent again, such as by the retry middleware.
+type Multipart struct {
+	parts    []multipartPart
+	boundary string
+}
+
+// NewMultipart creates a new Multipart instance with a random boundary.
+func NewMultipart() *Multipart {
+	return &Multipart{
+		parts:    nil,
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+// Field adds a form field.
+func (m *Multipart) Field(name, value string) *Multipart {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	m.parts = append(m.parts, multipartPart{header: header, value: value, reader: nil})
+	return m
+}
+
+// File adds a file read from r with the application/octet-stream content type.
+func (m *Multipart) File(name, filename string, r io.Reader) *Multipart {
+	return m.FileWithType(name, filename, "application/octet-stream", r)
+}
+
+// FileWithType adds a file read from r with the given content type.
+// If r is an io.Closer, it is closed once read.
+func (m *Multipart) FileWithType(name, filename, contentType string, r io.Reader) *Multipart {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(name), escapeQuotes(filename)))
+	header.Set("Content-Type", contentType)
+	return m.Part(header, r)
+}
+
+// Part adds a part with custom headers read from r.
+// If r is an io.Closer, it is closed once read.
+func (m *Multipart) Part(header textproto.MIMEHeader, r io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{header: header, value: "", reader: r})
+	return m
+}
+
+// ContentType returns the content type of the body, including its boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// Multipart sets the multipart body of the request, along with its content type.
+// Request body transformations do not apply to multipart bodies.
+func (rb *Request) Multipart(m *Multipart) *Request {
+	rb.multipart = m
+	rb.header.Set("Content-Type", m.ContentType())
+	return rb
+}
+
+// reader returns a reader streaming the body, which starts encoding the parts on first read.
+func (m *Multipart) reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	return &multipartReader{PipeReader: pr, writer: pw, multipart: m, once: sync.Once{}}
+}
+
+// write encodes every part into w.
+func (m *Multipart) write(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, part := range m.parts {
+		pw, err := writer.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+
+		if part.reader == nil {
+			if _, err := io.WriteString(pw, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = io.Copy(pw, part.reader)
+		if closer, ok := part.reader.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// multipartReader streams a multipart body through a pipe. The parts are encoded by a goroutine
+// started on first read, so that no goroutine leaks if the body is never read.
+type multipartReader struct {
+	*io.PipeReader
+	writer    *io.PipeWriter
+	multipart *Multipart
+	once      sync.Once
+}
+
+// Read reads the encoded body.
+func (r *multipartReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		go func() {
+			r.writer.CloseWithError(r.multipart.write(r.writer))
+		}()
+	})
+	return r.PipeReader.Read(p)
+}
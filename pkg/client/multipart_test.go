@@ -0,0 +1,87 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipart(t *testing.T) {
+	t.Parallel()
+
+	// The server describes every part it received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+
+			content, err := io.ReadAll(part)
+			require.NoError(t, err)
+			parts = append(parts, fmt.Sprintf("%s|%s|%s|%s", part.FormName(), part.FileName(), part.Header.Get("Content-Type"), content))
+		}
+
+		w.Header().Set("X-Transfer-Encoding", strings.Join(r.TransferEncoding, ","))
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write([]byte(strings.Join(parts, "\n")))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Stream fields and files", func(t *testing.T) {
+		t.Parallel()
+
+		body := client.NewMultipart().
+			Field("title", "report").
+			File("data", "data.bin", strings.NewReader("binary")).
+			FileWithType("doc", `my "doc".json`, "application/json", io.NopCloser(strings.NewReader(`{"a":1}`)))
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			Multipart(body).
+			DoResponse(context.Background())
+		require.NoError(t, err)
+
+		parts, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, strings.Join([]string{
+			"title|||report",
+			"data|data.bin|application/octet-stream|binary",
+			`doc|my "doc".json|application/json|{"a":1}`,
+		}, "\n"), parts)
+
+		// The body is streamed rather than sent with a known length
+		assert.Equal(t, "chunked", resp.Header("X-Transfer-Encoding"))
+	})
+
+	t.Run("Reject multipart bodies along with other bodies", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			Body([]byte("raw")).
+			Multipart(client.NewMultipart().Field("title", "report")).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrBodyMarshalConflict)
+	})
+}
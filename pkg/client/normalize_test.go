@@ -0,0 +1,46 @@
+package client_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLNormalizer(t *testing.T) {
+	t.Parallel()
+
+	normalize := func(t *testing.T, normalizer *middleware.URLNormalizer, rawURL string) string {
+		t.Helper()
+
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		return normalizer.Normalize(u)
+	}
+
+	t.Run("Normalize with the default rules", func(t *testing.T) {
+		t.Parallel()
+
+		normalizer := middleware.NewURLNormalizer()
+		want := "https://example.com/Path?a=1&b=2&b=1"
+		assert.Equal(t, want, normalize(t, normalizer, "HTTPS://Example.COM:443/Path?b=2&utm_source=news&a=1&b=1&gclid=x#top"))
+		assert.Equal(t, want, normalize(t, normalizer, "https://example.com/Path?a=1&b=2&b=1"))
+
+		// Encoding and non-default ports are kept
+		assert.Equal(t, "http://example.com:8080/?q=a%20b", normalize(t, normalizer, "http://example.com:8080/?q=a%20b&utm_medium=x"))
+		assert.Equal(t, "http://example.com", normalize(t, normalizer, "http://example.com?utm_campaign=x"))
+	})
+
+	t.Run("Configure the rules", func(t *testing.T) {
+		t.Parallel()
+
+		normalizer := middleware.NewURLNormalizer().
+			SortQuery(false).
+			NormalizeHost(false).
+			DropParams("session", "ref_*")
+		assert.Equal(t, "https://Example.com/?b=2&utm_source=x&a=1",
+			normalize(t, normalizer, "https://Example.com/?b=2&session=abc&utm_source=x&ref_id=1&a=1"))
+	})
+}
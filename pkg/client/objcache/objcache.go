@@ -0,0 +1,220 @@
+// Package objcache caches the values decoded from responses, keyed by request, so repeated
+// identical requests skip both the network and the decoding of the response.
+package objcache
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// InvalidateFunc is called with the URL and value of an entry removed from a Cache by an
+// invalidation.
+type InvalidateFunc[T any] func(url string, value T)
+
+// Cache holds the values of type T decoded from the responses of GET requests. Values are shared
+// by the callers of Do, so they must not be modified.
+type Cache[T any] struct {
+	ttl        time.Duration
+	entries    map[string]entry[T]
+	normalizer *middleware.URLNormalizer
+	hooks      []InvalidateFunc[T]
+	mu         sync.Mutex
+}
+
+// entry is a value held by a Cache.
+type entry[T any] struct {
+	url     string
+	value   T
+	expires time.Time
+}
+
+// New creates a new Cache instance keeping values for ttl, or until invalidated if it is zero.
+func New[T any](ttl time.Duration) *Cache[T] {
+	return &Cache[T]{
+		ttl:        ttl,
+		entries:    make(map[string]entry[T]),
+		normalizer: middleware.NewURLNormalizer(),
+		hooks:      nil,
+		mu:         sync.Mutex{},
+	}
+}
+
+// SetNormalizer sets the normalizer applied to request URLs before deriving their key, so
+// semantically identical URLs share entries. Passing nil uses URLs as they are.
+func (c *Cache[T]) SetNormalizer(normalizer *middleware.URLNormalizer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.normalizer = normalizer
+}
+
+// OnInvalidate adds a hook called for each entry removed by an invalidation, such as to
+// invalidate the values derived from it.
+func (c *Cache[T]) OnInvalidate(fn InvalidateFunc[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks = append(c.hooks, fn)
+}
+
+// Do returns the value cached for the request, or executes the request with client.Do and caches
+// the decoded value. Requests are keyed by their normalized URL and headers, and errors are not
+// cached. Other methods than GET are never cached, and once they succeed, they invalidate the
+// entries of their resource and its sub-resources, like InvalidatePrefix.
+func (c *Cache[T]) Do(ctx context.Context, rb *client.Request, opts ...client.RequestOption) (T, error) {
+	// Build a copy of the request to derive its key, without sending it
+	req, err := rb.With(opts...).Build(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	rawURL := c.normalize(req)
+
+	if req.Method != http.MethodGet {
+		value, err := c.fetch(ctx, rb, opts)
+		if err == nil {
+			c.InvalidatePrefix(strings.SplitN(rawURL, "?", 2)[0])
+		}
+		return value, err
+	}
+
+	key := cacheKey(rawURL, req.Header)
+	if value, ok := c.get(key); ok {
+		return value, nil
+	}
+
+	value, err := c.fetch(ctx, rb, opts)
+	if err != nil {
+		return value, err
+	}
+	c.set(key, rawURL, value)
+	return value, nil
+}
+
+// Invalidate removes the entries of the URLs and values matched by fn, calling the
+// invalidation hooks for each of them, and returns the number of entries removed.
+func (c *Cache[T]) Invalidate(fn func(url string, value T) bool) int {
+	c.mu.Lock()
+	var removed []entry[T]
+	for key, e := range c.entries {
+		if fn(e.url, e.value) {
+			removed = append(removed, e)
+			delete(c.entries, key)
+		}
+	}
+	hooks := c.hooks
+	c.mu.Unlock()
+
+	for _, e := range removed {
+		for _, hook := range hooks {
+			hook(e.url, e.value)
+		}
+	}
+	return len(removed)
+}
+
+// InvalidatePrefix removes the entries of the URL and of the URLs below it, such as
+// "https://api.example.com/users/42" and "https://api.example.com/users/42/posts?page=2" for
+// the former, and returns the number of entries removed. The URL is normalized like the keys.
+func (c *Cache[T]) InvalidatePrefix(rawURL string) int {
+	if req, err := http.NewRequest(http.MethodGet, rawURL, nil); err == nil {
+		rawURL = c.normalize(req)
+	}
+
+	return c.Invalidate(func(url string, _ T) bool {
+		rest, ok := strings.CutPrefix(url, rawURL)
+		return ok && (rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "?"))
+	})
+}
+
+// InvalidateAll removes every entry and returns the number of entries removed.
+func (c *Cache[T]) InvalidateAll() int {
+	return c.Invalidate(func(string, T) bool { return true })
+}
+
+// Len returns the number of entries held, including those expired since the last value was cached.
+func (c *Cache[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// fetch executes the request with the options and decodes its response.
+func (c *Cache[T]) fetch(ctx context.Context, rb *client.Request, opts []client.RequestOption) (T, error) {
+	value, resp, err := client.Do[T](ctx, rb, opts...)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	return value, err
+}
+
+// get returns the value of the key if it has not expired.
+func (c *Cache[T]) get(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+		var zero T
+		return zero, false
+	}
+	return e.value, true
+}
+
+// set stores the value of the key, forgetting the expired entries.
+func (c *Cache[T]) set(key, url string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range c.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	e := entry[T]{url: url, value: value, expires: time.Time{}}
+	if c.ttl > 0 {
+		e.expires = now.Add(c.ttl)
+	}
+	c.entries[key] = e
+}
+
+// normalize returns the normalized URL of the request.
+func (c *Cache[T]) normalize(req *http.Request) string {
+	c.mu.Lock()
+	normalizer := c.normalizer
+	c.mu.Unlock()
+
+	if normalizer == nil {
+		return req.URL.String()
+	}
+	return normalizer.Normalize(req.URL)
+}
+
+// cacheKey returns the key of a request with the URL and headers.
+func cacheKey(url string, header http.Header) string {
+	var b strings.Builder
+	b.WriteString(url)
+
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		b.WriteString("\n" + key + ": " + strings.Join(header[key], ", "))
+	}
+	return b.String()
+}
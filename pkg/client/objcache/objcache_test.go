@@ -0,0 +1,127 @@
+package objcache_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/objcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(t *testing.T) (*httptest.Server, *atomic.Int32) {
+		t.Helper()
+
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id": 42, "name": "Ada"}`))
+		}))
+		t.Cleanup(server.Close)
+		return server, &calls
+	}
+
+	t.Run("Serve decoded values of identical requests", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newServer(t)
+		c := client.NewClient(client.WithBaseURL(server.URL))
+		cache := objcache.New[*User](time.Minute)
+
+		for _, url := range []string{"/users/42?b=2&a=1", "/users/42?a=1&b=2"} {
+			user, err := cache.Do(context.Background(), c.NewRequest().URL(url))
+			require.NoError(t, err)
+			assert.Equal(t, &User{ID: 42, Name: "Ada"}, user)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+
+		// Requests with other headers have their own entries
+		_, err := cache.Do(context.Background(), c.NewRequest().URL("/users/42?a=1&b=2").Header("Accept-Language", "fr"))
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("Apply the options to a single call", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newServer(t)
+		c := client.NewClient(client.WithBaseURL(server.URL))
+		cache := objcache.New[*User](time.Minute)
+
+		rb := c.NewRequest().URL("/users/42")
+		_, err := cache.Do(context.Background(), rb, client.WithRequestTimeout(time.Nanosecond))
+		require.Error(t, err)
+
+		// The builder is left without the timeout
+		user, err := cache.Do(context.Background(), rb)
+		require.NoError(t, err)
+		assert.Equal(t, &User{ID: 42, Name: "Ada"}, user)
+	})
+
+	t.Run("Expire values and skip errors", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newServer(t)
+		c := client.NewClient(client.WithBaseURL(server.URL), client.WithErrorOnStatus(client.IsErrorStatus))
+		cache := objcache.New[User](20 * time.Millisecond)
+
+		_, err := cache.Do(context.Background(), c.NewRequest().URL("/missing"))
+		require.Error(t, err)
+		assert.Zero(t, cache.Len())
+
+		_, err = cache.Do(context.Background(), c.NewRequest().URL("/users/42"))
+		require.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+		_, err = cache.Do(context.Background(), c.NewRequest().URL("/users/42"))
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Invalidate resources and call the hooks", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newServer(t)
+		c := client.NewClient(client.WithBaseURL(server.URL))
+		cache := objcache.New[User](0)
+
+		var invalidated []string
+		cache.OnInvalidate(func(url string, user User) {
+			invalidated = append(invalidated, url)
+		})
+
+		for _, url := range []string{"/users/42", "/users/42/posts", "/users/420", "/users"} {
+			_, err := cache.Do(context.Background(), c.NewRequest().URL(url))
+			require.NoError(t, err)
+		}
+
+		// A write through the cache invalidates its resource and sub-resources
+		_, err := cache.Do(context.Background(), c.NewRequest().Method(http.MethodPut).URL("/users/42"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{server.URL + "/users/42", server.URL + "/users/42/posts"}, invalidated)
+		assert.Equal(t, 2, cache.Len())
+
+		assert.Equal(t, 1, cache.InvalidatePrefix(server.URL+"/users/420"))
+		assert.Equal(t, 1, cache.InvalidateAll())
+
+		_, err = cache.Do(context.Background(), c.NewRequest().URL("/users/42"))
+		require.NoError(t, err)
+		assert.Equal(t, int32(6), calls.Load())
+	})
+}
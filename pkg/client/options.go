@@ -3,12 +3,20 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
 	"time"
 
+	"github.com/jaxron/axonet/pkg/client/codec"
+	"github.com/jaxron/axonet/pkg/client/dns"
 	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/events"
 	"github.com/jaxron/axonet/pkg/client/logger"
 	"github.com/jaxron/axonet/pkg/client/middleware"
 )
@@ -19,33 +27,567 @@ type MarshalFunc func(interface{}) ([]byte, error)
 // UnmarshalFunc is a function type that matches standard unmarshal functions.
 type UnmarshalFunc func([]byte, interface{}) error
 
+// TransformFunc is a function type that transforms a request or response body,
+// such as to encrypt fields or scrub personal data.
+type TransformFunc func([]byte) ([]byte, error)
+
 // Option is a function type that modifies the Client configuration.
 type Option func(*Client)
 
-// WithMiddleware adds or updates the middleware for the Client with a specified priority.
+// OptionE adapts a function that can fail into an Option. Its error is returned by NewClientE
+// and Client.Err, such as for options that load files or parse URLs.
+func OptionE(fn func(*Client) error) Option {
+	return func(c *Client) {
+		c.fail(fn(c))
+	}
+}
+
+// validMiddleware reports whether the middleware is set, recording an error otherwise.
+// Middleware of a type the Client already has replaces it, as documented by WithMiddleware.
+func (c *Client) validMiddleware(option string, m middleware.Middleware) bool {
+	if m == nil {
+		c.fail(fmt.Errorf("%w: %s: middleware is nil", errors.ErrInvalidOption, option))
+		return false
+	}
+	return true
+}
+
+// WithMiddleware adds or updates the middleware for the Client with the default priority of 0.
 func WithMiddleware(middleware middleware.Middleware) Option {
 	return func(c *Client) {
-		c.middlewareChain.Then(middleware)
+		if c.validMiddleware("WithMiddleware", middleware) {
+			c.middlewareChain.Then(middleware)
+		}
+	}
+}
+
+// WithMiddlewarePriority adds or updates the middleware for the Client with a specified priority.
+// Middleware with a higher priority runs earlier regardless of the order of the options, and
+// middleware with the same priority runs in the order of the options.
+func WithMiddlewarePriority(priority int, middleware middleware.Middleware) Option {
+	return func(c *Client) {
+		if c.validMiddleware("WithMiddlewarePriority", middleware) {
+			c.middlewareChain.ThenWithPriority(priority, middleware)
+		}
+	}
+}
+
+// WithOptionalMiddleware adds middleware to the Client that is skipped while the Client is degraded.
+func WithOptionalMiddleware(middleware middleware.Middleware) Option {
+	return func(c *Client) {
+		if c.validMiddleware("WithOptionalMiddleware", middleware) {
+			c.middlewareChain.ThenOptional(middleware)
+		}
+	}
+}
+
+// WithDegradationSource makes the Client degraded whenever the source reports an incident,
+// such as the error budget middleware once a host's budget is nearly exhausted.
+func WithDegradationSource(source middleware.DegradationSource) Option {
+	return func(c *Client) {
+		c.middlewareChain.AddDegradationSource(source)
+	}
+}
+
+// WithFlags sets the flags of the Client, which may be shared with other clients or updated by
+// a configuration manager.
+func WithFlags(flags *middleware.FlagSet) Option {
+	return func(c *Client) {
+		if current := c.middlewareChain.Flags(); current != flags && len(current.Values()) > 0 {
+			c.conflict(fmt.Errorf("%w: WithFlags: replaces the flags set by earlier options, use it before WithFlag", errors.ErrOptionConflict))
+		}
+		c.middlewareChain.SetFlags(flags)
+	}
+}
+
+// WithFlag enables or disables a middleware flag for every request of the Client.
+func WithFlag(flag middleware.Flag, enabled bool) Option {
+	return func(c *Client) {
+		c.middlewareChain.Flags().Set(flag, enabled)
+	}
+}
+
+// WithRequiredOrder requires the middleware of the same type as outer to run before the middleware
+// of the same type as inner whenever both are part of the Client, such as a retry wrapping a cache.
+// Any value of the types can be used, such as a nil pointer. NewClient reports a violation with Err.
+func WithRequiredOrder(outer, inner middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewareChain.RequireOrder(outer, inner)
+	}
+}
+
+// WithNamedMiddleware adds the middleware to the Client under a name, so it can be retrieved
+// with Client.Middleware to be reconfigured at runtime.
+func WithNamedMiddleware(name string, m middleware.Middleware) Option {
+	return func(c *Client) {
+		if existing, ok := c.middlewareChain.Get(name); ok && !middleware.Same(existing, m) {
+			c.conflict(fmt.Errorf("%w: WithNamedMiddleware: name %q is already used by %T", errors.ErrOptionConflict, name, existing))
+		}
+		if c.validMiddleware("WithNamedMiddleware", m) {
+			c.middlewareChain.ThenNamed(name, m)
+		}
+	}
+}
+
+// WithMiddlewareBefore adds the middleware to the Client immediately before the middleware of the
+// same type as target. If the Client has no middleware of that type, the middleware is not added
+// and NewClientE reports errors.ErrMissingPrerequisite.
+func WithMiddlewareBefore(target, middleware middleware.Middleware) Option {
+	return func(c *Client) {
+		if c.validMiddleware("WithMiddlewareBefore", middleware) && !c.middlewareChain.InsertBefore(reflect.TypeOf(target), middleware) {
+			c.fail(fmt.Errorf("%w: WithMiddlewareBefore: no %T to insert %T next to", errors.ErrMissingPrerequisite, target, middleware))
+		}
+	}
+}
+
+// WithMiddlewareAfter adds the middleware to the Client immediately after the middleware of the
+// same type as target, such as a tracing middleware running inside the retry middleware.
+// If the Client has no middleware of that type, the middleware is not added and NewClientE
+// reports errors.ErrMissingPrerequisite.
+func WithMiddlewareAfter(target, middleware middleware.Middleware) Option {
+	return func(c *Client) {
+		if c.validMiddleware("WithMiddlewareAfter", middleware) && !c.middlewareChain.InsertAfter(reflect.TypeOf(target), middleware) {
+			c.fail(fmt.Errorf("%w: WithMiddlewareAfter: no %T to insert %T next to", errors.ErrMissingPrerequisite, target, middleware))
+		}
+	}
+}
+
+// WithoutMiddleware removes the middleware of the same type as m from the Client.
+// Combined with a shared list of options, it derives a client without, for example, the cache middleware.
+func WithoutMiddleware(m middleware.Middleware) Option {
+	return func(c *Client) {
+		c.middlewareChain.RemoveByType(reflect.TypeOf(m))
 	}
 }
 
 // WithTimeout sets the timeout for the Client.
 func WithTimeout(timeout time.Duration) Option {
 	return func(c *Client) {
+		if timeout < 0 {
+			c.fail(fmt.Errorf("%w: WithTimeout: negative timeout %s", errors.ErrInvalidOption, timeout))
+			return
+		}
 		c.httpClient.Timeout = timeout
 	}
 }
 
+// WithRedirectPolicy sets how redirects are followed, replacing the default policy of up to 10
+// redirects to any host. Later changes to the policy do not affect the Client.
+func WithRedirectPolicy(policy *RedirectPolicy) Option {
+	return func(c *Client) {
+		if policy == nil {
+			c.fail(fmt.Errorf("%w: WithRedirectPolicy: policy is nil", errors.ErrInvalidOption))
+			return
+		}
+		if err := policy.validate(); err != nil {
+			c.fail(fmt.Errorf("%w: WithRedirectPolicy: %w", errors.ErrInvalidOption, err))
+			return
+		}
+		c.redirectPolicy = policy.clone()
+	}
+}
+
+// WithRedirectCredentials sets whether cookies and the Authorization header are forwarded when
+// following redirects. By default, they are stripped once a redirect leaves the original origin.
+// It is a shorthand for the Credentials setting of the redirect policy.
+func WithRedirectCredentials(policy RedirectCredentialsPolicy) Option {
+	return func(c *Client) {
+		if policy < RedirectStripCrossOrigin || policy > RedirectStripAlways {
+			c.fail(fmt.Errorf("%w: WithRedirectCredentials: unknown policy %d", errors.ErrInvalidOption, policy))
+			return
+		}
+		c.redirectPolicy = c.redirectPolicy.clone().Credentials(policy)
+	}
+}
+
+// WithErrorOnStatus makes Do return a *StatusError along with the response whenever the status
+// code matches the predicate, such as IsErrorStatus for 4xx and 5xx responses. It is disabled by default.
+func WithErrorOnStatus(fn func(status int) bool) Option {
+	return func(c *Client) {
+		c.errorOnStatus = fn
+	}
+}
+
+// WithContentTypeCheck makes requests with a result fail with *errors.ContentTypeError when the
+// Content-Type header of the response, or the content type detected from the beginning of its body,
+// matches none of the media types, such as when an endpoint returns an HTML error page where JSON
+// was expected. Without media types, the response must be JSON, as decoded by the default unmarshal
+// function. Types may be wildcards such as "text/*", and "application/json" also matches types with
+// the "+json" suffix. Requests override the types with ExpectContentType.
+func WithContentTypeCheck(types ...string) Option {
+	return func(c *Client) {
+		if len(types) == 0 {
+			types = []string{"application/json"}
+		}
+		c.contentTypes = types
+	}
+}
+
+// WithClassifier sets the classifier deciding what counts as a failure for the middleware that
+// track the health of upstreams or retry requests, such as the circuit breaker, retry and error
+// budget middleware, so that they agree. It replaces middleware.DefaultClassifier.
+func WithClassifier(classifier middleware.Classifier) Option {
+	return func(c *Client) {
+		if classifier == nil {
+			c.fail(fmt.Errorf("%w: WithClassifier: classifier is nil", errors.ErrInvalidOption))
+			return
+		}
+		c.middlewareChain.SetClassifier(classifier)
+	}
+}
+
+// WithMaxResponseBytes limits response bodies to n bytes. Reading beyond the limit fails with a
+// *errors.ResponseTooLargeError, before middleware such as the cache or unmarshaling buffer the body,
+// and responses declaring a larger Content-Length fail immediately. Requests may override the limit.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *Client) {
+		if n < 0 {
+			c.fail(fmt.Errorf("%w: WithMaxResponseBytes: negative limit %d", errors.ErrInvalidOption, n))
+			return
+		}
+		c.maxResponseBytes = n
+	}
+}
+
+// WithBaseURL sets the URL that relative request URLs are resolved against. The path of a
+// relative URL is appended to the path of the base URL, and the query parameters of the base
+// URL are kept. Absolute request URLs are used as they are.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			c.fail(fmt.Errorf("%w: WithBaseURL: %w", errors.ErrInvalidOption, err))
+			return
+		}
+		if base.Scheme == "" || base.Host == "" {
+			c.fail(fmt.Errorf("%w: WithBaseURL: %q is not an absolute URL", errors.ErrInvalidOption, baseURL))
+			return
+		}
+		c.baseURL = base
+	}
+}
+
+// WithHTTP1 makes the Client use HTTP/1.1 even when servers support HTTP/2, which is required
+// for headers added with Request.RawHeader to keep their casing.
+func WithHTTP1() Option {
+	return func(c *Client) {
+		transport, ok := c.cloneTransport("WithHTTP1")
+		if !ok {
+			return
+		}
+
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithHTTP2 makes the Client use HTTP/2 for every request, failing with servers supporting
+// HTTP/1.1 only instead of falling back to it. Requests to http:// URLs require WithH2C.
+func WithHTTP2() Option {
+	return func(c *Client) {
+		transport, ok := c.cloneTransport("WithHTTP2")
+		if !ok {
+			return
+		}
+
+		protocols := protocolsOf(transport)
+		protocols.SetHTTP1(false)
+		protocols.SetHTTP2(true)
+		transport.Protocols = protocols
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithH2C makes the Client use cleartext HTTP/2 (h2c) with prior knowledge for http:// URLs, such
+// as for internal services. As HTTP/1.1 is then disabled, every request uses HTTP/2.
+func WithH2C() Option {
+	return func(c *Client) {
+		transport, ok := c.cloneTransport("WithH2C")
+		if !ok {
+			return
+		}
+
+		protocols := protocolsOf(transport)
+		protocols.SetHTTP1(false)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = protocols
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithHTTP2Config tunes the HTTP/2 connections of the Client, such as the ping timeouts used to
+// detect dead connections and the maximum number of concurrent streams per connection.
+func WithHTTP2Config(config http.HTTP2Config) Option {
+	return func(c *Client) {
+		transport, ok := c.cloneTransport("WithHTTP2Config")
+		if !ok {
+			return
+		}
+
+		transport.HTTP2 = &config
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithDialTimeout limits the time to establish each connection, unlike WithTimeout which covers
+// the whole request. The dialer of the default transport already gives up after 30 seconds.
+// With WithResolver added before it, the timeout includes resolving the host.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		if timeout < 0 {
+			c.fail(fmt.Errorf("%w: WithDialTimeout: negative timeout %s", errors.ErrInvalidOption, timeout))
+			return
+		}
+		transport, ok := c.transportCopy("WithDialTimeout")
+		if !ok || timeout == 0 {
+			return
+		}
+
+		dial := transport.DialContext
+		if dial == nil {
+			dialer := &net.Dialer{} //nolint:exhaustruct
+			dial = dialer.DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return dial(ctx, network, addr)
+		}
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithTLSHandshakeTimeout limits the time of the TLS handshake of each connection. Zero disables
+// the limit, which is 10 seconds with the default transport.
+func WithTLSHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		if timeout < 0 {
+			c.fail(fmt.Errorf("%w: WithTLSHandshakeTimeout: negative timeout %s", errors.ErrInvalidOption, timeout))
+			return
+		}
+		transport, ok := c.transportCopy("WithTLSHandshakeTimeout")
+		if !ok {
+			return
+		}
+
+		transport.TLSHandshakeTimeout = timeout
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithResponseHeaderTimeout limits the time to wait for the response headers once the request is
+// sent, so slow servers fail fast without limiting the time to read large bodies. Zero disables
+// the limit, which is the default.
+func WithResponseHeaderTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		if timeout < 0 {
+			c.fail(fmt.Errorf("%w: WithResponseHeaderTimeout: negative timeout %s", errors.ErrInvalidOption, timeout))
+			return
+		}
+		transport, ok := c.transportCopy("WithResponseHeaderTimeout")
+		if !ok {
+			return
+		}
+
+		transport.ResponseHeaderTimeout = timeout
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithResolver makes the Client resolve the hosts it connects to with the resolver, such as a
+// dns.Cache to avoid resolving the same hosts for every new connection. With a proxy, the
+// resolver resolves the host of the proxy, which resolves the target itself.
+func WithResolver(resolver dns.Resolver) Option {
+	return func(c *Client) {
+		if resolver == nil {
+			c.fail(fmt.Errorf("%w: WithResolver: resolver is nil", errors.ErrInvalidOption))
+			return
+		}
+
+		transport, ok := c.transportCopy("WithResolver")
+		if !ok {
+			return
+		}
+
+		transport.DialContext = dns.Dial(resolver, transport.DialContext)
+		c.httpClient.Transport = transport
+		c.resolver = resolver
+	}
+}
+
+// WithCookieJar sets the cookie jar of the Client, such as one from net/http/cookiejar. Cookies set by
+// responses are stored in the jar and sent with later requests matching their domain and path,
+// along with the cookies set by the cookie middleware. Requests may use another jar.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *Client) {
+		c.httpClient.Jar = jar
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the connections of the Client, such as client
+// certificates, trusted CAs or the minimum TLS version. Pins set with WithPinnedCerts still apply.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		if config == nil {
+			c.fail(fmt.Errorf("%w: WithTLSConfig: config is nil", errors.ErrInvalidOption))
+			return
+		}
+
+		transport, ok := c.transportCopy("WithTLSConfig")
+		if !ok {
+			return
+		}
+
+		clone := config.Clone()
+		if len(c.pins) > 0 {
+			clone.VerifyConnection = c.verifyPins(clone.VerifyConnection)
+		}
+		transport.TLSClientConfig = clone
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithPinnedCerts makes the Client only connect to servers presenting a certificate chain with one
+// of the pinned public keys, failing with an *errors.PinError otherwise. Pins are the base64
+// SHA-256 hashes of the SubjectPublicKeyInfo of a certificate, as printed by
+// "openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64",
+// optionally prefixed with "sha256/". The chain is still verified against the trusted CAs.
+func WithPinnedCerts(pins ...string) Option {
+	return func(c *Client) {
+		parsed := make([]string, 0, len(pins))
+		for _, pin := range pins {
+			p, err := parsePin(pin)
+			if err != nil {
+				c.fail(fmt.Errorf("%w: WithPinnedCerts: %w", errors.ErrInvalidOption, err))
+				return
+			}
+			parsed = append(parsed, p)
+		}
+
+		transport, ok := c.transportCopy("WithPinnedCerts")
+		if !ok {
+			return
+		}
+
+		// Pins added by an earlier option are already verified by the configuration
+		pinned := len(c.pins) > 0
+		c.pins = append(c.pins, parsed...)
+		if pinned {
+			return
+		}
+
+		config := &tls.Config{} //nolint:exhaustruct,gosec
+		if transport.TLSClientConfig != nil {
+			config = transport.TLSClientConfig.Clone()
+		}
+		config.VerifyConnection = c.verifyPins(config.VerifyConnection)
+		transport.TLSClientConfig = config
+		c.httpClient.Transport = transport
+	}
+}
+
+// cloneTransport returns a copy of the transport of the Client for the protocol option to configure,
+// recording an error if the option conflicts with the protocols chosen by an earlier option.
+func (c *Client) cloneTransport(option string) (*http.Transport, bool) {
+	// HTTP/1.1 only excludes every HTTP/2 option
+	if c.protocolOption != "" && (c.protocolOption == "WithHTTP1") != (option == "WithHTTP1") {
+		c.conflict(fmt.Errorf("%w: %s: conflicts with %s", errors.ErrOptionConflict, option, c.protocolOption))
+		return nil, false
+	}
+
+	transport, ok := c.transportCopy(option)
+	if ok {
+		c.protocolOption = option
+	}
+	return transport, ok
+}
+
+// transportCopy returns a copy of the transport of the Client for the option to configure,
+// recording an error if the transport is not an *http.Transport.
+func (c *Client) transportCopy(option string) (*http.Transport, bool) {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		c.fail(fmt.Errorf("%w: %s: transport %T is not an *http.Transport", errors.ErrInvalidOption, option, c.httpClient.Transport))
+		return nil, false
+	}
+	return transport.Clone(), true
+}
+
+// protocolsOf returns a copy of the protocols enabled on the transport.
+func protocolsOf(transport *http.Transport) *http.Protocols {
+	protocols := new(http.Protocols)
+	if transport.Protocols != nil {
+		*protocols = *transport.Protocols
+	}
+	return protocols
+}
+
+// WithEarlyRejection makes the Client fail fast with ErrTimeout when the context deadline
+// is earlier than the estimated time needed to complete the request.
+func WithEarlyRejection() Option {
+	return func(c *Client) {
+		c.middlewareChain.SetEarlyRejection(true)
+	}
+}
+
+// WithDeadlineHeader makes the Client send the remaining time of requests with a context
+// deadline in the header, in the format, so servers can stop working on requests the client no
+// longer waits for. An empty name uses X-Request-Timeout. The same header in responses is parsed
+// into the budget returned by middleware.DeadlineBudgetFromResponse.
+func WithDeadlineHeader(name string, format middleware.DeadlineFormat) Option {
+	return func(c *Client) {
+		if name == "" {
+			name = middleware.DefaultDeadlineHeader
+		}
+		c.middlewareChain.SetDeadlineHeader(name, format)
+	}
+}
+
 // WithLogger sets the logger for the Client and its middleware.
 func WithLogger(logger logger.Logger) Option {
 	return func(c *Client) {
+		if logger == nil {
+			c.fail(fmt.Errorf("%w: WithLogger: logger is nil", errors.ErrInvalidOption))
+			return
+		}
 		c.middlewareChain.SetLogger(logger)
 	}
 }
 
+// WithLogRedaction makes the Client and its middleware mask credentials in the messages and fields
+// they log with the redactor, whatever the order of WithLogger, such as the Authorization and
+// Cookie headers and the tokens in URLs. Use logger.NewRedactor for the default deny lists.
+func WithLogRedaction(redactor *logger.Redactor) Option {
+	return func(c *Client) {
+		if redactor == nil {
+			c.fail(fmt.Errorf("%w: WithLogRedaction: redactor is nil", errors.ErrInvalidOption))
+			return
+		}
+		c.middlewareChain.SetRedactor(redactor)
+	}
+}
+
+// WithEventBus publishes the events emitted about the requests of the Client to the bus, such as
+// retries, cache hits and misses, proxy rotations and circuit breaker state changes.
+func WithEventBus(bus *events.Bus) Option {
+	return func(c *Client) {
+		if bus == nil {
+			c.fail(fmt.Errorf("%w: WithEventBus: bus is nil", errors.ErrInvalidOption))
+			return
+		}
+		c.eventBus = bus
+	}
+}
+
 // WithMarshalFunc sets the marshal function for the Client.
 func WithMarshalFunc(fn MarshalFunc) Option {
 	return func(c *Client) {
+		if fn == nil {
+			c.fail(fmt.Errorf("%w: WithMarshalFunc: function is nil", errors.ErrInvalidOption))
+			return
+		}
 		c.marshalFunc = fn
 	}
 }
@@ -53,37 +595,139 @@ func WithMarshalFunc(fn MarshalFunc) Option {
 // WithUnmarshalFunc sets the unmarshal function for the Client.
 func WithUnmarshalFunc(fn UnmarshalFunc) Option {
 	return func(c *Client) {
+		if fn == nil {
+			c.fail(fmt.Errorf("%w: WithUnmarshalFunc: function is nil", errors.ErrInvalidOption))
+			return
+		}
 		c.unmarshalFunc = fn
 	}
 }
 
 // Request helps build requests using method chaining.
 type Request struct {
-	client        *Client
-	marshalFunc   MarshalFunc
-	unmarshalFunc UnmarshalFunc
-	result        interface{}
-	method        string
-	url           string
-	body          []byte
-	marshalBody   interface{}
-	header        http.Header
-	query         Query
+	client           *Client
+	marshalFunc      MarshalFunc
+	unmarshalFunc    UnmarshalFunc
+	result           interface{}
+	resultPath       string
+	contentTypes     []string
+	method           string
+	url              string
+	body             []byte
+	marshalBody      interface{}
+	header           http.Header
+	query            Query
+	priority         *middleware.Priority
+	middlewares      []middleware.Middleware
+	contextFuncs     []func(context.Context) context.Context
+	reqTransform     []TransformFunc
+	respTransform    []TransformFunc
+	timeout          time.Duration
+	multipart        *Multipart
+	bodyReader       io.Reader
+	getBody          func() (io.ReadCloser, error)
+	maxResponseBytes *int64
+	group            *Group
+}
+
+// RequestOption is a function type that tunes a single execution of a Request.
+type RequestOption func(*Request)
+
+// WithPriority sets the priority of the request.
+func WithPriority(priority middleware.Priority) RequestOption {
+	return func(rb *Request) {
+		rb.Priority(priority)
+	}
+}
+
+// WithRequestTimeout limits the time the request may take, including reading the response body.
+// It replaces the timeout of the Client, like the Timeout builder method.
+func WithRequestTimeout(timeout time.Duration) RequestOption {
+	return func(rb *Request) {
+		rb.Timeout(timeout)
+	}
+}
+
+// WithResponseLimit limits the response body of the request to n bytes, overriding the limit of the
+// Client. A limit of zero or less disables the limit for the request.
+func WithResponseLimit(n int64) RequestOption {
+	return func(rb *Request) {
+		rb.MaxResponseBytes(n)
+	}
+}
+
+// WithClientCertificate presents the certificate to servers requesting a client certificate for the request.
+func WithClientCertificate(cert *tls.Certificate) RequestOption {
+	return func(rb *Request) {
+		rb.ClientCertificate(cert)
+	}
+}
+
+// WithContextValue adds a value to the context of the request.
+// Middleware modules use it to provide options such as skipping the cache.
+func WithContextValue(key, value interface{}) RequestOption {
+	return func(rb *Request) {
+		rb.contextFuncs = append(rb.contextFuncs, func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, key, value)
+		})
+	}
+}
+
+// WithSkipMiddleware skips the middleware of the same types as the given middleware for the request.
+// Any value of the type can be used, such as a nil pointer.
+func WithSkipMiddleware(middlewares ...middleware.Middleware) RequestOption {
+	return func(rb *Request) {
+		rb.contextFuncs = append(rb.contextFuncs, func(ctx context.Context) context.Context {
+			return middleware.WithSkip(ctx, middlewares...)
+		})
+	}
+}
+
+// WithSkipNamed skips the middleware registered under the given names for the request,
+// without importing the packages of the middleware.
+func WithSkipNamed(names ...string) RequestOption {
+	return func(rb *Request) {
+		rb.contextFuncs = append(rb.contextFuncs, func(ctx context.Context) context.Context {
+			return middleware.WithSkipNamed(ctx, names...)
+		})
+	}
+}
+
+// WithRequestFlag enables or disables a middleware flag for the request, overriding its global state.
+func WithRequestFlag(flag middleware.Flag, enabled bool) RequestOption {
+	return func(rb *Request) {
+		rb.contextFuncs = append(rb.contextFuncs, func(ctx context.Context) context.Context {
+			return middleware.WithFlag(ctx, flag, enabled)
+		})
+	}
 }
 
 // NewRequest creates a new Request with default options.
 func (c *Client) NewRequest() *Request {
 	return &Request{
-		client:        c,
-		marshalFunc:   c.marshalFunc,
-		unmarshalFunc: c.unmarshalFunc,
-		result:        nil,
-		method:        "",
-		url:           "",
-		body:          nil,
-		marshalBody:   nil,
-		header:        make(http.Header),
-		query:         make(Query),
+		client:           c,
+		marshalFunc:      c.marshalFunc,
+		unmarshalFunc:    c.unmarshalFunc,
+		result:           nil,
+		resultPath:       "",
+		contentTypes:     c.contentTypes,
+		method:           "",
+		url:              "",
+		body:             nil,
+		marshalBody:      nil,
+		header:           make(http.Header),
+		query:            make(Query),
+		priority:         nil,
+		middlewares:      nil,
+		contextFuncs:     nil,
+		reqTransform:     nil,
+		respTransform:    nil,
+		timeout:          0,
+		multipart:        nil,
+		bodyReader:       nil,
+		getBody:          nil,
+		maxResponseBytes: nil,
+		group:            nil,
 	}
 }
 
@@ -111,9 +755,31 @@ func (rb *Request) UnmarshalWith(fn UnmarshalFunc) *Request {
 	return rb
 }
 
-// Result sets the result to unmarshal the response into.
+// Result sets the result to unmarshal the response into. Responses with an error status and a
+// problem details body fail with a *StatusError carrying the *errors.ProblemDetails instead.
 func (rb *Request) Result(result interface{}) *Request {
 	rb.result = result
+	rb.resultPath = ""
+	return rb
+}
+
+// ResultPath sets the result to decode the value at the dot-separated path of a JSON response
+// into, such as "data.items", or "data.items.0" for the first element of an array. The response is
+// decoded while it is streamed, skipping the rest of the document, which saves CPU and allocations
+// for large envelopes. The body is consumed and the unmarshal function of the request is not used.
+// Requests fail with errors.ErrPathNotFound if the response has no value at the path.
+func (rb *Request) ResultPath(path string, result interface{}) *Request {
+	rb.result = result
+	rb.resultPath = path
+	return rb
+}
+
+// ExpectContentType sets the media types the response must have for its result to be decoded,
+// overriding those of WithContentTypeCheck. Responses with another Content-Type header, or whose
+// body looks like another content type, fail with *errors.ContentTypeError. Without media types,
+// the content type is not checked.
+func (rb *Request) ExpectContentType(types ...string) *Request {
+	rb.contentTypes = types
 	return rb
 }
 
@@ -123,6 +789,37 @@ func (rb *Request) Body(body []byte) *Request {
 	return rb
 }
 
+// BodyAs sets the body of the request to v marshaled with the codec registered for the content
+// type, along with the Content-Type header. Building the request fails with
+// codec.ErrUnknownContentType if no codec is registered for it.
+func (rb *Request) BodyAs(contentType string, v interface{}) *Request {
+	rb.marshalBody = v
+	rb.marshalFunc = func(v interface{}) ([]byte, error) {
+		c, ok := codec.Lookup(contentType)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", codec.ErrUnknownContentType, contentType)
+		}
+		return c.Marshal(v)
+	}
+	rb.header.Set("Content-Type", contentType)
+	return rb
+}
+
+// BodyReader sets a body streamed from r instead of buffered in memory, for large payloads.
+// Request body transformations do not apply to streamed bodies. Unless a GetBody factory is set,
+// the body can only be read once, so middleware sending the request again cannot re-read it.
+func (rb *Request) BodyReader(r io.Reader) *Request {
+	rb.bodyReader = r
+	return rb
+}
+
+// GetBody sets the factory returning a new reader of the body, which middleware such as retry and
+// single flight use to read the body again. Without BodyReader, the body is read from a first call.
+func (rb *Request) GetBody(getBody func() (io.ReadCloser, error)) *Request {
+	rb.getBody = getBody
+	return rb
+}
+
 // MarshalBody sets the body of the request after marshaling the provided struct.
 func (rb *Request) MarshalBody(body interface{}) *Request {
 	rb.marshalBody = body
@@ -141,50 +838,223 @@ func (rb *Request) Header(key, value string) *Request {
 	return rb
 }
 
+// RawHeader adds a header to the request with the literal casing of the key, such as "SOAPAction",
+// for servers that do not treat header names as case-insensitive. The casing is kept over HTTP/1.1
+// only, as HTTP/2 lowercases header names; see WithHTTP1.
+func (rb *Request) RawHeader(key, value string) *Request {
+	rb.header[key] = append(rb.header[key], value)
+	return rb
+}
+
+// Priority sets the priority of the request.
+// Middleware such as the rate limiter and concurrency limiter use it to favor important requests.
+func (rb *Request) Priority(priority middleware.Priority) *Request {
+	rb.priority = &priority
+	return rb
+}
+
+// Timeout limits the time the request may take, including reading the response body, replacing
+// the timeout of the Client so it can be shorter or longer. A timeout of zero or less keeps the
+// timeout of the Client.
+func (rb *Request) Timeout(timeout time.Duration) *Request {
+	rb.timeout = timeout
+	return rb
+}
+
+// MaxResponseBytes limits the response body of the request to n bytes, overriding the limit of the
+// Client. A limit of zero or less disables the limit for the request.
+func (rb *Request) MaxResponseBytes(n int64) *Request {
+	rb.maxResponseBytes = &n
+	return rb
+}
+
+// ClientCertificate presents the certificate to servers requesting a client certificate (mTLS),
+// such as the certificate of a tenant. Requests with the same certificate share connections.
+func (rb *Request) ClientCertificate(cert *tls.Certificate) *Request {
+	rb.contextFuncs = append(rb.contextFuncs, func(ctx context.Context) context.Context {
+		return middleware.WithClientCertificate(ctx, cert)
+	})
+	return rb
+}
+
+// CookieJar uses the jar for the request instead of the jar of the Client, such as a jar per
+// user session. A nil jar disables cookie handling for the request.
+func (rb *Request) CookieJar(jar http.CookieJar) *Request {
+	rb.contextFuncs = append(rb.contextFuncs, func(ctx context.Context) context.Context {
+		return middleware.WithCookieJar(ctx, jar)
+	})
+	return rb
+}
+
+// TransformRequestBody adds a transformation applied to the request body after it is marshaled.
+// Transformations are applied in the order they were added.
+func (rb *Request) TransformRequestBody(fn TransformFunc) *Request {
+	rb.reqTransform = append(rb.reqTransform, fn)
+	return rb
+}
+
+// TransformResponseBody adds a transformation applied to the response body before it is unmarshaled.
+// The response body is replaced by the transformed body. Transformations are applied in the order they were added.
+func (rb *Request) TransformResponseBody(fn TransformFunc) *Request {
+	rb.respTransform = append(rb.respTransform, fn)
+	return rb
+}
+
+// WithMiddleware adds middleware for this request only, leaving the client chain unchanged.
+// The middleware replaces any client middleware of the same type, or runs after the client middleware otherwise.
+func (rb *Request) WithMiddleware(middlewares ...middleware.Middleware) *Request {
+	rb.middlewares = append(rb.middlewares, middlewares...)
+	return rb
+}
+
 // Build returns the final http.Request for execution.
 func (rb *Request) Build(ctx context.Context) (*http.Request, error) {
-	// Ensure only one of the body or marshalBody is set
-	if rb.body != nil && rb.marshalBody != nil {
+	// Ensure only one of the body, marshalBody, multipart or streamed body is set
+	streamed := rb.bodyReader != nil || rb.getBody != nil
+	bodies := 0
+	for _, set := range []bool{rb.body != nil, rb.marshalBody != nil, rb.multipart != nil, streamed} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
 		return nil, errors.ErrBodyMarshalConflict
 	}
 
 	var bodyReader io.Reader
+	switch {
+	case rb.multipart != nil:
+		bodyReader = rb.multipart.reader()
+	case rb.bodyReader != nil:
+		bodyReader = rb.bodyReader
+	case rb.getBody != nil:
+		body, err := rb.getBody()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
+		}
+		bodyReader = body
+	}
 
-	// Marshal the body if provided
+	// Marshal the body if provided, otherwise use the body if provided
+	body := rb.body
 	if rb.marshalBody != nil {
 		marshaledBody, err := rb.marshalFunc(rb.marshalBody)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
 		}
-		bodyReader = bytes.NewReader(marshaledBody)
+		body = marshaledBody
 	}
 
-	// Use the body if provided
-	if rb.body != nil {
-		bodyReader = bytes.NewReader(rb.body)
+	if body != nil {
+		// Apply the transformations to the body
+		body, err := transform(body, rb.reqTransform)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
+		}
+		bodyReader = bytes.NewReader(body)
+	}
+
+	// Resolve the URL against the base URL of the client
+	target, err := rb.client.resolveURL(rb.url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
 	}
 
 	// Create a new HTTP request
-	req, err := http.NewRequestWithContext(ctx, rb.method, rb.url, bodyReader)
+	req, err := http.NewRequestWithContext(rb.withContextValues(ctx), rb.method, target, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
 	}
+	if rb.getBody != nil {
+		req.GetBody = rb.getBody
+	}
 
-	// Set the query parameters
-	req.URL.RawQuery = rb.query.Encode()
+	// Set the query parameters, keeping those of the URL that the request does not set
+	query := Query(req.URL.Query())
+	for key, values := range rb.query {
+		query[key] = values
+	}
+	req.URL.RawQuery = query.Encode()
 
-	// Set the headers
+	// Set the headers, keeping the casing of raw headers
 	for key, values := range rb.header {
-		for _, value := range values {
-			req.Header.Add(key, value)
-		}
+		req.Header[key] = append(req.Header[key], values...)
 	}
 
 	return req, nil
 }
 
 // Do executes the request and returns the raw http.Response.
-func (rb *Request) Do(ctx context.Context) (*http.Response, error) {
+// The options apply on top of the builder configuration for this execution only.
+func (rb *Request) Do(ctx context.Context, opts ...RequestOption) (*http.Response, error) {
+	call := rb.withOptions(opts)
+	if call.group != nil {
+		return call.group.run(ctx, call.execute)
+	}
+	return call.execute(ctx)
+}
+
+// With returns a copy of the request with the options applied, leaving the builder as it is,
+// such as to build the request of a single call without sending it.
+func (rb *Request) With(opts ...RequestOption) *Request {
+	return rb.withOptions(opts)
+}
+
+// withOptions returns a copy of the request with the options applied, leaving the builder as it
+// is so it can be executed again, including concurrently, with other options.
+func (rb *Request) withOptions(opts []RequestOption) *Request {
+	call := *rb
+	call.header = rb.header.Clone()
+	call.query = make(Query, len(rb.query))
+	for key, values := range rb.query {
+		call.query[key] = slices.Clone(values)
+	}
+
+	// Appending to the slices of the copy must not write into the arrays of the builder
+	call.contentTypes = slices.Clip(rb.contentTypes)
+	call.middlewares = slices.Clip(rb.middlewares)
+	call.contextFuncs = slices.Clip(rb.contextFuncs)
+	call.reqTransform = slices.Clip(rb.reqTransform)
+	call.respTransform = slices.Clip(rb.respTransform)
+
+	for _, opt := range opts {
+		opt(&call)
+	}
+	return &call
+}
+
+// execute runs the request with its context values and timeout.
+func (rb *Request) execute(ctx context.Context) (*http.Response, error) {
+	ctx = rb.withContextValues(ctx)
+
+	// Bound the request by its timeout, releasing the context once the body is closed
+	if rb.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rb.timeout)
+		ctx = context.WithValue(ctx, requestTimeoutKey{}, rb.timeout)
+
+		resp, err := rb.do(ctx)
+		if resp == nil {
+			cancel()
+			return resp, err
+		}
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, err
+	}
+
+	return rb.do(ctx)
+}
+
+// Do executes the request and unmarshals the response into a value of type T with the
+// unmarshal function of the request, without declaring a result variable beforehand.
+func Do[T any](ctx context.Context, rb *Request, opts ...RequestOption) (T, *http.Response, error) {
+	var result T
+	resp, err := rb.Do(ctx, append(slices.Clip(opts), func(call *Request) { call.Result(&result) })...)
+	return result, resp, err
+}
+
+// do builds and executes the request with a context already carrying the request values.
+func (rb *Request) do(ctx context.Context) (*http.Response, error) {
 	// Build the request
 	req, err := rb.Build(ctx)
 	if err != nil {
@@ -192,13 +1062,33 @@ func (rb *Request) Do(ctx context.Context) (*http.Response, error) {
 	}
 
 	// Execute the request
-	resp, err := rb.client.Do(ctx, req)
+	resp, err := rb.client.do(ctx, req, rb.middlewares)
 	if err != nil {
 		return resp, err
 	}
 
-	// If a result is set, unmarshal the response
+	// Return problem details as errors rather than decoding them into the result
+	if rb.result != nil && IsErrorStatus(resp.StatusCode) && errors.IsProblem(resp.Header.Get("Content-Type")) {
+		return resp, &StatusError{HTTPError: errors.NewHTTPError(resp), Response: resp}
+	}
+
+	// Verify the response is of a content type the result can be decoded from
 	if rb.result != nil {
+		if err := checkContentType(resp, rb.contentTypes); err != nil {
+			return resp, err
+		}
+	}
+
+	// Decode the value at the result path while streaming the response, without reading the rest
+	if rb.resultPath != "" && len(rb.respTransform) == 0 {
+		err := decodePath(resp.Body, rb.resultPath, rb.result)
+		resp.Body.Close()
+		resp.Body = http.NoBody
+		return resp, err
+	}
+
+	// If a result or transformation is set, read the response
+	if rb.result != nil || len(rb.respTransform) > 0 {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return resp, err
@@ -207,10 +1097,67 @@ func (rb *Request) Do(ctx context.Context) (*http.Response, error) {
 		resp.Body.Close()
 		resp.Body = io.NopCloser(bytes.NewBuffer(body))
 
-		if err = rb.unmarshalFunc(body, rb.result); err != nil {
-			return resp, err
+		// Apply the transformations to the body
+		if len(rb.respTransform) > 0 {
+			if body, err = transform(body, rb.respTransform); err != nil {
+				return resp, err
+			}
+			resp.Body = io.NopCloser(bytes.NewBuffer(body))
+		}
+
+		// If a result is set, unmarshal the response
+		switch {
+		case rb.resultPath != "":
+			if err = decodePath(bytes.NewReader(body), rb.resultPath, rb.result); err != nil {
+				return resp, err
+			}
+		case rb.result != nil:
+			if err = rb.unmarshalFunc(body, rb.result); err != nil {
+				return resp, err
+			}
 		}
 	}
 
 	return resp, nil
 }
+
+// transform applies the transformations to the body in order.
+func transform(body []byte, fns []TransformFunc) ([]byte, error) {
+	for _, fn := range fns {
+		var err error
+		if body, err = fn(body); err != nil {
+			return nil, fmt.Errorf("%w: %w", errors.ErrBodyTransform, err)
+		}
+	}
+	return body, nil
+}
+
+// withContextValues returns a copy of ctx carrying the values configured on the request.
+func (rb *Request) withContextValues(ctx context.Context) context.Context {
+	if rb.priority != nil {
+		ctx = middleware.WithPriority(ctx, *rb.priority)
+	}
+	if rb.maxResponseBytes != nil {
+		ctx = middleware.WithMaxResponseBytes(ctx, *rb.maxResponseBytes)
+	}
+	for _, fn := range rb.contextFuncs {
+		ctx = fn(ctx)
+	}
+	return ctx
+}
+
+// requestTimeoutKey is the context key marking requests bounded by their own timeout.
+type requestTimeoutKey struct{}
+
+// cancelOnClose is a response body that cancels the request context when closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context.
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -1,15 +1,19 @@
 package client_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
 	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -34,6 +38,318 @@ func TestWithMiddleware(t *testing.T) {
 	mockMiddleware.AssertExpectations(t)
 }
 
+func TestWithMiddlewarePriority(t *testing.T) {
+	t.Parallel()
+
+	type FirstMiddleware struct{ IntrospectMiddleware }
+	type SecondMiddleware struct{ IntrospectMiddleware }
+	type ThirdMiddleware struct{ IntrospectMiddleware }
+	type FourthMiddleware struct{ IntrospectMiddleware }
+
+	c := NewTestClient(
+		client.WithMiddleware(&ThirdMiddleware{}),
+		client.WithMiddlewarePriority(-10, &FourthMiddleware{}),
+		client.WithMiddlewarePriority(10, &FirstMiddleware{}),
+		client.WithMiddlewarePriority(10, &SecondMiddleware{}),
+	)
+
+	types := func() []string {
+		var types []string
+		for _, snapshot := range c.Introspect().Middlewares {
+			types = append(types, snapshot.Type)
+		}
+		return types
+	}
+
+	assert.Equal(t, []string{
+		"*client_test.FirstMiddleware",
+		"*client_test.SecondMiddleware",
+		"*client_test.ThirdMiddleware",
+		"*client_test.FourthMiddleware",
+	}, types())
+	assert.Equal(t, 10, c.Introspect().Middlewares[0].Priority)
+
+	// Replacing a middleware with another priority moves it
+	c = NewTestClient(
+		client.WithMiddleware(&FirstMiddleware{}),
+		client.WithMiddleware(&SecondMiddleware{}),
+		client.WithMiddlewarePriority(1, &SecondMiddleware{}),
+	)
+	assert.Equal(t, []string{
+		"*client_test.SecondMiddleware",
+		"*client_test.FirstMiddleware",
+	}, types())
+}
+
+func TestWithMiddlewareBeforeAndAfter(t *testing.T) {
+	t.Parallel()
+
+	type FirstMiddleware struct{ IntrospectMiddleware }
+	type SecondMiddleware struct{ IntrospectMiddleware }
+	type ThirdMiddleware struct{ IntrospectMiddleware }
+	type MissingMiddleware struct{ IntrospectMiddleware }
+
+	c := NewTestClient(
+		client.WithMiddleware(&FirstMiddleware{}),
+		client.WithMiddlewarePriority(5, &ThirdMiddleware{}),
+		client.WithMiddlewareAfter((*ThirdMiddleware)(nil), &SecondMiddleware{}),
+		client.WithMiddlewareBefore((*FirstMiddleware)(nil), &HeaderMiddleware{value: "client"}),
+		client.WithMiddlewareAfter((*MissingMiddleware)(nil), &IntrospectMiddleware{}),
+	)
+
+	var types []string
+	for _, snapshot := range c.Introspect().Middlewares {
+		types = append(types, snapshot.Type)
+	}
+	assert.Equal(t, []string{
+		"*client_test.ThirdMiddleware",
+		"*client_test.SecondMiddleware",
+		"*client_test.HeaderMiddleware",
+		"*client_test.FirstMiddleware",
+	}, types)
+	assert.Equal(t, 5, c.Introspect().Middlewares[1].Priority)
+
+	// The middleware placed next to a missing middleware is reported
+	require.ErrorIs(t, c.Err(), errors.ErrMissingPrerequisite)
+	assert.Contains(t, c.Err().Error(), "WithMiddlewareAfter: no *client_test.MissingMiddleware to insert *client_test.IntrospectMiddleware next to")
+}
+
+func TestNonComparableMiddleware(t *testing.T) {
+	t.Parallel()
+
+	c, err := client.NewClientE(
+		client.WithMiddleware(&HeaderMiddleware{value: "client"}),
+		client.WithMiddlewarePriority(5, ValuesMiddleware{values: []string{"first"}}),
+		client.WithMiddlewarePriority(5, ValuesMiddleware{values: []string{"second"}}),
+		client.WithMiddlewareAfter((*HeaderMiddleware)(nil), &IntrospectMiddleware{}),
+		client.WithNamedMiddleware("values", ValuesMiddleware{values: []string{"named"}}),
+		client.WithNamedMiddleware("values", ValuesMiddleware{values: []string{"named"}}),
+	)
+	require.NoError(t, err)
+
+	var types []string
+	for _, snapshot := range c.Introspect().Middlewares {
+		types = append(types, snapshot.Type)
+	}
+	assert.Equal(t, []string{
+		"*client_test.HeaderMiddleware",
+		"*client_test.IntrospectMiddleware",
+		"client_test.ValuesMiddleware",
+	}, types)
+	assert.Equal(t, "values", c.Introspect().Middlewares[2].Name)
+
+	named, ok := c.Middleware("values")
+	require.True(t, ok)
+	assert.Equal(t, []string{"named"}, named.(ValuesMiddleware).values)
+}
+
+// ValuesMiddleware is a middleware that cannot be compared.
+type ValuesMiddleware struct {
+	values []string
+}
+
+func (m ValuesMiddleware) Process(ctx context.Context, c *http.Client, req *http.Request, next clientMiddleware.NextFunc) (*http.Response, error) {
+	return next(ctx, c, req)
+}
+
+func (m ValuesMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestOptionConflicts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Replace duplicate middleware", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := client.NewClientE(
+			client.WithMiddleware(&HeaderMiddleware{value: "client"}),
+			client.WithMiddlewarePriority(5, &HeaderMiddleware{value: "other"}),
+			client.WithFlags(clientMiddleware.NewFlagSet()),
+			client.WithFlag("test.flag", true),
+		)
+		require.NoError(t, err)
+		assert.Len(t, c.Introspect().Middlewares, 1)
+	})
+
+	t.Run("Only report conflicts through NewClientE", func(t *testing.T) {
+		t.Parallel()
+
+		c := client.NewClient(client.WithHTTP1(), client.WithH2C())
+		require.NoError(t, c.Err())
+
+		_, err := client.NewClientE(client.WithHTTP1(), client.WithH2C())
+		require.ErrorIs(t, err, errors.ErrOptionConflict)
+	})
+
+	t.Run("Report names used twice", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(
+			client.WithNamedMiddleware("test", &HeaderMiddleware{value: "client"}),
+			client.WithNamedMiddleware("test", &IntrospectMiddleware{}),
+		)
+		require.ErrorIs(t, err, errors.ErrOptionConflict)
+		assert.Contains(t, err.Error(), `name "test" is already used by *client_test.HeaderMiddleware`)
+	})
+
+	t.Run("Report flags replaced by WithFlags", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(
+			client.WithFlag("test.flag", true),
+			client.WithFlags(clientMiddleware.NewFlagSet()),
+		)
+		require.ErrorIs(t, err, errors.ErrOptionConflict)
+	})
+}
+
+// ConsumingMiddleware is a middleware reading the request body without restoring it.
+type ConsumingMiddleware struct{ IntrospectMiddleware }
+
+func (m *ConsumingMiddleware) Capabilities() []clientMiddleware.Capability {
+	return []clientMiddleware.Capability{clientMiddleware.ConsumesBody}
+}
+
+// ReadingMiddleware is a middleware reading the request body.
+type ReadingMiddleware struct{ IntrospectMiddleware }
+
+func (m *ReadingMiddleware) Capabilities() []clientMiddleware.Capability {
+	return []clientMiddleware.Capability{clientMiddleware.ReadsBody}
+}
+
+// RewindingMiddleware is a middleware restoring the request body.
+type RewindingMiddleware struct{ IntrospectMiddleware }
+
+func (m *RewindingMiddleware) Capabilities() []clientMiddleware.Capability {
+	return []clientMiddleware.Capability{clientMiddleware.RewindsBody}
+}
+
+// ReplayingMiddleware is a middleware sending the request again without numbering the attempts.
+type ReplayingMiddleware struct{ IntrospectMiddleware }
+
+func (m *ReplayingMiddleware) Capabilities() []clientMiddleware.Capability {
+	return []clientMiddleware.Capability{clientMiddleware.ReplaysRequest}
+}
+
+// SharingMiddleware is a middleware sharing responses between concurrent identical requests.
+type SharingMiddleware struct{ IntrospectMiddleware }
+
+func (m *SharingMiddleware) Capabilities() []clientMiddleware.Capability {
+	return []clientMiddleware.Capability{clientMiddleware.SharesResponses}
+}
+
+func TestComposition(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Accept a valid composition", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(
+			client.WithMiddleware(&ReadingMiddleware{}),
+			client.WithMiddleware(&ConsumingMiddleware{}),
+			client.WithMiddleware(&RewindingMiddleware{}),
+			client.WithMiddleware(&HeaderMiddleware{value: "client"}),
+			client.WithRequiredOrder((*ReadingMiddleware)(nil), (*HeaderMiddleware)(nil)),
+			client.WithRequiredOrder((*ReadingMiddleware)(nil), (*IntrospectMiddleware)(nil)),
+		)
+		require.NoError(t, c.Err())
+	})
+
+	t.Run("Report every invalid composition", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(
+			client.WithMiddleware(&ConsumingMiddleware{}),
+			client.WithMiddleware(&ReadingMiddleware{}),
+			client.WithMiddleware(&HeaderMiddleware{value: "client"}),
+			client.WithRequiredOrder((*HeaderMiddleware)(nil), (*ConsumingMiddleware)(nil)),
+		)
+
+		err := c.Err()
+		require.ErrorIs(t, err, errors.ErrInvalidComposition)
+
+		var composition *clientMiddleware.CompositionError
+		require.ErrorAs(t, err, &composition)
+		assert.Equal(t, []clientMiddleware.CompositionIssue{
+			{
+				Index:      1,
+				Middleware: "*client_test.ReadingMiddleware",
+				Reason:     "needs the request body, which *client_test.ConsumingMiddleware at index 0 consumes without rewinding",
+			},
+			{
+				Index:      2,
+				Middleware: "*client_test.HeaderMiddleware",
+				Reason:     "must run before *client_test.ConsumingMiddleware at index 0",
+			},
+		}, composition.Issues)
+
+		// Requests fail with the composition error
+		_, err = c.NewRequest().Method(http.MethodGet).URL("http://localhost").Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrInvalidComposition)
+	})
+
+	t.Run("Report shared responses after replays without attempt numbers", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, NewTestClient(
+			client.WithMiddleware(&SharingMiddleware{}),
+			client.WithMiddleware(&ReplayingMiddleware{}),
+		).Err())
+
+		c := NewTestClient(
+			client.WithMiddleware(&ReplayingMiddleware{}),
+			client.WithMiddleware(&SharingMiddleware{}),
+		)
+
+		var composition *clientMiddleware.CompositionError
+		require.ErrorAs(t, c.Err(), &composition)
+		assert.Equal(t, []clientMiddleware.CompositionIssue{
+			{
+				Index:      1,
+				Middleware: "*client_test.SharingMiddleware",
+				Reason:     "shares responses between requests, which *client_test.ReplayingMiddleware at index 0 replays without numbering attempts",
+			},
+		}, composition.Issues)
+	})
+}
+
+func TestWithoutMiddleware(t *testing.T) {
+	t.Parallel()
+
+	opts := []client.Option{
+		client.WithMiddleware(&IntrospectMiddleware{}),
+		client.WithMiddleware(&HeaderMiddleware{value: "client"}),
+	}
+
+	c := NewTestClient(append(opts, client.WithoutMiddleware((*IntrospectMiddleware)(nil)))...)
+
+	snapshot := c.Introspect()
+	require.Len(t, snapshot.Middlewares, 1)
+	assert.Equal(t, "*client_test.HeaderMiddleware", snapshot.Middlewares[0].Type)
+	assert.Len(t, NewTestClient(opts...).Introspect().Middlewares, 2)
+}
+
+func TestWithNamedMiddleware(t *testing.T) {
+	t.Parallel()
+
+	header := &HeaderMiddleware{value: "client"}
+	c := NewTestClient(client.WithNamedMiddleware("header", header))
+
+	m, ok := c.Middleware("header")
+	require.True(t, ok)
+	assert.Same(t, header, m)
+
+	_, ok = c.Middleware("missing")
+	assert.False(t, ok)
+
+	// Replacing the middleware with one of the same type drops the name
+	c = NewTestClient(
+		client.WithNamedMiddleware("header", header),
+		client.WithMiddleware(&HeaderMiddleware{value: "other"}),
+	)
+	_, ok = c.Middleware("header")
+	assert.False(t, ok)
+}
+
 func TestWithTimeout(t *testing.T) {
 	t.Parallel()
 
@@ -68,6 +384,58 @@ func TestWithTimeout(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestWithBaseURL(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-URI", r.URL.RequestURI())
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name     string
+		base     string
+		url      string
+		query    [2]string
+		expected string
+	}{
+		{"Join path segments", server.URL + "/v1", "/users", [2]string{}, "/v1/users"},
+		{"Join with trailing slash", server.URL + "/v1/", "users/1", [2]string{}, "/v1/users/1"},
+		{"Keep base path without request path", server.URL + "/v1", "", [2]string{}, "/v1"},
+		{"Keep escaped request path segments", server.URL + "/v1", "/files/a%2Fb", [2]string{}, "/v1/files/a%2Fb"},
+		{"Keep escaped base path segments", server.URL + "/v1/a%2Fb", "/c d", [2]string{}, "/v1/a%2Fb/c%20d"},
+		{"Keep base query parameters", server.URL + "/v1?key=secret", "/users?page=2", [2]string{"sort", "name"}, "/v1/users?key=secret&page=2&sort=name"},
+		{"Override base query parameters", server.URL + "/v1?page=1", "/users", [2]string{"page", "3"}, "/v1/users?page=3"},
+		{"Use absolute URLs as they are", "https://example.invalid/v1", server.URL + "/other", [2]string{}, "/other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := NewTestClient(client.WithBaseURL(tt.base)).NewRequest().
+				Method(http.MethodGet).
+				URL(tt.url)
+			if tt.query[0] != "" {
+				req.Query(tt.query[0], tt.query[1])
+			}
+
+			resp, err := req.Do(context.Background())
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expected, resp.Header.Get("X-Request-URI"))
+		})
+	}
+
+	t.Run("Reject relative base URLs", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithBaseURL("/v1"))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+}
+
 func TestWithLogger(t *testing.T) {
 	t.Parallel()
 
@@ -136,6 +504,88 @@ func TestUnmarshalWith(t *testing.T) {
 	assert.Equal(t, "custom", result["format"])
 }
 
+func TestTransformBody(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"secret":"REDACTED"}`, string(body))
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write([]byte(`{"name":"old"}`))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Transform request and response bodies", func(t *testing.T) {
+		t.Parallel()
+
+		var result map[string]string
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			MarshalBody(map[string]string{"secret": "password"}).
+			TransformRequestBody(func(body []byte) ([]byte, error) {
+				return bytes.ReplaceAll(body, []byte("password"), []byte("REDACTED")), nil
+			}).
+			TransformResponseBody(func(body []byte) ([]byte, error) {
+				return bytes.ReplaceAll(body, []byte(`"old"`), []byte(`"new"`)), nil
+			}).
+			Result(&result).
+			Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "new", result["name"])
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"new"}`, string(body))
+	})
+
+	t.Run("Return transformation errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodPost).
+			URL(server.URL).
+			Body([]byte(`{}`)).
+			TransformRequestBody(func(body []byte) ([]byte, error) {
+				return nil, ErrMiddleware
+			}).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrBodyTransform)
+		require.ErrorIs(t, err, ErrMiddleware)
+	})
+}
+
+func TestDoTyped(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"name":"axonet"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	type Result struct {
+		Name string `json:"name"`
+	}
+
+	result, resp, err := client.Do[Result](context.Background(), NewTestClient().NewRequest().
+		Method(http.MethodGet).
+		URL(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, Result{Name: "axonet"}, result)
+
+	_, _, err = client.Do[Result](context.Background(), NewTestClient().NewRequest().
+		Method(http.MethodGet).
+		URL(server.URL).
+		UnmarshalWith(func(_ []byte, _ interface{}) error { return ErrMiddleware }))
+	require.ErrorIs(t, err, ErrMiddleware)
+}
+
 func TestQuery(t *testing.T) {
 	t.Parallel()
 
@@ -154,6 +604,418 @@ func TestQuery(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestPriority(t *testing.T) {
+	t.Parallel()
+
+	mockMiddleware := &MockMiddleware{}
+	mockMiddleware.On("SetLogger", mock.AnythingOfType("*logger.BasicLogger")).Return()
+	mockMiddleware.On("Process", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			assert.Equal(t, clientMiddleware.PriorityHigh, clientMiddleware.PriorityFromContext(ctx))
+		}).
+		Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	c := NewTestClient(client.WithMiddleware(mockMiddleware))
+
+	_, err := c.NewRequest().
+		Method(http.MethodGet).
+		URL("http://example.com").
+		Priority(clientMiddleware.PriorityHigh).
+		Do(context.Background())
+
+	require.NoError(t, err)
+	mockMiddleware.AssertExpectations(t)
+}
+
+// HeaderMiddleware is a middleware that sets a fixed header on every request.
+type HeaderMiddleware struct {
+	value string
+}
+
+func (m *HeaderMiddleware) Process(ctx context.Context, c *http.Client, req *http.Request, next clientMiddleware.NextFunc) (*http.Response, error) {
+	req.Header.Add("X-Middleware", m.value)
+	return next(ctx, c, req)
+}
+
+func (m *HeaderMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestRequestWithMiddleware(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["X-Middleware"] = r.Header.Values("X-Middleware")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewTestClient(client.WithMiddleware(&HeaderMiddleware{value: "client"}))
+
+	t.Run("Replace client middleware of the same type", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			WithMiddleware(&HeaderMiddleware{value: "request"}).
+			Do(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"request"}, resp.Header.Values("X-Middleware"))
+	})
+
+	t.Run("Leave the client chain unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"client"}, resp.Header.Values("X-Middleware"))
+		assert.Len(t, c.Introspect().Middlewares, 1)
+	})
+}
+
+func TestSkipMiddleware(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["X-Middleware"] = r.Header.Values("X-Middleware")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	type NamedMiddleware struct{ HeaderMiddleware }
+
+	c := NewTestClient(
+		client.WithMiddleware(&HeaderMiddleware{value: "typed"}),
+		client.WithNamedMiddleware("named", &NamedMiddleware{HeaderMiddleware{value: "named"}}),
+	)
+
+	do := func(ctx context.Context, opts ...client.RequestOption) []string {
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(ctx, opts...)
+		require.NoError(t, err)
+		return resp.Header.Values("X-Middleware")
+	}
+
+	assert.Equal(t, []string{"typed", "named"}, do(context.Background()))
+	assert.Equal(t, []string{"named"}, do(client.SkipMiddleware(context.Background(), (*HeaderMiddleware)(nil))))
+	assert.Equal(t, []string{"typed"}, do(client.SkipNamed(context.Background(), "named")))
+	assert.Empty(t, do(context.Background(),
+		client.WithSkipMiddleware((*HeaderMiddleware)(nil)),
+		client.WithSkipNamed("named"),
+	))
+}
+
+// contextTestKey is the context key used to test request options.
+type contextTestKey struct{}
+
+func TestRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Apply options to the context", func(t *testing.T) {
+		t.Parallel()
+
+		mockMiddleware := &MockMiddleware{}
+		mockMiddleware.On("SetLogger", mock.AnythingOfType("*logger.BasicLogger")).Return()
+		mockMiddleware.On("Process", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				ctx := args.Get(0).(context.Context)
+				assert.Equal(t, clientMiddleware.PriorityHigh, clientMiddleware.PriorityFromContext(ctx))
+				assert.Equal(t, "value", ctx.Value(contextTestKey{}))
+
+				_, ok := ctx.Deadline()
+				assert.True(t, ok)
+			}).
+			Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+
+		c := NewTestClient(client.WithMiddleware(mockMiddleware))
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL("http://example.com").
+			Do(context.Background(),
+				client.WithPriority(clientMiddleware.PriorityHigh),
+				client.WithContextValue(contextTestKey{}, "value"),
+				client.WithRequestTimeout(time.Second),
+			)
+
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		mockMiddleware.AssertExpectations(t)
+	})
+
+	t.Run("Apply options to a single execution", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"header":"` + r.Header.Get("X-Call") + `"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		rb := NewTestClient().NewRequest().URL(server.URL)
+		withHeader := func(call *client.Request) { call.Header("X-Call", "first") }
+
+		result, _, err := client.Do[map[string]string](context.Background(), rb, withHeader)
+		require.NoError(t, err)
+		assert.Equal(t, "first", result["header"])
+
+		// The builder keeps neither the header nor the result of the previous execution
+		result, _, err = client.Do[map[string]string](context.Background(), rb)
+		require.NoError(t, err)
+		assert.Empty(t, result["header"])
+	})
+
+	t.Run("Time out the request", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background(), client.WithRequestTimeout(20*time.Millisecond))
+
+		require.ErrorIs(t, err, errors.ErrTimeout)
+	})
+
+	t.Run("Replace the timeout of the client", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		c := NewTestClient(client.WithTimeout(20 * time.Millisecond))
+
+		// A longer timeout lets slow requests complete
+		resp, err := c.NewRequest().URL(server.URL).Timeout(time.Second).Do(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		_, err = c.NewRequest().URL(server.URL).Do(context.Background())
+		require.Error(t, err)
+
+		// A shorter timeout fails fast
+		_, err = NewTestClient(client.WithTimeout(time.Second)).NewRequest().
+			URL(server.URL).
+			Timeout(20 * time.Millisecond).
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrTimeout)
+	})
+}
+
+// DegradationSource is a degradation source with a fixed state.
+type DegradationSource struct {
+	degraded bool
+}
+
+func (s *DegradationSource) Degraded() bool {
+	return s.degraded
+}
+
+func TestDegradedMode(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["X-Middleware"] = r.Header.Values("X-Middleware")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Skip optional middleware while degraded", func(t *testing.T) {
+		t.Parallel()
+
+		mockMiddleware := &MockMiddleware{}
+		mockMiddleware.On("SetLogger", mock.AnythingOfType("*logger.BasicLogger")).Return()
+		mockMiddleware.On("Process", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				assert.True(t, clientMiddleware.DegradedFromContext(args.Get(0).(context.Context)))
+			}).
+			Return(&http.Response{StatusCode: http.StatusOK}, nil).Once()
+
+		c := NewTestClient(
+			client.WithOptionalMiddleware(&HeaderMiddleware{value: "optional"}),
+			client.WithMiddleware(mockMiddleware),
+		)
+
+		c.SetDegraded(true)
+		assert.True(t, c.Degraded())
+		assert.True(t, c.Introspect().Degraded)
+
+		_, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background())
+		require.NoError(t, err)
+		mockMiddleware.AssertExpectations(t)
+
+		c.SetDegraded(false)
+		assert.False(t, c.Degraded())
+	})
+
+	t.Run("Run optional middleware otherwise", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithOptionalMiddleware(&HeaderMiddleware{value: "optional"}))
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"optional"}, resp.Header.Values("X-Middleware"))
+	})
+
+	t.Run("Degrade when a source reports an incident", func(t *testing.T) {
+		t.Parallel()
+
+		source := &DegradationSource{degraded: false}
+		c := NewTestClient(
+			client.WithOptionalMiddleware(&HeaderMiddleware{value: "optional"}),
+			client.WithDegradationSource(source),
+		)
+		assert.False(t, c.Degraded())
+
+		source.degraded = true
+		assert.True(t, c.Degraded())
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, resp.Header.Values("X-Middleware"))
+	})
+}
+
+func TestFlags(t *testing.T) {
+	t.Parallel()
+
+	const flag clientMiddleware.Flag = "test.flag"
+
+	var enabled []bool
+	mockMiddleware := &MockMiddleware{}
+	mockMiddleware.On("SetLogger", mock.AnythingOfType("*logger.BasicLogger")).Return()
+	mockMiddleware.On("Process", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			enabled = append(enabled, clientMiddleware.FlagEnabled(args.Get(0).(context.Context), flag))
+		}).
+		Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	flags := clientMiddleware.NewFlagSet()
+	c := NewTestClient(client.WithMiddleware(mockMiddleware), client.WithFlags(flags))
+
+	do := func(opts ...client.RequestOption) {
+		_, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL("http://example.com").
+			Do(context.Background(), opts...)
+		require.NoError(t, err)
+	}
+
+	do()
+	do(client.WithRequestFlag(flag, true))
+	flags.Set(flag, true)
+	do()
+	do(client.WithRequestFlag(flag, false))
+	c.SetFlag(flag, false)
+	do()
+
+	assert.Equal(t, []bool{false, true, true, false, false}, enabled)
+	assert.Equal(t, map[clientMiddleware.Flag]bool{flag: false}, c.Introspect().Flags)
+}
+
+// SlowMiddleware is a middleware that reports a fixed cost for every request.
+type SlowMiddleware struct {
+	cost time.Duration
+}
+
+func (m *SlowMiddleware) Process(ctx context.Context, c *http.Client, req *http.Request, next clientMiddleware.NextFunc) (*http.Response, error) {
+	return next(ctx, c, req)
+}
+
+func (m *SlowMiddleware) SetLogger(_ logger.Logger) {}
+
+func (m *SlowMiddleware) EstimateCost(_ *http.Request) time.Duration {
+	return m.cost
+}
+
+func TestWithEarlyRejection(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Reject request whose deadline cannot be met", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(
+			client.WithMiddleware(&SlowMiddleware{cost: time.Second}),
+			client.WithEarlyRejection(),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(ctx)
+
+		require.ErrorIs(t, err, errors.ErrTimeout)
+		require.ErrorIs(t, err, errors.ErrDeadlineUnreachable)
+	})
+
+	t.Run("Allow request without deadline", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(
+			client.WithMiddleware(&SlowMiddleware{cost: time.Second}),
+			client.WithEarlyRejection(),
+		)
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithMiddleware(&SlowMiddleware{cost: time.Second}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
 // MockLogger implementation.
 type MockLogger struct {
 	mock.Mock
@@ -0,0 +1,118 @@
+package pagination
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrCheckpoint = errors.New("checkpoint store error")
+
+// CheckpointStore persists the cursor of a paginated iteration.
+type CheckpointStore interface {
+	// Load returns the saved cursor for key, or an empty string if there is none.
+	Load(ctx context.Context, key string) (string, error)
+	// Save stores the cursor for key.
+	Save(ctx context.Context, key, cursor string) error
+	// Delete removes the cursor for key.
+	Delete(ctx context.Context, key string) error
+}
+
+// noopStore is a CheckpointStore that does not persist anything.
+type noopStore struct{}
+
+func (noopStore) Load(_ context.Context, _ string) (string, error) { return "", nil }
+func (noopStore) Save(_ context.Context, _, _ string) error        { return nil }
+func (noopStore) Delete(_ context.Context, _ string) error         { return nil }
+
+// MemoryStore is a CheckpointStore that keeps cursors in memory.
+type MemoryStore struct {
+	cursors map[string]string
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a new MemoryStore instance.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		cursors: make(map[string]string),
+		mu:      sync.RWMutex{},
+	}
+}
+
+func (s *MemoryStore) Load(_ context.Context, key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cursors[key], nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, key, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursors[key] = cursor
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cursors, key)
+	return nil
+}
+
+// FileStore is a CheckpointStore that keeps each cursor in a file inside a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a new FileStore instance storing cursors in dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		dir: dir,
+	}
+}
+
+func (s *FileStore) Load(_ context.Context, key string) (string, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	return string(data), nil
+}
+
+func (s *FileStore) Save(_ context.Context, key, cursor string) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+
+	// Write to a temporary file first so an interruption never leaves a partial cursor
+	tmp := s.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(cursor), 0o600); err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	if err := os.Rename(tmp, s.path(key)); err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrCheckpoint, err)
+	}
+	return nil
+}
+
+// path returns the file path for the key, encoded so any key is a valid file name.
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(key))+".cursor")
+}
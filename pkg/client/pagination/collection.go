@@ -0,0 +1,297 @@
+package pagination
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrCollectionCache = errors.New("collection cache error")
+	// ErrGenerationInvalidated is returned when storing the pages of a generation started before
+	// the collection was invalidated, which may hold data from before the invalidation.
+	ErrGenerationInvalidated = errors.New("collection invalidated during refresh")
+)
+
+// CollectionStore stores the pages of cached collections. Pages are written under a new generation
+// of the collection, which only becomes visible once committed, so readers never see pages of
+// different generations mixed together. Invalidating a collection starts a new epoch, and the
+// generations begun in an earlier epoch can no longer be written or committed.
+type CollectionStore interface {
+	// LoadPages returns the pages of the committed generation of key, or false if there is none.
+	LoadPages(ctx context.Context, key string) ([][]byte, bool, error)
+	// Begin starts the generation of key in the current epoch of the collection.
+	Begin(ctx context.Context, key, generation string) error
+	// SavePage stores the page at index for the generation of key, failing with
+	// ErrGenerationInvalidated if the collection was invalidated since the generation began.
+	SavePage(ctx context.Context, key, generation string, index int, page []byte) error
+	// Commit makes the generation of key visible, replacing the previous one, failing with
+	// ErrGenerationInvalidated if the collection was invalidated since the generation began.
+	Commit(ctx context.Context, key, generation string, count int) error
+	// Discard removes the generation of key and its pages, such as after a failed refresh.
+	Discard(ctx context.Context, key, generation string) error
+	// Invalidate removes the committed and pending generations of key and starts a new epoch.
+	Invalidate(ctx context.Context, key string) error
+}
+
+// Collection caches every page of a paginated endpoint under a collection key, serving and
+// invalidating the whole collection at once.
+type Collection[T any] struct {
+	fetch    PageFunc[T]
+	store    CollectionStore
+	throttle *Throttle
+	key      string
+}
+
+// NewCollection creates a new Collection caching the pages returned by fetch in store under key.
+func NewCollection[T any](fetch PageFunc[T], store CollectionStore, key string) *Collection[T] {
+	return &Collection[T]{
+		fetch:    fetch,
+		store:    store,
+		throttle: nil,
+		key:      key,
+	}
+}
+
+// SetThrottle sets the throttle pacing the page fetches of refreshes and waiting out rate limits.
+// Passing nil fetches pages back to back, which is the default.
+func (c *Collection[T]) SetThrottle(throttle *Throttle) {
+	c.throttle = throttle
+}
+
+// Pages returns every page of the collection, from the cache if a generation is committed, or
+// fetched from the first page and cached otherwise.
+func (c *Collection[T]) Pages(ctx context.Context) ([][]T, error) {
+	cached, ok, err := c.store.LoadPages(ctx, c.key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		pages := make([][]T, len(cached))
+		for i, data := range cached {
+			if err := json.Unmarshal(data, &pages[i]); err != nil {
+				return nil, fmt.Errorf("%w: page %d: %w", ErrCollectionCache, i, err)
+			}
+		}
+		return pages, nil
+	}
+
+	return c.Refresh(ctx)
+}
+
+// All returns the items of every page of the collection, as Pages does.
+func (c *Collection[T]) All(ctx context.Context) ([]T, error) {
+	pages, err := c.Pages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+	for _, page := range pages {
+		items = append(items, page...)
+	}
+	return items, nil
+}
+
+// Refresh fetches every page of the collection and caches them as a new generation,
+// which replaces the cached one once every page is stored. If the collection is invalidated
+// during the refresh, the refresh fails with ErrGenerationInvalidated, as its pages may predate
+// the invalidation. The generation of a failed refresh is discarded.
+func (c *Collection[T]) Refresh(ctx context.Context) ([][]T, error) {
+	generation, err := newGeneration()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.Begin(ctx, c.key, generation); err != nil {
+		return nil, err
+	}
+
+	pages, err := c.refresh(ctx, generation)
+	if err != nil {
+		// Remove the pages stored so far, even if the context is done
+		if discardErr := c.store.Discard(context.WithoutCancel(ctx), c.key, generation); discardErr != nil {
+			return nil, errors.Join(err, discardErr)
+		}
+		return nil, err
+	}
+	return pages, nil
+}
+
+// refresh fetches and stores every page of the collection under the generation, then commits it.
+func (c *Collection[T]) refresh(ctx context.Context, generation string) ([][]T, error) {
+	var (
+		pages  [][]T
+		cursor string
+	)
+	for {
+		items, next, err := fetchThrottled(ctx, c.throttle, c.fetch, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(items)
+		if err != nil {
+			return nil, fmt.Errorf("%w: page %d: %w", ErrCollectionCache, len(pages), err)
+		}
+		if err := c.store.SavePage(ctx, c.key, generation, len(pages), data); err != nil {
+			return nil, err
+		}
+		pages = append(pages, items)
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if err := c.store.Commit(ctx, c.key, generation, len(pages)); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// Invalidate removes the cached collection, so the next call to Pages fetches it again.
+func (c *Collection[T]) Invalidate(ctx context.Context) error {
+	return c.store.Invalidate(ctx, c.key)
+}
+
+// newGeneration returns a random identifier for a generation of a collection.
+func newGeneration() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCollectionCache, err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// memoryCollection holds the generations of a collection kept by a MemoryCollectionStore.
+type memoryCollection struct {
+	committed [][]byte
+	pending   map[string]*memoryGeneration
+	epoch     uint64
+}
+
+// memoryGeneration holds the pages of a pending generation and the epoch it began in.
+type memoryGeneration struct {
+	pages [][]byte
+	epoch uint64
+}
+
+// MemoryCollectionStore is a CollectionStore that keeps collections in memory.
+type MemoryCollectionStore struct {
+	collections map[string]*memoryCollection
+	mu          sync.RWMutex
+}
+
+// NewMemoryCollectionStore creates a new MemoryCollectionStore instance.
+func NewMemoryCollectionStore() *MemoryCollectionStore {
+	return &MemoryCollectionStore{
+		collections: make(map[string]*memoryCollection),
+		mu:          sync.RWMutex{},
+	}
+}
+
+func (s *MemoryCollectionStore) LoadPages(_ context.Context, key string) ([][]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	collection, ok := s.collections[key]
+	if !ok || collection.committed == nil {
+		return nil, false, nil
+	}
+	return collection.committed, true, nil
+}
+
+func (s *MemoryCollectionStore) Begin(_ context.Context, key, generation string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection := s.collection(key)
+	collection.pending[generation] = &memoryGeneration{pages: nil, epoch: collection.epoch}
+	return nil
+}
+
+func (s *MemoryCollectionStore) SavePage(_ context.Context, key, generation string, index int, page []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, err := s.pending(key, generation)
+	if err != nil {
+		return err
+	}
+
+	for len(pending.pages) <= index {
+		pending.pages = append(pending.pages, nil)
+	}
+	pending.pages[index] = page
+	return nil
+}
+
+func (s *MemoryCollectionStore) Commit(_ context.Context, key, generation string, count int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, err := s.pending(key, generation)
+	if err != nil {
+		return err
+	}
+	if len(pending.pages) != count {
+		return fmt.Errorf("%w: generation %s has %d pages, expected %d", ErrCollectionCache, generation, len(pending.pages), count)
+	}
+
+	collection := s.collections[key]
+	delete(collection.pending, generation)
+	collection.committed = pending.pages
+	return nil
+}
+
+func (s *MemoryCollectionStore) Discard(_ context.Context, key, generation string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if collection, ok := s.collections[key]; ok {
+		delete(collection.pending, generation)
+	}
+	return nil
+}
+
+func (s *MemoryCollectionStore) Invalidate(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Keep the epoch, so generations begun before the invalidation stay rejected
+	collection := s.collection(key)
+	collection.committed = nil
+	collection.pending = make(map[string]*memoryGeneration)
+	collection.epoch++
+	return nil
+}
+
+// collection returns the collection of key, creating it if needed. It must be called with the
+// lock held.
+func (s *MemoryCollectionStore) collection(key string) *memoryCollection {
+	collection, ok := s.collections[key]
+	if !ok {
+		collection = &memoryCollection{committed: nil, pending: make(map[string]*memoryGeneration), epoch: 0}
+		s.collections[key] = collection
+	}
+	return collection
+}
+
+// pending returns the pending generation of key, failing with ErrGenerationInvalidated if it
+// began before the last invalidation of the collection. It must be called with the lock held.
+func (s *MemoryCollectionStore) pending(key, generation string) (*memoryGeneration, error) {
+	collection, ok := s.collections[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: generation %s", ErrGenerationInvalidated, generation)
+	}
+	pending, ok := collection.pending[generation]
+	if !ok || pending.epoch != collection.epoch {
+		return nil, fmt.Errorf("%w: generation %s", ErrGenerationInvalidated, generation)
+	}
+	return pending, nil
+}
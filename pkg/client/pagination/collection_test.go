@@ -0,0 +1,110 @@
+package pagination_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Serve cached pages", func(t *testing.T) {
+		t.Parallel()
+
+		var fetched []string
+		collection := pagination.NewCollection(pages(3, -1, &fetched), pagination.NewMemoryCollectionStore(), "users")
+
+		items, err := collection.All(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2}, items)
+
+		pages, err := collection.Pages(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, [][]int{{0}, {1}, {2}}, pages)
+		assert.Equal(t, []string{"", "1", "2"}, fetched)
+	})
+
+	t.Run("Invalidate the whole collection", func(t *testing.T) {
+		t.Parallel()
+
+		var fetched []string
+		collection := pagination.NewCollection(pages(2, -1, &fetched), pagination.NewMemoryCollectionStore(), "users")
+
+		_, err := collection.Pages(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, collection.Invalidate(context.Background()))
+
+		_, err = collection.Pages(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "1", "", "1"}, fetched)
+	})
+
+	t.Run("Keep the previous generation if a refresh fails", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := pagination.NewMemoryCollectionStore()
+
+		var fetched []string
+		_, err := pagination.NewCollection(pages(3, -1, &fetched), store, "users").Pages(ctx)
+		require.NoError(t, err)
+
+		// A refresh failing midway stores no page of its generation
+		_, err = pagination.NewCollection(pages(3, 2, &fetched), store, "users").Refresh(ctx)
+		require.ErrorIs(t, err, ErrInterrupted)
+
+		cached, ok, err := store.LoadPages(ctx, "users")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, [][]byte{[]byte("[0]"), []byte("[1]"), []byte("[2]")}, cached)
+	})
+
+	t.Run("Reject generations begun before invalidation", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := pagination.NewMemoryCollectionStore()
+
+		// Pages saved after the invalidation are rejected too, as the generation began before it
+		require.NoError(t, store.Begin(ctx, "users", "stale"))
+		require.NoError(t, store.Invalidate(ctx, "users"))
+		require.ErrorIs(t, store.SavePage(ctx, "users", "stale", 0, []byte("[0]")), pagination.ErrGenerationInvalidated)
+		require.ErrorIs(t, store.Commit(ctx, "users", "stale", 1), pagination.ErrGenerationInvalidated)
+
+		_, ok, err := store.LoadPages(ctx, "users")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		// Generations begun after the invalidation are stored
+		require.NoError(t, store.Begin(ctx, "users", "fresh"))
+		require.NoError(t, store.SavePage(ctx, "users", "fresh", 0, []byte("[0]")))
+		require.NoError(t, store.Commit(ctx, "users", "fresh", 1))
+	})
+
+	t.Run("Fail refreshes invalidated midway", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := pagination.NewMemoryCollectionStore()
+
+		var fetched []string
+		next := pages(3, -1, &fetched)
+		fetch := func(ctx context.Context, cursor string) ([]int, string, error) {
+			if cursor == "1" {
+				require.NoError(t, store.Invalidate(ctx, "users"))
+			}
+			return next(ctx, cursor)
+		}
+
+		_, err := pagination.NewCollection(fetch, store, "users").Refresh(ctx)
+		require.ErrorIs(t, err, pagination.ErrGenerationInvalidated)
+
+		_, ok, err := store.LoadPages(ctx, "users")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
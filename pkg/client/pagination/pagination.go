@@ -0,0 +1,114 @@
+// Package pagination provides an iterator over cursor-based paginated APIs
+// that can persist its progress and resume after an interruption.
+package pagination
+
+import (
+	"context"
+)
+
+// PageFunc fetches the page at the given cursor and returns its items and the cursor of the next page.
+// An empty cursor is passed for the first page, and an empty next cursor marks the last page.
+type PageFunc[T any] func(ctx context.Context, cursor string) ([]T, string, error)
+
+// Iterator iterates over the pages returned by a PageFunc.
+// If a CheckpointStore is set, the cursor of the next page is saved once the current page
+// has been processed, so an interrupted iteration resumes where it left off.
+type Iterator[T any] struct {
+	fetch    PageFunc[T]
+	store    CheckpointStore
+	throttle *Throttle
+	key      string
+	cursor   string
+	page     []T
+	err      error
+	started  bool
+	last     bool
+	finished bool
+}
+
+// NewIterator creates a new Iterator over the pages returned by fetch.
+// The progress is persisted in store under key. Passing a nil store disables persistence.
+func NewIterator[T any](fetch PageFunc[T], store CheckpointStore, key string) *Iterator[T] {
+	if store == nil {
+		store = noopStore{}
+	}
+
+	return &Iterator[T]{
+		fetch:    fetch,
+		store:    store,
+		throttle: nil,
+		key:      key,
+		cursor:   "",
+		page:     nil,
+		err:      nil,
+		started:  false,
+		last:     false,
+		finished: false,
+	}
+}
+
+// SetThrottle sets the throttle pacing the page fetches and waiting out rate limits.
+// Passing nil fetches pages as fast as they are processed, which is the default.
+func (it *Iterator[T]) SetThrottle(throttle *Throttle) {
+	it.throttle = throttle
+}
+
+// Next fetches the next page, marking the previous page as processed.
+// It returns false when there are no more pages or an error occurred.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil || it.finished {
+		return false
+	}
+
+	if !it.started {
+		// Resume from the last checkpoint if there is one
+		cursor, err := it.store.Load(ctx, it.key)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.cursor = cursor
+		it.started = true
+	} else {
+		// The previous page was the last one, clear the checkpoint
+		if it.last {
+			it.finished = true
+			it.page = nil
+			it.err = it.store.Delete(ctx, it.key)
+			return false
+		}
+
+		// The previous page was processed, persist the cursor of the next one
+		if err := it.store.Save(ctx, it.key, it.cursor); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	items, next, err := fetchThrottled(ctx, it.throttle, it.fetch, it.cursor)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.page = items
+	it.cursor = next
+	it.last = next == ""
+
+	return true
+}
+
+// Page returns the items of the current page.
+func (it *Iterator[T]) Page() []T {
+	return it.page
+}
+
+// Cursor returns the cursor of the next page.
+func (it *Iterator[T]) Cursor() string {
+	return it.cursor
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
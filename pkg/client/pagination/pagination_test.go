@@ -0,0 +1,109 @@
+package pagination_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrInterrupted = errors.New("interrupted")
+
+// pages returns a PageFunc serving count pages of one item each, failing at the page failAt.
+func pages(count, failAt int, fetched *[]string) pagination.PageFunc[int] {
+	return func(_ context.Context, cursor string) ([]int, string, error) {
+		page := 0
+		if cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		if page == failAt {
+			return nil, "", ErrInterrupted
+		}
+		*fetched = append(*fetched, cursor)
+
+		next := ""
+		if page+1 < count {
+			next = strconv.Itoa(page + 1)
+		}
+		return []int{page}, next, nil
+	}
+}
+
+func TestIterator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Iterate over all pages", func(t *testing.T) {
+		t.Parallel()
+
+		var fetched []string
+		it := pagination.NewIterator(pages(3, -1, &fetched), nil, "export")
+
+		var items []int
+		for it.Next(context.Background()) {
+			items = append(items, it.Page()...)
+		}
+
+		require.NoError(t, it.Err())
+		assert.Equal(t, []int{0, 1, 2}, items)
+		assert.False(t, it.Next(context.Background()))
+	})
+
+	t.Run("Resume from checkpoint after interruption", func(t *testing.T) {
+		t.Parallel()
+
+		store := pagination.NewMemoryStore()
+
+		var fetched []string
+		it := pagination.NewIterator(pages(4, 2, &fetched), store, "export")
+		for it.Next(context.Background()) {
+			assert.Len(t, it.Page(), 1)
+		}
+		require.ErrorIs(t, it.Err(), ErrInterrupted)
+
+		cursor, err := store.Load(context.Background(), "export")
+		require.NoError(t, err)
+		assert.Equal(t, "2", cursor)
+
+		fetched = nil
+		it = pagination.NewIterator(pages(4, -1, &fetched), store, "export")
+
+		var items []int
+		for it.Next(context.Background()) {
+			items = append(items, it.Page()...)
+		}
+
+		require.NoError(t, it.Err())
+		assert.Equal(t, []int{2, 3}, items)
+		assert.Equal(t, []string{"2", "3"}, fetched)
+
+		// The checkpoint is removed once the iteration completes
+		cursor, err = store.Load(context.Background(), "export")
+		require.NoError(t, err)
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("File store persists cursors", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := pagination.NewFileStore(t.TempDir())
+
+		cursor, err := store.Load(ctx, "users/export")
+		require.NoError(t, err)
+		assert.Empty(t, cursor)
+
+		require.NoError(t, store.Save(ctx, "users/export", "abc"))
+		cursor, err = store.Load(ctx, "users/export")
+		require.NoError(t, err)
+		assert.Equal(t, "abc", cursor)
+
+		require.NoError(t, store.Delete(ctx, "users/export"))
+		cursor, err = store.Load(ctx, "users/export")
+		require.NoError(t, err)
+		assert.Empty(t, cursor)
+	})
+}
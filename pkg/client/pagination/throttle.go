@@ -0,0 +1,164 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/backoff"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// DefaultMaxRateLimitRetries is the number of times a rate-limited page is fetched again by default.
+const DefaultMaxRateLimitRetries = 5
+
+// Throttle paces the page fetches of iterations and waits out rate limits, so long pagination
+// loops need no sleep logic. Fetches are spaced by the interval, and rate-limited fetches are
+// retried after the delay of the Retry-After header, or of the backoff policy without one.
+// Iterations sharing a Throttle share its pace, and all pause while one waits out a rate limit.
+//
+// Rate limits are recognized from errors matching *errors.HTTPError with a 429 status or a 503
+// status with a Retry-After header, such as those returned with client.WithErrorOnStatus, and
+// from *errors.OverloadedError.
+type Throttle struct {
+	interval time.Duration
+	policy   backoff.Policy
+	next     time.Time
+	mu       sync.Mutex
+}
+
+// NewThrottle creates a new Throttle spacing fetches by interval. Rate-limited fetches are
+// retried up to DefaultMaxRateLimitRetries times with exponential backoff from one second.
+func NewThrottle(interval time.Duration) *Throttle {
+	return &Throttle{
+		interval: interval,
+		policy:   backoff.Exponential(time.Second, time.Minute).WithMaxRetries(DefaultMaxRateLimitRetries),
+		next:     time.Time{},
+		mu:       sync.Mutex{},
+	}
+}
+
+// SetBackoff sets the policy of the waits before fetching a rate-limited page again, used when
+// the response has no Retry-After header. Its MaxRetries and MaxElapsedTime bound the retries
+// even with the header.
+func (t *Throttle) SetBackoff(policy backoff.Policy) *Throttle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.policy = policy
+	return t
+}
+
+// fetchThrottled fetches the page at the cursor, paced and retried by the throttle if it is not nil.
+func fetchThrottled[T any](ctx context.Context, t *Throttle, fetch PageFunc[T], cursor string) ([]T, string, error) {
+	if t == nil {
+		return fetch(ctx, cursor)
+	}
+
+	t.mu.Lock()
+	policy := t.policy
+	t.mu.Unlock()
+
+	start := time.Now()
+	for retry := uint64(1); ; retry++ {
+		if err := t.wait(ctx); err != nil {
+			return nil, "", err
+		}
+
+		items, next, err := fetch(ctx, cursor)
+		delay, limited := rateLimitDelay(err, time.Now())
+		if !limited {
+			return items, next, err
+		}
+
+		// Give up once the retries or the time are exhausted
+		if delay <= 0 {
+			delay = policy.Delay(retry)
+		}
+		if policy.MaxRetries != backoff.Unlimited && retry > policy.MaxRetries {
+			return items, next, err
+		}
+		if policy.MaxElapsedTime > 0 && time.Since(start)+delay > policy.MaxElapsedTime {
+			return items, next, err
+		}
+		t.pause(delay)
+	}
+}
+
+// wait blocks until the next fetch may start, reserving its slot.
+func (t *Throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	start := now
+	if t.next.After(now) {
+		start = t.next
+	}
+	t.next = start.Add(t.interval)
+	t.mu.Unlock()
+
+	if delay := start.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// pause delays the next fetches by at least the delay.
+func (t *Throttle) pause(delay time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if resume := time.Now().Add(delay); resume.After(t.next) {
+		t.next = resume
+	}
+}
+
+// rateLimitDelay reports whether the error comes from a rate limit, and the delay it asks to
+// wait, or zero if it gives none.
+func rateLimitDelay(err error, now time.Time) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+
+	var overloaded *clientErrors.OverloadedError
+	if errors.As(err, &overloaded) {
+		return overloaded.RetryAfter, true
+	}
+
+	var httpErr *clientErrors.HTTPError
+	if !errors.As(err, &httpErr) {
+		return 0, false
+	}
+	delay, ok := parseRetryAfter(httpErr.Header.Get("Retry-After"), now)
+	switch {
+	case httpErr.StatusCode == http.StatusTooManyRequests:
+		return delay, true
+	case httpErr.StatusCode == http.StatusServiceUnavailable && ok:
+		return delay, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header holding a number of seconds or an HTTP date into
+// the delay to wait from now.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
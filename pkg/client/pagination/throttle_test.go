@@ -0,0 +1,76 @@
+package pagination_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/backoff"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Pace page fetches", func(t *testing.T) {
+		t.Parallel()
+
+		var fetched []string
+		it := pagination.NewIterator(pages(3, -1, &fetched), nil, "paced")
+		it.SetThrottle(pagination.NewThrottle(20 * time.Millisecond))
+
+		start := time.Now()
+		for it.Next(context.Background()) {
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, []string{"", "1", "2"}, fetched)
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("Resume after rate limits", func(t *testing.T) {
+		t.Parallel()
+
+		var fetched []string
+		limited := []error{
+			&errors.HTTPError{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"0"}}},
+			&errors.OverloadedError{RetryAfter: 20 * time.Millisecond, QueueDepth: 1},
+		}
+		next := pages(2, -1, &fetched)
+		fetch := func(ctx context.Context, cursor string) ([]int, string, error) {
+			if cursor == "1" && len(limited) > 0 {
+				err := limited[0]
+				limited = limited[1:]
+				return nil, "", err
+			}
+			return next(ctx, cursor)
+		}
+
+		it := pagination.NewIterator(fetch, nil, "limited")
+		it.SetThrottle(pagination.NewThrottle(0).SetBackoff(backoff.Exponential(10*time.Millisecond, time.Second).WithJitter(backoff.JitterNone)))
+
+		start := time.Now()
+		for it.Next(context.Background()) {
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, []string{"", "1"}, fetched)
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("Give up after the retries", func(t *testing.T) {
+		t.Parallel()
+
+		fetch := func(ctx context.Context, cursor string) ([]int, string, error) {
+			return nil, "", &errors.HTTPError{StatusCode: http.StatusTooManyRequests}
+		}
+
+		c := pagination.NewCollection(fetch, pagination.NewMemoryCollectionStore(), "users")
+		c.SetThrottle(pagination.NewThrottle(0).SetBackoff(backoff.Exponential(time.Millisecond, time.Millisecond).WithMaxRetries(2)))
+
+		_, err := c.Refresh(context.Background())
+		require.ErrorIs(t, err, errors.ErrBadStatus)
+	})
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// Preconnect opens connections to the hosts ahead of their first requests, so those requests don't
+// pay for resolving the host, connecting and the TLS handshake, such as after startup or rotating
+// proxies. Hosts are origins like "https://api.example.com" or host names, which use HTTPS.
+// Without hosts, the base URL of the Client is preconnected.
+//
+// A HEAD request is sent to every host and its response discarded, leaving the connection idle in
+// the pool, where HTTP/2 connections are ready to carry concurrent streams. Middleware sending
+// requests with their own transports, such as the proxy middleware, warm those instead: every
+// proxy, or only the proxy stored in ctx with middleware.WithProxy. The errors of the hosts that
+// could not be reached are joined and match ErrPreconnect.
+func (c *Client) Preconnect(ctx context.Context, hosts ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	if len(hosts) == 0 && c.baseURL != nil {
+		hosts = []string{c.baseURL.Scheme + "://" + c.baseURL.Host}
+	}
+	targets := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		target, err := preconnectTarget(host)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	warm := func(ctx context.Context, httpClient *http.Client) error {
+		return warmConnections(ctx, httpClient, targets)
+	}
+
+	// Warm the connections of middleware transports the same way requests open them
+	if c.resolver != nil {
+		ctx = middleware.WithResolver(ctx, c.resolver)
+	}
+
+	var preconnected bool
+	var errs []error
+	for _, m := range c.middlewareChain.Middlewares() {
+		if p, ok := m.(middleware.Preconnector); ok {
+			preconnected = true
+			errs = append(errs, p.Preconnect(ctx, c.httpClient, warm))
+		}
+	}
+	if !preconnected {
+		errs = append(errs, warm(ctx, c.httpClient))
+	}
+	return errors.Join(errs...)
+}
+
+// preconnectTarget returns the URL requested to preconnect to the host.
+func preconnectTarget(host string) (string, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%w: Preconnect: invalid host %q", errors.ErrInvalidOption, host)
+	}
+	return u.Scheme + "://" + u.Host + "/", nil
+}
+
+// warmConnections sends a HEAD request to every target concurrently, discarding the responses so
+// their connections return to the pool of the HTTP client.
+func warmConnections(ctx context.Context, httpClient *http.Client, targets []string) error {
+	// Redirects and cookies of the responses are ignored
+	client := *httpClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	client.Jar = nil
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+			if err != nil {
+				errs[i] = fmt.Errorf("%w: %s: %w", errors.ErrPreconnect, target, err)
+				return
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				errs[i] = fmt.Errorf("%w: %s: %w", errors.ErrPreconnect, target, err)
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PreconnectMiddleware warms the connections of its own HTTP client.
+type PreconnectMiddleware struct {
+	httpClient *http.Client
+	warmed     atomic.Int32
+}
+
+func (m *PreconnectMiddleware) Process(ctx context.Context, httpClient *http.Client, req *http.Request, next middleware.NextFunc) (*http.Response, error) {
+	return next(ctx, m.httpClient, req)
+}
+
+func (m *PreconnectMiddleware) Preconnect(ctx context.Context, httpClient *http.Client, warm middleware.WarmFunc) error {
+	m.warmed.Add(1)
+	return warm(ctx, m.httpClient)
+}
+
+func (m *PreconnectMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestPreconnect(t *testing.T) {
+	t.Parallel()
+
+	// newServer starts a server counting the connections opened to it and the HEAD requests received
+	newServer := func(t *testing.T) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+		t.Helper()
+
+		var conns, heads atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				heads.Add(1)
+			}
+		}))
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		server.Start()
+		t.Cleanup(server.Close)
+		return server, &conns, &heads
+	}
+
+	t.Run("Reuse the preconnected connection", func(t *testing.T) {
+		t.Parallel()
+
+		server, conns, heads := newServer(t)
+		c := client.NewClient(client.WithHTTP1())
+
+		require.NoError(t, c.Preconnect(context.Background(), server.URL))
+		assert.Equal(t, int32(1), conns.Load())
+		assert.Equal(t, int32(1), heads.Load())
+
+		resp, err := c.NewRequest().URL(server.URL + "/data").Do(context.Background())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), conns.Load())
+	})
+
+	t.Run("Preconnect the base URL", func(t *testing.T) {
+		t.Parallel()
+
+		server, _, heads := newServer(t)
+		c := client.NewClient(client.WithHTTP1(), client.WithBaseURL(server.URL+"/v1"))
+
+		require.NoError(t, c.Preconnect(context.Background()))
+		assert.Equal(t, int32(1), heads.Load())
+	})
+
+	t.Run("Warm the connections of middleware", func(t *testing.T) {
+		t.Parallel()
+
+		server, conns, _ := newServer(t)
+		m := &PreconnectMiddleware{httpClient: &http.Client{Transport: &http.Transport{}}}
+		c := client.NewClient(client.WithMiddleware(m))
+
+		require.NoError(t, c.Preconnect(context.Background(), server.URL))
+		assert.Equal(t, int32(1), m.warmed.Load())
+
+		// Requests use the connections of the middleware
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), conns.Load())
+	})
+
+	t.Run("Report hosts that cannot be reached", func(t *testing.T) {
+		t.Parallel()
+
+		server, _, heads := newServer(t)
+		c := client.NewClient(client.WithHTTP1())
+
+		err := c.Preconnect(context.Background(), server.URL, "http://127.0.0.1:1")
+		require.ErrorIs(t, err, errors.ErrPreconnect)
+		assert.Contains(t, err.Error(), "http://127.0.0.1:1/")
+		assert.Equal(t, int32(1), heads.Load())
+
+		err = c.Preconnect(context.Background(), "https://")
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/middleware"
+)
+
+// DoRaw sends the raw bytes of an HTTP/1.1 request, as constructed by the caller, to the host of
+// target and returns the parsed response. It is an escape hatch for servers that break on Go's
+// request normalization, such as header ordering or malformed request lines. The request runs
+// through the middleware chain, so the proxy selection and logging still apply, but middleware
+// that modify the request have no effect on the bytes sent. The connection is closed with the body.
+func (c *Client) DoRaw(ctx context.Context, target string, raw []byte) (*http.Response, error) {
+	// The method tells how to parse the response, such as the missing body of HEAD responses
+	method, _, ok := bytes.Cut(raw, []byte(" "))
+	if !ok {
+		return nil, fmt.Errorf("%w: raw request has no request line", errors.ErrRequestCreation)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, string(method), target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
+	}
+
+	return c.Do(middleware.WithRawRequest(ctx, raw), req)
+}
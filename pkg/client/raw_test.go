@@ -0,0 +1,145 @@
+package client_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/logger"
+	clientMiddleware "github.com/jaxron/axonet/pkg/client/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rawServer accepts connections, sends every request head it reads to the channel and
+// answers with a fixed response.
+func rawServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	heads := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				var head string
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					head += line
+					if line == "\r\n" {
+						break
+					}
+				}
+				heads <- head
+
+				_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), heads
+}
+
+// ProxyTransportMiddleware routes requests through a proxy, like the proxy middleware.
+type ProxyTransportMiddleware struct {
+	proxy *url.URL
+}
+
+func (m *ProxyTransportMiddleware) Process(ctx context.Context, c *http.Client, req *http.Request, next clientMiddleware.NextFunc) (*http.Response, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(m.proxy)
+	return next(ctx, &http.Client{Transport: transport}, req)
+}
+
+func (m *ProxyTransportMiddleware) SetLogger(_ logger.Logger) {}
+
+func TestDoRaw(t *testing.T) {
+	t.Parallel()
+
+	// The raw request keeps an unusual header order, casing and spacing
+	raw := "GET /path?q=1 HTTP/1.1\r\nhost: example\r\nX-Z:  first\r\nx-a: second\r\nConnection: close\r\n\r\n"
+
+	t.Run("Send the raw request", func(t *testing.T) {
+		t.Parallel()
+
+		addr, heads := rawServer(t)
+
+		resp, err := NewTestClient().DoRaw(context.Background(), "http://"+addr, []byte(raw))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, raw, <-heads)
+	})
+
+	t.Run("Send the raw request through the selected proxy", func(t *testing.T) {
+		t.Parallel()
+
+		addr, heads := rawServer(t)
+		proxyAddr := connectProxy(t)
+
+		c := NewTestClient(client.WithMiddleware(&ProxyTransportMiddleware{proxy: &url.URL{Scheme: "http", Host: proxyAddr}}))
+		resp, err := c.DoRaw(context.Background(), "http://"+addr, []byte(raw))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, raw, <-heads)
+	})
+}
+
+// connectProxy starts a proxy accepting CONNECT tunnels.
+func connectProxy(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					return
+				}
+				defer target.Close()
+
+				_, _ = conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+				go func() { _, _ = io.Copy(target, conn) }()
+				_, _ = io.Copy(conn, target)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
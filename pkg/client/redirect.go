@@ -0,0 +1,217 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// defaultMaxRedirects is the number of redirects followed before giving up, matching http.Client.
+const defaultMaxRedirects = 10
+
+// credentialHeaders are the headers carrying credentials that are subject to the redirect policy.
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+// RedirectCredentialsPolicy controls whether cookies and the Authorization header of a request
+// are forwarded when following redirects.
+type RedirectCredentialsPolicy int
+
+const (
+	// RedirectStripCrossOrigin forwards credentials only while every redirect stays on the origin
+	// of the original request, matching browser behavior. It is the default policy.
+	RedirectStripCrossOrigin RedirectCredentialsPolicy = iota
+	// RedirectStripCrossDomain forwards credentials to the domain of the original request and its
+	// subdomains, on any scheme and port, matching the behavior of http.Client.
+	RedirectStripCrossDomain
+	// RedirectForwardAlways forwards credentials to every redirect target.
+	RedirectForwardAlways
+	// RedirectStripAlways never forwards credentials when following redirects.
+	RedirectStripAlways
+)
+
+// RedirectPolicy controls how the Client follows redirects. It is built with NewRedirectPolicy
+// and chained setters, then applied with WithRedirectPolicy:
+//
+//	client.WithRedirectPolicy(client.NewRedirectPolicy().
+//		MaxRedirects(3).
+//		SameHostOnly().
+//		StripHeaders("X-Api-Key"))
+type RedirectPolicy struct {
+	maxRedirects int
+	sameHost     bool
+	credentials  RedirectCredentialsPolicy
+	stripHeaders []string
+}
+
+// NewRedirectPolicy creates a new RedirectPolicy with the default settings: up to 10 redirects
+// to any host, stripping credentials once a redirect leaves the original origin.
+func NewRedirectPolicy() *RedirectPolicy {
+	return &RedirectPolicy{
+		maxRedirects: defaultMaxRedirects,
+		sameHost:     false,
+		credentials:  RedirectStripCrossOrigin,
+		stripHeaders: nil,
+	}
+}
+
+// MaxRedirects sets the number of redirects followed before failing with a
+// *errors.TooManyRedirectsError. Zero makes any redirect fail.
+func (p *RedirectPolicy) MaxRedirects(n int) *RedirectPolicy {
+	p.maxRedirects = n
+	return p
+}
+
+// SameHostOnly makes redirects to another host than the one of the original request fail with
+// an *errors.RedirectNotAllowedError. Redirects to another scheme or port of the same host are followed.
+func (p *RedirectPolicy) SameHostOnly() *RedirectPolicy {
+	p.sameHost = true
+	return p
+}
+
+// Credentials sets whether cookies and the Authorization header are forwarded.
+func (p *RedirectPolicy) Credentials(policy RedirectCredentialsPolicy) *RedirectPolicy {
+	p.credentials = policy
+	return p
+}
+
+// StripHeaders adds headers that are removed once a redirect leaves the origin of the original
+// request, such as custom API key headers. Cookies and the Authorization header are governed by
+// the credentials policy instead.
+func (p *RedirectPolicy) StripHeaders(keys ...string) *RedirectPolicy {
+	for _, key := range keys {
+		p.stripHeaders = append(p.stripHeaders, http.CanonicalHeaderKey(key))
+	}
+	return p
+}
+
+// validate reports the settings that cannot be applied.
+func (p *RedirectPolicy) validate() error {
+	if p.maxRedirects < 0 {
+		return fmt.Errorf("negative redirect limit %d", p.maxRedirects)
+	}
+	if p.credentials < RedirectStripCrossOrigin || p.credentials > RedirectStripAlways {
+		return fmt.Errorf("unknown credentials policy %d", p.credentials)
+	}
+	return nil
+}
+
+// clone returns a copy of the policy, so the Client is not affected by later changes.
+func (p *RedirectPolicy) clone() *RedirectPolicy {
+	clone := *p
+	clone.stripHeaders = slices.Clone(p.stripHeaders)
+	return &clone
+}
+
+// checkRedirect applies the redirect policy of the Client.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	return c.redirectPolicy.check(req, via)
+}
+
+// check is the CheckRedirect function of the http.Client applying the policy.
+func (p *RedirectPolicy) check(req *http.Request, via []*http.Request) error {
+	original := via[0]
+	if len(via) > p.maxRedirects {
+		return &errors.TooManyRedirectsError{Max: p.maxRedirects, Via: redirectURLs(append(via, req))}
+	}
+	if p.sameHost && !strings.EqualFold(original.URL.Hostname(), req.URL.Hostname()) {
+		return &errors.RedirectNotAllowedError{From: original.URL.Redacted(), To: req.URL.Redacted()}
+	}
+
+	crossOrigin := false
+	for _, hop := range append(via[1:], req) {
+		if !sameOrigin(original.URL, hop.URL) {
+			crossOrigin = true
+			break
+		}
+	}
+	if crossOrigin {
+		for _, key := range p.stripHeaders {
+			req.Header.Del(key)
+		}
+	}
+
+	switch p.credentials {
+	case RedirectStripCrossOrigin:
+		if crossOrigin {
+			stripCredentials(req)
+		}
+	case RedirectStripCrossDomain:
+		// http.Client already strips the credentials it copies from the original request
+	case RedirectForwardAlways:
+		for _, key := range credentialHeaders {
+			if values := original.Header.Values(key); len(values) > 0 {
+				req.Header[key] = append([]string(nil), values...)
+			}
+		}
+	case RedirectStripAlways:
+		stripCredentials(req)
+	}
+
+	return nil
+}
+
+// Redirect is a redirect followed to get a response.
+type Redirect struct {
+	// URL is the URL that answered with the redirect.
+	URL *url.URL
+	// StatusCode is the status of the redirect, such as 301 or 302.
+	StatusCode int
+	// Location is the URL the redirect pointed to.
+	Location *url.URL
+}
+
+// RedirectHistory returns the redirects followed to get the response, from the first to the last.
+// It is empty if the request was not redirected.
+func RedirectHistory(resp *http.Response) []Redirect {
+	var history []Redirect
+	if resp == nil || resp.Request == nil {
+		return history
+	}
+	for req := resp.Request; req.Response != nil && req.Response.Request != nil; req = req.Response.Request {
+		history = append(history, Redirect{
+			URL:        req.Response.Request.URL,
+			StatusCode: req.Response.StatusCode,
+			Location:   req.URL,
+		})
+	}
+	slices.Reverse(history)
+	return history
+}
+
+// stripCredentials removes the credential headers from the request.
+func stripCredentials(req *http.Request) {
+	for _, key := range credentialHeaders {
+		req.Header.Del(key)
+	}
+}
+
+// redirectURLs returns the redacted URLs of the requests.
+func redirectURLs(reqs []*http.Request) []string {
+	urls := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		urls = append(urls, req.URL.Redacted())
+	}
+	return urls
+}
+
+// sameOrigin reports whether both URLs have the same scheme, host and port.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		port(a) == port(b)
+}
+
+// port returns the port of the URL, defaulting to the port of its scheme.
+func port(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
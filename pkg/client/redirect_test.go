@@ -0,0 +1,187 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectCredentials(t *testing.T) {
+	t.Parallel()
+
+	// The target echoes the credentials it received
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(target.Close)
+
+	// The origin redirects to the target, which is on the same host but another port
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/loop" {
+			http.Redirect(w, r, "/loop", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/local" {
+			w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		target := target.URL
+		if r.URL.Path == "/same-origin" {
+			target = "/local"
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	}))
+	t.Cleanup(origin.Close)
+
+	do := func(t *testing.T, path string, opts ...client.Option) *http.Response {
+		t.Helper()
+
+		resp, err := NewTestClient(opts...).NewRequest().
+			Method(http.MethodGet).
+			URL(origin.URL+path).
+			Header("Authorization", "Bearer token").
+			Header("Cookie", "session=abc").
+			Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Strip credentials on cross-origin redirects by default", func(t *testing.T) {
+		t.Parallel()
+
+		resp := do(t, "/")
+		assert.Empty(t, resp.Header.Get("X-Authorization"))
+		assert.Empty(t, resp.Header.Get("X-Cookie"))
+	})
+
+	t.Run("Forward credentials on same-origin redirects", func(t *testing.T) {
+		t.Parallel()
+
+		resp := do(t, "/same-origin")
+		assert.Equal(t, "Bearer token", resp.Header.Get("X-Authorization"))
+	})
+
+	t.Run("Forward credentials to the same domain", func(t *testing.T) {
+		t.Parallel()
+
+		resp := do(t, "/", client.WithRedirectCredentials(client.RedirectStripCrossDomain))
+		assert.Equal(t, "Bearer token", resp.Header.Get("X-Authorization"))
+		assert.Equal(t, "session=abc", resp.Header.Get("X-Cookie"))
+	})
+
+	t.Run("Never forward credentials", func(t *testing.T) {
+		t.Parallel()
+
+		resp := do(t, "/same-origin", client.WithRedirectCredentials(client.RedirectStripAlways))
+		assert.Empty(t, resp.Header.Get("X-Authorization"))
+	})
+
+	t.Run("Stop after too many redirects", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewTestClient().NewRequest().
+			Method(http.MethodGet).
+			URL(origin.URL + "/loop").
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrTooManyRedirects)
+		assert.Contains(t, err.Error(), "stopped after 10 redirects")
+	})
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	t.Parallel()
+
+	// The other server stands for another host, as it is reached through localhost
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Api-Key", r.Header.Get("X-Api-Key"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(other.Close)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		default:
+			w.Header().Set("X-Api-Key", r.Header.Get("X-Api-Key"))
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	do := func(path string, policy *client.RedirectPolicy) (*http.Response, error) {
+		return NewTestClient(client.WithRedirectPolicy(policy)).NewRequest().
+			URL(server.URL+path).
+			Header("X-Api-Key", "secret").
+			Do(context.Background())
+	}
+
+	t.Run("Capture the redirect history", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := do("/a", client.NewRedirectPolicy().StripHeaders("x-api-key"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Headers are only stripped on cross-origin redirects
+		assert.Equal(t, "secret", resp.Header.Get("X-Api-Key"))
+
+		history := client.RedirectHistory(resp)
+		require.Len(t, history, 2)
+		assert.Equal(t, "/a", history[0].URL.Path)
+		assert.Equal(t, http.StatusMovedPermanently, history[0].StatusCode)
+		assert.Equal(t, "/b", history[0].Location.Path)
+		assert.Equal(t, "/c", history[1].Location.Path)
+	})
+
+	t.Run("Fail beyond the redirect limit", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := do("/a", client.NewRedirectPolicy().MaxRedirects(1))
+		require.ErrorIs(t, err, errors.ErrTooManyRedirects)
+		assert.False(t, errors.IsTemporary(err))
+
+		var redirectErr *errors.TooManyRedirectsError
+		require.ErrorAs(t, err, &redirectErr)
+		assert.Equal(t, 1, redirectErr.Max)
+		assert.Equal(t, []string{server.URL + "/a", server.URL + "/b", server.URL + "/c"}, redirectErr.Via)
+	})
+
+	t.Run("Reject redirects to other hosts", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := do("/other", client.NewRedirectPolicy().SameHostOnly())
+		require.ErrorIs(t, err, errors.ErrRedirectNotAllowed)
+		assert.False(t, errors.IsTemporary(err))
+
+		resp, err := do("/other", client.NewRedirectPolicy().StripHeaders("X-Api-Key"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Empty(t, resp.Header.Get("X-Api-Key"))
+	})
+
+	t.Run("Reject invalid policies", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithRedirectPolicy(client.NewRedirectPolicy().MaxRedirects(-1)))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+
+		_, err = client.NewClientE(client.WithRedirectPolicy(nil))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+}
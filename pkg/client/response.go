@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/jaxron/axonet/pkg/client/codec"
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// Response wraps an http.Response with helpers that read and close its body, so callers do not
+// have to. The body is read once and kept in memory, so the helpers can be called repeatedly.
+// The Header method shadows the field of the embedded response, which remains available as Response.Header.
+type Response struct {
+	*http.Response
+
+	body []byte
+	err  error
+	once sync.Once
+}
+
+// NewResponse wraps the http.Response.
+func NewResponse(resp *http.Response) *Response {
+	return &Response{
+		Response: resp,
+		body:     nil,
+		err:      nil,
+		once:     sync.Once{},
+	}
+}
+
+// DoResponse executes the request like Do and wraps the response.
+// The response is returned along with the error if the request failed after receiving it.
+func (rb *Request) DoResponse(ctx context.Context, opts ...RequestOption) (*Response, error) {
+	resp, err := rb.Do(ctx, opts...)
+	if resp == nil {
+		return nil, err
+	}
+	return NewResponse(resp), err
+}
+
+// Bytes returns the body of the response, reading and closing it on first use. It fails with a
+// *errors.TrailerStatusError when the trailers of a gRPC or gRPC-Web response carry an error status.
+func (r *Response) Bytes() ([]byte, error) {
+	r.once.Do(func() {
+		defer r.Body.Close()
+		r.body, r.err = io.ReadAll(r.Body)
+	})
+	return r.body, r.err
+}
+
+// String returns the body of the response as a string.
+func (r *Response) String() (string, error) {
+	body, err := r.Bytes()
+	return string(body), err
+}
+
+// JSON unmarshals the JSON body of the response into v.
+func (r *Response) JSON(v interface{}) error {
+	body, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Decode unmarshals the body of the response into v with the codec registered for its Content-Type
+// header, returning codec.ErrUnknownContentType if there is none.
+func (r *Response) Decode(v interface{}) error {
+	contentType := r.Response.Header.Get("Content-Type")
+	c, ok := codec.Lookup(contentType)
+	if !ok {
+		return fmt.Errorf("%w: %q", codec.ErrUnknownContentType, contentType)
+	}
+
+	body, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(body, v)
+}
+
+// IsSuccess reports whether the response has a 2xx status code.
+func (r *Response) IsSuccess() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Header returns the first value of the response header associated with the key.
+func (r *Response) Header(key string) string {
+	return r.Response.Header.Get(key)
+}
+
+// Trailers returns the trailers of the response, reading the body first since trailers are only
+// known once it is consumed. The trailers of gRPC-Web responses, sent in the last frame of the
+// body, are included.
+func (r *Response) Trailers() (http.Header, error) {
+	if _, err := r.Bytes(); err != nil && !errors.Is(err, errors.ErrTrailerStatus) {
+		return nil, err
+	}
+	return r.Trailer, nil
+}
+
+// Close closes the body of the response, which is only needed when the body is not read.
+func (r *Response) Close() error {
+	return r.Body.Close()
+}
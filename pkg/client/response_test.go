@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoResponse(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Name", "axonet")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"name":"axonet"}`))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Read the body with helpers", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			DoResponse(context.Background())
+		require.NoError(t, err)
+
+		assert.True(t, resp.IsSuccess())
+		assert.Equal(t, "axonet", resp.Header("X-Name"))
+		assert.Equal(t, http.StatusOK, resp.Response.StatusCode)
+
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, `{"name":"axonet"}`, body)
+
+		// The body can be read again once consumed
+		var result struct {
+			Name string `json:"name"`
+		}
+		require.NoError(t, resp.JSON(&result))
+		assert.Equal(t, "axonet", result.Name)
+	})
+
+	t.Run("Report unsuccessful responses", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL + "/missing").
+			DoResponse(context.Background())
+		require.NoError(t, err)
+		defer resp.Close()
+
+		assert.False(t, resp.IsSuccess())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// decodePath decodes the value at the dot-separated path of the JSON document read from r into v,
+// such as "data.items" or "data.items.0". The document is streamed, values before the path are
+// skipped without being decoded and the rest of the document is not read.
+func decodePath(r io.Reader, path string, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	for _, segment := range strings.Split(path, ".") {
+		found, err := seek(decoder, segment)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%w: %q has no %q", errors.ErrPathNotFound, path, segment)
+		}
+	}
+	return decoder.Decode(v)
+}
+
+// seek advances the decoder to the member of the next object with the key, or to the element of
+// the next array at the index, reporting whether it exists.
+func seek(decoder *json.Decoder, segment string) (bool, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return false, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return false, err
+			}
+			if key == segment {
+				return true, nil
+			}
+			if err := skip(decoder); err != nil {
+				return false, err
+			}
+		}
+	case json.Delim('['):
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			return false, nil //nolint:nilerr // a key cannot match an array element
+		}
+		for i := 0; decoder.More(); i++ {
+			if i == index {
+				return true, nil
+			}
+			if err := skip(decoder); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// skip skips the next value of the decoder without decoding it.
+func skip(decoder *json.Decoder) error {
+	var raw json.RawMessage
+	return decoder.Decode(&raw)
+}
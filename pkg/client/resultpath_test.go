@@ -0,0 +1,84 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultPath(t *testing.T) {
+	t.Parallel()
+
+	// The envelope has a large value before and after the items
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"meta": {"padding": "` + strings.Repeat("x", 1<<16) + `", "nested": [{"items": "decoy"}]},
+			"data": {"total": 2, "items": [{"id": 1, "name": "Ada"}, {"id": 2, "name": "Grace"}]},
+			"trailer": [1, 2, 3]
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	c := NewTestClient()
+
+	t.Run("Decode the value at the path", func(t *testing.T) {
+		t.Parallel()
+
+		var items []Item
+		resp, err := c.NewRequest().URL(server.URL).ResultPath("data.items", &items).Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, []Item{{ID: 1, Name: "Ada"}, {ID: 2, Name: "Grace"}}, items)
+
+		var total int
+		_, err = c.NewRequest().URL(server.URL).ResultPath("data.total", &total).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("Index into arrays", func(t *testing.T) {
+		t.Parallel()
+
+		var item Item
+		_, err := c.NewRequest().URL(server.URL).ResultPath("data.items.1", &item).Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "Grace", item.Name)
+	})
+
+	t.Run("Apply response transformations first", func(t *testing.T) {
+		t.Parallel()
+
+		var name string
+		_, err := c.NewRequest().
+			URL(server.URL).
+			TransformResponseBody(func(body []byte) ([]byte, error) {
+				return []byte(strings.Replace(string(body), "Ada", "Ada Lovelace", 1)), nil
+			}).
+			ResultPath("data.items.0.name", &name).
+			Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "Ada Lovelace", name)
+	})
+
+	t.Run("Fail on missing paths", func(t *testing.T) {
+		t.Parallel()
+
+		var value interface{}
+		for _, path := range []string{"data.missing", "data.items.5", "data.items.name", "data.total.value"} {
+			_, err := c.NewRequest().URL(server.URL).ResultPath(path, &value).Do(context.Background())
+			require.ErrorIs(t, err, errors.ErrPathNotFound, path)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// SignatureParams names the query parameters of pre-signed URLs.
+type SignatureParams struct {
+	// Expires holds the Unix time after which the URL is no longer valid.
+	Expires string
+	// Signature holds the hex-encoded HMAC-SHA256 signature of the URL.
+	Signature string
+	// KeyID holds the identifier of the signing key, if the signer has one.
+	KeyID string
+}
+
+// DefaultSignatureParams are the query parameters of pre-signed URLs by default.
+var DefaultSignatureParams = SignatureParams{
+	Expires:   "expires",
+	Signature: "signature",
+	KeyID:     "key_id",
+}
+
+// URLSigner generates pre-signed URLs: URLs carrying an expiry time and an HMAC signature in their
+// query, which other systems can use until they expire without holding any credentials.
+//
+// The signature covers the method, the host, the escaped path and the query parameters other
+// than the signature, encoded with their keys sorted as by Query.Encode, so the order of the
+// parameters does not matter.
+type URLSigner struct {
+	key    []byte
+	keyID  string
+	params SignatureParams
+}
+
+// NewURLSigner creates a new URLSigner signing with the key.
+func NewURLSigner(key []byte) *URLSigner {
+	return &URLSigner{
+		key:    key,
+		keyID:  "",
+		params: DefaultSignatureParams,
+	}
+}
+
+// SetKeyID sets the identifier of the key added to signed URLs, so verifiers holding several keys,
+// such as during key rotation, know which one to use. An empty identifier is not added.
+func (s *URLSigner) SetKeyID(id string) {
+	s.keyID = id
+}
+
+// SetParams sets the names of the query parameters of signed URLs. Empty names keep the defaults.
+func (s *URLSigner) SetParams(params SignatureParams) {
+	if params.Expires == "" {
+		params.Expires = DefaultSignatureParams.Expires
+	}
+	if params.Signature == "" {
+		params.Signature = DefaultSignatureParams.Signature
+	}
+	if params.KeyID == "" {
+		params.KeyID = DefaultSignatureParams.KeyID
+	}
+	s.params = params
+}
+
+// Sign returns the URL signed for requests with the method until the expiry time. An empty method
+// is GET. Any signature parameters already in the URL are replaced.
+func (s *URLSigner) Sign(method, rawURL string, expires time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
+	}
+
+	query := Query(u.Query())
+	delete(query, s.params.Signature)
+	delete(query, s.params.KeyID)
+	query.Set(s.params.Expires, strconv.FormatInt(expires.Unix(), 10))
+	query.Set(s.params.KeyID, s.keyID)
+
+	query[s.params.Signature] = []string{s.signature(method, u, query)}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Verify checks that the URL was signed with the key for requests with the method, failing with
+// errors.ErrSignatureInvalid if its signature does not match, and with errors.ErrSignatureExpired
+// if it expired before now.
+func (s *URLSigner) Verify(method, rawURL string, now time.Time) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errors.ErrSignatureInvalid, err)
+	}
+
+	query := Query(u.Query())
+	signature := query.Get(s.params.Signature)
+	delete(query, s.params.Signature)
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(s.signature(method, u, query))) {
+		return errors.ErrSignatureInvalid
+	}
+
+	expires, err := strconv.ParseInt(query.Get(s.params.Expires), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errors.ErrSignatureInvalid, err)
+	}
+	if now.After(time.Unix(expires, 0)) {
+		return fmt.Errorf("%w: expired at %s", errors.ErrSignatureExpired, time.Unix(expires, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// signature returns the hex-encoded signature of the URL with the query, which must not hold the
+// signature parameter.
+func (s *URLSigner) signature(method string, u *url.URL, query Query) string {
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(method),
+		strings.ToLower(u.Host),
+		u.EscapedPath(),
+		query.Encode(),
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Presign returns the URL of the request signed with the signer, valid for the duration. The URL
+// is resolved against the base URL of the client and carries the query parameters of the request,
+// as when the request is sent, but the request itself is not sent.
+func (rb *Request) Presign(signer *URLSigner, ttl time.Duration) (string, error) {
+	target, err := rb.client.resolveURL(rb.url)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errors.ErrRequestCreation, err)
+	}
+
+	// Set the query parameters, keeping those of the URL that the request does not set
+	query := Query(u.Query())
+	for key, values := range rb.query {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return signer.Sign(rb.method, u.String(), time.Now().Add(ttl))
+}
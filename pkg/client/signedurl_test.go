@@ -0,0 +1,80 @@
+package client_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLSigner(t *testing.T) {
+	t.Parallel()
+
+	signer := client.NewURLSigner([]byte("secret"))
+	expires := time.Unix(1_900_000_000, 0)
+
+	t.Run("Sign and verify URLs", func(t *testing.T) {
+		t.Parallel()
+
+		signed, err := signer.Sign(http.MethodGet, "https://files.example.com/exports/report.csv?b=2&a=1", expires)
+		require.NoError(t, err)
+
+		u, err := url.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "1900000000", u.Query().Get("expires"))
+		assert.Len(t, u.Query().Get("signature"), 64)
+		assert.Empty(t, u.Query().Get("key_id"))
+
+		require.NoError(t, signer.Verify(http.MethodGet, signed, expires.Add(-time.Minute)))
+		require.ErrorIs(t, signer.Verify(http.MethodGet, signed, expires.Add(time.Second)), errors.ErrSignatureExpired)
+		require.ErrorIs(t, signer.Verify(http.MethodPut, signed, expires.Add(-time.Minute)), errors.ErrSignatureInvalid)
+
+		// Tampering with the query invalidates the signature
+		tampered := u.Query()
+		tampered.Set("a", "3")
+		u.RawQuery = tampered.Encode()
+		require.ErrorIs(t, signer.Verify(http.MethodGet, u.String(), expires.Add(-time.Minute)), errors.ErrSignatureInvalid)
+
+		// Signatures do not verify with another key
+		other := client.NewURLSigner([]byte("other"))
+		require.ErrorIs(t, other.Verify(http.MethodGet, signed, expires.Add(-time.Minute)), errors.ErrSignatureInvalid)
+	})
+
+	t.Run("Use configured parameter names and key ID", func(t *testing.T) {
+		t.Parallel()
+
+		custom := client.NewURLSigner([]byte("secret"))
+		custom.SetParams(client.SignatureParams{Expires: "X-Expires", Signature: "X-Signature", KeyID: ""})
+		custom.SetKeyID("2024-10")
+
+		signed, err := custom.Sign("", "https://files.example.com/a", expires)
+		require.NoError(t, err)
+
+		u, err := url.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "1900000000", u.Query().Get("X-Expires"))
+		assert.NotEmpty(t, u.Query().Get("X-Signature"))
+		assert.Equal(t, "2024-10", u.Query().Get("key_id"))
+		require.NoError(t, custom.Verify(http.MethodGet, signed, expires.Add(-time.Minute)))
+	})
+
+	t.Run("Presign requests like they are sent", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithBaseURL("https://api.example.com/v1?tenant=acme"))
+		signed, err := c.NewRequest().URL("/exports/42").Query("format", "csv").Presign(signer, time.Hour)
+		require.NoError(t, err)
+
+		u, err := url.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "/v1/exports/42", u.Path)
+		assert.Equal(t, "acme", u.Query().Get("tenant"))
+		assert.Equal(t, "csv", u.Query().Get("format"))
+		require.NoError(t, signer.Verify(http.MethodGet, signed, time.Now()))
+	})
+}
@@ -0,0 +1,254 @@
+// Package sitemap fetches and parses sitemaps, following sitemap indexes and
+// decompressing gzipped sitemaps.
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/compression"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+)
+
+var (
+	ErrInvalidSitemap = errors.New("invalid sitemap")
+	ErrMaxDepth       = errors.New("sitemap index nesting too deep")
+)
+
+// maxIndexDepth is the maximum number of nested sitemap indexes that are followed.
+const maxIndexDepth = 3
+
+// DefaultMaxSize is the maximum size of a sitemap once decompressed, which is the limit of the
+// sitemaps protocol.
+const DefaultMaxSize = 50 << 20
+
+// gzipMagic is the header every gzip stream starts with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// URL is a page listed in a sitemap.
+type URL struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   float64
+}
+
+// urlSet is the root element of a sitemap.
+type urlSet struct {
+	URLs []struct {
+		Loc        string  `xml:"loc"`
+		LastMod    string  `xml:"lastmod"`
+		ChangeFreq string  `xml:"changefreq"`
+		Priority   float64 `xml:"priority"`
+	} `xml:"url"`
+}
+
+// sitemapIndex is the root element of a sitemap index.
+type sitemapIndex struct {
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// Option configures how sitemaps are fetched and parsed.
+type Option func(*options)
+
+// options holds the settings of a fetch or parse.
+type options struct {
+	maxSize int64
+}
+
+// WithMaxSize limits the size of each sitemap, once decompressed, to n bytes. Larger sitemaps fail
+// with errors.ErrResponseTooLarge. A size of zero or less removes the limit. It defaults to
+// DefaultMaxSize.
+func WithMaxSize(n int64) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// newOptions returns the settings with the options applied.
+func newOptions(opts []Option) *options {
+	o := &options{maxSize: DefaultMaxSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Fetch fetches the sitemap at url using the client and returns the URLs it lists.
+// Sitemap indexes are followed recursively and gzipped sitemaps are decompressed.
+func Fetch(ctx context.Context, c *client.Client, url string, opts ...Option) ([]URL, error) {
+	return fetch(ctx, c, url, 0, newOptions(opts))
+}
+
+// fetch fetches the sitemap at url, following indexes up to maxIndexDepth.
+func fetch(ctx context.Context, c *client.Client, url string, depth int, o *options) ([]URL, error) {
+	if depth > maxIndexDepth {
+		return nil, fmt.Errorf("%w: %s", ErrMaxDepth, url)
+	}
+
+	data, err := fetchBody(ctx, c, url, o.maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	urls, children, err := parse(data, o)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, url)
+	}
+
+	// Follow the sitemaps listed in an index
+	for _, child := range children {
+		childURLs, err := fetch(ctx, c, child, depth+1, o)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, childURLs...)
+	}
+
+	return urls, nil
+}
+
+// Parse parses a sitemap or sitemap index.
+// It returns the listed URLs for a sitemap, or the locations of the listed sitemaps for an index.
+// Gzipped data is decompressed first.
+func Parse(data []byte, opts ...Option) ([]URL, []string, error) {
+	return parse(data, newOptions(opts))
+}
+
+// parse parses a sitemap or sitemap index with the settings.
+func parse(data []byte, o *options) ([]URL, []string, error) {
+	data, err := decompress(data, o.maxSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch root {
+	case "urlset":
+		var set urlSet
+		if err := xml.Unmarshal(data, &set); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+		}
+
+		urls := make([]URL, 0, len(set.URLs))
+		for _, u := range set.URLs {
+			urls = append(urls, URL{
+				Loc:        strings.TrimSpace(u.Loc),
+				LastMod:    parseTime(u.LastMod),
+				ChangeFreq: strings.TrimSpace(u.ChangeFreq),
+				Priority:   u.Priority,
+			})
+		}
+		return urls, nil, nil
+	case "sitemapindex":
+		var index sitemapIndex
+		if err := xml.Unmarshal(data, &index); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+		}
+
+		locations := make([]string, 0, len(index.Sitemaps))
+		for _, s := range index.Sitemaps {
+			locations = append(locations, strings.TrimSpace(s.Loc))
+		}
+		return nil, locations, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unexpected root element %q", ErrInvalidSitemap, root)
+	}
+}
+
+// fetchBody fetches the body at url, failing on non-2xx responses and bodies over maxSize bytes.
+func fetchBody(ctx context.Context, c *client.Client, url string, maxSize int64) ([]byte, error) {
+	resp, err := c.NewRequest().
+		Method(http.MethodGet).
+		URL(url).
+		Do(ctx)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, clientErrors.NewHTTPError(resp)
+	}
+
+	return readLimited(resp.Body, maxSize)
+}
+
+// decompress decompresses the data if it is gzipped, failing once it exceeds maxSize bytes.
+func decompress(data []byte, maxSize int64) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	codec, _ := compression.Lookup("gzip")
+	r, err := codec.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+	}
+	defer r.Close()
+
+	decompressed, err := readLimited(r, maxSize)
+	if err != nil && !errors.Is(err, clientErrors.ErrResponseTooLarge) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+	}
+	return decompressed, err
+}
+
+// readLimited reads r to the end, failing with errors.ErrResponseTooLarge once it exceeds
+// maxSize bytes, unless maxSize is zero or less.
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: sitemap exceeds %d bytes", clientErrors.ErrResponseTooLarge, maxSize)
+	}
+	return data, nil
+}
+
+// rootElement returns the local name of the root element of the XML document.
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// parseTime parses a W3C datetime, returning the zero time if it is invalid.
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,109 @@
+package sitemap_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/jaxron/axonet/pkg/client/sitemap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	_, err := w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/b</loc></url>
+</urlset>`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + serverURL + `/pages.xml</loc></sitemap>
+  <sitemap><loc>` + serverURL + `/posts.xml.gz</loc></sitemap>
+</sitemapindex>`))
+		case "/pages.xml":
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/a</loc>
+    <lastmod>2024-01-02</lastmod>
+    <changefreq>daily</changefreq>
+    <priority>0.8</priority>
+  </url>
+</urlset>`))
+		case "/posts.xml.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write(gzipped.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	serverURL = server.URL
+
+	t.Run("Follow sitemap index", func(t *testing.T) {
+		t.Parallel()
+
+		urls, err := sitemap.Fetch(context.Background(), client.NewClient(), server.URL+"/sitemap.xml")
+		require.NoError(t, err)
+		require.Len(t, urls, 2)
+
+		assert.Equal(t, "https://example.com/a", urls[0].Loc)
+		assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), urls[0].LastMod)
+		assert.Equal(t, "daily", urls[0].ChangeFreq)
+		assert.InDelta(t, 0.8, urls[0].Priority, 0.001)
+		assert.Equal(t, "https://example.com/b", urls[1].Loc)
+	})
+
+	t.Run("Fail on bad status", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := sitemap.Fetch(context.Background(), client.NewClient(), server.URL+"/missing.xml")
+		require.Error(t, err)
+	})
+
+	t.Run("Limit the size of decompressed sitemaps", func(t *testing.T) {
+		t.Parallel()
+
+		// A small gzipped sitemap expanding far beyond the limit
+		var bomb bytes.Buffer
+		w := gzip.NewWriter(&bomb)
+		_, err := w.Write(bytes.Repeat([]byte(" "), 1<<20))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		_, _, err = sitemap.Parse(bomb.Bytes(), sitemap.WithMaxSize(1<<10))
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+
+		// The gzipped sitemap of the index fits in the limit
+		urls, err := sitemap.Fetch(context.Background(), client.NewClient(), server.URL+"/sitemap.xml", sitemap.WithMaxSize(1<<10))
+		require.NoError(t, err)
+		assert.Len(t, urls, 2)
+
+		_, err = sitemap.Fetch(context.Background(), client.NewClient(), server.URL+"/sitemap.xml", sitemap.WithMaxSize(16))
+		require.ErrorIs(t, err, errors.ErrResponseTooLarge)
+	})
+
+	t.Run("Reject unknown documents", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := sitemap.Parse([]byte(`<html></html>`))
+		require.ErrorIs(t, err, sitemap.ErrInvalidSitemap)
+	})
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// StatusError is returned by Do for responses whose status matches the predicate set with
+// WithErrorOnStatus. It matches errors.ErrBadStatus with errors.Is, and *errors.HTTPError with errors.As.
+type StatusError struct {
+	*errors.HTTPError
+
+	// Response is the response that failed. Its body is left readable from the start and must
+	// be closed by the caller.
+	Response *http.Response
+}
+
+// Unwrap returns the HTTPError describing the response.
+func (e *StatusError) Unwrap() error {
+	return e.HTTPError
+}
+
+// IsErrorStatus reports whether the status code is a 4xx client error or a 5xx server error.
+// It is the usual predicate for WithErrorOnStatus.
+func IsErrorStatus(status int) bool {
+	return status >= http.StatusBadRequest
+}
@@ -0,0 +1,114 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithErrorOnStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/problem" {
+			w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, err := w.Write([]byte(`{
+				"title": "Your request parameters didn't validate.",
+				"detail": "age must be a positive integer",
+				"instance": "/problem/42",
+				"invalid-params": [{"name": "age", "reason": "must be a positive integer"}]
+			}`))
+			assert.NoError(t, err)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, err := w.Write([]byte("not found"))
+			assert.NoError(t, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Ignore the status by default", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL + "/missing").
+			Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Return an error for matching statuses", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithErrorOnStatus(client.IsErrorStatus))
+
+		resp, err := c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL + "/missing").
+			Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrBadStatus)
+		assert.Contains(t, err.Error(), "GET "+server.URL+"/missing: 404")
+
+		var statusErr *client.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Same(t, resp, statusErr.Response)
+
+		var httpErr *errors.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		assert.Equal(t, "not found", httpErr.BodySnippet)
+
+		body, err := client.NewResponse(statusErr.Response).String()
+		require.NoError(t, err)
+		assert.Equal(t, "not found", body)
+
+		resp, err = c.NewRequest().
+			Method(http.MethodGet).
+			URL(server.URL).
+			Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+	})
+	t.Run("Decode problem details", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithErrorOnStatus(client.IsErrorStatus))
+
+		_, err := c.NewRequest().URL(server.URL + "/problem").Do(context.Background())
+		require.ErrorIs(t, err, errors.ErrBadStatus)
+
+		var problem *errors.ProblemDetails
+		require.ErrorAs(t, err, &problem)
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		assert.Equal(t, "age must be a positive integer", problem.Detail)
+		assert.Equal(t, "/problem/42", problem.Instance)
+		assert.Equal(t, "Your request parameters didn't validate. (422): age must be a positive integer", problem.Error())
+
+		var invalid []struct{ Name, Reason string }
+		ok, err := problem.Extension("invalid-params", &invalid)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "age", invalid[0].Name)
+
+		// Requests with a result return problems without a status predicate
+		var result map[string]interface{}
+		resp, err := NewTestClient().NewRequest().URL(server.URL + "/problem").Result(&result).Do(context.Background())
+		require.ErrorAs(t, err, &problem)
+		assert.Nil(t, result)
+		defer resp.Body.Close()
+	})
+}
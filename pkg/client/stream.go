@@ -0,0 +1,77 @@
+package client
+
+import (
+	"io"
+)
+
+// Consumer is a writer handing what is written to a function reading it as a stream, such as a
+// parser decoding the body while it is saved to a file. It is used with Response.StreamTo.
+type Consumer struct {
+	fn   func(io.Reader) error
+	pr   *io.PipeReader
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// NewConsumer creates a new Consumer running fn on the streamed body. Bytes left unread when fn
+// returns without an error are discarded, and an error returned by fn stops the stream.
+func NewConsumer(fn func(io.Reader) error) *Consumer {
+	pr, pw := io.Pipe()
+	return &Consumer{
+		fn:   fn,
+		pr:   pr,
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+}
+
+// Write hands p to the function of the consumer, blocking until it is read.
+func (c *Consumer) Write(p []byte) (int, error) {
+	return c.pw.Write(p)
+}
+
+// start runs the function of the consumer on the stream.
+func (c *Consumer) start() {
+	go func() {
+		err := c.fn(c.pr)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, c.pr)
+		}
+		c.pr.CloseWithError(err)
+		c.done <- err
+	}()
+}
+
+// finish ends the stream with err, or io.EOF if err is nil, and waits for the function to return.
+func (c *Consumer) finish(err error) error {
+	c.pw.CloseWithError(err)
+	return <-c.done
+}
+
+// StreamTo copies the body of the response to every writer in a single pass without buffering it,
+// then closes the body. Writers such as files and hashes receive the body as it is read, while
+// consumers created with NewConsumer read it concurrently. Writing stops at the first error, and
+// a body exceeding the response size limit fails with *errors.ResponseTooLargeError once the
+// writers received the bytes up to the limit. The body cannot be read again afterwards.
+func (r *Response) StreamTo(writers ...io.Writer) (int64, error) {
+	defer r.Body.Close()
+
+	var consumers []*Consumer
+	for _, w := range writers {
+		if c, ok := w.(*Consumer); ok {
+			c.start()
+			consumers = append(consumers, c)
+		}
+	}
+
+	n, err := io.Copy(io.MultiWriter(writers...), r.Body)
+
+	// End the streams of the consumers. An error of a consumer while the body was copied already
+	// stopped the copy, so only errors returned at the end of the body are added.
+	for _, c := range consumers {
+		if consumerErr := c.finish(err); err == nil {
+			err = consumerErr
+		}
+	}
+	return n, err
+}
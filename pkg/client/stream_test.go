@@ -0,0 +1,92 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	clientErrors "github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTo(t *testing.T) {
+	t.Parallel()
+
+	payload := `{"items":["` + strings.Repeat("a", 64*1024) + `"]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(payload))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("Tee the body to writers and consumers", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().URL(server.URL).DoResponse(context.Background())
+		require.NoError(t, err)
+
+		var file bytes.Buffer
+		hash := sha256.New()
+		var parsed struct {
+			Items []string `json:"items"`
+		}
+		parser := client.NewConsumer(func(r io.Reader) error {
+			return json.NewDecoder(r).Decode(&parsed)
+		})
+
+		n, err := resp.StreamTo(&file, hash, parser)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(payload)), n)
+		assert.Equal(t, payload, file.String())
+
+		sum := sha256.Sum256([]byte(payload))
+		assert.Equal(t, hex.EncodeToString(sum[:]), hex.EncodeToString(hash.Sum(nil)))
+		require.Len(t, parsed.Items, 1)
+		assert.Len(t, parsed.Items[0], 64*1024)
+	})
+
+	t.Run("Stop at the first consumer error", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().URL(server.URL).DoResponse(context.Background())
+		require.NoError(t, err)
+
+		errRejected := errors.New("rejected")
+		var file bytes.Buffer
+		_, err = resp.StreamTo(&file, client.NewConsumer(func(r io.Reader) error {
+			_, err := r.Read(make([]byte, 8))
+			assert.NoError(t, err)
+			return errRejected
+		}))
+		require.ErrorIs(t, err, errRejected)
+		assert.Less(t, file.Len(), len(payload))
+	})
+
+	t.Run("Stop at the response size limit", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := NewTestClient().NewRequest().URL(server.URL).DoResponse(context.Background(), client.WithResponseLimit(1024))
+		require.NoError(t, err)
+
+		var file bytes.Buffer
+		var consumed int64
+		n, err := resp.StreamTo(&file, client.NewConsumer(func(r io.Reader) error {
+			var err error
+			consumed, err = io.Copy(io.Discard, r)
+			return err
+		}))
+		require.ErrorIs(t, err, clientErrors.ErrResponseTooLarge)
+		assert.Equal(t, int64(1024), n)
+		assert.Equal(t, 1024, file.Len())
+		assert.Equal(t, int64(1024), consumed)
+	})
+}
@@ -0,0 +1,107 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// BlockingResolver is a resolver that never answers.
+type BlockingResolver struct{}
+
+func (BlockingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestGranularTimeouts(t *testing.T) {
+	t.Parallel()
+
+	// elapsed returns the time taken by the request and its error
+	elapsed := func(c *client.Client, url string) (time.Duration, error) {
+		start := time.Now()
+		resp, err := c.NewRequest().URL(url).Do(context.Background())
+		if err == nil {
+			resp.Body.Close()
+		}
+		return time.Since(start), err
+	}
+
+	t.Run("Time out connecting", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewTestClient(client.WithResolver(BlockingResolver{}), client.WithDialTimeout(20*time.Millisecond))
+		duration, err := elapsed(c, "http://slow.example")
+		require.Error(t, err)
+		assert.Less(t, duration, time.Second)
+	})
+
+	t.Run("Time out the TLS handshake", func(t *testing.T) {
+		t.Parallel()
+
+		// The listener accepts connections but never answers the handshake
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					_, _ = io.Copy(io.Discard, conn)
+					conn.Close()
+				}()
+			}
+		}()
+
+		c := NewTestClient(client.WithTLSHandshakeTimeout(20 * time.Millisecond))
+		duration, err := elapsed(c, "https://"+listener.Addr().String())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TLS handshake timeout")
+		assert.Less(t, duration, time.Second)
+	})
+
+	t.Run("Time out waiting for the response headers", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+
+		c := NewTestClient(client.WithResponseHeaderTimeout(50 * time.Millisecond))
+		_, err := elapsed(c, server.URL+"/slow")
+		require.Error(t, err)
+		assert.True(t, errors.IsTemporary(err))
+
+		_, err = elapsed(c, server.URL)
+		require.NoError(t, err)
+	})
+
+	t.Run("Reject negative timeouts", func(t *testing.T) {
+		t.Parallel()
+
+		for _, opt := range []client.Option{
+			client.WithDialTimeout(-time.Second),
+			client.WithTLSHandshakeTimeout(-time.Second),
+			client.WithResponseHeaderTimeout(-time.Second),
+		} {
+			_, err := client.NewClientE(opt)
+			require.ErrorIs(t, err, errors.ErrInvalidOption)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+// pinPrefix is the prefix of pins naming their hash algorithm.
+const pinPrefix = "sha256/"
+
+// parsePin validates a pin, returning it without its prefix.
+func parsePin(pin string) (string, error) {
+	pin = strings.TrimPrefix(pin, pinPrefix)
+	hash, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(hash) != sha256.Size {
+		return "", fmt.Errorf("pin %q is not a base64 SHA-256 hash", pin)
+	}
+	return pin, nil
+}
+
+// spkiPin returns the pin of the public key of the certificate.
+func spkiPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// verifyPins returns a connection check requiring a certificate of a verified chain, or the leaf
+// certificate when verification is skipped, to match a pin of the Client, after running the check
+// of the configuration, if any.
+func (c *Client) verifyPins(verify func(tls.ConnectionState) error) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+
+		// Verified chains are empty when verification is skipped. Only the leaf is then matched, as
+		// the other presented certificates are not known to sign it
+		chains := cs.VerifiedChains
+		if len(chains) == 0 && len(cs.PeerCertificates) > 0 {
+			chains = [][]*x509.Certificate{cs.PeerCertificates[:1]}
+		}
+
+		var presented []string
+		for _, chain := range chains {
+			for _, cert := range chain {
+				pin := spkiPin(cert)
+				if slices.Contains(c.pins, pin) {
+					return nil
+				}
+				if !slices.Contains(presented, pinPrefix+pin) {
+					presented = append(presented, pinPrefix+pin)
+				}
+			}
+		}
+		return &errors.PinError{Host: cs.ServerName, Presented: presented}
+	}
+}
@@ -0,0 +1,93 @@
+package client_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client"
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	trusted := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12} //nolint:exhaustruct
+
+	hash := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(hash[:])
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	send := func(t *testing.T, opts ...client.Option) error {
+		t.Helper()
+
+		c, err := client.NewClientE(opts...)
+		require.NoError(t, err)
+		resp, err := c.NewRequest().URL(server.URL).Do(context.Background())
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("Trust servers with the TLS configuration", func(t *testing.T) {
+		t.Parallel()
+
+		require.Error(t, send(t))
+		require.NoError(t, send(t, client.WithTLSConfig(trusted)))
+	})
+
+	t.Run("Accept servers matching a pin", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, send(t, client.WithTLSConfig(trusted), client.WithPinnedCerts(otherPin, "sha256/"+pin)))
+
+		// Pins apply whatever the order of the options
+		require.NoError(t, send(t, client.WithPinnedCerts(pin), client.WithTLSConfig(trusted)))
+	})
+
+	t.Run("Reject servers matching no pin", func(t *testing.T) {
+		t.Parallel()
+
+		err := send(t, client.WithPinnedCerts(otherPin), client.WithTLSConfig(trusted))
+		require.ErrorIs(t, err, errors.ErrCertificatePin)
+		assert.False(t, errors.IsTemporary(err))
+
+		var pinErr *errors.PinError
+		require.ErrorAs(t, err, &pinErr)
+		assert.Equal(t, []string{"sha256/" + pin}, pinErr.Presented)
+
+		// Pins added by later options apply too
+		require.NoError(t, send(t, client.WithTLSConfig(trusted), client.WithPinnedCerts(otherPin), client.WithPinnedCerts(pin)))
+	})
+
+	t.Run("Reject invalid pins", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.NewClientE(client.WithPinnedCerts("not a pin"))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+
+		_, err = client.NewClientE(client.WithTLSConfig(nil))
+		require.ErrorIs(t, err, errors.ErrInvalidOption)
+	})
+
+	t.Run("Match the leaf when verification is skipped", func(t *testing.T) {
+		t.Parallel()
+
+		insecure := &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12} //nolint:exhaustruct,gosec
+		require.NoError(t, send(t, client.WithTLSConfig(insecure), client.WithPinnedCerts(pin)))
+		require.ErrorIs(t, send(t, client.WithTLSConfig(insecure), client.WithPinnedCerts(otherPin)), errors.ErrCertificatePin)
+	})
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+)
+
+const (
+	// grpcFrameHeaderSize is the size of the flag and length prefixing gRPC-Web frames.
+	grpcFrameHeaderSize = 5
+	// grpcTrailerFlag marks gRPC-Web frames carrying the trailers.
+	grpcTrailerFlag = 0x80
+	// maxTrailerBlock is the size of trailer frames kept, which is plenty for status trailers.
+	maxTrailerBlock = 64 << 10
+	// grpcUnknown is the gRPC status code used when grpc-status is not a number.
+	grpcUnknown = 2
+)
+
+// withTrailerStatus wraps the body of gRPC and gRPC-Web responses, whose status is only known once
+// the body is consumed, so reading it to the end fails with a *errors.TrailerStatusError when the
+// trailers carry an error status. The trailers of gRPC-Web frames are added to resp.Trailer.
+func withTrailerStatus(resp *http.Response) {
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/grpc") || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+
+	resp.Body = &trailerBody{
+		ReadCloser: resp.Body,
+		resp:       resp,
+		framed:     strings.HasPrefix(contentType, "application/grpc-web") && !strings.HasPrefix(contentType, "application/grpc-web-text"),
+		header:     [grpcFrameHeaderSize]byte{},
+		headerLen:  0,
+		remaining:  0,
+		inTrailer:  false,
+		block:      nil,
+		err:        nil,
+	}
+}
+
+// trailerBody is a response body checking the status trailers once read to the end. For binary
+// gRPC-Web responses, it scans the frames as they are read to collect the trailer frame.
+type trailerBody struct {
+	io.ReadCloser
+	resp      *http.Response
+	framed    bool
+	header    [grpcFrameHeaderSize]byte
+	headerLen int
+	remaining uint32
+	inTrailer bool
+	block     []byte
+	err       error
+}
+
+// Read reads the body, returning the trailer status error instead of io.EOF.
+func (b *trailerBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if b.framed {
+		b.scan(p[:n])
+	}
+	if err == io.EOF { //nolint:errorlint // io.EOF is never wrapped by readers
+		if statusErr := b.status(); statusErr != nil {
+			b.err = statusErr
+			return n, statusErr
+		}
+	}
+	return n, err
+}
+
+// scan follows the gRPC-Web frames in the data, keeping the content of trailer frames.
+func (b *trailerBody) scan(data []byte) {
+	for len(data) > 0 {
+		if b.headerLen < grpcFrameHeaderSize {
+			n := copy(b.header[b.headerLen:], data)
+			b.headerLen += n
+			data = data[n:]
+			if b.headerLen == grpcFrameHeaderSize {
+				b.inTrailer = b.header[0]&grpcTrailerFlag != 0
+				b.remaining = binary.BigEndian.Uint32(b.header[1:])
+				if b.remaining == 0 {
+					b.headerLen = 0
+				}
+			}
+			continue
+		}
+
+		n := min(uint32(len(data)), b.remaining) //nolint:gosec // len is non-negative
+		if b.inTrailer && len(b.block)+int(n) <= maxTrailerBlock {
+			b.block = append(b.block, data[:n]...)
+		}
+		data = data[n:]
+		b.remaining -= n
+		if b.remaining == 0 {
+			b.headerLen = 0
+		}
+	}
+}
+
+// status returns the error described by the status trailers, if any. Trailers-only responses
+// carry the status in their headers instead.
+func (b *trailerBody) status() error {
+	if len(b.block) > 0 {
+		if b.resp.Trailer == nil {
+			b.resp.Trailer = make(http.Header)
+		}
+		for _, line := range strings.Split(string(b.block), "\r\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok {
+				b.resp.Trailer.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+			}
+		}
+		b.block = nil
+	}
+
+	source := b.resp.Trailer
+	if source.Get("Grpc-Status") == "" {
+		source = b.resp.Header
+	}
+	code := source.Get("Grpc-Status")
+	if code == "" || code == "0" {
+		return nil
+	}
+
+	statusErr := &errors.TrailerStatusError{
+		Code:    grpcUnknown,
+		Message: source.Get("Grpc-Message"),
+		URL:     "",
+		Method:  "",
+	}
+	if n, err := strconv.Atoi(code); err == nil {
+		statusErr.Code = n
+	}
+	// The message is percent-encoded
+	if message, err := url.PathUnescape(statusErr.Message); err == nil {
+		statusErr.Message = message
+	}
+	if b.resp.Request != nil {
+		statusErr.URL = b.resp.Request.URL.String()
+		statusErr.Method = b.resp.Request.Method
+	}
+	return statusErr
+}
@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaxron/axonet/pkg/client/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// grpcWebFrame encodes a gRPC-Web frame with the flag and payload.
+func grpcWebFrame(flag byte, payload string) []byte {
+	frame := []byte{flag, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload))) //nolint:gosec
+	return append(frame, payload...)
+}
+
+func TestTrailerStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/grpc":
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+			_, _ = w.Write([]byte("message"))
+			w.Header().Set("Grpc-Status", r.URL.Query().Get("status"))
+			w.Header().Set("Grpc-Message", "try%20again%20later")
+		case "/grpc-web":
+			w.Header().Set("Content-Type", "application/grpc-web+proto")
+			_, _ = w.Write(grpcWebFrame(0x00, "message"))
+			_, _ = w.Write(grpcWebFrame(0x80, "grpc-status: 5\r\ngrpc-message: user not found\r\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewTestClient()
+
+	t.Run("Surface error statuses of HTTP trailers", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := c.NewRequest().URL(server.URL + "/grpc?status=14").DoResponse(context.Background())
+		require.NoError(t, err)
+
+		body, err := resp.Bytes()
+		assert.Equal(t, "message", string(body))
+		require.ErrorIs(t, err, errors.ErrTrailerStatus)
+		assert.True(t, errors.IsTemporary(err))
+
+		var statusErr *errors.TrailerStatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, 14, statusErr.Code)
+		assert.Equal(t, "try again later", statusErr.Message)
+
+		trailers, err := resp.Trailers()
+		require.NoError(t, err)
+		assert.Equal(t, "14", trailers.Get("Grpc-Status"))
+	})
+
+	t.Run("Ignore successful statuses", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := c.NewRequest().URL(server.URL + "/grpc?status=0").DoResponse(context.Background())
+		require.NoError(t, err)
+
+		_, err = resp.Bytes()
+		require.NoError(t, err)
+	})
+
+	t.Run("Parse the trailer frame of gRPC-Web responses", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := c.NewRequest().URL(server.URL + "/grpc-web").Do(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		_, err = io.ReadAll(resp.Body)
+		require.ErrorIs(t, err, errors.ErrTrailerStatus)
+		assert.False(t, errors.IsTemporary(err))
+		assert.Contains(t, err.Error(), "code 5: user not found")
+		assert.Equal(t, "user not found", resp.Trailer.Get("Grpc-Message"))
+	})
+}